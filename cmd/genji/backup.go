@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/badgerengine"
+	"github.com/genjidb/genji/engine/boltengine"
+	bolt "go.etcd.io/bbolt"
+)
+
+// lockTimeout is the time to wait for the lock of a Bolt database used by another process.
+const lockTimeout = time.Second
+
+// openDB opens the database stored at dbPath using the selected engine.
+// Bolt and Badger lock the files of the database: it fails if the database
+// is used by another process.
+func openDB(e, dbPath string) (*genji.DB, error) {
+	if dbPath == "" {
+		return nil, errors.New("db path required")
+	}
+
+	var ng engine.Engine
+	var err error
+
+	switch e {
+	case "bolt":
+		ng, err = boltengine.NewEngine(dbPath, 0660, &bolt.Options{Timeout: lockTimeout})
+		if err == bolt.ErrTimeout {
+			err = fmt.Errorf("database %q is used by another process", dbPath)
+		}
+	case "badger":
+		ng, err = badgerengine.NewEngine(badger.DefaultOptions(dbPath).WithLogger(nil))
+	default:
+		return nil, fmt.Errorf("unknown engine %q", e)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return genji.New(ng)
+}
+
+// runBackupCommand writes a snapshot of the database to the backup file,
+// or to w if the path of the backup file is empty.
+// If portable is true, the snapshot can be restored using any engine.
+// The database must not be used by another process: the snapshot is consistent
+// with the transactions of this process only. Processes using a database can
+// back it up while it is in use by calling DB.Backup.
+func runBackupCommand(e, dbPath, backupPath string, portable bool, w io.Writer) error {
+	db, err := openDB(e, dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	backup := db.DB.Backup
+	if portable {
+		backup = db.DB.Dump
+	}
+
+	if backupPath == "" {
+		return backup(w)
+	}
+
+	f, err := os.Create(backupPath)
+	if err != nil {
+		return err
+	}
+
+	err = backup(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// runRestoreCommand creates a database from the backup file,
+// or from r if the path of the backup file is empty.
+// The database must not exist.
+func runRestoreCommand(e, dbPath, backupPath string, r io.Reader) error {
+	if dbPath == "" {
+		return errors.New("db path required")
+	}
+
+	_, err := os.Stat(dbPath)
+	if err == nil {
+		return fmt.Errorf("database %q already exists", dbPath)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if backupPath != "" {
+		f, err := os.Open(backupPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	db, err := openDB(e, dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.DB.Restore(r)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestoreCommands(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		from, to string
+		portable bool
+	}{
+		{"bolt", "bolt", false},
+		{"badger", "badger", false},
+		{"bolt", "badger", true},
+		{"badger", "bolt", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from+" to "+test.to, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "genji")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			dbPath := filepath.Join(dir, "test.db")
+			db, err := openDB(test.from, dbPath)
+			require.NoError(t, err)
+			err = db.Exec(ctx, `
+				CREATE TABLE foo;
+				CREATE INDEX idx_foo_a ON foo(a);
+				INSERT INTO foo (a) VALUES (1), (2), (3);
+			`)
+			require.NoError(t, err)
+			err = db.Close()
+			require.NoError(t, err)
+
+			backupPath := filepath.Join(dir, "backup")
+			err = runBackupCommand(test.from, dbPath, backupPath, test.portable, nil)
+			require.NoError(t, err)
+
+			// restoring into an existing database must fail
+			err = runRestoreCommand(test.to, dbPath, backupPath, nil)
+			require.Error(t, err)
+
+			restoredPath := filepath.Join(dir, "restored.db")
+			err = runRestoreCommand(test.to, restoredPath, backupPath, nil)
+			require.NoError(t, err)
+
+			db, err = openDB(test.to, restoredPath)
+			require.NoError(t, err)
+			defer db.Close()
+
+			res, err := db.Query(ctx, "SELECT a FROM foo WHERE a >= 2")
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			require.JSONEq(t, `[{"a": 2}, {"a": 3}]`, buf.String())
+		})
+	}
+}
+
+func TestBackupCommandDatabaseInUse(t *testing.T) {
+	for _, e := range []string{"bolt", "badger"} {
+		t.Run(e, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "genji")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			dbPath := filepath.Join(dir, "test.db")
+			db, err := openDB(e, dbPath)
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = runBackupCommand(e, dbPath, filepath.Join(dir, "backup"), false, nil)
+			require.Error(t, err)
+		})
+	}
+}
@@ -10,6 +10,7 @@ require (
 	github.com/genjidb/genji/engine/badgerengine v0.9.0
 	github.com/stretchr/testify v1.6.1
 	github.com/urfave/cli/v2 v2.2.0
+	go.etcd.io/bbolt v1.3.5
 )
 
 replace (
@@ -87,6 +87,82 @@ $ curl https://api.github.com/repos/genjidb/genji/issues | genji insert --db my.
 				return runInsertCommand(c.Context, engine, dbPath, table, c.Bool("auto"), args)
 			},
 		},
+		{
+			Name:      "backup",
+			Usage:     "Write a consistent snapshot of a database",
+			UsageText: "genji backup [options] [file]",
+			Description: `
+The backup command writes a snapshot of a database to a file:
+
+$ genji backup --db my.db my.db.backup
+
+If no file is given, the snapshot is written to standard output:
+
+$ genji backup --db my.db > my.db.backup
+
+By default, the format of the snapshot depends on the engine and can only be
+restored using the same engine. Use the --portable flag to write a snapshot
+that can be restored using any engine:
+
+$ genji backup --db my.db --portable my.db.backup
+$ genji restore -e badger --db my-badger-dir my.db.backup
+
+Bolt and Badger lock the files of the database: the command fails if the database
+is used by another process. Programs using a database can back it up while it is
+in use by calling the DB.Backup method.`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:  "portable",
+					Usage: "write a snapshot that can be restored using any engine",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runBackupCommand(c.String("engine"), c.String("db"), c.Args().First(), c.Bool("portable"), os.Stdout)
+			},
+		},
+		{
+			Name:      "restore",
+			Usage:     "Create a database from a snapshot",
+			UsageText: "genji restore [options] [file]",
+			Description: `
+The restore command creates a database from a snapshot written by the backup command:
+
+$ genji restore --db my.db my.db.backup
+
+If no file is given, the snapshot is read from standard input:
+
+$ genji restore --db my.db < my.db.backup
+
+The database must not exist. Unless the snapshot was written using the --portable flag,
+the database must use the same engine as the backed up database.`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database to create",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runRestoreCommand(c.String("engine"), c.String("db"), c.Args().First(), os.Stdin)
+			},
+		},
 		{
 			Name:  "version",
 			Usage: "Shows Genji and Genji CLI version",
@@ -12,6 +12,7 @@ import (
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/scanner"
 )
 
 var commands = []struct {
@@ -103,7 +104,7 @@ func displayTableIndex(db *genji.DB, tableName string) error {
 				return err
 			}
 
-			fmt.Printf("%s ON %s (%s)\n", index.IndexName, index.TableName, index.Path)
+			fmt.Printf("%s ON %s (%s)%s\n", index.IndexName, index.TableName, index.PathsString(), whereClause(&index))
 
 			return nil
 		})
@@ -127,13 +128,23 @@ func displayAllIndexes(db *genji.DB) error {
 			return err
 		}
 
-		fmt.Printf("%s ON %s (%s)\n", index.IndexName, index.TableName, index.Path)
+		fmt.Printf("%s ON %s (%s)%s\n", index.IndexName, index.TableName, index.PathsString(), whereClause(&index))
 
 		return nil
 	})
 
 }
 
+// whereClause returns the WHERE clause of partial indexes, preceded by a space,
+// or an empty string.
+func whereClause(index *database.IndexConfig) string {
+	if index.Where == "" {
+		return ""
+	}
+
+	return " WHERE " + index.Where
+}
+
 // runIndexesCmd executes all indexes of the database or all indexes of the given table.
 func runIndexesCmd(db *genji.DB, in []string) error {
 	switch len(in) {
@@ -198,6 +209,29 @@ func displaySuggestions(in string) error {
 	return nil
 }
 
+// identEscaper escapes the characters of identifiers enclosed in backquotes.
+var identEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`", "\n", "\\n")
+
+// quoteIdent encloses an identifier in backquotes, unless it only contains
+// letters, digits and underscores, doesn't start with a digit and isn't a keyword.
+func quoteIdent(ident string) string {
+	plain := ident != "" && scanner.Lookup(ident) == scanner.IDENT
+	for i, r := range ident {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && (!isDigit || i == 0) {
+			plain = false
+			break
+		}
+	}
+
+	if plain {
+		return ident
+	}
+
+	return "`" + identEscaper.Replace(ident) + "`"
+}
+
 // dumpTable displays the content of the given table as SQL statements.
 func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 	var buf bytes.Buffer
@@ -218,10 +252,26 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 
 	fcs := ti.FieldConstraints
 	// Fields constraints should be displayed between parenthesis.
-	if len(fcs) > 0 {
+	if len(fcs) > 0 || len(ti.CheckConstraints) > 0 {
 		buf.WriteString(" (\n")
 	}
 
+	// check constraints declared on a field are displayed with the field,
+	// the others after the fields.
+	fieldChecks := make(map[string][]database.CheckConstraint)
+	for _, fc := range fcs {
+		fieldChecks[fc.Path.String()] = nil
+	}
+
+	var tableChecks []database.CheckConstraint
+	for _, cc := range ti.CheckConstraints {
+		if _, ok := fieldChecks[cc.Path.String()]; ok && len(cc.Path) > 0 {
+			fieldChecks[cc.Path.String()] = append(fieldChecks[cc.Path.String()], cc)
+		} else {
+			tableChecks = append(tableChecks, cc)
+		}
+	}
+
 	for i, fc := range fcs {
 		// Don't display the last comma.
 		if i > 0 {
@@ -237,10 +287,22 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 		if fc.IsNotNull {
 			buf.WriteString(" NOT NULL")
 		}
+
+		for _, cc := range fieldChecks[fc.Path.String()] {
+			fmt.Fprintf(&buf, " CONSTRAINT %s CHECK (%s)", quoteIdent(cc.Name), cc.Expr)
+		}
+	}
+
+	for i, cc := range tableChecks {
+		if i > 0 || len(fcs) > 0 {
+			buf.WriteString(",\n")
+		}
+
+		fmt.Fprintf(&buf, "  CONSTRAINT %s CHECK (%s)", quoteIdent(cc.Name), cc.Expr)
 	}
 
 	// Fields constraints close parenthesis.
-	if len(fcs) > 0 {
+	if len(fcs) > 0 || len(ti.CheckConstraints) > 0 {
 		buf.WriteString("\n);\n")
 	} else {
 		buf.WriteString(";\n")
@@ -263,9 +325,12 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 		if index.Opts.Unique {
 			u = " UNIQUE"
 		}
+		if index.Opts.FullText {
+			u = " FULLTEXT"
+		}
 
-		_, err = fmt.Fprintf(w, "CREATE%s INDEX %s ON %s (%s);\n", u, index.Opts.IndexName, index.Opts.TableName,
-			index.Opts.Path)
+		_, err = fmt.Fprintf(w, "CREATE%s INDEX %s ON %s (%s)%s;\n", u, index.Opts.IndexName, index.Opts.TableName,
+			index.Opts.PathsString(), whereClause(&index.Opts))
 		if err != nil {
 			return err
 		}
@@ -99,13 +99,16 @@ func TestRunDumpCmd(t *testing.T) {
 		{"Values / With columns", `INSERT INTO test (a, b, c) VALUES ('a', 'b', 'c')`, ``, `INSERT INTO test VALUES {"a": "a", "b": "b", "c": "c"};`, false, nil},
 		{"text / not null with type constraint", `INSERT INTO test (a, b, c) VALUES ('a', 'b', 'c')`, `TEXT NOT NULL`, `INSERT INTO test VALUES {"a": "a", "b": "b", "c": "c"};`, false, nil},
 		{"text / pk and not null with type constraint", `INSERT INTO test (a, b, c) VALUES ('a', 'b', 'c')`, `TEXT PRIMARY KEY NOT NULL`, `INSERT INTO test VALUES {"a": "a", "b": "b", "c": "c"};`, false, nil},
+		{"text / check constraint", `INSERT INTO test (a, b, c) VALUES ('a', 'b', 'c')`, `TEXT CONSTRAINT test_a_check CHECK (a != '')`, `INSERT INTO test VALUES {"a": "a", "b": "b", "c": "c"};`, false, nil},
+		{"text / table check constraint", `INSERT INTO test (a, b, c) VALUES ('a', 'b', 'c')`, "TEXT,\n  CONSTRAINT test_check CHECK (b < c)", `INSERT INTO test VALUES {"a": "a", "b": "b", "c": "c"};`, false, nil},
+		{"text / quoted check constraint", `INSERT INTO test (a, b, c) VALUES ('a', 'b', 'c')`, "TEXT CONSTRAINT `a check` CHECK (a != ''),\n  CONSTRAINT `select` CHECK (b < c),\n  CONSTRAINT `b\\`c` CHECK (b != c)", `INSERT INTO test VALUES {"a": "a", "b": "b", "c": "c"};`, false, nil},
 	}
 
 	ctx := context.Background()
 
 	for _, tt := range tests {
 
-		testFn := func(withIndexes, withConstraints bool) func(t *testing.T) {
+		testFn := func(withIndexes, withConstraints bool, where string) func(t *testing.T) {
 			return func(t *testing.T) {
 				db, err := genji.Open(":memory:")
 				require.NoError(t, err)
@@ -130,9 +133,11 @@ func TestRunDumpCmd(t *testing.T) {
 				}
 
 				if withIndexes {
-					err = db.Exec(ctx, `
-						CREATE INDEX idx_a ON test (a);
-					`)
+					q := `CREATE INDEX idx_a ON test (a)`
+					if where != "" {
+						q += " WHERE " + where
+					}
+					err = db.Exec(ctx, q)
 					require.NoError(t, err)
 					err = db.View(func(tx *genji.Tx) error {
 						// indexes is unordered, we cannot guess the order.
@@ -140,9 +145,12 @@ func TestRunDumpCmd(t *testing.T) {
 						indexes, err := tx.ListIndexes()
 						require.NoError(t, err)
 						for _, index := range indexes {
-							info := fmt.Sprintf("CREATE INDEX %s ON %s (%s);\n", index.IndexName, index.TableName,
-								index.Path)
-							bwant.WriteString(info)
+							info := fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index.IndexName, index.TableName,
+								index.PathsString())
+							if index.Where != "" {
+								info += fmt.Sprintf(" WHERE %s", index.Where)
+							}
+							bwant.WriteString(info + ";\n")
 						}
 						return nil
 					})
@@ -167,9 +175,10 @@ func TestRunDumpCmd(t *testing.T) {
 			}
 		}
 
-		t.Run("No Index/"+tt.name, testFn(false, false))
-		t.Run("With Index/"+tt.name, testFn(true, false))
-		t.Run("With FieldsConstraints/"+tt.name, testFn(true, true))
+		t.Run("No Index/"+tt.name, testFn(false, false, ""))
+		t.Run("With Index/"+tt.name, testFn(true, false, ""))
+		t.Run("With Partial Index/"+tt.name, testFn(true, false, "a IS NOT NULL"))
+		t.Run("With FieldsConstraints/"+tt.name, testFn(true, true, ""))
 	}
 
 }
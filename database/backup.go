@@ -0,0 +1,205 @@
+package database
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// dumpHeader is written at the beginning of the snapshots written by Dump.
+const dumpHeader = "genji-dump\x00"
+
+// Backup writes a consistent snapshot of the database to w.
+// If the engine implements the engine.Backuper interface, the snapshot is written
+// using the format of the engine, otherwise it is written by Dump.
+func (db *Database) Backup(w io.Writer) error {
+	if b, ok := db.ng.(engine.Backuper); ok {
+		return b.Backup(w)
+	}
+
+	return db.Dump(w)
+}
+
+// Dump writes a consistent snapshot of the database to w, using a format that
+// doesn't depend on the engine.
+// The tables are read within a read-only transaction and written using engine.DumpStore.
+// Indexes are not part of the snapshot, they are rebuilt by Restore.
+// Neither are the statistics computed by Analyze.
+func (db *Database) Dump(w io.Writer) error {
+	tx, err := db.ng.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = io.WriteString(w, dumpHeader)
+	if err != nil {
+		return err
+	}
+
+	st, err := tx.GetStore([]byte(tableInfoStoreName))
+	if err != nil {
+		return err
+	}
+
+	// read the list of tables from the snapshot rather than from
+	// the cache, which may contain uncommitted tables.
+	var infos []TableInfo
+	var seq uint64
+	it := st.NewIterator(engine.IteratorConfig{})
+	var b []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		b, err = it.Item().ValueCopy(b)
+		if err != nil {
+			it.Close()
+			return err
+		}
+
+		var ti TableInfo
+		err = ti.ScanDocument(db.Codec.NewDocument(b))
+		if err != nil {
+			it.Close()
+			return err
+		}
+		infos = append(infos, ti)
+
+		// store names are generated using the sequence of the store
+		if n, _ := binary.Uvarint(ti.storeName[1:]); n > seq {
+			seq = n
+		}
+	}
+	err = it.Close()
+	if err != nil {
+		return err
+	}
+
+	err = engine.DumpStore(w, []byte(tableInfoStoreName), seq, st)
+	if err != nil {
+		return err
+	}
+
+	st, err = tx.GetStore([]byte(indexStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = engine.DumpStore(w, []byte(indexStoreName), 0, st)
+	if err != nil {
+		return err
+	}
+
+	for _, ti := range infos {
+		st, err = tx.GetStore(ti.storeName)
+		if err != nil {
+			return err
+		}
+
+		seq = 0
+		// tables without primary key generate keys using the sequence of the store
+		if ti.GetPrimaryKey() == nil {
+			seq, err = maxDocID(st)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = engine.DumpStore(w, ti.storeName, seq, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxDocID returns the greatest key generated by the sequence of the store.
+// Keys are encoded as uvarints, which don't preserve ordering, so every key must be read.
+func maxDocID(st engine.Store) (uint64, error) {
+	var max uint64
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	for it.Seek(nil); it.Valid(); it.Next() {
+		if n, _ := binary.Uvarint(it.Item().Key()); n > max {
+			max = n
+		}
+	}
+
+	return max, it.Close()
+}
+
+// Restore loads a snapshot written by Backup or Dump into the database, which must be empty.
+// No other transaction must be running during the restoration.
+// Snapshots written by Dump are loaded using engine.LoadStores and every index is rebuilt.
+// Otherwise, the engine must implement the engine.Restorer interface.
+func (db *Database) Restore(r io.Reader) (err error) {
+	// the snapshot replaces the list of tables, which must be reloaded
+	// whether the restoration succeeds or not.
+	defer func() {
+		lerr := db.loadTableInfo()
+		if err == nil {
+			err = lerr
+		}
+	}()
+
+	br := bufio.NewReader(r)
+	h, err := br.Peek(len(dumpHeader))
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if string(h) != dumpHeader {
+		rs, ok := db.ng.(engine.Restorer)
+		if !ok {
+			return errors.New("unsupported snapshot format")
+		}
+
+		return rs.Restore(br)
+	}
+
+	_, err = br.Discard(len(dumpHeader))
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = engine.LoadStores(tx.tx, br)
+	if err != nil {
+		return err
+	}
+
+	tx.indexStore, err = tx.getIndexStore()
+	if err != nil {
+		return err
+	}
+
+	err = db.tableInfoStore.loadAllTableInfo(tx.tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.ReIndexAll()
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadTableInfo reloads the list of tables from the engine.
+func (db *Database) loadTableInfo() error {
+	tx, err := db.ng.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	return db.tableInfoStore.loadAllTableInfo(tx)
+}
@@ -0,0 +1,127 @@
+package database_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+// basicEngine hides the engine.Backuper and engine.Restorer
+// implementations of the underlying engine.
+type basicEngine struct {
+	engine.Engine
+}
+
+func TestBackupRestore(t *testing.T) {
+	tests := []struct {
+		name   string
+		ng     func() engine.Engine
+		backup func(db *database.Database, w io.Writer) error
+	}{
+		{"engine.Backuper", func() engine.Engine { return memoryengine.NewEngine() }, (*database.Database).Backup},
+		{"generic", func() engine.Engine { return basicEngine{memoryengine.NewEngine()} }, (*database.Database).Backup},
+		{"dump", func() engine.Engine { return memoryengine.NewEngine() }, (*database.Database).Dump},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := database.New(test.ng(), database.Options{Codec: msgpack.NewCodec()})
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.CreateTable("test1", nil)
+			require.NoError(t, err)
+			err = tx.CreateTable("test2", &database.TableInfo{
+				FieldConstraints: []database.FieldConstraint{
+					{Path: parsePath(t, "a"), IsPrimaryKey: true},
+				},
+			})
+			require.NoError(t, err)
+			err = tx.CreateIndex(database.IndexConfig{
+				IndexName: "idx_test1_a",
+				TableName: "test1",
+				Paths:     []document.ValuePath{parsePath(t, "a")},
+			})
+			require.NoError(t, err)
+
+			for _, name := range []string{"test1", "test2"} {
+				tb, err := tx.GetTable(name)
+				require.NoError(t, err)
+
+				for i := int64(0); i < 10; i++ {
+					_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(i)))
+					require.NoError(t, err)
+				}
+			}
+
+			err = tx.Commit()
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = test.backup(db, &buf)
+			require.NoError(t, err)
+
+			db2, err := database.New(test.ng(), database.Options{Codec: msgpack.NewCodec()})
+			require.NoError(t, err)
+			defer db2.Close()
+
+			err = db2.Restore(&buf)
+			require.NoError(t, err)
+
+			tx, err = db2.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			for _, name := range []string{"test1", "test2"} {
+				tb, err := tx.GetTable(name)
+				require.NoError(t, err)
+
+				var count int
+				err = tb.Iterate(func(d document.Document) error {
+					count++
+					return nil
+				})
+				require.NoError(t, err)
+				require.Equal(t, 10, count)
+			}
+
+			// the sequence of the table must be restored to avoid
+			// generating keys that already exist.
+			tb, err := tx.GetTable("test1")
+			require.NoError(t, err)
+			_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10)))
+			require.NoError(t, err)
+
+			idx, err := tx.GetIndex("idx_test1_a")
+			require.NoError(t, err)
+
+			var count int
+			err = idx.AscendGreaterOrEqual(document.Value{Type: document.IntegerValue}, func(v, k []byte, isEqual bool) error {
+				count++
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, 11, count)
+		})
+	}
+}
+
+func TestRestoreUnsupportedFormat(t *testing.T) {
+	db, err := database.New(basicEngine{memoryengine.NewEngine()}, database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Restore(bytes.NewReader([]byte("not a snapshot")))
+	require.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"sync"
+
+	"github.com/genjidb/genji/document"
+)
+
+// ChangeType describes the operation that modified a document.
+type ChangeType int
+
+// List of change types.
+const (
+	// The document was inserted.
+	ChangeInsert ChangeType = iota + 1
+	// The document was replaced by another one.
+	ChangeReplace
+	// The document was deleted.
+	ChangeDelete
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeInsert:
+		return "insert"
+	case ChangeReplace:
+		return "replace"
+	case ChangeDelete:
+		return "delete"
+	}
+
+	return ""
+}
+
+// A Change describes the modification of a document of a table.
+type Change struct {
+	Type  ChangeType
+	Table string
+	Key   []byte
+	// Old is the document before the change. It is nil for inserts.
+	Old document.Document
+	// New is the document after the change. It is nil for deletes.
+	New document.Document
+}
+
+// changeFeed dispatches the changes made by committed transactions
+// to the subscribers.
+type changeFeed struct {
+	mu     sync.RWMutex
+	lastID int
+	// subscribers keyed by table name, then by subscription id.
+	// Subscribers registered for every table use the empty string.
+	subs map[string]map[int]func(Change)
+}
+
+func (f *changeFeed) subscribe(table string, fn func(Change)) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.subs == nil {
+		f.subs = make(map[string]map[int]func(Change))
+	}
+	if f.subs[table] == nil {
+		f.subs[table] = make(map[int]func(Change))
+	}
+
+	f.lastID++
+	id := f.lastID
+	f.subs[table][id] = fn
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		delete(f.subs[table], id)
+		if len(f.subs[table]) == 0 {
+			delete(f.subs, table)
+		}
+	}
+}
+
+// watched returns true if changes made to the given table have at least one subscriber.
+func (f *changeFeed) watched(table string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.subs[table]) > 0 || len(f.subs[""]) > 0
+}
+
+// publish calls the subscribers of each change, in order.
+func (f *changeFeed) publish(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, c := range changes {
+		f.mu.RLock()
+		fns := make([]func(Change), 0, len(f.subs[c.Table])+len(f.subs[""]))
+		for _, fn := range f.subs[c.Table] {
+			fns = append(fns, fn)
+		}
+		for _, fn := range f.subs[""] {
+			fns = append(fns, fn)
+		}
+		f.mu.RUnlock()
+
+		for _, fn := range fns {
+			fn(c)
+		}
+	}
+}
+
+// Subscribe registers fn to be called for every document inserted, replaced or deleted
+// in the given table, or in any table if table is empty.
+// Changes are published after the transaction that made them is successfully committed,
+// in the order they were made. Rolled back transactions publish nothing.
+// fn is called synchronously by Commit, after the transaction is closed, and can be called
+// concurrently by transactions committed at the same time.
+// Truncating or dropping a table doesn't publish any change.
+// Subscribe returns a function that cancels the subscription.
+func (db *Database) Subscribe(table string, fn func(Change)) (unsubscribe func()) {
+	return db.changeFeed.subscribe(table, fn)
+}
+
+// recordChange keeps track of the change until the transaction is committed.
+func (tx *Transaction) recordChange(c Change) {
+	tx.changes = append(tx.changes, c)
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/index"
+	"github.com/genjidb/genji/key"
 )
 
 const storePrefix = 't'
@@ -81,6 +83,59 @@ func (f *FieldConstraint) ScanDocument(d document.Document) error {
 	return nil
 }
 
+// CheckConstraint is a predicate that the documents of a table must satisfy.
+// Documents for which the predicate is false are rejected. Like in SQL,
+// documents for which it is NULL, e.g. because a field is missing, are accepted.
+type CheckConstraint struct {
+	// Name of the constraint, reported when a document violates it.
+	Name string
+	// Path of the field the constraint was declared on, if any.
+	// It is empty for constraints declared on the table.
+	Path document.ValuePath
+	// Text of the predicate, parsed using the ParseExpr option of the database.
+	Expr string
+}
+
+// ToDocument returns a document from c.
+func (c *CheckConstraint) ToDocument() document.Document {
+	buf := document.NewFieldBuffer()
+
+	buf.Add("name", document.NewTextValue(c.Name))
+	if len(c.Path) > 0 {
+		buf.Add("path", document.NewArrayValue(valuePathToArray(c.Path)))
+	}
+	buf.Add("expr", document.NewTextValue(c.Expr))
+	return buf
+}
+
+// ScanDocument implements the document.Scanner interface.
+func (c *CheckConstraint) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("name")
+	if err != nil {
+		return err
+	}
+	c.Name = v.V.(string)
+
+	v, err = d.GetByField("path")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		c.Path, err = arrayToValuePath(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err = d.GetByField("expr")
+	if err != nil {
+		return err
+	}
+	c.Expr = v.V.(string)
+
+	return nil
+}
+
 // TableInfo contains information about a table.
 type TableInfo struct {
 	// name of the table.
@@ -93,6 +148,7 @@ type TableInfo struct {
 	transactionID int64
 
 	FieldConstraints []FieldConstraint
+	CheckConstraints []CheckConstraint
 }
 
 // GetPrimaryKey returns the field constraint of the primary key.
@@ -121,6 +177,15 @@ func (ti *TableInfo) ToDocument() document.Document {
 
 	buf.Add("field_constraints", document.NewArrayValue(vbuf))
 
+	if len(ti.CheckConstraints) > 0 {
+		vbuf = document.NewValueBuffer()
+		for _, cc := range ti.CheckConstraints {
+			vbuf = vbuf.Append(document.NewDocumentValue(cc.ToDocument()))
+		}
+
+		buf.Add("check_constraints", document.NewArrayValue(vbuf))
+	}
+
 	buf.Add("read_only", document.NewBoolValue(ti.readOnly))
 	return buf
 }
@@ -160,6 +225,28 @@ func (ti *TableInfo) ScanDocument(d document.Document) error {
 		return err
 	}
 
+	// tables created by previous versions have no check constraints.
+	v, err = d.GetByField("check_constraints")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.CheckConstraints = ti.CheckConstraints[:0]
+		err = v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+			var cc CheckConstraint
+			err := cc.ScanDocument(value.V.(document.Document))
+			if err != nil {
+				return err
+			}
+
+			ti.CheckConstraints = append(ti.CheckConstraints, cc)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	v, err = d.GetByField("read_only")
 	if err != nil {
 		return err
@@ -439,13 +526,89 @@ func (t *tableInfoStore) GetTableInfo() map[string]TableInfo {
 type IndexConfig struct {
 	TableName string
 	IndexName string
-	Path      document.ValuePath
+
+	// Paths of the indexed fields. Indexes created on more than one path
+	// are composite indexes: they index an array containing the value of each path,
+	// in order.
+	Paths []document.ValuePath
 
 	// If set to true, values will be associated with at most one key. False by default.
 	Unique bool
 
 	// If set, the index is typed and only accepts that type
 	Type document.ValueType
+
+	// If true, the index is a multi-valued index created on a single path.
+	// It stores one entry per distinct element of the array found at that path,
+	// and nothing if the document doesn't contain an array at that path.
+	Multi bool
+
+	// If true, the index is a full-text index created on a single path.
+	// It stores the words of the texts found at that path, and nothing if
+	// the document doesn't contain a text at that path.
+	FullText bool
+
+	// If set, the index is an expression index: it indexes the value of this
+	// expression instead of the value of paths, and Paths is empty. It is the text
+	// of the expression, parsed using the ParseExpr option of the database.
+	Expr string
+
+	// If set, the index is a partial index: only the documents
+	// matching this predicate are indexed. It is the text of
+	// the expression, parsed using the ParseExpr option of the database.
+	Where string
+}
+
+// IsComposite returns true if the index is created on more than one path.
+func (i *IndexConfig) IsComposite() bool {
+	return len(i.Paths) > 1
+}
+
+// PathsString returns the paths of the index separated by commas,
+// or the indexed expression if the index is an expression index.
+// The path of multi-valued indexes is followed by "[*]".
+func (i *IndexConfig) PathsString() string {
+	if i.Expr != "" {
+		return i.Expr
+	}
+
+	if i.Multi {
+		return i.Paths[0].String() + "[*]"
+	}
+
+	var sb strings.Builder
+
+	for j, path := range i.Paths {
+		if j > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(path.String())
+	}
+
+	return sb.String()
+}
+
+// Value returns the value of d to store in the index.
+// For composite indexes, it returns an array containing the value of each path,
+// missing fields being replaced by NULL.
+func (i *IndexConfig) Value(d document.Document) (document.Value, error) {
+	if !i.IsComposite() {
+		return i.Paths[0].GetValue(d)
+	}
+
+	vb := document.NewValueBuffer()
+	for _, path := range i.Paths {
+		v, err := path.GetValue(d)
+		if err == document.ErrFieldNotFound {
+			v = document.NewNullValue()
+		} else if err != nil {
+			return v, err
+		}
+
+		vb = vb.Append(v)
+	}
+
+	return document.NewArrayValue(vb), nil
 }
 
 // ToDocument creates a document from an IndexConfig.
@@ -455,10 +618,27 @@ func (i *IndexConfig) ToDocument() document.Document {
 	buf.Add("unique", document.NewBoolValue(i.Unique))
 	buf.Add("index_name", document.NewTextValue(i.IndexName))
 	buf.Add("table_name", document.NewTextValue(i.TableName))
-	buf.Add("path", document.NewArrayValue(valuePathToArray(i.Path)))
+
+	paths := document.NewValueBuffer()
+	for _, path := range i.Paths {
+		paths = paths.Append(document.NewArrayValue(valuePathToArray(path)))
+	}
+	buf.Add("paths", document.NewArrayValue(paths))
 	if i.Type != 0 {
 		buf.Add("type", document.NewIntegerValue(int64(i.Type)))
 	}
+	if i.Multi {
+		buf.Add("multi", document.NewBoolValue(i.Multi))
+	}
+	if i.FullText {
+		buf.Add("fulltext", document.NewBoolValue(i.FullText))
+	}
+	if i.Expr != "" {
+		buf.Add("expr", document.NewTextValue(i.Expr))
+	}
+	if i.Where != "" {
+		buf.Add("where", document.NewTextValue(i.Where))
+	}
 	return buf
 }
 
@@ -482,21 +662,77 @@ func (i *IndexConfig) ScanDocument(d document.Document) error {
 	}
 	i.TableName = string(v.V.(string))
 
-	v, err = d.GetByField("path")
-	if err != nil {
+	v, err = d.GetByField("paths")
+	if err == document.ErrFieldNotFound {
+		// indexes created by previous versions only have one path.
+		v, err = d.GetByField("path")
+		if err != nil {
+			return err
+		}
+
+		path, err := arrayToValuePath(v)
+		if err != nil {
+			return err
+		}
+		i.Paths = []document.ValuePath{path}
+	} else {
+		if err != nil {
+			return err
+		}
+
+		i.Paths = i.Paths[:0]
+		err = v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+			path, err := arrayToValuePath(value)
+			if err != nil {
+				return err
+			}
+
+			i.Paths = append(i.Paths, path)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err = d.GetByField("type")
+	if err != nil && err != document.ErrFieldNotFound {
 		return err
 	}
-	i.Path, err = arrayToValuePath(v)
-	if err != nil {
+	if err == nil {
+		i.Type = document.ValueType(v.V.(int64))
+	}
+
+	v, err = d.GetByField("multi")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		i.Multi = v.V.(bool)
+	}
+
+	v, err = d.GetByField("fulltext")
+	if err != nil && err != document.ErrFieldNotFound {
 		return err
 	}
+	if err == nil {
+		i.FullText = v.V.(bool)
+	}
 
-	v, err = d.GetByField("type")
+	v, err = d.GetByField("expr")
 	if err != nil && err != document.ErrFieldNotFound {
 		return err
 	}
 	if err == nil {
-		i.Type = document.ValueType(v.V.(int64))
+		i.Expr = v.V.(string)
+	}
+
+	v, err = d.GetByField("where")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		i.Where = v.V.(string)
 	}
 
 	return nil
@@ -507,6 +743,145 @@ func (i *IndexConfig) ScanDocument(d document.Document) error {
 type Index struct {
 	*index.Index
 	Opts IndexConfig
+
+	tx *Transaction
+	// inverted index of full-text indexes, stored in the same store as Index.
+	fullText *index.FullTextIndex
+	// indexed expression of expression indexes.
+	expr Expr
+	// predicate of partial indexes.
+	where Expr
+}
+
+// Expr returns the indexed expression of expression indexes, or nil.
+func (idx *Index) Expr() Expr {
+	return idx.expr
+}
+
+// FullText returns the inverted index of full-text indexes, or nil.
+func (idx *Index) FullText() *index.FullTextIndex {
+	return idx.fullText
+}
+
+// Set associates a value with a key. Full-text indexes store the words of the value.
+func (idx *Index) Set(v document.Value, k []byte) error {
+	if idx.fullText != nil {
+		return idx.fullText.Set(v, k)
+	}
+
+	return idx.Index.Set(v, k)
+}
+
+// Delete removes the association between a value and a key.
+// Full-text indexes remove the words of the value.
+func (idx *Index) Delete(v document.Value, k []byte) error {
+	if idx.fullText != nil {
+		return idx.fullText.Delete(v, k)
+	}
+
+	return idx.Index.Delete(v, k)
+}
+
+// Predicate returns the predicate of partial indexes, or nil.
+func (idx *Index) Predicate() Expr {
+	return idx.where
+}
+
+// Value returns the value of d to store in the index: the value of the
+// indexed paths or the result of the indexed expression.
+func (idx *Index) Value(d document.Document) (document.Value, error) {
+	if idx.expr == nil {
+		return idx.Opts.Value(d)
+	}
+
+	return idx.expr.Eval(idx.tx, d)
+}
+
+// Values returns the values of d to store in the index. Multi-valued indexes
+// store every distinct element of the indexed array, full-text indexes store
+// the indexed text if any, other indexes store a single value.
+func (idx *Index) Values(d document.Document) ([]document.Value, error) {
+	v, err := idx.Value(d)
+	if idx.Opts.FullText {
+		if err == document.ErrFieldNotFound || (err == nil && v.Type != document.TextValue) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return []document.Value{v}, nil
+	}
+
+	if !idx.Opts.Multi {
+		if err != nil {
+			return nil, err
+		}
+
+		return []document.Value{v}, nil
+	}
+
+	if err == document.ErrFieldNotFound || (err == nil && v.Type != document.ArrayValue) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var vb document.ValueBuffer
+	seen := make(map[string]struct{})
+	err = v.V.(document.Array).Iterate(func(_ int, elem document.Value) error {
+		elem = MultiIndexValue(elem)
+
+		k, err := key.AppendValue(nil, elem)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[string(k)]; ok {
+			return nil
+		}
+		seen[string(k)] = struct{}{}
+
+		vb = vb.Append(elem)
+		return nil
+	})
+	return vb, err
+}
+
+// MultiIndexValue returns the value stored in multi-valued indexes for an element of
+// an indexed array. Integers and doubles are encoded differently, so integers are
+// stored as doubles if they can be converted without loss, the same way they are
+// compared. Values looked up in multi-valued indexes must be converted as well.
+func MultiIndexValue(v document.Value) document.Value {
+	switch v.Type {
+	case document.IntegerValue:
+		i := v.V.(int64)
+		if f := float64(i); int64(f) == i {
+			return document.NewDoubleValue(f)
+		}
+	case document.DoubleValue:
+		// -0 and 0 are equal
+		if v.V.(float64) == 0 {
+			return document.NewDoubleValue(0)
+		}
+	}
+
+	return v
+}
+
+// Matches reports whether d must be stored in the index.
+// Partial indexes only store the documents matching their predicate.
+func (idx *Index) Matches(d document.Document) (bool, error) {
+	if idx.where == nil {
+		return true, nil
+	}
+
+	v, err := idx.where.Eval(idx.tx, d)
+	if err != nil {
+		return false, err
+	}
+
+	return v.IsTruthy()
 }
 
 type indexStore struct {
@@ -23,6 +23,10 @@ func TestTableInfo(t *testing.T) {
 		FieldConstraints: []FieldConstraint{
 			{Path: newValuePath("k"), Type: document.DoubleValue, IsPrimaryKey: true},
 		},
+		CheckConstraints: []CheckConstraint{
+			{Name: "k_check", Path: newValuePath("k"), Expr: "k > 0"},
+			{Name: "check", Expr: "k < 10"},
+		},
 	}
 
 	doc := info.ToDocument()
@@ -30,6 +34,7 @@ func TestTableInfo(t *testing.T) {
 	var res TableInfo
 	err := res.ScanDocument(doc)
 	require.NoError(t, err)
+	require.Equal(t, info.CheckConstraints, res.CheckConstraints)
 }
 
 func TestTableInfoStore(t *testing.T) {
@@ -157,8 +162,10 @@ func TestIndexStore(t *testing.T) {
 		cfg := IndexConfig{
 			TableName: "test",
 			IndexName: "idx_test",
+			Paths:     []document.ValuePath{newValuePath("a"), newValuePath("b")},
 			Unique:    true,
 			Type:      document.BoolValue,
+			Where:     "c > 10",
 		}
 
 		err = idxs.Insert(cfg)
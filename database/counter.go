@@ -0,0 +1,98 @@
+package database
+
+import (
+	"github.com/genjidb/genji/engine"
+)
+
+// A KeyCounter counts the keys read by a transaction from the stores
+// of the tables and of the indexes.
+type KeyCounter struct {
+	// TableKeys is the number of keys read from the stores of the tables.
+	TableKeys int64
+	// IndexKeys is the number of keys read from the stores of the indexes.
+	IndexKeys int64
+}
+
+// CountKeys makes the tables and indexes returned by the transaction count the keys
+// they read using c. Tables and indexes returned before the call are not affected.
+// Calling it with nil stops counting.
+func (tx *Transaction) CountKeys(c *KeyCounter) {
+	tx.keyCounter = c
+}
+
+// tableStore returns st, counting the keys read from it if needed.
+func (tx *Transaction) tableStore(st engine.Store) engine.Store {
+	if tx.keyCounter == nil {
+		return st
+	}
+
+	return &countingStore{Store: st, count: &tx.keyCounter.TableKeys}
+}
+
+// indexTx returns the engine transaction used by the indexes, which counts
+// the keys they read if needed.
+func (tx *Transaction) indexTx() engine.Transaction {
+	if tx.keyCounter == nil {
+		return tx.tx
+	}
+
+	return &countingTransaction{Transaction: tx.tx, count: &tx.keyCounter.IndexKeys}
+}
+
+// countingTransaction returns stores that count the keys read from them.
+type countingTransaction struct {
+	engine.Transaction
+
+	count *int64
+}
+
+func (t *countingTransaction) GetStore(name []byte) (engine.Store, error) {
+	st, err := t.Transaction.GetStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingStore{Store: st, count: t.count}, nil
+}
+
+// countingStore counts the keys returned by Get and by its iterators.
+type countingStore struct {
+	engine.Store
+
+	count *int64
+}
+
+func (s *countingStore) Get(k []byte) ([]byte, error) {
+	v, err := s.Store.Get(k)
+	if err == nil {
+		*s.count++
+	}
+
+	return v, err
+}
+
+func (s *countingStore) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return &countingIterator{Iterator: s.Store.NewIterator(cfg), count: s.count}
+}
+
+type countingIterator struct {
+	engine.Iterator
+
+	count *int64
+}
+
+func (it *countingIterator) Seek(k []byte) {
+	it.Iterator.Seek(k)
+	it.countItem()
+}
+
+func (it *countingIterator) Next() {
+	it.Iterator.Next()
+	it.countItem()
+}
+
+func (it *countingIterator) countItem() {
+	if it.Iterator.Valid() {
+		*it.count++
+	}
+}
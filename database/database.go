@@ -3,9 +3,11 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/engine"
 )
@@ -22,19 +24,51 @@ type Database struct {
 	// incremented atomically every time Begin is called.
 	lastTransactionID int64
 
-	// If this is non-nil, the user is running an explicit transaction
-	// using the BEGIN statement.
-	// Only one attached transaction can be run at a time and any calls to DB.Begin()
-	// will cause an error until that transaction is rolled back or commited.
-	attachedTransaction *Transaction
-	attachedTxMu        sync.Mutex
+	// session used by transactions attached to the database
+	// rather than to a specific session.
+	session *Session
+
+	// changeFeed publishes the changes made by committed transactions.
+	changeFeed changeFeed
 
 	// Codec used to encode documents. Defaults to MessagePack.
 	Codec encoding.Codec
+
+	// Maximum number of bytes used to sort documents in memory.
+	// Beyond that, sorted documents are written to temporary files.
+	// It also limits the memory used to remove duplicates by DISTINCT,
+	// UNION, INTERSECT and EXCEPT.
+	// Defaults to DefaultSortBufferSize.
+	SortBufferSize int
+
+	// ParseExpr parses the expressions stored in the catalog,
+	// such as the predicates of partial indexes.
+	ParseExpr func(s string) (Expr, error)
+
+	// expressions already parsed by ParseExpr, keyed by their text.
+	exprs sync.Map
 }
 
+// An Expr is an expression stored in the catalog as text and evaluated
+// against the documents of a table, such as the predicate of a partial index.
+type Expr interface {
+	Eval(tx *Transaction, d document.Document) (document.Value, error)
+}
+
+// DefaultSortBufferSize is the default maximum number of bytes
+// used to sort documents in memory.
+const DefaultSortBufferSize = 64 << 20
+
 type Options struct {
 	Codec encoding.Codec
+
+	// Maximum number of bytes used to sort documents in memory.
+	// If zero, DefaultSortBufferSize is used.
+	SortBufferSize int
+
+	// Parser of the expressions stored in the catalog. Databases without
+	// a parser can't use indexes or constraints relying on expressions.
+	ParseExpr func(s string) (Expr, error)
 }
 
 // New initializes the DB using the given engine.
@@ -43,10 +77,17 @@ func New(ng engine.Engine, opts Options) (*Database, error) {
 		return nil, errors.New("missing codec")
 	}
 
+	if opts.SortBufferSize <= 0 {
+		opts.SortBufferSize = DefaultSortBufferSize
+	}
+
 	db := Database{
-		ng:    ng,
-		Codec: opts.Codec,
+		ng:             ng,
+		Codec:          opts.Codec,
+		SortBufferSize: opts.SortBufferSize,
+		ParseExpr:      opts.ParseExpr,
 	}
+	db.session = db.NewSession()
 
 	ntx, err := db.ng.Begin(true)
 	if err != nil {
@@ -88,6 +129,26 @@ func (db *Database) initInternalStores(tx engine.Transaction) error {
 	return err
 }
 
+// parseExpr parses an expression stored in the catalog.
+// Every expression is only parsed once.
+func (db *Database) parseExpr(s string) (Expr, error) {
+	if e, ok := db.exprs.Load(s); ok {
+		return e.(Expr), nil
+	}
+
+	if db.ParseExpr == nil {
+		return nil, fmt.Errorf("cannot parse expression %q: the database has no expression parser", s)
+	}
+
+	e, err := db.ParseExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	db.exprs.Store(s, e)
+	return e, nil
+}
+
 // Close the underlying engine.
 func (db *Database) Close() error {
 	return db.ng.Close()
@@ -104,20 +165,31 @@ func (db *Database) Begin(writable bool) (*Transaction, error) {
 // BeginTx starts a new transaction with the given options.
 // If opts is empty, it will use the default options.
 // The returned transaction must be closed either by calling Rollback or Commit.
-// If the Global option is passed, it opens a database level transaction.
+// If the Attached option is passed, the transaction is attached to the default session
+// of the database. Otherwise, the transaction belongs to the default session without
+// being attached to it: a writable transaction can't be started while a writable
+// transaction is attached to the default session, which it would wait for forever.
 func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 	if opts == nil {
 		opts = new(TxOptions)
 	}
 
-	db.attachedTxMu.Lock()
-	defer db.attachedTxMu.Unlock()
+	if opts.Attached {
+		return db.session.Begin(!opts.ReadOnly)
+	}
 
-	if db.attachedTransaction != nil {
-		return nil, errors.New("cannot open a transaction within a transaction")
+	if !opts.ReadOnly {
+		if tx := db.session.GetAttachedTx(); tx != nil && tx.Writable() {
+			return nil, errors.New("cannot open a transaction within a transaction")
+		}
 	}
 
-	ntx, err := db.ng.Begin(!opts.ReadOnly)
+	return db.beginTx(!opts.ReadOnly)
+}
+
+// beginTx starts a new transaction, without checking the transactions of the sessions.
+func (db *Database) beginTx(writable bool) (*Transaction, error) {
+	ntx, err := db.ng.Begin(writable)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +198,7 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 		id:             atomic.AddInt64(&db.lastTransactionID, 1),
 		db:             db,
 		tx:             ntx,
-		writable:       !opts.ReadOnly,
+		writable:       writable,
 		tableInfoStore: db.tableInfoStore,
 	}
 
@@ -135,10 +207,6 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 		return nil, err
 	}
 
-	if opts.Attached {
-		db.attachedTransaction = &tx
-	}
-
 	return &tx, nil
 }
 
@@ -146,18 +214,21 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 type TxOptions struct {
 	// Open a read-only transaction.
 	ReadOnly bool
-	// Set the transaction as global at the database level.
-	// Any queries run by the database will use that transaction until it is
+	// Attach the transaction to the default session of the database.
+	// Any queries run using that session will use that transaction until it is
 	// rolled back or commited.
 	Attached bool
 }
 
-// GetAttachedTx returns the transaction attached to the database. It returns nil if there is no
-// such transaction.
+// DefaultSession returns the session used by queries that are not
+// run using a specific session.
+func (db *Database) DefaultSession() *Session {
+	return db.session
+}
+
+// GetAttachedTx returns the transaction attached to the default session of the database.
+// It returns nil if there is no such transaction.
 // The returned transaction is not thread safe.
 func (db *Database) GetAttachedTx() *Transaction {
-	db.attachedTxMu.Lock()
-	defer db.attachedTxMu.Unlock()
-
-	return db.attachedTransaction
+	return db.session.GetAttachedTx()
 }
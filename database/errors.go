@@ -19,6 +19,9 @@ var (
 	// same name as an existing one.
 	ErrIndexAlreadyExists = errors.New("index already exists")
 
+	// ErrStatisticsNotFound is returned when the statistics of a table have not been computed.
+	ErrStatisticsNotFound = errors.New("statistics not found")
+
 	// ErrDocumentNotFound is returned when no document is associated with the provided key.
 	ErrDocumentNotFound = errors.New("document not found")
 
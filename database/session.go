@@ -0,0 +1,81 @@
+package database
+
+import (
+	"errors"
+	"sync"
+)
+
+// A Session represents a client of the database, such as a connection.
+// It owns at most one attached transaction, started by the BEGIN statement,
+// which is used by every query run using the session until it is
+// rolled back or committed.
+// Sessions allow multiple clients to run explicit transactions concurrently.
+type Session struct {
+	db *Database
+
+	mu sync.Mutex
+	tx *Transaction
+}
+
+// NewSession creates a session.
+func (db *Database) NewSession() *Session {
+	return &Session{db: db}
+}
+
+// DB returns the database of the session.
+func (s *Session) DB() *Database {
+	return s.db
+}
+
+// Begin starts a transaction and attaches it to the session.
+// It returns an error if a transaction is already attached to the session.
+func (s *Session) Begin(writable bool) (*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx != nil {
+		return nil, errors.New("cannot open a transaction within a transaction")
+	}
+
+	tx, err := s.db.beginTx(writable)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.session = s
+	s.tx = tx
+	return tx, nil
+}
+
+// GetAttachedTx returns the transaction attached to the session.
+// It returns nil if there is no such transaction.
+// The returned transaction is not thread safe.
+func (s *Session) GetAttachedTx() *Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tx
+}
+
+// Close rolls back the attached transaction, if any.
+func (s *Session) Close() error {
+	tx := s.GetAttachedTx()
+	if tx == nil {
+		return nil
+	}
+
+	return tx.Rollback()
+}
+
+// detach the transaction from its session.
+func (tx *Transaction) detach() {
+	if tx.session == nil {
+		return
+	}
+
+	tx.session.mu.Lock()
+	if tx.session.tx == tx {
+		tx.session.tx = nil
+	}
+	tx.session.mu.Unlock()
+}
@@ -0,0 +1,492 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/key"
+)
+
+// maxHistogramBuckets is the maximum number of buckets of the histograms computed by Analyze.
+const maxHistogramBuckets = 32
+
+// TableStatistics describes the documents of a table and the values of its indexes.
+// They are computed by Analyze and used by the query planner to estimate how many
+// documents a query reads.
+type TableStatistics struct {
+	TableName string
+
+	// RowCount is the number of documents of the table.
+	RowCount int64
+
+	// PrimaryKey describes the values of the primary key.
+	// It is nil if the table doesn't have a primary key.
+	PrimaryKey *ValueStatistics
+
+	// Indexes describes the values of the indexes of the table, keyed by index name.
+	// Indexes created after the last analysis are missing.
+	Indexes map[string]*ValueStatistics
+}
+
+// ValueStatistics describes the values of a primary key or of an index.
+type ValueStatistics struct {
+	// Count is the number of values.
+	Count int64
+
+	// DistinctCount is the number of different values.
+	DistinctCount int64
+
+	// Histogram divides the values, in increasing order, into buckets containing roughly
+	// the same number of values. Equal values always belong to the same bucket.
+	Histogram []HistogramBucket
+}
+
+// A HistogramBucket contains the values lesser than or equal to its upper bound that
+// are greater than the upper bound of the previous bucket.
+type HistogramBucket struct {
+	UpperBound document.Value
+	Count      int64
+}
+
+// ToDocument turns ts into a document.
+func (ts *TableStatistics) ToDocument() document.Document {
+	buf := document.NewFieldBuffer()
+
+	buf.Add("table_name", document.NewTextValue(ts.TableName))
+	buf.Add("row_count", document.NewIntegerValue(ts.RowCount))
+	if ts.PrimaryKey != nil {
+		buf.Add("primary_key", document.NewDocumentValue(ts.PrimaryKey.ToDocument()))
+	}
+
+	names := make([]string, 0, len(ts.Indexes))
+	for name := range ts.Indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	indexes := document.NewFieldBuffer()
+	for _, name := range names {
+		indexes.Add(name, document.NewDocumentValue(ts.Indexes[name].ToDocument()))
+	}
+	buf.Add("indexes", document.NewDocumentValue(indexes))
+
+	return buf
+}
+
+// ScanDocument decodes d into ts.
+func (ts *TableStatistics) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("table_name")
+	if err != nil {
+		return err
+	}
+	ts.TableName = v.V.(string)
+
+	v, err = d.GetByField("row_count")
+	if err != nil {
+		return err
+	}
+	ts.RowCount = v.V.(int64)
+
+	ts.PrimaryKey = nil
+	v, err = d.GetByField("primary_key")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ts.PrimaryKey = new(ValueStatistics)
+		err = ts.PrimaryKey.ScanDocument(v.V.(document.Document))
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err = d.GetByField("indexes")
+	if err != nil {
+		return err
+	}
+
+	ts.Indexes = make(map[string]*ValueStatistics)
+	return v.V.(document.Document).Iterate(func(name string, value document.Value) error {
+		var vs ValueStatistics
+		err := vs.ScanDocument(value.V.(document.Document))
+		if err != nil {
+			return err
+		}
+
+		ts.Indexes[name] = &vs
+		return nil
+	})
+}
+
+// ToDocument turns vs into a document.
+func (vs *ValueStatistics) ToDocument() document.Document {
+	buf := document.NewFieldBuffer()
+
+	buf.Add("count", document.NewIntegerValue(vs.Count))
+	buf.Add("distinct_count", document.NewIntegerValue(vs.DistinctCount))
+
+	vbuf := document.NewValueBuffer()
+	for _, b := range vs.Histogram {
+		bucket := document.NewFieldBuffer().
+			Add("upper_bound", b.UpperBound).
+			Add("count", document.NewIntegerValue(b.Count))
+		vbuf = vbuf.Append(document.NewDocumentValue(bucket))
+	}
+	buf.Add("histogram", document.NewArrayValue(vbuf))
+
+	return buf
+}
+
+// ScanDocument decodes d into vs.
+func (vs *ValueStatistics) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("count")
+	if err != nil {
+		return err
+	}
+	vs.Count = v.V.(int64)
+
+	v, err = d.GetByField("distinct_count")
+	if err != nil {
+		return err
+	}
+	vs.DistinctCount = v.V.(int64)
+
+	v, err = d.GetByField("histogram")
+	if err != nil {
+		return err
+	}
+
+	vs.Histogram = vs.Histogram[:0]
+	return v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+		bd := value.V.(document.Document)
+
+		ub, err := bd.GetByField("upper_bound")
+		if err != nil {
+			return err
+		}
+
+		c, err := bd.GetByField("count")
+		if err != nil {
+			return err
+		}
+
+		vs.Histogram = append(vs.Histogram, HistogramBucket{UpperBound: ub, Count: c.V.(int64)})
+		return nil
+	})
+}
+
+// histogramBuilder computes the statistics of a list of encoded values sorted in increasing order.
+// Values are added to the last bucket until it contains bucketSize values, unless they are equal
+// to the previous value. When there are too many buckets, they are merged two by two and the
+// size of the buckets is doubled, which keeps the buckets balanced without knowing the number
+// of values in advance.
+type histogramBuilder struct {
+	// type of the values if they are encoded without type information.
+	typ document.ValueType
+
+	count, distinct int64
+	bucketSize      int64
+	// encoded upper bound and number of values of each bucket.
+	bounds [][]byte
+	counts []int64
+}
+
+func newHistogramBuilder(typ document.ValueType) *histogramBuilder {
+	return &histogramBuilder{
+		typ:        typ,
+		bucketSize: 1,
+	}
+}
+
+// add adds an encoded value, which must be greater than or equal to the previous one.
+func (b *histogramBuilder) add(enc []byte) {
+	b.count++
+
+	n := len(b.counts)
+	// the upper bound of the last bucket is the previous value.
+	if n > 0 && bytes.Equal(b.bounds[n-1], enc) {
+		b.counts[n-1]++
+		return
+	}
+	b.distinct++
+
+	if n == 0 || b.counts[n-1] >= b.bucketSize {
+		if n == maxHistogramBuckets {
+			b.merge()
+		}
+
+		b.bounds = append(b.bounds, nil)
+		b.counts = append(b.counts, 0)
+	}
+
+	n = len(b.counts)
+	b.bounds[n-1] = append(b.bounds[n-1][:0], enc...)
+	b.counts[n-1]++
+}
+
+// merge merges the buckets two by two.
+func (b *histogramBuilder) merge() {
+	n := len(b.counts) / 2
+	for i := 0; i < n; i++ {
+		b.bounds[i] = b.bounds[2*i+1]
+		b.counts[i] = b.counts[2*i] + b.counts[2*i+1]
+	}
+
+	b.bounds = b.bounds[:n]
+	b.counts = b.counts[:n]
+	b.bucketSize *= 2
+}
+
+func (b *histogramBuilder) statistics() (*ValueStatistics, error) {
+	vs := ValueStatistics{
+		Count:         b.count,
+		DistinctCount: b.distinct,
+		Histogram:     make([]HistogramBucket, len(b.bounds)),
+	}
+
+	for i, enc := range b.bounds {
+		var v document.Value
+		var err error
+
+		if b.typ != 0 {
+			v, err = key.Decode(b.typ, enc)
+		} else {
+			v, err = key.DecodeValue(enc)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		vs.Histogram[i] = HistogramBucket{UpperBound: v, Count: b.counts[i]}
+	}
+
+	return &vs, nil
+}
+
+// analyze reads the documents of the table and the values of its indexes
+// and computes their statistics.
+func (t *Table) analyze() (*TableStatistics, error) {
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	ts := TableStatistics{
+		TableName: t.name,
+		Indexes:   make(map[string]*ValueStatistics),
+	}
+
+	// documents are sorted by key, primary keys are encoded
+	// so that the keys follow the order of the values.
+	var pkb *histogramBuilder
+	pk := info.GetPrimaryKey()
+	if pk != nil {
+		pkb = newHistogramBuilder(pk.Type)
+	}
+
+	err = t.Iterate(func(d document.Document) error {
+		ts.RowCount++
+		if pkb != nil {
+			pkb.add(d.(document.Keyer).Key())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if pkb != nil {
+		ts.PrimaryKey, err = pkb.statistics()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range indexes {
+		// full-text indexes store words, not values.
+		if idx.Opts.FullText {
+			continue
+		}
+
+		b := newHistogramBuilder(idx.Opts.Type)
+		err = idx.AscendGreaterOrEqual(document.Value{}, func(val, _ []byte, _ bool) error {
+			b.add(val)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ts.Indexes[idx.Opts.IndexName], err = b.statistics()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ts, nil
+}
+
+// Analyze computes the statistics of a table and of its indexes and stores them,
+// replacing the ones computed by a previous analysis.
+func (tx *Transaction) Analyze(tableName string) error {
+	ti, err := tx.tableInfoStore.Get(tx, tableName)
+	if err != nil {
+		return err
+	}
+
+	if ti.readOnly {
+		return errors.New("cannot analyze read-only table")
+	}
+
+	t, err := tx.GetTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	ts, err := t.analyze()
+	if err != nil {
+		return err
+	}
+
+	return tx.putStatistics(ts)
+}
+
+// AnalyzeAll computes the statistics of every table of the database.
+func (tx *Transaction) AnalyzeAll() error {
+	var names []string
+	for name, ti := range tx.tableInfoStore.GetTableInfo() {
+		// ignore internal tables and tables created by other transactions.
+		if ti.readOnly || (ti.transactionID != 0 && ti.transactionID != tx.id) {
+			continue
+		}
+
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := tx.Analyze(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetStatistics returns the statistics of a table computed by the last analysis.
+// If the table has never been analyzed, it returns ErrStatisticsNotFound.
+func (tx *Transaction) GetStatistics(tableName string) (*TableStatistics, error) {
+	st, err := tx.tx.GetStore([]byte(statisticsStoreName))
+	if err == engine.ErrStoreNotFound {
+		return nil, ErrStatisticsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := st.Get([]byte(tableName))
+	if err == engine.ErrKeyNotFound {
+		return nil, ErrStatisticsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the statistics are decoded lazily and refer to the buffer,
+	// which is only valid until the next operation on the store.
+	var ts TableStatistics
+	err = ts.ScanDocument(tx.db.Codec.NewDocument(append([]byte(nil), v...)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ts, nil
+}
+
+// putStatistics stores the statistics of a table.
+// The statistics store is created the first time a table is analyzed.
+func (tx *Transaction) putStatistics(ts *TableStatistics) error {
+	st, err := tx.tx.GetStore([]byte(statisticsStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.tx.CreateStore([]byte(statisticsStoreName))
+		if err != nil {
+			return err
+		}
+
+		st, err = tx.tx.GetStore([]byte(statisticsStoreName))
+	}
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = tx.db.Codec.NewEncoder(&buf).EncodeDocument(ts.ToDocument())
+	if err != nil {
+		return err
+	}
+
+	return st.Put([]byte(ts.TableName), buf.Bytes())
+}
+
+// deleteStatistics deletes the statistics of a table, if any.
+func (tx *Transaction) deleteStatistics(tableName string) error {
+	st, err := tx.tx.GetStore([]byte(statisticsStoreName))
+	if err == engine.ErrStoreNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = st.Delete([]byte(tableName))
+	if err == engine.ErrKeyNotFound {
+		return nil
+	}
+	return err
+}
+
+// renameStatistics moves the statistics of a table after it is renamed.
+func (tx *Transaction) renameStatistics(oldName, newName string) error {
+	ts, err := tx.GetStatistics(oldName)
+	if err == ErrStatisticsNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ts.TableName = newName
+	err = tx.putStatistics(ts)
+	if err != nil {
+		return err
+	}
+
+	return tx.deleteStatistics(oldName)
+}
+
+// deleteIndexStatistics removes the statistics of an index from the statistics of its table.
+func (tx *Transaction) deleteIndexStatistics(tableName, indexName string) error {
+	ts, err := tx.GetStatistics(tableName)
+	if err == ErrStatisticsNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ts.Indexes[indexName]; !ok {
+		return nil
+	}
+
+	delete(ts.Indexes, indexName)
+	return tx.putStatistics(ts)
+}
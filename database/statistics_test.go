@@ -0,0 +1,152 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxAnalyze(t *testing.T) {
+	// creates a table with an integer primary key, an index on a field with 3 different values
+	// and a unique index on a double field, and inserts n documents.
+	setup := func(t *testing.T, n int) (*database.Transaction, func()) {
+		tx, cleanup := newTestDB(t)
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "k"), Type: document.IntegerValue, IsPrimaryKey: true},
+			},
+		})
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_a", TableName: "test", Paths: []document.ValuePath{parsePath(t, "a")}})
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_b", TableName: "test", Paths: []document.ValuePath{parsePath(t, "b")}, Unique: true})
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		for i := 0; i < n; i++ {
+			d := document.NewFieldBuffer().
+				Add("k", document.NewIntegerValue(int64(i))).
+				Add("a", document.NewIntegerValue(int64(i%3))).
+				Add("b", document.NewDoubleValue(float64(i)/2))
+
+			_, err = tb.Insert(d)
+			require.NoError(t, err)
+		}
+
+		return tx, cleanup
+	}
+
+	t.Run("Analyze", func(t *testing.T) {
+		tx, cleanup := setup(t, 100)
+		defer cleanup()
+
+		_, err := tx.GetStatistics("test")
+		require.Equal(t, database.ErrStatisticsNotFound, err)
+
+		err = tx.Analyze("test")
+		require.NoError(t, err)
+
+		ts, err := tx.GetStatistics("test")
+		require.NoError(t, err)
+		require.Equal(t, "test", ts.TableName)
+		require.EqualValues(t, 100, ts.RowCount)
+
+		check := func(vs *database.ValueStatistics, count, distinct int64) {
+			require.NotNil(t, vs)
+			require.Equal(t, count, vs.Count)
+			require.Equal(t, distinct, vs.DistinctCount)
+			require.NotEmpty(t, vs.Histogram)
+
+			var total int64
+			var prev document.Value
+			for i, b := range vs.Histogram {
+				total += b.Count
+				if i > 0 {
+					ok, err := b.UpperBound.IsGreaterThan(prev)
+					require.NoError(t, err)
+					require.True(t, ok)
+				}
+				prev = b.UpperBound
+			}
+			require.Equal(t, count, total)
+		}
+
+		check(ts.PrimaryKey, 100, 100)
+		require.LessOrEqual(t, len(ts.PrimaryKey.Histogram), 32)
+		require.Equal(t, document.NewIntegerValue(99), ts.PrimaryKey.Histogram[len(ts.PrimaryKey.Histogram)-1].UpperBound)
+
+		check(ts.Indexes["idx_a"], 100, 3)
+		// equal values belong to the same bucket.
+		require.Len(t, ts.Indexes["idx_a"].Histogram, 3)
+
+		check(ts.Indexes["idx_b"], 100, 100)
+	})
+
+	t.Run("Analyze unknown table", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.Analyze("test")
+		require.Error(t, err)
+	})
+
+	t.Run("AnalyzeAll", func(t *testing.T) {
+		tx, cleanup := setup(t, 10)
+		defer cleanup()
+
+		err := tx.CreateTable("other", nil)
+		require.NoError(t, err)
+
+		err = tx.AnalyzeAll()
+		require.NoError(t, err)
+
+		ts, err := tx.GetStatistics("test")
+		require.NoError(t, err)
+		require.EqualValues(t, 10, ts.RowCount)
+
+		ts, err = tx.GetStatistics("other")
+		require.NoError(t, err)
+		require.EqualValues(t, 0, ts.RowCount)
+		require.Nil(t, ts.PrimaryKey)
+		require.Empty(t, ts.Indexes)
+	})
+
+	t.Run("Rename and drop", func(t *testing.T) {
+		tx, cleanup := setup(t, 10)
+		defer cleanup()
+
+		err := tx.Analyze("test")
+		require.NoError(t, err)
+
+		err = tx.DropIndex("idx_a")
+		require.NoError(t, err)
+
+		ts, err := tx.GetStatistics("test")
+		require.NoError(t, err)
+		require.NotContains(t, ts.Indexes, "idx_a")
+		require.Contains(t, ts.Indexes, "idx_b")
+
+		err = tx.RenameTable("test", "foo")
+		require.NoError(t, err)
+
+		_, err = tx.GetStatistics("test")
+		require.Equal(t, database.ErrStatisticsNotFound, err)
+
+		ts, err = tx.GetStatistics("foo")
+		require.NoError(t, err)
+		require.Equal(t, "foo", ts.TableName)
+		require.EqualValues(t, 10, ts.RowCount)
+
+		err = tx.DropTable("foo")
+		require.NoError(t, err)
+
+		_, err = tx.GetStatistics("foo")
+		require.Equal(t, database.ErrStatisticsNotFound, err)
+	})
+}
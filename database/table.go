@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
@@ -46,20 +47,26 @@ func (t *Table) Truncate() error {
 // in the given document.
 // If no primary key has been selected, a monotonic autoincremented integer key will be generated.
 func (t *Table) Insert(d document.Document) ([]byte, error) {
-	info, err := t.Info()
+	d, err := t.ValidateConstraints(d)
 	if err != nil {
 		return nil, err
 	}
 
-	if info.readOnly {
-		return nil, errors.New("cannot write to read-only table")
-	}
+	return t.InsertValidated(d)
+}
 
-	d, err = t.ValidateConstraints(d)
+// InsertValidated inserts a document that was already returned by ValidateConstraints,
+// without validating it again.
+func (t *Table) InsertValidated(d document.Document) ([]byte, error) {
+	info, err := t.Info()
 	if err != nil {
 		return nil, err
 	}
 
+	if info.readOnly {
+		return nil, errors.New("cannot write to read-only table")
+	}
+
 	key, err := t.generateKey(d)
 	if err != nil {
 		return nil, err
@@ -87,22 +94,142 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 	}
 
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
+		ok, err := idx.Matches(d)
 		if err != nil {
-			v = document.NewNullValue()
+			return nil, err
+		}
+		if !ok {
+			continue
 		}
 
-		err = idx.Set(v, key)
+		vs, err := idx.Values(d)
 		if err != nil {
-			if err == index.ErrDuplicate {
-				return nil, ErrDuplicateDocument
+			vs = []document.Value{document.NewNullValue()}
+		}
+
+		for _, v := range vs {
+			err = idx.Set(v, key)
+			if err != nil {
+				if err == index.ErrDuplicate {
+					return nil, ErrDuplicateDocument
+				}
+
+				return nil, err
 			}
+		}
+	}
 
+	if t.tx.db.changeFeed.watched(t.name) {
+		t.tx.recordChange(Change{
+			Type:  ChangeInsert,
+			Table: t.name,
+			Key:   key,
+			New:   t.tx.db.Codec.NewDocument(buf.Bytes()),
+		})
+	}
+
+	return key, nil
+}
+
+// FindConflict returns the key of the document that prevents d from being inserted,
+// because it has the same primary key or the same value for a unique index.
+// If paths is not empty, only the primary key or the unique index created on
+// these paths is checked, and an error is returned if there is none.
+// d must have been validated using ValidateConstraints.
+// If there is no conflict, it returns ErrDocumentNotFound.
+func (t *Table) FindConflict(d document.Document, paths ...document.ValuePath) ([]byte, error) {
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	var found bool
+
+	if pk := info.GetPrimaryKey(); pk != nil && (len(paths) == 0 || (len(paths) == 1 && paths[0].IsEqual(pk.Path))) {
+		found = true
+
+		k, err := encodePrimaryKey(pk, d)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = t.Store.Get(k)
+		if err == nil {
+			return k, nil
+		}
+		if err != engine.ErrKeyNotFound {
 			return nil, err
 		}
 	}
 
-	return key, nil
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range indexes {
+		if !idx.Opts.Unique || (len(paths) > 0 && !pathsEqual(idx.Opts.Paths, paths)) {
+			continue
+		}
+		found = true
+
+		ok, err := idx.Matches(d)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		// documents are indexed using NULL if the indexed value is missing.
+		vs, err := idx.Values(d)
+		if err != nil {
+			vs = []document.Value{document.NewNullValue()}
+		}
+
+		for _, v := range vs {
+			k, err := idx.Get(v)
+			if err == nil {
+				return k, nil
+			}
+			if err != engine.ErrKeyNotFound {
+				return nil, err
+			}
+		}
+	}
+
+	if !found && len(paths) > 0 {
+		return nil, fmt.Errorf("no primary key or unique index on %s", pathsString(paths))
+	}
+
+	return nil, ErrDocumentNotFound
+}
+
+func pathsEqual(a, b []document.ValuePath) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].IsEqual(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pathsString(paths []document.ValuePath) string {
+	var sb strings.Builder
+
+	for i, p := range paths {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.String())
+	}
+
+	return sb.String()
 }
 
 // Delete a document by key.
@@ -128,18 +255,51 @@ func (t *Table) Delete(key []byte) error {
 	}
 
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
+		ok, err := idx.Matches(d)
 		if err != nil {
 			return err
 		}
+		if !ok {
+			continue
+		}
+
+		vs, err := idx.Values(d)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range vs {
+			err = idx.Delete(v, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-		err = idx.Delete(v, key)
+	var old document.Document
+	watched := t.tx.db.changeFeed.watched(t.name)
+	if watched {
+		old, err = t.copyDocument(key)
 		if err != nil {
 			return err
 		}
 	}
 
-	return t.Store.Delete(key)
+	err = t.Store.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	if watched {
+		t.tx.recordChange(Change{
+			Type:  ChangeDelete,
+			Table: t.name,
+			Key:   append([]byte(nil), key...),
+			Old:   old,
+		})
+	}
+
+	return nil
 }
 
 // Replace a document by key.
@@ -177,12 +337,31 @@ func (t *Table) replace(indexes map[string]Index, key []byte, d document.Documen
 
 	// remove key from indexes
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(old)
+		ok, err := idx.Matches(old)
 		if err != nil {
 			return err
 		}
+		if !ok {
+			continue
+		}
 
-		err = idx.Delete(v, key)
+		vs, err := idx.Values(old)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range vs {
+			err = idx.Delete(v, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var oldCopy document.Document
+	watched := t.tx.db.changeFeed.watched(t.name)
+	if watched {
+		oldCopy, err = t.copyDocument(key)
 		if err != nil {
 			return err
 		}
@@ -203,21 +382,44 @@ func (t *Table) replace(indexes map[string]Index, key []byte, d document.Documen
 
 	// update indexes
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
+		ok, err := idx.Matches(d)
 		if err != nil {
+			return err
+		}
+		if !ok {
 			continue
 		}
 
-		err = idx.Set(v, key)
+		vs, err := idx.Values(d)
 		if err != nil {
-			return err
+			continue
+		}
+
+		for _, v := range vs {
+			err = idx.Set(v, key)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
+	if watched {
+		t.tx.recordChange(Change{
+			Type:  ChangeReplace,
+			Table: t.name,
+			Key:   append([]byte(nil), key...),
+			Old:   oldCopy,
+			New:   t.tx.db.Codec.NewDocument(buf.Bytes()),
+		})
+	}
+
 	return err
 }
 
-// Indexes returns a map of all the indexes of a table.
+// Indexes returns a map of all the indexes of a table, keyed by the paths
+// of the indexed fields, separated by commas. The keys of partial indexes
+// are followed by their predicate, e.g. "a, b WHERE c > 10", and the keys
+// of full-text indexes are preceded by "FULLTEXT ", e.g. "FULLTEXT a".
 func (t *Table) Indexes() (map[string]Index, error) {
 	s, err := t.tx.tx.GetStore([]byte(indexStoreName))
 	if err != nil {
@@ -248,15 +450,19 @@ func (t *Table) Indexes() (map[string]Index, error) {
 				return err
 			}
 
-			idx := index.NewIndex(t.tx.tx, opts.IndexName, index.Options{
-				Unique: opts.Unique,
-				Type:   opts.Type,
-			})
+			idx, err := t.tx.newIndex(opts)
+			if err != nil {
+				return err
+			}
 
-			indexes[opts.Path.String()] = Index{
-				Index: idx,
-				Opts:  opts,
+			k := opts.PathsString()
+			if opts.FullText {
+				k = "FULLTEXT " + k
+			}
+			if opts.Where != "" {
+				k += " WHERE " + opts.Where
 			}
+			indexes[k] = *idx
 
 			return nil
 		})
@@ -324,17 +530,37 @@ func (d *lazilyDecodedDocument) copyFromItem() error {
 // Iterate goes through all the documents of the table and calls the given function by passing each one of them.
 // If the given function returns an error, the iteration stops.
 func (t *Table) Iterate(fn func(d document.Document) error) error {
+	return t.AscendGreaterOrEqual(nil, fn)
+}
+
+// AscendGreaterOrEqual goes through the documents of the table whose key is greater than or equal
+// to the pivot, in increasing order of keys, and calls the given function by passing each one of them.
+// If the pivot is empty, starts from the beginning.
+// If the given function returns an error, the iteration stops.
+func (t *Table) AscendGreaterOrEqual(pivot []byte, fn func(d document.Document) error) error {
+	return t.iterate(pivot, false, fn)
+}
+
+// DescendLessOrEqual goes through the documents of the table whose key is less than or equal
+// to the pivot, in decreasing order of keys, and calls the given function by passing each one of them.
+// If the pivot is empty, starts from the end.
+// If the given function returns an error, the iteration stops.
+func (t *Table) DescendLessOrEqual(pivot []byte, fn func(d document.Document) error) error {
+	return t.iterate(pivot, true, fn)
+}
+
+func (t *Table) iterate(pivot []byte, reverse bool, fn func(d document.Document) error) error {
 	// To avoid unnecessary allocations, we create the struct once and reuse
 	// it during each iteration.
 	d := lazilyDecodedDocument{
 		codec: t.tx.db.Codec,
 	}
 
-	it := t.Store.NewIterator(engine.IteratorConfig{})
+	it := t.Store.NewIterator(engine.IteratorConfig{Reverse: reverse})
 	defer it.Close()
 
 	var err error
-	for it.Seek(nil); it.Valid(); it.Next() {
+	for it.Seek(pivot); it.Valid(); it.Next() {
 		d.Reset()
 		d.item = it.Item()
 		// d must be passed as pointer, not value,
@@ -365,6 +591,17 @@ func (t *Table) GetDocument(key []byte) (document.Document, error) {
 	return &d, err
 }
 
+// copyDocument returns a copy of the document stored at the given key
+// that remains valid after the transaction is closed.
+func (t *Table) copyDocument(key []byte) (document.Document, error) {
+	v, err := t.Store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.tx.db.Codec.NewDocument(append([]byte(nil), v...)), nil
+}
+
 // generate a key for d based on the table configuration.
 // if the table has a primary key, it extracts the field from
 // the document, converts it to the targeted type and returns
@@ -378,23 +615,7 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 	}
 
 	if pk := ti.GetPrimaryKey(); pk != nil {
-		v, err := pk.Path.GetValue(d)
-		if err == document.ErrFieldNotFound {
-			return nil, fmt.Errorf("missing primary key at path %q", pk.Path)
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		// if a primary key type is specified,
-		// encode the key using the optimized encoding solution
-		if pk.Type != 0 {
-			return key.Append(nil, v.Type, v.V)
-		}
-
-		// it no primary key type is specified,
-		// encode keys regardless of type.
-		return key.AppendValue(nil, v)
+		return encodePrimaryKey(pk, d)
 	}
 
 	docid, err := t.Store.NextSequence()
@@ -407,10 +628,31 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 	return buf[:n], nil
 }
 
+// encodePrimaryKey extracts the primary key from d and encodes it.
+func encodePrimaryKey(pk *FieldConstraint, d document.Document) ([]byte, error) {
+	v, err := pk.Path.GetValue(d)
+	if err == document.ErrFieldNotFound {
+		return nil, fmt.Errorf("missing primary key at path %q", pk.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// if a primary key type is specified,
+	// encode the key using the optimized encoding solution
+	if pk.Type != 0 {
+		return key.Append(nil, v.Type, v.V)
+	}
+
+	// it no primary key type is specified,
+	// encode keys regardless of type.
+	return key.AppendValue(nil, v)
+}
+
 // ValidateConstraints check the table configuration for constraints and validates the document
 // against them. If the types defined by the constraints are different than the ones found in
 // the document, the fields are converted to these types when possible. if the conversion
-// fails, an error is returned.
+// fails, an error is returned. Check constraints are then evaluated against the converted document.
 func (t *Table) ValidateConstraints(d document.Document) (document.Document, error) {
 	info, err := t.Info()
 	if err != nil {
@@ -419,7 +661,7 @@ func (t *Table) ValidateConstraints(d document.Document) (document.Document, err
 
 	pk := info.GetPrimaryKey()
 
-	if len(info.FieldConstraints) == 0 && pk == nil {
+	if len(info.FieldConstraints) == 0 && len(info.CheckConstraints) == 0 && pk == nil {
 		return d, nil
 	}
 
@@ -446,9 +688,43 @@ func (t *Table) ValidateConstraints(d document.Document) (document.Document, err
 		}
 	}
 
+	for _, cc := range info.CheckConstraints {
+		err := t.validateCheckConstraint(&fb, &cc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &fb, err
 }
 
+// validateCheckConstraint returns an error if the predicate of cc is false for d.
+func (t *Table) validateCheckConstraint(d document.Document, cc *CheckConstraint) error {
+	e, err := t.tx.db.parseExpr(cc.Expr)
+	if err != nil {
+		return err
+	}
+
+	v, err := e.Eval(t.tx, d)
+	if err != nil {
+		return fmt.Errorf("check constraint %q: %w", cc.Name, err)
+	}
+
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	ok, err := v.IsTruthy()
+	if err != nil {
+		return fmt.Errorf("check constraint %q: %w", cc.Name, err)
+	}
+	if !ok {
+		return fmt.Errorf("document violates check constraint %q", cc.Name)
+	}
+
+	return nil
+}
+
 func validateConstraint(d document.Document, c *FieldConstraint) error {
 	// get the parent buffer
 	parent, err := getParentValue(d, c.Path)
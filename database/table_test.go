@@ -4,12 +4,14 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding/msgpack"
 	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index"
 	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/parser"
 	"github.com/stretchr/testify/require"
@@ -96,6 +98,45 @@ func TestTableIterate(t *testing.T) {
 	})
 }
 
+// TestTableDescendLessOrEqual verifies DescendLessOrEqual behaviour.
+func TestTableDescendLessOrEqual(t *testing.T) {
+	tb, cleanup := newTestTable(t)
+	defer cleanup()
+
+	var keys [][]byte
+	for i := 0; i < 10; i++ {
+		k, err := tb.Insert(newDocument())
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+
+	descend := func(pivot []byte) [][]byte {
+		var res [][]byte
+		err := tb.DescendLessOrEqual(pivot, func(d document.Document) error {
+			res = append(res, append([]byte(nil), d.(document.Keyer).Key()...))
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Should iterate over all documents in reverse order", func(t *testing.T) {
+		res := descend(nil)
+		require.Len(t, res, 10)
+		for i, k := range res {
+			require.Equal(t, keys[9-i], k)
+		}
+	})
+
+	t.Run("Should start from the pivot", func(t *testing.T) {
+		res := descend(keys[4])
+		require.Len(t, res, 5)
+		for i, k := range res {
+			require.Equal(t, keys[4-i], k)
+		}
+	})
+}
+
 // TestTableGetDocument verifies GetDocument behaviour.
 func TestTableGetDocument(t *testing.T) {
 	t.Run("Should fail if not found", func(t *testing.T) {
@@ -292,7 +333,7 @@ func TestTableInsert(t *testing.T) {
 		require.NoError(t, err)
 
 		err = tx.CreateIndex(database.IndexConfig{
-			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+			IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
 		})
 		require.NoError(t, err)
 		idx, err := tx.GetIndex("idxFoo")
@@ -331,6 +372,58 @@ func TestTableInsert(t *testing.T) {
 		require.Equal(t, 2, count)
 	})
 
+	t.Run("Should index arrays of values if the index is composite", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idxFooBar", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo"), parsePath(t, "bar")},
+		})
+		require.NoError(t, err)
+		idx, err := tx.GetIndex("idxFooBar")
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		// create one document with both fields
+		doc1 := newDocument()
+		doc1.Add("foo", document.NewIntegerValue(1))
+		doc1.Add("bar", document.NewTextValue("a"))
+
+		// create one document without the bar field
+		doc2 := newDocument()
+		doc2.Add("foo", document.NewIntegerValue(1))
+
+		key1, err := tb.Insert(doc1)
+		require.NoError(t, err)
+		key2, err := tb.Insert(doc2)
+		require.NoError(t, err)
+
+		var keys [][]byte
+		err = idx.AscendPrefix([]document.Value{document.NewIntegerValue(1)}, document.Value{}, func(val, k []byte) error {
+			keys = append(keys, k)
+			return nil
+		})
+		require.NoError(t, err)
+		// missing fields are indexed as null values, which are the smallest possible values
+		require.Equal(t, [][]byte{key2, key1}, keys)
+
+		err = tb.Delete(key2)
+		require.NoError(t, err)
+
+		keys = nil
+		err = idx.AscendPrefix([]document.Value{document.NewIntegerValue(1)}, document.Value{}, func(val, k []byte) error {
+			keys = append(keys, k)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{key1}, keys)
+	})
+
 	t.Run("Should convert the fields if FieldsConstraints are specified", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
@@ -512,6 +605,99 @@ func TestTableInsert(t *testing.T) {
 				Append(document.NewIntegerValue(1)).Append(document.NewIntegerValue(2)))))
 		require.NoError(t, err)
 	})
+
+	t.Run("Should insert a validated document", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{parsePath(t, "foo"), document.IntegerValue, false, false, document.Value{}},
+			},
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		d, err := tb.ValidateConstraints(document.NewFieldBuffer().
+			Add("foo", document.NewDoubleValue(10)))
+		require.NoError(t, err)
+
+		key, err := tb.InsertValidated(d)
+		require.NoError(t, err)
+
+		d, err = tb.GetDocument(key)
+		require.NoError(t, err)
+		v, err := d.GetByField("foo")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(10), v)
+	})
+}
+
+// TestTableFindConflict verifies FindConflict behaviour.
+func TestTableFindConflict(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+		},
+	})
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idx_b", TableName: "test", Paths: []document.ValuePath{parsePath(t, "b")}, Unique: true,
+	})
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idx_c", TableName: "test", Paths: []document.ValuePath{parsePath(t, "c")},
+	})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	newDoc := func(a int64, b, c string) document.Document {
+		return document.NewFieldBuffer().
+			Add("a", document.NewIntegerValue(a)).
+			Add("b", document.NewTextValue(b)).
+			Add("c", document.NewTextValue(c))
+	}
+
+	k, err := tb.Insert(newDoc(1, "foo", "bar"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		d     document.Document
+		paths []document.ValuePath
+		found bool
+		fails bool
+	}{
+		{"no conflict", newDoc(2, "baz", "bar"), nil, false, false},
+		{"primary key", newDoc(1, "baz", "bar"), nil, true, false},
+		{"unique index", newDoc(2, "foo", "bar"), nil, true, false},
+		{"primary key target", newDoc(1, "foo", "bar"), []document.ValuePath{parsePath(t, "a")}, true, false},
+		{"other target", newDoc(2, "foo", "bar"), []document.ValuePath{parsePath(t, "a")}, false, false},
+		{"unique index target", newDoc(2, "foo", "bar"), []document.ValuePath{parsePath(t, "b")}, true, false},
+		{"non-unique index target", newDoc(2, "baz", "bar"), []document.ValuePath{parsePath(t, "c")}, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := tb.FindConflict(test.d, test.paths...)
+			switch {
+			case test.fails:
+				require.Error(t, err)
+				require.NotEqual(t, database.ErrDocumentNotFound, err)
+			case test.found:
+				require.NoError(t, err)
+				require.Equal(t, k, key)
+			default:
+				require.Equal(t, database.ErrDocumentNotFound, err)
+			}
+		})
+	}
 }
 
 // TestTableDelete verifies Delete behaviour.
@@ -673,25 +859,25 @@ func TestTableReIndex(t *testing.T) {
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "test1a",
 			TableName: "test1",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "test1b",
 			TableName: "test1",
-			Path:      parsePath(t, "b"),
+			Paths:     []document.ValuePath{parsePath(t, "b")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "test2a",
 			TableName: "test2",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "test2b",
 			TableName: "test2",
-			Path:      parsePath(t, "b"),
+			Paths:     []document.ValuePath{parsePath(t, "b")},
 		})
 		require.NoError(t, err)
 
@@ -752,21 +938,21 @@ func TestTableIndexes(t *testing.T) {
 			Unique:    true,
 			IndexName: "idx1a",
 			TableName: "test1",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			Unique:    false,
 			IndexName: "idx1b",
 			TableName: "test1",
-			Path:      parsePath(t, "b"),
+			Paths:     []document.ValuePath{parsePath(t, "b")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			Unique:    false,
 			IndexName: "ifx2a",
 			TableName: "test2",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 
@@ -782,6 +968,355 @@ func TestTableIndexes(t *testing.T) {
 	})
 }
 
+// predicateFunc is a predicate of a partial index, used instead of parsing one.
+type predicateFunc func(d document.Document) bool
+
+func (f predicateFunc) Eval(tx *database.Transaction, d document.Document) (document.Value, error) {
+	return document.NewBoolValue(f(d)), nil
+}
+
+func TestTablePartialIndex(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	tx.DB().ParseExpr = func(s string) (database.Expr, error) {
+		require.Equal(t, "status = 'pending'", s)
+
+		return predicateFunc(func(d document.Document) bool {
+			v, err := d.GetByField("status")
+			return err == nil && v.V == "pending"
+		}), nil
+	}
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")}, Where: "status = 'pending'",
+	})
+	require.NoError(t, err)
+	idx, err := tx.GetIndex("idxFoo")
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	m, err := tb.Indexes()
+	require.NoError(t, err)
+	require.Contains(t, m, "foo WHERE status = 'pending'")
+
+	indexedKeys := func() [][]byte {
+		var keys [][]byte
+		err := idx.AscendGreaterOrEqual(document.Value{}, func(val, k []byte, isEqual bool) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	newDoc := func(foo int64, status string) document.Document {
+		return document.NewFieldBuffer().
+			Add("foo", document.NewIntegerValue(foo)).
+			Add("status", document.NewTextValue(status))
+	}
+
+	key1, err := tb.Insert(newDoc(1, "pending"))
+	require.NoError(t, err)
+	key2, err := tb.Insert(newDoc(2, "done"))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{key1}, indexedKeys())
+
+	err = tb.Replace(key2, newDoc(2, "pending"))
+	require.NoError(t, err)
+	err = tb.Replace(key1, newDoc(1, "done"))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{key2}, indexedKeys())
+
+	err = tx.ReIndex("idxFoo")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{key2}, indexedKeys())
+
+	err = tb.Delete(key1)
+	require.NoError(t, err)
+	err = tb.Delete(key2)
+	require.NoError(t, err)
+	require.Empty(t, indexedKeys())
+}
+
+// exprFunc is the expression of an expression index, used instead of parsing one.
+type exprFunc func(d document.Document) document.Value
+
+func (f exprFunc) Eval(tx *database.Transaction, d document.Document) (document.Value, error) {
+	return f(d), nil
+}
+
+func TestTableExpressionIndex(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	tx.DB().ParseExpr = func(s string) (database.Expr, error) {
+		require.Equal(t, "lower(email)", s)
+
+		return exprFunc(func(d document.Document) document.Value {
+			v, err := d.GetByField("email")
+			if err != nil {
+				return document.NewNullValue()
+			}
+			return document.NewTextValue(strings.ToLower(v.V.(string)))
+		}), nil
+	}
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idxEmail", TableName: "test", Expr: "lower(email)", Unique: true,
+	})
+	require.NoError(t, err)
+	idx, err := tx.GetIndex("idxEmail")
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	m, err := tb.Indexes()
+	require.NoError(t, err)
+	require.Contains(t, m, "lower(email)")
+
+	newDoc := func(email string) document.Document {
+		return document.NewFieldBuffer().Add("email", document.NewTextValue(email))
+	}
+
+	key, err := tb.Insert(newDoc("Foo@example.com"))
+	require.NoError(t, err)
+
+	_, err = tb.Insert(newDoc("FOO@example.com"))
+	require.Equal(t, database.ErrDuplicateDocument, err)
+
+	var keys [][]byte
+	err = idx.AscendGreaterOrEqual(document.NewTextValue("foo@example.com"), func(val, k []byte, isEqual bool) error {
+		require.True(t, isEqual)
+		keys = append(keys, append([]byte(nil), k...))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{key}, keys)
+
+	err = tb.Delete(key)
+	require.NoError(t, err)
+
+	_, err = tb.Insert(newDoc("FOO@example.com"))
+	require.NoError(t, err)
+}
+
+func TestTableMultiValuedIndex(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idxTags", TableName: "test", Paths: []document.ValuePath{parsePath(t, "tags")}, Multi: true,
+	})
+	require.NoError(t, err)
+	idx, err := tx.GetIndex("idxTags")
+	require.NoError(t, err)
+	require.True(t, idx.Opts.Multi)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idxInvalid", TableName: "test", Paths: []document.ValuePath{parsePath(t, "a"), parsePath(t, "b")}, Multi: true,
+	})
+	require.Error(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	m, err := tb.Indexes()
+	require.NoError(t, err)
+	require.Contains(t, m, "tags[*]")
+
+	// returns the keys of the documents containing the given tag.
+	lookup := func(tag string) [][]byte {
+		var keys [][]byte
+		err := idx.AscendGreaterOrEqual(document.NewTextValue(tag), func(val, k []byte, isEqual bool) error {
+			if isEqual {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	newDoc := func(tags ...string) document.Document {
+		vb := document.NewValueBuffer()
+		for _, tag := range tags {
+			vb = vb.Append(document.NewTextValue(tag))
+		}
+		return document.NewFieldBuffer().Add("tags", document.NewArrayValue(vb))
+	}
+
+	key1, err := tb.Insert(newDoc("a", "b", "a"))
+	require.NoError(t, err)
+	key2, err := tb.Insert(newDoc("b"))
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer().Add("tags", document.NewTextValue("a")))
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer())
+	require.NoError(t, err)
+
+	require.Equal(t, [][]byte{key1}, lookup("a"))
+	require.Equal(t, [][]byte{key1, key2}, lookup("b"))
+
+	err = tb.Replace(key1, newDoc("c"))
+	require.NoError(t, err)
+	require.Empty(t, lookup("a"))
+	require.Equal(t, [][]byte{key2}, lookup("b"))
+	require.Equal(t, [][]byte{key1}, lookup("c"))
+
+	err = tx.ReIndex("idxTags")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{key1}, lookup("c"))
+
+	err = tb.Delete(key1)
+	require.NoError(t, err)
+	err = tb.Delete(key2)
+	require.NoError(t, err)
+	require.Empty(t, lookup("b"))
+	require.Empty(t, lookup("c"))
+}
+
+func TestTableFullTextIndex(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idxDesc", TableName: "test", Paths: []document.ValuePath{parsePath(t, "description")}, FullText: true,
+	})
+	require.NoError(t, err)
+	idx, err := tx.GetIndex("idxDesc")
+	require.NoError(t, err)
+	require.NotNil(t, idx.FullText())
+
+	for _, opts := range []database.IndexConfig{
+		{IndexName: "idxInvalid", TableName: "test", Paths: []document.ValuePath{parsePath(t, "a"), parsePath(t, "b")}, FullText: true},
+		{IndexName: "idxInvalid", TableName: "test", Paths: []document.ValuePath{parsePath(t, "a")}, FullText: true, Unique: true},
+		{IndexName: "idxInvalid", TableName: "test", Paths: []document.ValuePath{parsePath(t, "a")}, FullText: true, Multi: true},
+		{IndexName: "idxInvalid", TableName: "test", Paths: []document.ValuePath{parsePath(t, "a")}, FullText: true, Where: "a > 1"},
+	} {
+		err = tx.CreateIndex(opts)
+		require.Error(t, err)
+	}
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	m, err := tb.Indexes()
+	require.NoError(t, err)
+	require.Contains(t, m, "FULLTEXT description")
+
+	// returns the keys of the documents matching the query.
+	search := func(query string) [][]byte {
+		q, err := index.ParseFullTextQuery(query)
+		require.NoError(t, err)
+
+		var keys [][]byte
+		err = idx.FullText().Search(q, func(k []byte, _ float64) error {
+			keys = append(keys, k)
+			return nil
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	newDoc := func(description string) document.Document {
+		return document.NewFieldBuffer().Add("description", document.NewTextValue(description))
+	}
+
+	key1, err := tb.Insert(newDoc("The quick brown fox"))
+	require.NoError(t, err)
+	key2, err := tb.Insert(newDoc("A lazy dog"))
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer().Add("description", document.NewIntegerValue(10)))
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer())
+	require.NoError(t, err)
+
+	require.Equal(t, [][]byte{key1}, search("fox"))
+	require.Equal(t, [][]byte{key2}, search("dog"))
+
+	err = tb.Replace(key1, newDoc("A brown dog"))
+	require.NoError(t, err)
+	require.Empty(t, search("fox"))
+	require.ElementsMatch(t, [][]byte{key1, key2}, search("dog"))
+
+	err = tx.ReIndex("idxDesc")
+	require.NoError(t, err)
+	require.ElementsMatch(t, [][]byte{key1, key2}, search("dog"))
+
+	err = tb.Delete(key1)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{key2}, search("dog"))
+	require.Empty(t, search("brown"))
+}
+
+func TestTableCheckConstraints(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	// the only predicate is "a > 0".
+	tx.DB().ParseExpr = func(s string) (database.Expr, error) {
+		return exprFunc(func(d document.Document) document.Value {
+			v, err := d.GetByField("a")
+			if err != nil {
+				return document.NewNullValue()
+			}
+			return document.NewBoolValue(v.V.(int64) > 0)
+		}), nil
+	}
+
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "a"), Type: document.IntegerValue},
+		},
+		CheckConstraints: []database.CheckConstraint{
+			{Path: parsePath(t, "a"), Expr: "a > 0"},
+		},
+	})
+	require.NoError(t, err)
+
+	err = tx.CreateTable("other", &database.TableInfo{
+		CheckConstraints: []database.CheckConstraint{
+			{Name: "c", Expr: "a > 0"},
+			{Name: "c", Expr: "a > 0"},
+		},
+	})
+	require.Error(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	info, err := tb.Info()
+	require.NoError(t, err)
+	require.Equal(t, "test_a_check", info.CheckConstraints[0].Name)
+
+	// values are converted before being checked.
+	key, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewDoubleValue(1)))
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer())
+	require.NoError(t, err)
+
+	_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(-1)))
+	require.EqualError(t, err, `document violates check constraint "test_a_check"`)
+	err = tb.Replace(key, document.NewFieldBuffer().Add("a", document.NewIntegerValue(0)))
+	require.EqualError(t, err, `document violates check constraint "test_a_check"`)
+}
+
 // BenchmarkTableInsert benchmarks the Insert method with 1, 10, 1000 and 10000 successive insertions.
 func BenchmarkTableInsert(b *testing.B) {
 	for size := 1; size <= 10000; size *= 10 {
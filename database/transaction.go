@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
@@ -11,9 +12,10 @@ import (
 )
 
 var (
-	internalPrefix     = "__genji_"
-	tableInfoStoreName = internalPrefix + "tables"
-	indexStoreName     = internalPrefix + "indexes"
+	internalPrefix      = "__genji_"
+	tableInfoStoreName  = internalPrefix + "tables"
+	indexStoreName      = internalPrefix + "indexes"
+	statisticsStoreName = internalPrefix + "statistics"
 )
 
 // Transaction represents a database transaction. It provides methods for managing the
@@ -25,9 +27,16 @@ type Transaction struct {
 	db       *Database
 	tx       engine.Transaction
 	writable bool
+	// session the transaction is attached to, if any.
+	session *Session
 
 	tableInfoStore *tableInfoStore
 	indexStore     *indexStore
+
+	// changes made to the documents, published after commit.
+	changes []Change
+	// if set, counts the keys read by the tables and indexes.
+	keyCounter *KeyCounter
 }
 
 // DB returns the underlying database that created the transaction.
@@ -37,9 +46,6 @@ func (tx *Transaction) DB() *Database {
 
 // Rollback the transaction. Can be used safely after commit.
 func (tx *Transaction) Rollback() error {
-	tx.db.attachedTxMu.Lock()
-	defer tx.db.attachedTxMu.Unlock()
-
 	if tx.writable {
 		tx.tableInfoStore.rollback(tx)
 	}
@@ -49,18 +55,25 @@ func (tx *Transaction) Rollback() error {
 		return err
 	}
 
-	if tx.db.attachedTransaction != nil {
-		tx.db.attachedTransaction = nil
-	}
-
+	tx.detach()
+	tx.changes = nil
 	return nil
 }
 
-// Commit the transaction.
+// Commit the transaction and publish the changes it made to the subscribers.
 func (tx *Transaction) Commit() error {
-	tx.db.attachedTxMu.Lock()
-	defer tx.db.attachedTxMu.Unlock()
+	err := tx.commit()
+	if err != nil {
+		return err
+	}
 
+	changes := tx.changes
+	tx.changes = nil
+	tx.db.changeFeed.publish(changes)
+	return nil
+}
+
+func (tx *Transaction) commit() error {
 	if tx.writable {
 		tx.tableInfoStore.commit(tx)
 	}
@@ -70,12 +83,8 @@ func (tx *Transaction) Commit() error {
 		return err
 	}
 
-	if tx.db.attachedTransaction != nil {
-		tx.db.attachedTransaction = nil
-	}
-
+	tx.detach()
 	return nil
-
 }
 
 // Writable indicates if the transaction is writable or not.
@@ -95,7 +104,12 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 	}
 
 	info.tableName = name
-	err := tx.tableInfoStore.Insert(tx, name, info)
+	err := tx.prepareCheckConstraints(info)
+	if err != nil {
+		return err
+	}
+
+	err = tx.tableInfoStore.Insert(tx, name, info)
 	if err != nil {
 		return err
 	}
@@ -108,6 +122,51 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 	return nil
 }
 
+// prepareCheckConstraints makes sure the check constraints of a new table can be evaluated
+// and have distinct names. Missing names are generated from the name of the table
+// and the path of the field the constraint was declared on, e.g. "products_price_check".
+func (tx *Transaction) prepareCheckConstraints(info *TableInfo) error {
+	names := make(map[string]bool)
+	for _, cc := range info.CheckConstraints {
+		if cc.Name == "" {
+			continue
+		}
+
+		if names[cc.Name] {
+			return fmt.Errorf("duplicate check constraint %q", cc.Name)
+		}
+		names[cc.Name] = true
+	}
+
+	for i := range info.CheckConstraints {
+		cc := &info.CheckConstraints[i]
+
+		_, err := tx.db.parseExpr(cc.Expr)
+		if err != nil {
+			return err
+		}
+
+		if cc.Name != "" {
+			continue
+		}
+
+		var sb strings.Builder
+		sb.WriteString(info.tableName)
+		if len(cc.Path) > 0 {
+			writeNameParts(&sb, cc.Path.String())
+		}
+
+		base := sb.String() + "_check"
+		cc.Name = base
+		for j := 1; names[cc.Name]; j++ {
+			cc.Name = fmt.Sprintf("%s%d", base, j)
+		}
+		names[cc.Name] = true
+	}
+
+	return nil
+}
+
 // GetTable returns a table by name. The table instance is only valid for the lifetime of the transaction.
 func (tx *Transaction) GetTable(name string) (*Table, error) {
 	ti, err := tx.tableInfoStore.Get(tx, name)
@@ -122,7 +181,7 @@ func (tx *Transaction) GetTable(name string) (*Table, error) {
 
 	return &Table{
 		tx:        tx,
-		Store:     s,
+		Store:     tx.tableStore(s),
 		name:      name,
 		infoStore: tx.tableInfoStore,
 	}, nil
@@ -181,6 +240,11 @@ func (tx *Transaction) RenameTable(oldName, newName string) error {
 		}
 	}
 
+	err = tx.renameStatistics(oldName, newName)
+	if err != nil {
+		return err
+	}
+
 	// Delete the old reference from the tableInfoStore.
 	return tx.tableInfoStore.Delete(tx, oldName)
 }
@@ -230,6 +294,11 @@ func (tx *Transaction) DropTable(name string) error {
 		return err
 	}
 
+	err = tx.deleteStatistics(name)
+	if err != nil {
+		return err
+	}
+
 	err = tx.tableInfoStore.Delete(tx, name)
 	if err != nil {
 		return err
@@ -251,21 +320,94 @@ func (tx *Transaction) CreateIndex(opts IndexConfig) error {
 		return err
 	}
 
+	if opts.Multi && (len(opts.Paths) != 1 || opts.Expr != "") {
+		return errors.New("a multi-valued index must be created on a single path")
+	}
+
+	if opts.FullText && (len(opts.Paths) != 1 || opts.Expr != "" || opts.Unique || opts.Multi || opts.Where != "") {
+		return errors.New("a full-text index must be created on a single path and can't be unique, multi-valued or partial")
+	}
+
 	// if the index is created on a field on which we know the type,
 	// create a typed index.
-	for _, fc := range info.FieldConstraints {
-		if fc.Path.IsEqual(opts.Path) {
-			if fc.Type != 0 {
-				opts.Type = fc.Type
+	// composite and multi-valued indexes store arrays and their elements
+	// and thus can't be typed, the type of the values of expression
+	// indexes is unknown and full-text indexes store words.
+	if len(opts.Paths) == 1 && !opts.Multi && !opts.FullText {
+		for _, fc := range info.FieldConstraints {
+			if fc.Path.IsEqual(opts.Paths[0]) {
+				if fc.Type != 0 {
+					opts.Type = fc.Type
+				}
+
+				break
 			}
+		}
+	}
+
+	// make sure the expressions of the index can be evaluated.
+	for _, e := range []string{opts.Expr, opts.Where} {
+		if e == "" {
+			continue
+		}
+
+		_, err = tx.db.parseExpr(e)
+		if err != nil {
+			return err
+		}
+	}
 
-			break
+	if opts.IndexName == "" {
+		opts.IndexName, err = tx.generateIndexName(&opts)
+		if err != nil {
+			return err
 		}
 	}
 
 	return tx.indexStore.Insert(opts)
 }
 
+// generateIndexName generates the name of an index from the name of its table
+// and its paths or expression, e.g. "users_lower_email_idx".
+// A number is added to the name if an index with the same name already exists.
+func (tx *Transaction) generateIndexName(opts *IndexConfig) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(opts.TableName)
+	writeNameParts(&sb, opts.PathsString())
+
+	base := sb.String() + "_idx"
+	name := base
+	for i := 1; ; i++ {
+		_, err := tx.indexStore.Get(name)
+		if err == ErrIndexNotFound {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// writeNameParts writes the sequences of letters and digits of s
+// to sb, each one preceded by an underscore.
+func writeNameParts(sb *strings.Builder, s string) {
+	sep := true
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if sep {
+				sb.WriteByte('_')
+				sep = false
+			}
+			sb.WriteRune(r)
+			continue
+		}
+
+		sep = true
+	}
+}
+
 // GetIndex returns an index by name.
 func (tx *Transaction) GetIndex(name string) (*Index, error) {
 	opts, err := tx.indexStore.Get(name)
@@ -273,15 +415,40 @@ func (tx *Transaction) GetIndex(name string) (*Index, error) {
 		return nil, err
 	}
 
-	idx := index.NewIndex(tx.tx, opts.IndexName, index.Options{
-		Unique: opts.Unique,
-		Type:   opts.Type,
-	})
+	return tx.newIndex(*opts)
+}
 
-	return &Index{
-		Index: idx,
-		Opts:  *opts,
-	}, nil
+// newIndex returns the index described by opts, parsing its predicate if any.
+func (tx *Transaction) newIndex(opts IndexConfig) (*Index, error) {
+	idx := Index{
+		Index: index.NewIndex(tx.indexTx(), opts.IndexName, index.Options{
+			Unique: opts.Unique,
+			Type:   opts.Type,
+		}),
+		Opts: opts,
+		tx:   tx,
+	}
+
+	if opts.FullText {
+		idx.fullText = index.NewFullTextIndex(tx.indexTx(), opts.IndexName)
+	}
+
+	var err error
+	if opts.Expr != "" {
+		idx.expr, err = tx.db.parseExpr(opts.Expr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Where != "" {
+		idx.where, err = tx.db.parseExpr(opts.Where)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &idx, nil
 }
 
 // DropIndex deletes an index from the database.
@@ -295,6 +462,11 @@ func (tx *Transaction) DropIndex(name string) error {
 		return err
 	}
 
+	err = tx.deleteIndexStatistics(opts.TableName, name)
+	if err != nil {
+		return err
+	}
+
 	idx := index.NewIndex(tx.tx, opts.IndexName, index.Options{
 		Unique: opts.Unique,
 		Type:   opts.Type,
@@ -326,7 +498,12 @@ func (tx *Transaction) ReIndex(indexName string) error {
 	}
 
 	return tb.Iterate(func(d document.Document) error {
-		v, err := idx.Opts.Path.GetValue(d)
+		ok, err := idx.Matches(d)
+		if err != nil || !ok {
+			return err
+		}
+
+		vs, err := idx.Values(d)
 		if err == document.ErrFieldNotFound {
 			return nil
 		}
@@ -334,7 +511,14 @@ func (tx *Transaction) ReIndex(indexName string) error {
 			return err
 		}
 
-		return idx.Set(v, d.(document.Keyer).Key())
+		for _, v := range vs {
+			err = idx.Set(v, d.(document.Keyer).Key())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 }
 
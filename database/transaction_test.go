@@ -2,13 +2,13 @@ package database_test
 
 import (
 	"errors"
-	"testing"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding/msgpack"
 	"github.com/genjidb/genji/engine/memoryengine"
 	"github.com/genjidb/genji/key"
 	"github.com/stretchr/testify/require"
+	"testing"
 )
 
 func newTestDB(t testing.TB) (*database.Transaction, func()) {
@@ -121,9 +121,9 @@ func TestTxTable(t *testing.T) {
 		err := tx.CreateTable("foo", ti)
 		require.NoError(t, err)
 
-		err = tx.CreateIndex(database.IndexConfig{Path: parsePath(t, "gender"), IndexName: "idx_gender", TableName: "foo"})
+		err = tx.CreateIndex(database.IndexConfig{Paths: []document.ValuePath{parsePath(t, "gender")}, IndexName: "idx_gender", TableName: "foo"})
 		require.NoError(t, err)
-		err = tx.CreateIndex(database.IndexConfig{Path: parsePath(t, "city"), IndexName: "idx_city", TableName: "foo", Unique: true})
+		err = tx.CreateIndex(database.IndexConfig{Paths: []document.ValuePath{parsePath(t, "city")}, IndexName: "idx_city", TableName: "foo", Unique: true})
 		require.NoError(t, err)
 
 		err = tx.RenameTable("foo", "zoo")
@@ -212,7 +212,7 @@ func TestTxCreateIndex(t *testing.T) {
 		require.NoError(t, err)
 
 		err = tx.CreateIndex(database.IndexConfig{
-			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+			IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
 		})
 		require.NoError(t, err)
 		idx, err := tx.GetIndex("idxFoo")
@@ -228,12 +228,12 @@ func TestTxCreateIndex(t *testing.T) {
 		require.NoError(t, err)
 
 		err = tx.CreateIndex(database.IndexConfig{
-			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+			IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
 		})
 		require.NoError(t, err)
 
 		err = tx.CreateIndex(database.IndexConfig{
-			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+			IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
 		})
 		require.Equal(t, database.ErrIndexAlreadyExists, err)
 	})
@@ -243,12 +243,32 @@ func TestTxCreateIndex(t *testing.T) {
 		defer cleanup()
 
 		err := tx.CreateIndex(database.IndexConfig{
-			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+			IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
 		})
 		if !errors.Is(err, database.ErrTableNotFound) {
 			require.Equal(t, err, database.ErrTableNotFound)
 		}
 	})
+
+	t.Run("Should generate a name if it's empty", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			err = tx.CreateIndex(database.IndexConfig{
+				TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo.bar"), parsePath(t, "baz[1]")},
+			})
+			require.NoError(t, err)
+		}
+
+		_, err = tx.GetIndex("test_foo_bar_baz_1_idx")
+		require.NoError(t, err)
+		_, err = tx.GetIndex("test_foo_bar_baz_1_idx1")
+		require.NoError(t, err)
+	})
 }
 
 func TestTxDropIndex(t *testing.T) {
@@ -260,7 +280,7 @@ func TestTxDropIndex(t *testing.T) {
 		require.NoError(t, err)
 
 		err = tx.CreateIndex(database.IndexConfig{
-			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+			IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
 		})
 		require.NoError(t, err)
 
@@ -299,13 +319,13 @@ func TestTxReIndex(t *testing.T) {
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "a",
 			TableName: "test",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "b",
 			TableName: "test",
-			Path:      parsePath(t, "b"),
+			Paths:     []document.ValuePath{parsePath(t, "b")},
 		})
 		require.NoError(t, err)
 
@@ -337,7 +357,7 @@ func TestTxReIndex(t *testing.T) {
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "b",
 			TableName: "test",
-			Path:      parsePath(t, "b"),
+			Paths:     []document.ValuePath{parsePath(t, "b")},
 		})
 
 		err = tx.ReIndex("b")
@@ -417,13 +437,13 @@ func TestReIndexAll(t *testing.T) {
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "t1a",
 			TableName: "test1",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 		err = tx.CreateIndex(database.IndexConfig{
 			IndexName: "t2a",
 			TableName: "test2",
-			Path:      parsePath(t, "a"),
+			Paths:     []document.ValuePath{parsePath(t, "a")},
 		})
 		require.NoError(t, err)
 
@@ -459,3 +479,52 @@ func TestReIndexAll(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestTxCountKeys(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", nil)
+	require.NoError(t, err)
+
+	err = tx.CreateIndex(database.IndexConfig{
+		IndexName: "idxFoo", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")},
+	})
+	require.NoError(t, err)
+
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	var keys [][]byte
+	for i := 0; i < 5; i++ {
+		k, err := tb.Insert(document.NewFieldBuffer().Add("foo", document.NewIntegerValue(int64(i))))
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+
+	var c database.KeyCounter
+	tx.CountKeys(&c)
+
+	tb, err = tx.GetTable("test")
+	require.NoError(t, err)
+	idx, err := tx.GetIndex("idxFoo")
+	require.NoError(t, err)
+
+	err = tb.Iterate(func(d document.Document) error { return nil })
+	require.NoError(t, err)
+	_, err = tb.GetDocument(keys[0])
+	require.NoError(t, err)
+	require.Equal(t, database.KeyCounter{TableKeys: 6}, c)
+
+	err = idx.AscendGreaterOrEqual(document.Value{}, func(val, key []byte, isEqual bool) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, database.KeyCounter{TableKeys: 6, IndexKeys: 5}, c)
+
+	// tables returned after counting stopped don't count the keys they read.
+	tx.CountKeys(nil)
+	tb, err = tx.GetTable("test")
+	require.NoError(t, err)
+	err = tb.Iterate(func(d document.Document) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, database.KeyCounter{TableKeys: 6, IndexKeys: 5}, c)
+}
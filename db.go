@@ -59,6 +59,14 @@ func (db *DB) Update(fn func(tx *Tx) error) error {
 	return tx.Commit()
 }
 
+// Subscribe registers fn to be called for every document inserted, replaced or deleted
+// in the given table, or in any table if table is empty, once the transaction
+// that changed it is committed. It returns a function that cancels the subscription.
+// See database.Database.Subscribe for details.
+func (db *DB) Subscribe(table string, fn func(database.Change)) (unsubscribe func()) {
+	return db.DB.Subscribe(table, fn)
+}
+
 // Exec a query against the database without returning the result.
 func (db *DB) Exec(ctx context.Context, q string, args ...interface{}) error {
 	res, err := db.Query(ctx, q, args...)
@@ -77,7 +85,7 @@ func (db *DB) Query(ctx context.Context, q string, args ...interface{}) (*query.
 		return nil, err
 	}
 
-	return pq.Run(ctx, db.DB, argsToParams(args))
+	return pq.Run(ctx, db.DB.DefaultSession(), argsToParams(args))
 }
 
 // QueryDocument runs the query and returns the first document.
@@ -107,6 +115,43 @@ func (db *DB) QueryDocument(ctx context.Context, q string, args ...interface{})
 	return &fb, nil
 }
 
+// NewSession creates a session. Sessions own their own transaction started
+// with the BEGIN statement, which allows multiple clients to run
+// explicit transactions concurrently.
+// The session must be closed after usage.
+func (db *DB) NewSession() *Session {
+	return &Session{
+		Session: db.DB.NewSession(),
+	}
+}
+
+// A Session runs queries using its own attached transaction, if any.
+// A session must not be used by multiple goroutines concurrently.
+type Session struct {
+	*database.Session
+}
+
+// Query the database using the session and return the result.
+// The returned result must always be closed after usage.
+func (s *Session) Query(ctx context.Context, q string, args ...interface{}) (*query.Result, error) {
+	pq, err := parser.ParseQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return pq.Run(ctx, s.Session, argsToParams(args))
+}
+
+// Exec a query using the session without returning the result.
+func (s *Session) Exec(ctx context.Context, q string, args ...interface{}) error {
+	res, err := s.Query(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+
+	return res.Close()
+}
+
 // Tx represents a database transaction. It provides methods for managing the
 // collection of tables and the transaction itself.
 // Tx is either read-only or read/write. Read-only can be used to read tables
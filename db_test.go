@@ -2,6 +2,7 @@ package genji_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
@@ -121,3 +122,63 @@ func TestQueryDocument(t *testing.T) {
 		require.Nil(t, r)
 	})
 }
+
+func TestSubscribe(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; CREATE TABLE other")
+	require.NoError(t, err)
+
+	var changes []string
+	unsubscribe := db.Subscribe("test", func(c database.Change) {
+		s := c.Type.String()
+		for _, d := range []document.Document{c.Old, c.New} {
+			if d == nil {
+				s += " nil"
+				continue
+			}
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			s += " " + string(data)
+		}
+		changes = append(changes, s)
+	})
+
+	var all int
+	defer db.Subscribe("", func(c database.Change) { all++ })()
+
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (1), (2)")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "INSERT INTO other (a) VALUES (1)")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "UPDATE test SET a = 10 WHERE a = 1")
+	require.NoError(t, err)
+	err = db.Exec(ctx, "DELETE FROM test WHERE a = 2")
+	require.NoError(t, err)
+
+	// rolled back transactions emit nothing
+	err = db.Update(func(tx *genji.Tx) error {
+		err := tx.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+		require.NoError(t, err)
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	require.Equal(t, []string{
+		`insert nil {"a": 1}`,
+		`insert nil {"a": 2}`,
+		`replace {"a": 1} {"a": 10}`,
+		`delete {"a": 2} nil`,
+	}, changes)
+	require.Equal(t, 5, all)
+
+	unsubscribe()
+	err = db.Exec(ctx, "INSERT INTO test (a) VALUES (4)")
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	require.Equal(t, 6, all)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strconv"
+	"time"
 )
 
 // CastAs casts v as the selected type when possible.
@@ -24,6 +25,8 @@ func (v Value) CastAs(t ValueType) (Value, error) {
 		return v.CastAsInteger()
 	case DoubleValue:
 		return v.CastAsDouble()
+	case TimestampValue:
+		return v.CastAsTimestamp()
 	case BlobValue:
 		return v.CastAsBlob()
 	case TextValue:
@@ -62,6 +65,7 @@ func (v Value) CastAsBool() (Value, error) {
 // CastAsInteger casts according to the following rules:
 // Bool: returns 1 if true, 0 if false.
 // Double: cuts off the decimal and remaining numbers.
+// Timestamp: returns the number of seconds elapsed since January 1, 1970 UTC.
 // Text: uses strconv.ParseInt to determine the integer value,
 // then casts it to an integer. If it fails uses strconv.ParseFloat
 // to determine the double value, then casts it to an integer
@@ -78,6 +82,8 @@ func (v Value) CastAsInteger() (Value, error) {
 		return NewIntegerValue(0), nil
 	case DoubleValue:
 		return NewIntegerValue(int64(v.V.(float64))), nil
+	case TimestampValue:
+		return NewIntegerValue(v.V.(time.Time).Unix()), nil
 	case TextValue:
 		i, err := strconv.ParseInt(v.V.(string), 10, 64)
 		if err != nil {
@@ -116,6 +122,28 @@ func (v Value) CastAsDouble() (Value, error) {
 	return Value{}, fmt.Errorf("cannot cast %s as double", v.Type)
 }
 
+// CastAsTimestamp casts according to the following rules:
+// Integer: considered as the number of seconds elapsed since January 1, 1970 UTC.
+// Text: parses a timestamp formatted using RFC 3339, it fails if the text
+// doesn't contain a valid timestamp.
+// Any other type is considered an invalid cast.
+func (v Value) CastAsTimestamp() (Value, error) {
+	switch v.Type {
+	case TimestampValue:
+		return v, nil
+	case IntegerValue:
+		return NewTimestampValue(time.Unix(v.V.(int64), 0)), nil
+	case TextValue:
+		t, err := time.Parse(time.RFC3339Nano, v.V.(string))
+		if err != nil {
+			return Value{}, fmt.Errorf(`cannot cast %q as timestamp: %w`, v.V, err)
+		}
+		return NewTimestampValue(t), nil
+	}
+
+	return Value{}, fmt.Errorf("cannot cast %s as timestamp", v.Type)
+}
+
 // CastAsText returns a JSON representation of v.
 // If the representation is a string, it gets unquoted.
 func (v Value) CastAsText() (Value, error) {
@@ -130,7 +158,7 @@ func (v Value) CastAsText() (Value, error) {
 
 	s := string(d)
 
-	if v.Type == BlobValue {
+	if v.Type == BlobValue || v.Type == TimestampValue {
 		s, err = strconv.Unquote(s)
 		if err != nil {
 			return Value{}, err
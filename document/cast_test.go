@@ -2,6 +2,7 @@ package document
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -17,6 +18,7 @@ func TestCastAs(t *testing.T) {
 	doubleV := NewDoubleValue(10.5)
 	textV := NewTextValue("foo")
 	blobV := NewBlobValue([]byte("abc"))
+	timestampV := NewTimestampValue(time.Date(2020, 10, 5, 12, 30, 10, 0, time.UTC))
 	arrayV := NewArrayValue(NewValueBuffer().
 		Append(NewTextValue("bar")).
 		Append(integerV))
@@ -62,6 +64,7 @@ func TestCastAs(t *testing.T) {
 			{textV, Value{}, true},
 			{NewTextValue("10"), integerV, false},
 			{NewTextValue("10.5"), integerV, false},
+			{timestampV, NewIntegerValue(1601901010), false},
 			{blobV, Value{}, true},
 			{arrayV, Value{}, true},
 			{docV, Value{}, true},
@@ -89,6 +92,7 @@ func TestCastAs(t *testing.T) {
 			{doubleV, NewTextValue("10.5"), false},
 			{textV, textV, false},
 			{blobV, NewTextValue("YWJj"), false},
+			{timestampV, NewTextValue("2020-10-05T12:30:10Z"), false},
 			{arrayV, NewTextValue(`["bar", 10]`), false},
 			{docV,
 				NewTextValue(`{"a": 10, "b": "foo"}`),
@@ -96,6 +100,22 @@ func TestCastAs(t *testing.T) {
 		})
 	})
 
+	t.Run("timestamp", func(t *testing.T) {
+		check(t, TimestampValue, []test{
+			{boolV, Value{}, true},
+			{NewIntegerValue(1601901010), timestampV, false},
+			{doubleV, Value{}, true},
+			{textV, Value{}, true},
+			{NewTextValue("2020-10-05T12:30:10Z"), timestampV, false},
+			{NewTextValue("2020-10-05T14:30:10+02:00"), timestampV, false},
+			{NewTextValue("2020-10-05T12:30:10.000000001Z"), timestampV, false},
+			{timestampV, timestampV, false},
+			{blobV, Value{}, true},
+			{arrayV, Value{}, true},
+			{docV, Value{}, true},
+		})
+	})
+
 	t.Run("blob", func(t *testing.T) {
 		check(t, BlobValue, []test{
 			{boolV, Value{}, true},
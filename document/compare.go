@@ -3,6 +3,7 @@ package document
 import (
 	"bytes"
 	"strings"
+	"time"
 )
 
 type operator uint8
@@ -68,6 +69,9 @@ func (v Value) IsLesserThanOrEqual(other Value) (bool, error) {
 }
 
 func compare(op operator, l, r Value, compareDifferentTypes bool) (bool, error) {
+	// texts compared with timestamps are converted to timestamps.
+	l, r = timestampOperands(l, r)
+
 	switch {
 	// deal with nil
 	case l.Type == NullValue || r.Type == NullValue:
@@ -93,6 +97,10 @@ func compare(op operator, l, r Value, compareDifferentTypes bool) (bool, error)
 	case l.Type.IsNumber() && r.Type.IsNumber():
 		return compareNumbers(op, l, r)
 
+	// compare timestamps together
+	case l.Type == TimestampValue && r.Type == TimestampValue:
+		return compareTimestamps(op, l.V.(time.Time), r.V.(time.Time)), nil
+
 	// compare arrays together
 	case l.Type == ArrayValue && r.Type == ArrayValue:
 		return compareArrays(op, l.V.(Array), r.V.(Array))
@@ -116,6 +124,23 @@ func compare(op operator, l, r Value, compareDifferentTypes bool) (bool, error)
 	return false, nil
 }
 
+// timestampOperands converts l or r to a timestamp if it is a text representing a timestamp
+// and the other one is a timestamp.
+func timestampOperands(l, r Value) (Value, Value) {
+	switch {
+	case l.Type == TimestampValue && r.Type == TextValue:
+		if v, err := r.CastAsTimestamp(); err == nil {
+			r = v
+		}
+	case l.Type == TextValue && r.Type == TimestampValue:
+		if v, err := l.CastAsTimestamp(); err == nil {
+			l = v
+		}
+	}
+
+	return l, r
+}
+
 func compareWithNull(op operator, l, r Value) (bool, error) {
 	switch op {
 	case operatorEq, operatorGte, operatorLte:
@@ -228,6 +253,23 @@ func compareNumbers(op operator, l, r Value) (bool, error) {
 	return ok, nil
 }
 
+func compareTimestamps(op operator, l, r time.Time) bool {
+	switch op {
+	case operatorEq:
+		return l.Equal(r)
+	case operatorGt:
+		return l.After(r)
+	case operatorGte:
+		return !l.Before(r)
+	case operatorLt:
+		return l.Before(r)
+	case operatorLte:
+		return !l.After(r)
+	}
+
+	return false
+}
+
 func compareArrays(op operator, l Array, r Array) (bool, error) {
 	var i, j int
 
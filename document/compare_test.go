@@ -37,6 +37,13 @@ func toText(t testing.TB, x string) document.Value {
 	return document.NewTextValue(x)
 }
 
+func toTimestamp(t testing.TB, x string) document.Value {
+	v, err := document.NewTextValue(x).CastAsTimestamp()
+	require.NoError(t, err)
+
+	return v
+}
+
 func toBlob(t testing.TB, x string) document.Value {
 	return document.NewBlobValue([]byte(x))
 }
@@ -136,6 +143,16 @@ func TestCompare(t *testing.T) {
 		{"<=", "a", "b", true, toText},
 		{"<=", "b", "b", true, toText},
 
+		// timestamp
+		{"=", "2020-10-05T12:00:00Z", "2020-10-05T12:00:00Z", true, toTimestamp},
+		{"=", "2020-10-05T12:00:00Z", "2020-10-05T14:00:00+02:00", true, toTimestamp},
+		{"!=", "2020-10-05T12:00:00Z", "2020-10-05T12:00:01Z", true, toTimestamp},
+		{">", "2020-10-05T12:00:01Z", "2020-10-05T12:00:00Z", true, toTimestamp},
+		{">", "2020-10-05T12:00:00Z", "2020-10-05T12:00:01Z", false, toTimestamp},
+		{">=", "2020-10-05T12:00:00Z", "2020-10-05T12:00:00Z", true, toTimestamp},
+		{"<", "1969-10-05T12:00:00Z", "2020-10-05T12:00:00Z", true, toTimestamp},
+		{"<=", "2020-10-05T12:00:01Z", "2020-10-05T12:00:00Z", false, toTimestamp},
+
 		// blob
 		{"=", "b", "a", false, toBlob},
 		{"=", "b", "b", true, toBlob},
@@ -249,3 +266,55 @@ func TestCompare(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareTimestampWithText(t *testing.T) {
+	ts := toTimestamp(t, "2020-10-05T12:00:00Z")
+
+	tests := []struct {
+		op   string
+		text string
+		ok   bool
+	}{
+		{"=", "2020-10-05T12:00:00Z", true},
+		{"=", "2020-10-05T14:00:00+02:00", true},
+		{"=", "2020-10-05T12:00:01Z", false},
+		{"!=", "2020-10-05T12:00:01Z", true},
+		{">", "2020-01-01T00:00:00Z", true},
+		{">", "2021-01-01T00:00:00Z", false},
+		{"<", "2021-01-01T00:00:00Z", true},
+		// texts that are not timestamps are never equal, greater or lesser than a timestamp.
+		{"=", "foo", false},
+		{">", "foo", false},
+		{"<", "foo", false},
+	}
+
+	for _, test := range tests {
+		text := document.NewTextValue(test.text)
+		t.Run(fmt.Sprintf("%v%v%v", ts, test.op, text), func(t *testing.T) {
+			for _, operands := range [][2]document.Value{{ts, text}, {text, ts}} {
+				a, b := operands[0], operands[1]
+				op := test.op
+				// the operator is reversed when the text is the left operand.
+				if a.Type == document.TextValue {
+					op = map[string]string{"=": "=", "!=": "!=", ">": "<", "<": ">"}[op]
+				}
+
+				var ok bool
+				var err error
+
+				switch op {
+				case "=":
+					ok, err = a.IsEqual(b)
+				case "!=":
+					ok, err = a.IsNotEqual(b)
+				case ">":
+					ok, err = a.IsGreaterThan(b)
+				case "<":
+					ok, err = a.IsLesserThan(b)
+				}
+				require.NoError(t, err)
+				require.Equal(t, test.ok, ok)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
@@ -127,6 +128,8 @@ func EncodeValue(v document.Value) ([]byte, error) {
 		return encodeInt64(v.V.(int64)), nil
 	case document.DoubleValue:
 		key.AppendFloat64(nil, v.V.(float64))
+	case document.TimestampValue:
+		return key.AppendTimestamp(nil, v.V.(time.Time)), nil
 	case document.NullValue:
 		return nil, nil
 	}
@@ -328,6 +331,12 @@ func DecodeValue(t document.ValueType, data []byte) (document.Value, error) {
 			return document.Value{}, err
 		}
 		return document.NewDoubleValue(x), nil
+	case document.TimestampValue:
+		x, err := key.DecodeTimestamp(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewTimestampValue(x), nil
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	}
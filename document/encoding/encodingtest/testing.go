@@ -4,6 +4,7 @@ package encodingtest
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
@@ -46,7 +47,8 @@ func testEncodeDecode(t *testing.T, codecBuilder func() encoding.Codec) {
 		Append(document.NewBoolValue(true)).
 		Append(document.NewTextValue("hello")).
 		Append(document.NewDocumentValue(addressMapDoc)).
-		Append(document.NewArrayValue(document.NewValueBuffer().Append(document.NewIntegerValue(11))))
+		Append(document.NewArrayValue(document.NewValueBuffer().Append(document.NewIntegerValue(11)))).
+		Append(document.NewTimestampValue(time.Date(2020, 10, 2, 15, 4, 5, 123456000, time.UTC)))
 
 	tests := []struct {
 		name     string
@@ -72,7 +74,7 @@ func testEncodeDecode(t *testing.T, codecBuilder func() encoding.Codec) {
 				Add("name", document.NewTextValue("john")).
 				Add("address", document.NewDocumentValue(addressMapDoc)).
 				Add("array", document.NewArrayValue(complexArray)),
-			`{"age": 10, "name": "john", "address": {"city": "Ajaccio", "country": "France"}, "array": [-40, true, "hello", {"city": "Ajaccio", "country": "France"}, [11]]}`,
+			`{"age": 10, "name": "john", "address": {"city": "Ajaccio", "country": "France"}, "array": [-40, true, "hello", {"city": "Ajaccio", "country": "France"}, [11], "2020-10-02T15:04:05.123456Z"]}`,
 		},
 	}
 
@@ -1,8 +1,10 @@
 package msgpack
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
@@ -109,6 +111,7 @@ func (e *Encoder) EncodeArray(a document.Array) error {
 // - int32 -> int32
 // - int64 -> int64
 // - float64 -> float64
+// - timestamp -> timestamp extension
 func (e *Encoder) EncodeValue(v document.Value) error {
 	switch v.Type {
 	case document.DocumentValue:
@@ -127,6 +130,8 @@ func (e *Encoder) EncodeValue(v document.Value) error {
 		return e.enc.EncodeInt64(v.V.(int64))
 	case document.DoubleValue:
 		return e.enc.EncodeFloat64(v.V.(float64))
+	case document.TimestampValue:
+		return e.enc.EncodeTime(v.V.(time.Time))
 	}
 
 	return e.enc.Encode(v.V)
@@ -232,11 +237,59 @@ func (d *Decoder) DecodeValue() (v document.Value, err error) {
 		}
 		v.Type = document.DoubleValue
 		return
+	// the timestamp extension is the only extension used by Genji
+	case codes.FixExt4, codes.FixExt8, codes.Ext8:
+		var t time.Time
+		t, err = d.decodeTime()
+		if err != nil {
+			return
+		}
+		v = document.NewTimestampValue(t)
+		return
 	}
 
 	panic(fmt.Sprintf("unsupported type %v", c))
 }
 
+// timestampExtID is the type id of the MessagePack timestamp extension.
+const timestampExtID = -1
+
+// decodeTime decodes a timestamp extension. Other extensions are rejected
+// instead of being misread as timestamps.
+func (d *Decoder) decodeTime() (time.Time, error) {
+	typeID, length, err := d.dec.DecodeExtHeader()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if typeID != timestampExtID {
+		return time.Time{}, fmt.Errorf("unsupported extension type %d", typeID)
+	}
+
+	b := make([]byte, length)
+	err = d.dec.ReadFull(b)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	switch length {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(b)), 0)
+	case 8:
+		sec := binary.BigEndian.Uint64(b)
+		t = time.Unix(int64(sec&0x00000003ffffffff), int64(sec>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b)))
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp length %d", length)
+	}
+
+	if t.IsZero() {
+		return t.UTC(), nil
+	}
+	return t, nil
+}
+
 // DecodeDocument decodes one document from the reader.
 // If the document is malformed, it will not return an error.
 // However, calls to Iterate or GetByField will fail.
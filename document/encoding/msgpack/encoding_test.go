@@ -2,9 +2,12 @@ package msgpack
 
 import (
 	"testing"
+	"time"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/document/encoding/encodingtest"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCodec(t *testing.T) {
@@ -18,3 +21,17 @@ func BenchmarkCodec(b *testing.B) {
 		return NewCodec()
 	})
 }
+
+func TestDecodeExtension(t *testing.T) {
+	// {"a": <fixext4>}
+	doc := func(typeID byte) EncodedDocument {
+		return EncodedDocument{0x81, 0xa1, 'a', 0xd6, typeID, 0, 0, 0, 42}
+	}
+
+	v, err := doc(0xff).GetByField("a")
+	require.NoError(t, err)
+	require.Equal(t, document.NewTimestampValue(time.Unix(42, 0)), v)
+
+	_, err = doc(2).GetByField("a")
+	require.EqualError(t, err, "unsupported extension type 2")
+}
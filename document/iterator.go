@@ -2,8 +2,12 @@ package document
 
 import (
 	"bufio"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"time"
 )
 
 // ErrStreamClosed is used to indicate that a stream must be closed.
@@ -257,21 +261,39 @@ func (s Stream) First() (d Document, err error) {
 	return
 }
 
-// GroupBy tags each document with the group they belong to. The group is determined by the value returned by groupFn.
-func (s Stream) GroupBy(groupFn func(d Document) (Value, error)) Stream {
+// GroupBy tags each document with the group they belong to. The group is determined by the values
+// returned by groupFns. If there is more than one function, the group is a tuple, represented by an array
+// containing the value returned by each function. Missing fields are considered NULL.
+func (s Stream) GroupBy(groupFns ...func(d Document) (Value, error)) Stream {
 	return s.Pipe(func() func(d Document) (Document, error) {
 		var gd groupedDocument
+		var vb ValueBuffer
 
 		return func(d Document) (Document, error) {
-			v, err := groupFn(d)
-			if err != nil && err != ErrFieldNotFound {
-				return nil, err
+			vb = vb[:0]
+			for _, fn := range groupFns {
+				v, err := fn(d)
+				if err != nil && err != ErrFieldNotFound {
+					return nil, err
+				}
+				if err == ErrFieldNotFound {
+					v = NewNullValue()
+				}
+
+				vb = vb.Append(v)
 			}
-			if err == ErrFieldNotFound {
-				v = NewNullValue()
+
+			var err error
+			gd.key, err = AppendGroupKey(gd.key[:0], NewArrayValue(vb))
+			if err != nil {
+				return nil, err
 			}
 
-			gd.group = v
+			if len(vb) == 1 {
+				gd.group = vb[0]
+			} else {
+				gd.group = NewArrayValue(vb)
+			}
 			gd.Document = d
 
 			return &gd, nil
@@ -280,33 +302,53 @@ func (s Stream) GroupBy(groupFn func(d Document) (Value, error)) Stream {
 }
 
 // Aggregate builds a list of aggregators for each group of documents and passes each document of the stream to them.
+// It returns one document per group, in the order the groups were found, containing the fields created by
+// the aggregators. The other fields are those of the first document of the group.
+// Documents that weren't tagged by GroupBy all belong to the same group.
 func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
 	return NewStream(IteratorFunc(func(fn func(d Document) error) error {
-		aggregates := make(map[Value][]Aggregator)
-		var groups []Value
+		type group struct {
+			aggs  []Aggregator
+			first FieldBuffer
+		}
+
+		// groups are identified by the encoding of their value,
+		// which allows using arrays and documents as keys.
+		groups := make(map[string]*group)
+		var keys []string
 
 		nullValue := NewNullValue()
 
 		err := s.Iterate(func(d Document) error {
-			group := nullValue
+			value := nullValue
+			var key []byte
 
 			if gd, ok := d.(*groupedDocument); ok {
-				group = gd.group
+				value = gd.group
+				key = gd.key
+				d = gd.Document
 			}
 
-			aggs, ok := aggregates[group]
+			g, ok := groups[string(key)]
 			if !ok {
-				groups = append(groups, group)
-				aggs = make([]Aggregator, len(aggregatorBuilders))
+				g = &group{
+					aggs: make([]Aggregator, len(aggregatorBuilders)),
+				}
 				for i, builder := range aggregatorBuilders {
-					aggs[i] = builder.NewAggregator(group)
+					g.aggs[i] = builder.NewAggregator(value)
 				}
-				aggregates[group] = aggs
+
+				err := g.first.Copy(d)
+				if err != nil {
+					return err
+				}
+
+				groups[string(key)] = g
+				keys = append(keys, string(key))
 			}
 
-			var err error
-			for _, agg := range aggs {
-				err = agg.Add(d)
+			for _, agg := range g.aggs {
+				err := agg.Add(d)
 				if err != nil {
 					return err
 				}
@@ -318,17 +360,20 @@ func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
 			return err
 		}
 
-		for _, group := range groups {
-			fb := NewFieldBuffer()
-			aggs := aggregates[group]
-			for _, agg := range aggs {
-				err = agg.Aggregate(fb)
+		for _, k := range keys {
+			g := groups[k]
+
+			ad := aggregatedDocument{
+				first: &g.first,
+			}
+			for _, agg := range g.aggs {
+				err = agg.Aggregate(&ad.fb)
 				if err != nil {
 					return err
 				}
 			}
 
-			err = fn(fb)
+			err = fn(&ad)
 			if err != nil {
 				return err
 			}
@@ -338,6 +383,38 @@ func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
 	}))
 }
 
+// An aggregatedDocument is a document returned by Aggregate.
+// Fields that weren't created by an aggregator are read from the
+// first document of the group.
+type aggregatedDocument struct {
+	fb    FieldBuffer
+	first Document
+}
+
+func (d *aggregatedDocument) GetByField(field string) (Value, error) {
+	v, err := d.fb.GetByField(field)
+	if err != ErrFieldNotFound {
+		return v, err
+	}
+
+	return d.first.GetByField(field)
+}
+
+func (d *aggregatedDocument) Iterate(fn func(field string, value Value) error) error {
+	err := d.fb.Iterate(fn)
+	if err != nil {
+		return err
+	}
+
+	return d.first.Iterate(func(field string, value Value) error {
+		if _, err := d.fb.GetByField(field); err == nil {
+			return nil
+		}
+
+		return fn(field, value)
+	})
+}
+
 // An Aggregator aggregates documents into a single one.
 type Aggregator interface {
 	Add(d Document) error
@@ -355,6 +432,83 @@ type groupedDocument struct {
 	Document
 
 	group Value
+	// encoding of the group value, see AppendGroupKey.
+	key []byte
+}
+
+// AppendGroupKey appends an encoding of v to buf. Two values have the same encoding
+// if and only if they are equal, integers being equal to doubles with the same value.
+// It is used to group documents and to remove duplicate documents.
+// Every encoded value starts with a non-zero byte, which allows arrays and documents
+// to mark the end of their content with a zero byte.
+func AppendGroupKey(buf []byte, v Value) ([]byte, error) {
+	switch v.Type {
+	case NullValue:
+		return append(buf, byte(v.Type)), nil
+	case BoolValue:
+		if v.V.(bool) {
+			return append(buf, byte(v.Type), 1), nil
+		}
+		return append(buf, byte(v.Type), 0), nil
+	case IntegerValue:
+		i := v.V.(int64)
+		if f := float64(i); int64(f) == i {
+			return AppendGroupKey(buf, NewDoubleValue(f))
+		}
+		buf = append(buf, byte(v.Type))
+		return appendUint64(buf, uint64(i)), nil
+	case DoubleValue:
+		f := v.V.(float64)
+		// -0 and 0 are equal
+		if f == 0 {
+			f = 0
+		}
+		buf = append(buf, byte(v.Type))
+		return appendUint64(buf, math.Float64bits(f)), nil
+	case TimestampValue:
+		t := v.V.(time.Time)
+		buf = append(buf, byte(v.Type))
+		buf = appendUint64(buf, uint64(t.Unix()))
+		return appendUint64(buf, uint64(t.Nanosecond())), nil
+	case TextValue:
+		buf = append(buf, byte(v.Type))
+		return appendBytes(buf, []byte(v.V.(string))), nil
+	case BlobValue:
+		buf = append(buf, byte(v.Type))
+		return appendBytes(buf, v.V.([]byte)), nil
+	case ArrayValue:
+		buf = append(buf, byte(v.Type))
+		err := v.V.(Array).Iterate(func(i int, value Value) error {
+			var err error
+			buf, err = AppendGroupKey(buf, value)
+			return err
+		})
+		return append(buf, 0), err
+	case DocumentValue:
+		buf = append(buf, byte(v.Type))
+		err := v.V.(Document).Iterate(func(field string, value Value) error {
+			var err error
+			buf = appendBytes(append(buf, 1), []byte(field))
+			buf, err = AppendGroupKey(buf, value)
+			return err
+		})
+		return append(buf, 0), err
+	}
+
+	return nil, fmt.Errorf("cannot group by value of type %s", v.Type)
+}
+
+func appendUint64(buf []byte, x uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	return append(buf, b[:]...)
+}
+
+// appendBytes appends the length of b followed by b.
+func appendBytes(buf []byte, b []byte) []byte {
+	var l [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(l[:], uint64(len(b)))
+	return append(append(buf, l[:n]...), b...)
 }
 
 // An StreamOperator is used to modify a stream.
@@ -170,3 +170,71 @@ func TestIteratorToJSONArray(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, `[{"a": 0}, {"a": 1}, {"a": 2}]`, buf.String())
 }
+
+type counterBuilder struct{}
+
+func (counterBuilder) NewAggregator(group document.Value) document.Aggregator {
+	return new(counter)
+}
+
+type counter struct {
+	n int64
+}
+
+func (c *counter) Add(d document.Document) error {
+	c.n++
+	return nil
+}
+
+func (c *counter) Aggregate(fb *document.FieldBuffer) error {
+	fb.Add("count", document.NewIntegerValue(c.n))
+	return nil
+}
+
+func TestStreamAggregate(t *testing.T) {
+	var docs []document.Document
+	for _, s := range []string{
+		`{"g": [1, 2], "h": "x"}`,
+		`{"g": {"a": 1}, "h": "y"}`,
+		`{"g": [1.0, 2.0], "h": "x"}`,
+		`{"g": {"a": 1.0}, "h": "z"}`,
+		`{"g": [2, 1]}`,
+	} {
+		fb := document.NewFieldBuffer()
+		err := json.Unmarshal([]byte(s), fb)
+		require.NoError(t, err)
+		docs = append(docs, fb)
+	}
+
+	field := func(name string) func(d document.Document) (document.Value, error) {
+		return func(d document.Document) (document.Value, error) {
+			return d.GetByField(name)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		groupFns []func(d document.Document) (document.Value, error)
+		expected string
+	}{
+		{"No group", nil, `[{"count": 5, "g": [1, 2], "h": "x"}]`},
+		{"Arrays and documents", []func(d document.Document) (document.Value, error){field("g")},
+			`[{"count": 2, "g": [1, 2], "h": "x"}, {"count": 2, "g": {"a": 1}, "h": "y"}, {"count": 1, "g": [2, 1]}]`},
+		{"Tuple", []func(d document.Document) (document.Value, error){field("g"), field("h")},
+			`[{"count": 2, "g": [1, 2], "h": "x"}, {"count": 1, "g": {"a": 1}, "h": "y"}, {"count": 1, "g": {"a": 1.0}, "h": "z"}, {"count": 1, "g": [2, 1]}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			st := document.NewStream(document.NewIterator(docs...))
+			if test.groupFns != nil {
+				st = st.GroupBy(test.groupFns...)
+			}
+
+			var buf bytes.Buffer
+			err := document.IteratorToJSONArray(&buf, st.Aggregate(counterBuilder{}))
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
@@ -315,6 +315,7 @@ func scanValue(v Value, ref reflect.Value) error {
 	// test with supported stdlib types
 	switch ref.Type().String() {
 	case "time.Time":
+		// parse texts directly to keep their precision
 		if v.Type == TextValue {
 			parsed, err := time.Parse(time.RFC3339Nano, v.V.(string))
 			if err != nil {
@@ -324,6 +325,14 @@ func scanValue(v Value, ref reflect.Value) error {
 			ref.Set(reflect.ValueOf(parsed))
 			return nil
 		}
+
+		v, err := v.CastAsTimestamp()
+		if err != nil {
+			return err
+		}
+
+		ref.Set(reflect.ValueOf(v.V.(time.Time)))
+		return nil
 	}
 
 	switch ref.Kind() {
@@ -7,18 +7,20 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"time"
 
 	"github.com/buger/jsonparser"
 )
 
 var (
-	boolZeroValue     = NewZeroValue(BoolValue)
-	integerZeroValue  = NewZeroValue(IntegerValue)
-	doubleZeroValue   = NewZeroValue(DoubleValue)
-	blobZeroValue     = NewZeroValue(BlobValue)
-	textZeroValue     = NewZeroValue(TextValue)
-	arrayZeroValue    = NewZeroValue(ArrayValue)
-	documentZeroValue = NewZeroValue(DocumentValue)
+	boolZeroValue      = NewZeroValue(BoolValue)
+	integerZeroValue   = NewZeroValue(IntegerValue)
+	doubleZeroValue    = NewZeroValue(DoubleValue)
+	timestampZeroValue = NewZeroValue(TimestampValue)
+	blobZeroValue      = NewZeroValue(BlobValue)
+	textZeroValue      = NewZeroValue(TextValue)
+	arrayZeroValue     = NewZeroValue(ArrayValue)
+	documentZeroValue  = NewZeroValue(DocumentValue)
 )
 
 // ErrUnsupportedType is used to skip struct or array fields that are not supported.
@@ -48,6 +50,9 @@ const (
 	// double family: 0xA0 to 0xAF
 	DoubleValue ValueType = 0xA0
 
+	// timestamp family: 0xB0 to 0xBF
+	TimestampValue ValueType = 0xB0
+
 	// string family: 0xC0 to 0xCF
 	TextValue ValueType = 0xC0
 
@@ -71,6 +76,8 @@ func (t ValueType) String() string {
 		return "integer"
 	case DoubleValue:
 		return "double"
+	case TimestampValue:
+		return "timestamp"
 	case BlobValue:
 		return "blob"
 	case TextValue:
@@ -127,6 +134,16 @@ func NewDoubleValue(x float64) Value {
 	}
 }
 
+// NewTimestampValue returns a value of type Timestamp.
+// Timestamps are stored in UTC with a microsecond precision,
+// x is converted accordingly.
+func NewTimestampValue(x time.Time) Value {
+	return Value{
+		Type: TimestampValue,
+		V:    x.UTC().Truncate(time.Microsecond),
+	}
+}
+
 // NewBlobValue encodes x and returns a value.
 func NewBlobValue(x []byte) Value {
 	return Value{
@@ -171,6 +188,8 @@ func NewZeroValue(t ValueType) Value {
 		return NewIntegerValue(0)
 	case DoubleValue:
 		return NewDoubleValue(0)
+	case TimestampValue:
+		return NewTimestampValue(time.Time{})
 	case BlobValue:
 		return NewBlobValue(nil)
 	case TextValue:
@@ -204,6 +223,8 @@ func (v Value) IsZeroValue() (bool, error) {
 		return v.V == integerZeroValue.V, nil
 	case DoubleValue:
 		return v.V == doubleZeroValue.V, nil
+	case TimestampValue:
+		return v.V.(time.Time).Equal(timestampZeroValue.V.(time.Time)), nil
 	case BlobValue:
 		return bytes.Compare(v.V.([]byte), blobZeroValue.V.([]byte)) == 0, nil
 	case TextValue:
@@ -259,6 +280,8 @@ func (v Value) MarshalJSON() ([]byte, error) {
 		}
 
 		return strconv.AppendFloat(nil, v.V.(float64), fmt, -1, 64), nil
+	case TimestampValue:
+		return []byte(strconv.Quote(v.V.(time.Time).Format(time.RFC3339Nano))), nil
 	case TextValue:
 		return []byte(strconv.Quote(v.V.(string))), nil
 	case BlobValue:
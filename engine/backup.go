@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// A Backuper is an engine that can write a consistent snapshot of its data
+// while other transactions are running.
+type Backuper interface {
+	// Backup writes a consistent snapshot of the engine to w.
+	// The format of the snapshot is specific to the engine and can only
+	// be loaded by the Restore method of the same engine.
+	Backup(w io.Writer) error
+}
+
+// A Restorer is an engine that can load a snapshot written by its Backup method.
+type Restorer interface {
+	// Restore reads a snapshot from r and loads it into the engine.
+	// Stores found in the snapshot replace the existing ones.
+	Restore(r io.Reader) error
+}
+
+// A SequenceSetter is a store whose sequence can be set directly.
+// Stores implementing it are restored by LoadStores without generating
+// every number of their sequence.
+type SequenceSetter interface {
+	// SetSequence sets the last number returned by NextSequence.
+	SetSequence(seq uint64) error
+}
+
+// DumpStore writes the name, the content and the sequence of a store to w,
+// using a format that can be loaded into any engine using LoadStores.
+// Multiple stores can be written to the same writer.
+// Each store is encoded as follows:
+//
+//	uvarint(len(name)) | name | items... | 0 | uvarint(seq)
+//
+// and each item as:
+//
+//	uvarint(len(key) + 1) | key | uvarint(len(value)) | value
+func DumpStore(w io.Writer, name []byte, seq uint64, st Store) error {
+	bw := bufio.NewWriter(w)
+
+	err := writeBytes(bw, name, 0)
+	if err != nil {
+		return err
+	}
+
+	it := st.NewIterator(IteratorConfig{})
+	defer it.Close()
+
+	var v []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+
+		err = writeBytes(bw, item.Key(), 1)
+		if err != nil {
+			return err
+		}
+
+		v, err = item.ValueCopy(v[:0])
+		if err != nil {
+			return err
+		}
+
+		err = writeBytes(bw, v, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeUvarint(bw, 0)
+	if err != nil {
+		return err
+	}
+
+	err = writeUvarint(bw, seq)
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// LoadStores reads every store written by DumpStore from r and writes them to tx.
+// Stores are created if they don't exist, or truncated otherwise.
+// The sequence of stores implementing the SequenceSetter interface is set directly,
+// the sequence of other stores is restored by calling NextSequence until it reaches
+// the saved value.
+func LoadStores(tx Transaction, r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	for {
+		name, err := readBytes(br, 0)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		st, err := tx.GetStore(name)
+		switch err {
+		case nil:
+			err = st.Truncate()
+		case ErrStoreNotFound:
+			err = tx.CreateStore(name)
+		}
+		if err != nil {
+			return err
+		}
+
+		st, err = tx.GetStore(name)
+		if err != nil {
+			return err
+		}
+
+		for {
+			k, err := readBytes(br, 1)
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			// a nil key marks the end of the store
+			if k == nil {
+				break
+			}
+
+			v, err := readBytes(br, 0)
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+
+			err = st.Put(k, v)
+			if err != nil {
+				return err
+			}
+		}
+
+		seq, err := binary.ReadUvarint(br)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+
+		err = setSequence(st, seq)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// setSequence sets the sequence of st to seq, which must not be lower
+// than the current sequence unless st implements SequenceSetter.
+func setSequence(st Store, seq uint64) error {
+	if ss, ok := st.(SequenceSetter); ok {
+		return ss.SetSequence(seq)
+	}
+
+	var cur uint64
+	var err error
+	for cur < seq {
+		cur, err = st.NextSequence()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeUvarint(w *bufio.Writer, x uint64) error {
+	var buf [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(buf[:], x)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+// writeBytes writes the length of b, increased by delta, followed by b.
+func writeBytes(w *bufio.Writer, b []byte, delta uint64) error {
+	err := writeUvarint(w, uint64(len(b))+delta)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// readBytes reads bytes written by writeBytes with the same delta.
+// If delta is not zero and the length is zero, it returns nil.
+func readBytes(r *bufio.Reader, delta uint64) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if n < delta {
+		return nil, nil
+	}
+
+	b := make([]byte, n-delta)
+	_, err = io.ReadFull(r, b)
+	return b, unexpectedEOF(err)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -3,6 +3,7 @@ package badgerengine
 
 import (
 	"bytes"
+	"io"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/genjidb/genji/engine"
@@ -12,6 +13,9 @@ const (
 	separator   byte = 0x1F
 	storeKey         = "__genji.store"
 	storePrefix      = 's'
+
+	// maximum number of pending writes when restoring a backup.
+	maxPendingWrites = 256
 )
 
 // Engine represents a Badger engine.
@@ -47,6 +51,19 @@ func (e *Engine) Close() error {
 	return e.DB.Close()
 }
 
+// Backup writes a consistent snapshot of the database to w, using Badger's backup format.
+func (e *Engine) Backup(w io.Writer) error {
+	_, err := e.DB.Backup(w, 0)
+	return err
+}
+
+// Restore loads a snapshot written by Backup.
+// It must be called on an empty database, since keys that are not
+// part of the snapshot are kept.
+func (e *Engine) Restore(r io.Reader) error {
+	return e.DB.Load(r, maxPendingWrites)
+}
+
 // A Transaction uses Badger's transactions.
 type Transaction struct {
 	ng        *Engine
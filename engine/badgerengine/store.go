@@ -2,6 +2,7 @@ package badgerengine
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 
 	"github.com/dgraph-io/badger/v2"
@@ -127,6 +128,20 @@ func (s *Store) NextSequence() (uint64, error) {
 	return nb + 1, nil
 }
 
+// SetSequence sets the last number returned by NextSequence.
+// Badger sequences store the next number to lease under the name of the store,
+// as a big-endian integer: since NextSequence adds 1 to the numbers generated by Badger,
+// the next number to lease is seq.
+func (s *Store) SetSequence(seq uint64) error {
+	if !s.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], seq)
+	return s.tx.Set([]byte(s.name), buf[:])
+}
+
 // NewIterator uses a Badger iterator with default options.
 // Only one iterator is allowed per read-write transaction.
 func (s *Store) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
@@ -2,6 +2,8 @@
 package boltengine
 
 import (
+	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/genjidb/genji/engine"
@@ -47,6 +49,68 @@ func (e *Engine) Close() error {
 	return e.DB.Close()
 }
 
+// Backup writes a consistent snapshot of the database to w, using a read-only transaction.
+// The snapshot is a valid Bolt database file.
+func (e *Engine) Backup(w io.Writer) error {
+	return e.DB.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// Restore loads a snapshot written by Backup.
+// The snapshot is written to a temporary file, from which every bucket is copied
+// in a single transaction, replacing the existing buckets with the same name.
+func (e *Engine) Restore(r io.Reader) error {
+	f, err := ioutil.TempFile("", "genji-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	src, err := bolt.Open(f.Name(), 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return src.View(func(stx *bolt.Tx) error {
+		return e.DB.Update(func(tx *bolt.Tx) error {
+			return stx.ForEach(func(name []byte, sb *bolt.Bucket) error {
+				err := tx.DeleteBucket(name)
+				if err != nil && err != bolt.ErrBucketNotFound {
+					return err
+				}
+
+				b, err := tx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+
+				err = sb.ForEach(func(k, v []byte) error {
+					return b.Put(k, v)
+				})
+				if err != nil {
+					return err
+				}
+
+				return b.SetSequence(sb.Sequence())
+			})
+		})
+	})
+}
+
 // A Transaction uses Bolt's transactions.
 type Transaction struct {
 	tx       *bolt.Tx
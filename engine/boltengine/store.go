@@ -58,7 +58,7 @@ func (s *Store) Truncate() error {
 		return err
 	}
 
-	_, err = s.tx.CreateBucket(s.name)
+	s.bucket, err = s.tx.CreateBucket(s.name)
 	return err
 }
 
@@ -71,6 +71,15 @@ func (s *Store) NextSequence() (uint64, error) {
 	return s.bucket.NextSequence()
 }
 
+// SetSequence sets the sequence of the bucket.
+func (s *Store) SetSequence(seq uint64) error {
+	if !s.bucket.Writable() {
+		return engine.ErrTransactionReadOnly
+	}
+
+	return s.bucket.SetSequence(seq)
+}
+
 // NewIterator uses the bucket cursor.
 func (s *Store) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
 	return &iterator{
@@ -0,0 +1,460 @@
+// Package encryptedengine implements an engine that encrypts the data of another engine.
+//
+// Values are encrypted using AES-GCM with a random nonce, and authenticated with the
+// name of their store and their key, which prevents moving them from one key to another.
+//
+// Keys are stored in the clear: engines rely on the lexicographic order of keys to
+// iterate over stores, and that order cannot be preserved by a secure encryption scheme.
+// Since keys contain the primary keys of documents and the values indexed by indexes,
+// data that must be kept secret must not be used as a primary key or be indexed.
+//
+// Store names can optionally be encrypted, using a deterministic scheme that allows
+// looking stores up by name. Since the same name always produces the same
+// encrypted name, this hides the names of the tables but not the number of stores.
+package encryptedengine
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/genjidb/genji/engine"
+)
+
+const (
+	// metaStoreName is the name of the store used by the engine
+	// to keep track of the stores, their sequences and to check the key.
+	metaStoreName = "__genji_encryptedengine"
+
+	// prefixes of the keys of the meta store.
+	checkKey     = 'c'
+	storesPrefix = 's'
+	seqPrefix    = 'q'
+)
+
+// checkValue is encrypted and stored in the meta store
+// to verify the key when the engine is opened.
+var checkValue = []byte("genji")
+
+// ErrInvalidKey is returned when the key doesn't match the one
+// used to encrypt the data.
+var ErrInvalidKey = errors.New("invalid encryption key")
+
+// Options of the engine.
+type Options struct {
+	// If set to true, store names are encrypted.
+	// This option must be the same every time the engine is opened.
+	EncryptStoreNames bool
+}
+
+// Engine wraps an engine and encrypts the data stored by it.
+// Since the engine keeps track of the stores in a dedicated store, it must only
+// be used with engines that were created empty and always used through it.
+type Engine struct {
+	ng   engine.Engine
+	opts Options
+
+	mu sync.RWMutex
+	c  *cipherSet
+}
+
+// NewEngine creates an engine that encrypts the data of ng using the given key.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+// If ng already contains data, the key must be the one used to encrypt it,
+// otherwise ErrInvalidKey is returned.
+func NewEngine(ng engine.Engine, key []byte, opts Options) (*Engine, error) {
+	c, err := newCipherSet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	e := Engine{
+		ng:   ng,
+		opts: opts,
+		c:    c,
+	}
+
+	tx, err := ng.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	st, err := tx.GetStore([]byte(metaStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(metaStoreName))
+		if err != nil {
+			return nil, err
+		}
+
+		st, err = tx.GetStore([]byte(metaStoreName))
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.c.put(st, []byte(metaStoreName), []byte{checkKey}, checkValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := e.c.get(st, []byte(metaStoreName), []byte{checkKey})
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(v, checkValue) {
+		return nil, ErrInvalidKey
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// Begin creates a transaction using the underlying engine.
+// Transactions prevent keys from being rotated until they are terminated.
+func (e *Engine) Begin(writable bool) (engine.Transaction, error) {
+	e.mu.RLock()
+
+	tx, err := e.ng.Begin(writable)
+	if err != nil {
+		e.mu.RUnlock()
+		return nil, err
+	}
+
+	return &transaction{
+		tx:     tx,
+		c:      e.c,
+		opts:   e.opts,
+		unlock: e.mu.RUnlock,
+	}, nil
+}
+
+// Close the underlying engine.
+func (e *Engine) Close() error {
+	return e.ng.Close()
+}
+
+// Backup writes a snapshot of the underlying engine to w.
+// The snapshot contains encrypted data and can only be restored using an engine
+// with the same key. The underlying engine must implement the engine.Backuper interface.
+func (e *Engine) Backup(w io.Writer) error {
+	b, ok := e.ng.(engine.Backuper)
+	if !ok {
+		return errors.New("the underlying engine doesn't implement engine.Backuper")
+	}
+
+	return b.Backup(w)
+}
+
+// Restore loads a snapshot written by Backup.
+// The underlying engine must implement the engine.Restorer interface.
+func (e *Engine) Restore(r io.Reader) error {
+	rs, ok := e.ng.(engine.Restorer)
+	if !ok {
+		return errors.New("the underlying engine doesn't implement engine.Restorer")
+	}
+
+	return rs.Restore(r)
+}
+
+// rotateBatchSize is the number of values of a store read at once by Rotate.
+const rotateBatchSize = 1000
+
+// Rotate re-encrypts every store with a new key, within a single transaction.
+// Every value is decrypted and encrypted again, and stores are renamed if store names are
+// encrypted. Rotate waits for the running transactions to terminate and new transactions
+// wait for the rotation to complete: it must not be called by a goroutine running a transaction.
+func (e *Engine) Rotate(newKey []byte) error {
+	nc, err := newCipherSet(newKey)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tx, err := e.ng.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	meta, err := tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+
+	// read the list of stores and their sequences
+	type storeInfo struct {
+		name []byte
+		seq  []byte
+	}
+	var stores []storeInfo
+	it := meta.NewIterator(engine.IteratorConfig{})
+	for it.Seek([]byte{storesPrefix}); it.Valid() && it.Item().Key()[0] == storesPrefix; it.Next() {
+		name, err := e.c.decryptItem(nil, []byte(metaStoreName), it.Item())
+		if err != nil {
+			it.Close()
+			return err
+		}
+
+		stores = append(stores, storeInfo{name: name})
+	}
+	err = it.Close()
+	if err != nil {
+		return err
+	}
+
+	for i := range stores {
+		stores[i].seq, err = e.c.get(meta, []byte(metaStoreName), seqKey(e.c.storeName(stores[i].name, e.opts)))
+		if err != nil && err != engine.ErrKeyNotFound {
+			return err
+		}
+	}
+
+	// re-encrypt every store
+	for _, s := range stores {
+		err = e.rotateStore(tx, nc, s.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// rewrite the meta store
+	err = meta.Truncate()
+	if err != nil {
+		return err
+	}
+
+	meta, err = tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = nc.put(meta, []byte(metaStoreName), []byte{checkKey}, checkValue)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range stores {
+		name := nc.storeName(s.name, e.opts)
+
+		err = nc.put(meta, []byte(metaStoreName), storeKey(name), s.name)
+		if err != nil {
+			return err
+		}
+
+		if s.seq != nil {
+			err = nc.put(meta, []byte(metaStoreName), seqKey(name), s.seq)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	e.c = nc
+	return nil
+}
+
+// rotateStore re-encrypts the values of a store with nc, and renames the store
+// if its name changes. Values are read and written in batches, to avoid modifying
+// the store while iterating over it without loading it entirely in memory.
+func (e *Engine) rotateStore(tx engine.Transaction, nc *cipherSet, name []byte) error {
+	oldName := e.c.storeName(name, e.opts)
+	newName := nc.storeName(name, e.opts)
+
+	src, err := tx.GetStore(oldName)
+	if err != nil {
+		return err
+	}
+
+	dst := src
+	renamed := !bytes.Equal(oldName, newName)
+	if renamed {
+		err = tx.CreateStore(newName)
+		if err != nil {
+			return err
+		}
+
+		dst, err = tx.GetStore(newName)
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([][]byte, 0, rotateBatchSize)
+	values := make([][]byte, 0, rotateBatchSize)
+	var pivot []byte
+	for {
+		keys, values = keys[:0], values[:0]
+
+		it := src.NewIterator(engine.IteratorConfig{})
+		for it.Seek(pivot); it.Valid() && len(keys) < rotateBatchSize; it.Next() {
+			v, err := e.c.decryptItem(nil, name, it.Item())
+			if err != nil {
+				it.Close()
+				return err
+			}
+
+			keys = append(keys, append([]byte(nil), it.Item().Key()...))
+			values = append(values, v)
+		}
+		err = it.Close()
+		if err != nil {
+			return err
+		}
+
+		for i := range keys {
+			err = nc.put(dst, name, keys[i], values[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(keys) < rotateBatchSize {
+			break
+		}
+
+		// the next batch starts right after the last key
+		last := keys[len(keys)-1]
+		pivot = append(pivot[:0], last...)
+		pivot = append(pivot, 0)
+	}
+
+	if renamed {
+		return tx.DropStore(oldName)
+	}
+
+	return nil
+}
+
+func storeKey(name []byte) []byte {
+	return append([]byte{storesPrefix}, name...)
+}
+
+func seqKey(name []byte) []byte {
+	return append([]byte{seqPrefix}, name...)
+}
+
+// cipherSet holds the ciphers derived from a key.
+type cipherSet struct {
+	aead cipher.AEAD
+	// key used to generate the nonces of store names
+	nameKey []byte
+}
+
+func newCipherSet(key []byte) (*cipherSet, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("store names"))
+
+	return &cipherSet{
+		aead:    aead,
+		nameKey: mac.Sum(nil),
+	}, nil
+}
+
+// additionalData returns the data used to authenticate the value of a key.
+func additionalData(storeName, k []byte) []byte {
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(storeName)+len(k))
+	n := binary.PutUvarint(buf, uint64(len(storeName)))
+	buf = append(buf[:n], storeName...)
+	return append(buf, k...)
+}
+
+// encrypt returns the nonce followed by the encrypted value.
+func (c *cipherSet) encrypt(storeName, k, v []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(v)+c.aead.Overhead())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, v, additionalData(storeName, k)), nil
+}
+
+// decrypt decrypts the value and appends it to dst, which must not overlap v.
+func (c *cipherSet) decrypt(dst, storeName, k, v []byte) ([]byte, error) {
+	if len(v) < c.aead.NonceSize() {
+		return nil, ErrInvalidKey
+	}
+
+	nonce, v := v[:c.aead.NonceSize()], v[c.aead.NonceSize():]
+	v, err := c.aead.Open(dst, nonce, v, additionalData(storeName, k))
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return v, nil
+}
+
+// decryptItem decrypts the value of the item and appends it to dst.
+func (c *cipherSet) decryptItem(dst, storeName []byte, it engine.Item) ([]byte, error) {
+	v, err := it.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decrypt(dst, storeName, it.Key(), v)
+}
+
+func (c *cipherSet) put(st engine.Store, storeName, k, v []byte) error {
+	v, err := c.encrypt(storeName, k, v)
+	if err != nil {
+		return err
+	}
+
+	return st.Put(k, v)
+}
+
+func (c *cipherSet) get(st engine.Store, storeName, k []byte) ([]byte, error) {
+	v, err := st.Get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decrypt(nil, storeName, k, v)
+}
+
+// storeName returns the name of the store in the underlying engine.
+// If store names are encrypted, the nonce is derived from the name, which
+// allows to always generate the same encrypted name.
+func (c *cipherSet) storeName(name []byte, opts Options) []byte {
+	if !opts.EncryptStoreNames {
+		return name
+	}
+
+	mac := hmac.New(sha256.New, c.nameKey)
+	mac.Write(name)
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+
+	return c.aead.Seal(nonce, nonce, name, nil)
+}
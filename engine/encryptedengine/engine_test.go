@@ -0,0 +1,175 @@
+package encryptedengine_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/encryptedengine"
+	"github.com/genjidb/genji/engine/enginetest"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	key    = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func builder(opts encryptedengine.Options) func() (engine.Engine, func()) {
+	return func() (engine.Engine, func()) {
+		ng, err := encryptedengine.NewEngine(memoryengine.NewEngine(), key, opts)
+		if err != nil {
+			panic(err)
+		}
+
+		return ng, func() { ng.Close() }
+	}
+}
+
+func TestEncryptedEngine(t *testing.T) {
+	enginetest.TestSuite(t, builder(encryptedengine.Options{}))
+}
+
+func TestEncryptedEngineWithEncryptedStoreNames(t *testing.T) {
+	enginetest.TestSuite(t, builder(encryptedengine.Options{EncryptStoreNames: true}))
+}
+
+// fill creates a store named "foo" in ng and stores a key value pair in it.
+func fill(t *testing.T, ng engine.Engine) {
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.CreateStore([]byte("foo"))
+	require.NoError(t, err)
+	st, err := tx.GetStore([]byte("foo"))
+	require.NoError(t, err)
+	err = st.Put([]byte("key"), []byte("secret"))
+	require.NoError(t, err)
+	_, err = st.NextSequence()
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+}
+
+// check ensures the store "foo" of ng contains the data written by fill.
+func check(t *testing.T, ng engine.Engine) {
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	st, err := tx.GetStore([]byte("foo"))
+	require.NoError(t, err)
+	v, err := st.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), v)
+	seq, err := st.NextSequence()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, seq)
+}
+
+func TestEncryption(t *testing.T) {
+	for _, encryptNames := range []bool{false, true} {
+		encryptNames := encryptNames
+		opts := encryptedengine.Options{EncryptStoreNames: encryptNames}
+
+		t.Run(fmt.Sprintf("EncryptStoreNames=%v", encryptNames), func(t *testing.T) {
+			t.Run("Values", func(t *testing.T) {
+				mem := memoryengine.NewEngine()
+				ng, err := encryptedengine.NewEngine(mem, key, opts)
+				require.NoError(t, err)
+				fill(t, ng)
+
+				tx, err := mem.Begin(false)
+				require.NoError(t, err)
+				defer tx.Rollback()
+
+				st, err := tx.GetStore([]byte("foo"))
+				if encryptNames {
+					require.Equal(t, engine.ErrStoreNotFound, err)
+					return
+				}
+				require.NoError(t, err)
+
+				// keys are stored in the clear
+				v, err := st.Get([]byte("key"))
+				require.NoError(t, err)
+				require.NotContains(t, string(v), "secret")
+			})
+
+			t.Run("Invalid key", func(t *testing.T) {
+				mem := memoryengine.NewEngine()
+				ng, err := encryptedengine.NewEngine(mem, key, opts)
+				require.NoError(t, err)
+				fill(t, ng)
+
+				_, err = encryptedengine.NewEngine(mem, newKey, opts)
+				require.Equal(t, encryptedengine.ErrInvalidKey, err)
+
+				_, err = encryptedengine.NewEngine(mem, []byte("too short"), opts)
+				require.Error(t, err)
+			})
+
+			t.Run("Rotate", func(t *testing.T) {
+				mem := memoryengine.NewEngine()
+				ng, err := encryptedengine.NewEngine(mem, key, opts)
+				require.NoError(t, err)
+				fill(t, ng)
+
+				err = ng.Rotate(newKey)
+				require.NoError(t, err)
+				check(t, ng)
+
+				_, err = encryptedengine.NewEngine(mem, key, opts)
+				require.Equal(t, encryptedengine.ErrInvalidKey, err)
+
+				ng, err = encryptedengine.NewEngine(mem, newKey, opts)
+				require.NoError(t, err)
+				check(t, ng)
+			})
+
+			t.Run("Rotate multiple batches", func(t *testing.T) {
+				ng, err := encryptedengine.NewEngine(memoryengine.NewEngine(), key, opts)
+				require.NoError(t, err)
+
+				tx, err := ng.Begin(true)
+				require.NoError(t, err)
+				err = tx.CreateStore([]byte("foo"))
+				require.NoError(t, err)
+				st, err := tx.GetStore([]byte("foo"))
+				require.NoError(t, err)
+				for i := 0; i < 2500; i++ {
+					err = st.Put([]byte(fmt.Sprintf("%05d", i)), []byte(fmt.Sprintf("secret %d", i)))
+					require.NoError(t, err)
+				}
+				err = tx.Commit()
+				require.NoError(t, err)
+
+				err = ng.Rotate(newKey)
+				require.NoError(t, err)
+
+				tx, err = ng.Begin(false)
+				require.NoError(t, err)
+				defer tx.Rollback()
+				st, err = tx.GetStore([]byte("foo"))
+				require.NoError(t, err)
+
+				var i int
+				it := st.NewIterator(engine.IteratorConfig{})
+				defer it.Close()
+				var buf []byte
+				for it.Seek(nil); it.Valid(); it.Next() {
+					require.Equal(t, fmt.Sprintf("%05d", i), string(it.Item().Key()))
+					buf, err = it.Item().ValueCopy(buf)
+					require.NoError(t, err)
+					require.Equal(t, fmt.Sprintf("secret %d", i), string(buf))
+					i++
+				}
+				require.Equal(t, 2500, i)
+			})
+		})
+	}
+}
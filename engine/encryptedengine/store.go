@@ -0,0 +1,218 @@
+package encryptedengine
+
+import (
+	"encoding/binary"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// A transaction wraps a transaction of the underlying engine.
+type transaction struct {
+	tx   engine.Transaction
+	c    *cipherSet
+	opts Options
+	// unlock releases the lock of the engine when the transaction
+	// is terminated. It is set to nil once called.
+	unlock func()
+}
+
+func (t *transaction) Rollback() error {
+	err := t.tx.Rollback()
+	t.terminate()
+	return err
+}
+
+func (t *transaction) Commit() error {
+	err := t.tx.Commit()
+	t.terminate()
+	return err
+}
+
+func (t *transaction) terminate() {
+	if t.unlock != nil {
+		t.unlock()
+		t.unlock = nil
+	}
+}
+
+// GetStore returns a store by name.
+func (t *transaction) GetStore(name []byte) (engine.Store, error) {
+	ename := t.c.storeName(name, t.opts)
+
+	st, err := t.tx.GetStore(ename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store{
+		st:    st,
+		tx:    t,
+		name:  append([]byte(nil), name...),
+		ename: ename,
+	}, nil
+}
+
+// CreateStore creates a store and registers it in the meta store.
+func (t *transaction) CreateStore(name []byte) error {
+	ename := t.c.storeName(name, t.opts)
+
+	err := t.tx.CreateStore(ename)
+	if err != nil {
+		return err
+	}
+
+	meta, err := t.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+
+	return t.c.put(meta, []byte(metaStoreName), storeKey(ename), name)
+}
+
+// DropStore deletes the store and unregisters it from the meta store.
+func (t *transaction) DropStore(name []byte) error {
+	ename := t.c.storeName(name, t.opts)
+
+	err := t.tx.DropStore(ename)
+	if err != nil {
+		return err
+	}
+
+	meta, err := t.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = meta.Delete(storeKey(ename))
+	if err != nil && err != engine.ErrKeyNotFound {
+		return err
+	}
+
+	err = meta.Delete(seqKey(ename))
+	if err != nil && err != engine.ErrKeyNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// A store encrypts the values of a store of the underlying engine.
+type store struct {
+	st engine.Store
+	tx *transaction
+	// name of the store, and name in the underlying engine.
+	name, ename []byte
+}
+
+func (s *store) Get(k []byte) ([]byte, error) {
+	return s.tx.c.get(s.st, s.name, k)
+}
+
+func (s *store) Put(k, v []byte) error {
+	return s.tx.c.put(s.st, s.name, k, v)
+}
+
+func (s *store) Delete(k []byte) error {
+	return s.st.Delete(k)
+}
+
+func (s *store) Truncate() error {
+	return s.st.Truncate()
+}
+
+// NextSequence returns a monotonically increasing integer.
+// Sequences are stored in the meta store rather than in the underlying engine
+// to be preserved when stores are renamed by a key rotation.
+func (s *store) NextSequence() (uint64, error) {
+	meta, err := s.tx.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return 0, err
+	}
+
+	k := seqKey(s.ename)
+
+	var seq uint64
+	v, err := s.tx.c.get(meta, []byte(metaStoreName), k)
+	switch err {
+	case nil:
+		seq, _ = binary.Uvarint(v)
+	case engine.ErrKeyNotFound:
+	default:
+		return 0, err
+	}
+
+	seq++
+	err = s.putSequence(meta, seq)
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// SetSequence sets the last number returned by NextSequence.
+func (s *store) SetSequence(seq uint64) error {
+	meta, err := s.tx.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+
+	return s.putSequence(meta, seq)
+}
+
+func (s *store) putSequence(meta engine.Store, seq uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, seq)
+
+	return s.tx.c.put(meta, []byte(metaStoreName), seqKey(s.ename), buf[:n])
+}
+
+func (s *store) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return &iterator{
+		it:    s.st.NewIterator(cfg),
+		store: s,
+	}
+}
+
+// An iterator decrypts the values of the underlying iterator.
+type iterator struct {
+	it    engine.Iterator
+	store *store
+	item  item
+}
+
+func (it *iterator) Seek(pivot []byte) {
+	it.it.Seek(pivot)
+}
+
+func (it *iterator) Next() {
+	it.it.Next()
+}
+
+func (it *iterator) Valid() bool {
+	return it.it.Valid()
+}
+
+func (it *iterator) Item() engine.Item {
+	it.item.it = it.it.Item()
+	it.item.store = it.store
+	return &it.item
+}
+
+func (it *iterator) Close() error {
+	return it.it.Close()
+}
+
+type item struct {
+	it    engine.Item
+	store *store
+}
+
+func (i *item) Key() []byte {
+	return i.it.Key()
+}
+
+// ValueCopy decrypts the value and appends it to buf[:0].
+func (i *item) ValueCopy(buf []byte) ([]byte, error) {
+	return i.store.tx.c.decryptItem(buf[:0], i.store.name, i.it)
+}
@@ -41,6 +41,7 @@ func TestSuite(t *testing.T, builder Builder) {
 		{"Store/NextSequence", TestStoreNextSequence},
 		{"TestQueries", TestQueries},
 		{"TestQueriesSameTransaction", TestQueriesSameTransaction},
+		{"Backup", TestBackup},
 	}
 
 	for _, test := range tests {
@@ -846,6 +847,39 @@ func TestStoreNextSequence(t *testing.T, builder Builder) {
 		require.NoError(t, err)
 		require.Equal(t, s1+1, s2)
 	})
+
+	t.Run("Should continue from the sequence set by SetSequence", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("test"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+
+		ss, ok := st.(engine.SequenceSetter)
+		if !ok {
+			t.Skip("the store doesn't implement engine.SequenceSetter")
+		}
+
+		_, err = st.NextSequence()
+		require.NoError(t, err)
+		err = ss.SetSequence(1e9)
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		s, err := st.NextSequence()
+		require.NoError(t, err)
+		require.EqualValues(t, 1e9+1, s)
+	})
 }
 
 // TestQueries test simple queries against the engine.
@@ -1059,3 +1093,120 @@ func TestQueriesSameTransaction(t *testing.T, builder Builder) {
 		require.NoError(t, err)
 	})
 }
+
+// TestBackup tests the Backup and Restore methods of engines
+// implementing the engine.Backuper and engine.Restorer interfaces.
+func TestBackup(t *testing.T, builder Builder) {
+	ng, cleanup := builder()
+	defer cleanup()
+
+	if _, ok := ng.(engine.Backuper); !ok {
+		t.Skip("engine.Backuper not implemented")
+	}
+
+	if _, ok := ng.(engine.Restorer); !ok {
+		t.Skip("engine.Restorer not implemented")
+	}
+
+	t.Run("Stores", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateStore([]byte("foo"))
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("bar"))
+		require.NoError(t, err)
+
+		st, err := tx.GetStore([]byte("foo"))
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			err = st.Put([]byte{'a' + byte(i)}, []byte{byte(i)})
+			require.NoError(t, err)
+		}
+		for i := 0; i < 3; i++ {
+			_, err = st.NextSequence()
+			require.NoError(t, err)
+		}
+
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = ng.(engine.Backuper).Backup(&buf)
+		require.NoError(t, err)
+
+		ng2, cleanup2 := builder()
+		defer cleanup2()
+
+		err = ng2.(engine.Restorer).Restore(&buf)
+		require.NoError(t, err)
+
+		tx, err = ng2.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetStore([]byte("bar"))
+		require.NoError(t, err)
+
+		st, err = tx.GetStore([]byte("foo"))
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			v, err := st.Get([]byte{'a' + byte(i)})
+			require.NoError(t, err)
+			require.Equal(t, []byte{byte(i)}, v)
+		}
+
+		seq, err := st.NextSequence()
+		require.NoError(t, err)
+		require.EqualValues(t, 4, seq)
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		ctx := context.Background()
+
+		ng, cleanup := builder()
+		defer cleanup()
+
+		db, err := genji.New(ng)
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			CREATE INDEX idx_test_a ON test(a);
+			INSERT INTO test (a) VALUES (1), (2), (3);
+		`)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = db.DB.Backup(&buf)
+		require.NoError(t, err)
+
+		ng2, cleanup2 := builder()
+		defer cleanup2()
+
+		db2, err := genji.New(ng2)
+		require.NoError(t, err)
+		defer db2.Close()
+
+		err = db2.DB.Restore(&buf)
+		require.NoError(t, err)
+
+		err = db2.Exec(ctx, "INSERT INTO test (a) VALUES (4)")
+		require.NoError(t, err)
+
+		st, err := db2.Query(ctx, "SELECT a FROM test WHERE a > 1")
+		require.NoError(t, err)
+		defer st.Close()
+
+		var out bytes.Buffer
+		err = document.IteratorToJSONArray(&out, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a": 2}, {"a": 3}, {"a": 4}]`, out.String())
+	})
+}
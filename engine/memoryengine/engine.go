@@ -2,6 +2,8 @@ package memoryengine
 
 import (
 	"errors"
+	"io"
+	"sort"
 	"sync"
 
 	"github.com/genjidb/genji/engine"
@@ -15,12 +17,20 @@ import (
 const btreeDegree = 12
 
 // Engine is a simple memory engine implementation that stores data in
-// an in-memory Btree. It allows multiple readers and one single writer.
+// in-memory Btrees. It allows multiple readers and one single writer.
+// Btrees are copy-on-write: read transactions see a snapshot of the data
+// taken when they begin and are never blocked by the writer, while the changes made
+// by the writer become visible atomically on commit.
 type Engine struct {
+	// mu protects the committed state of the engine.
+	// Committed btrees and maps are never modified, they are replaced on commit.
+	mu        sync.RWMutex
 	closed    bool
 	stores    map[string]*btree.BTree
 	sequences map[string]uint64
-	mu        sync.RWMutex
+
+	// writer is held by the write transaction for its whole lifetime.
+	writer sync.Mutex
 }
 
 // NewEngine creates an in-memory engine.
@@ -34,22 +44,54 @@ func NewEngine() *Engine {
 // Begin creates a transaction.
 func (ng *Engine) Begin(writable bool) (engine.Transaction, error) {
 	if writable {
-		ng.mu.Lock()
-	} else {
-		ng.mu.RLock()
+		ng.writer.Lock()
 	}
 
-	if ng.closed {
+	ng.mu.RLock()
+	closed, stores, sequences := ng.closed, ng.stores, ng.sequences
+	ng.mu.RUnlock()
+
+	if closed {
+		if writable {
+			ng.writer.Unlock()
+		}
 		return nil, errors.New("engine closed")
 	}
 
-	return &transaction{ng: ng, writable: writable}, nil
+	tx := transaction{
+		ng:        ng,
+		writable:  writable,
+		stores:    stores,
+		sequences: sequences,
+	}
+
+	// the writer works on its own copy of the maps, and
+	// clones every btree it accesses.
+	if writable {
+		tx.stores = make(map[string]*btree.BTree, len(stores))
+		for name, tr := range stores {
+			tx.stores[name] = tr
+		}
+		tx.sequences = make(map[string]uint64, len(sequences))
+		for name, seq := range sequences {
+			tx.sequences[name] = seq
+		}
+		tx.cloned = make(map[string]bool)
+		tx.snapshots = make(map[string]*btree.BTree)
+	}
+
+	return &tx, nil
 }
 
 // Close the engine.
+// It waits for the write transaction to terminate.
 func (ng *Engine) Close() error {
+	ng.writer.Lock()
+	defer ng.writer.Unlock()
+
 	ng.mu.Lock()
 	defer ng.mu.Unlock()
+
 	if ng.closed {
 		return errors.New("engine already closed")
 	}
@@ -58,20 +100,71 @@ func (ng *Engine) Close() error {
 	return nil
 }
 
+// Backup writes a snapshot of every store to w, using the format of engine.DumpStore.
+func (ng *Engine) Backup(w io.Writer) error {
+	t, err := ng.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer t.Rollback()
+	tx := t.(*transaction)
+
+	names := make([]string, 0, len(tx.stores))
+	for name := range tx.stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		st, err := tx.GetStore([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		err = engine.DumpStore(w, []byte(name), tx.sequences[name], st)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restore loads a snapshot written by Backup.
+func (ng *Engine) Restore(r io.Reader) error {
+	tx, err := ng.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = engine.LoadStores(tx, r)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // This implements the engine.Transaction type.
 type transaction struct {
-	ng         *Engine
-	writable   bool
-	onRollback []func() // called during a rollback
-	onCommit   []func() // called during a commit
+	ng       *Engine
+	writable bool
+	// stores and sequences visible by the transaction.
+	// read-only transactions share them with the engine and must not modify them.
+	stores    map[string]*btree.BTree
+	sequences map[string]uint64
+	// btrees cloned or created by the write transaction, that it can modify.
+	cloned map[string]bool
+	// clones of the btrees of the write transaction read by iterators,
+	// until the btrees are modified.
+	snapshots  map[string]*btree.BTree
 	terminated bool
 	wg         sync.WaitGroup
 }
 
-// If the transaction is writable, rollback calls
-// every function stored in the onRollback slice
-// to undo every mutation done since the beginning
-// of the transaction.
+// Rollback discards the changes made by the transaction,
+// if any, and releases the writer lock.
 func (tx *transaction) Rollback() error {
 	if tx.terminated {
 		return nil
@@ -82,21 +175,15 @@ func (tx *transaction) Rollback() error {
 	tx.wg.Wait()
 
 	if tx.writable {
-		for _, undo := range tx.onRollback {
-			undo()
-		}
-		tx.ng.mu.Unlock()
-	} else {
-		tx.ng.mu.RUnlock()
+		tx.ng.writer.Unlock()
 	}
 
 	return nil
 }
 
-// If the transaction is writable, Commit calls
-// every function stored in the onCommit slice
-// to finalize every mutation done since the beginning
-// of the transaction.
+// Commit replaces the committed state of the engine with the
+// stores of the transaction, making its changes visible to the transactions
+// created afterwards.
 func (tx *transaction) Commit() error {
 	if tx.terminated {
 		return errors.New("transaction already terminated")
@@ -110,21 +197,32 @@ func (tx *transaction) Commit() error {
 
 	tx.terminated = true
 
-	for _, fn := range tx.onCommit {
-		fn()
-	}
-
+	tx.ng.mu.Lock()
+	tx.ng.stores = tx.stores
+	tx.ng.sequences = tx.sequences
 	tx.ng.mu.Unlock()
 
+	tx.ng.writer.Unlock()
+
 	return nil
 }
 
 func (tx *transaction) GetStore(name []byte) (engine.Store, error) {
-	tr, ok := tx.ng.stores[string(name)]
+	tr, ok := tx.stores[string(name)]
 	if !ok {
 		return nil, engine.ErrStoreNotFound
 	}
 
+	// clone the committed btree the first time
+	// the write transaction accesses it.
+	// Clone only replaces the copy-on-write context of the committed btree,
+	// which readers never access.
+	if tx.writable && !tx.cloned[string(name)] {
+		tr = tr.Clone()
+		tx.stores[string(name)] = tr
+		tx.cloned[string(name)] = true
+	}
+
 	return &storeTx{tx: tx, tr: tr, name: string(name)}, nil
 }
 
@@ -133,19 +231,14 @@ func (tx *transaction) CreateStore(name []byte) error {
 		return engine.ErrTransactionReadOnly
 	}
 
-	_, err := tx.GetStore(name)
-	if err == nil {
+	_, ok := tx.stores[string(name)]
+	if ok {
 		return engine.ErrStoreAlreadyExists
 	}
 
-	tr := btree.New(btreeDegree)
-
-	tx.ng.stores[string(name)] = tr
-
-	// on rollback, remove the btree from the list of stores
-	tx.onRollback = append(tx.onRollback, func() {
-		delete(tx.ng.stores, string(name))
-	})
+	tx.stores[string(name)] = btree.New(btreeDegree)
+	tx.cloned[string(name)] = true
+	tx.invalidateSnapshot(string(name))
 
 	return nil
 }
@@ -155,17 +248,20 @@ func (tx *transaction) DropStore(name []byte) error {
 		return engine.ErrTransactionReadOnly
 	}
 
-	rb, ok := tx.ng.stores[string(name)]
+	_, ok := tx.stores[string(name)]
 	if !ok {
 		return engine.ErrStoreNotFound
 	}
 
-	delete(tx.ng.stores, string(name))
-
-	// on rollback put back the btree to the list of stores
-	tx.onRollback = append(tx.onRollback, func() {
-		tx.ng.stores[string(name)] = rb
-	})
+	delete(tx.stores, string(name))
+	delete(tx.cloned, string(name))
+	tx.invalidateSnapshot(string(name))
 
 	return nil
 }
+
+// invalidateSnapshot discards the snapshot of a btree modified by the write transaction.
+// Iterators reading it are not affected.
+func (tx *transaction) invalidateSnapshot(name string) {
+	delete(tx.snapshots, name)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/enginetest"
 	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
 )
 
 func builder() (engine.Engine, func()) {
@@ -17,6 +18,122 @@ func TestMemoryEngine(t *testing.T) {
 	enginetest.TestSuite(t, builder)
 }
 
+func TestSnapshots(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	defer ng.Close()
+
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	err = tx.CreateStore([]byte("test"))
+	require.NoError(t, err)
+	st, err := tx.GetStore([]byte("test"))
+	require.NoError(t, err)
+	err = st.Put([]byte("a"), []byte("1"))
+	require.NoError(t, err)
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	// get returns the value of k, or nil if it doesn't exist.
+	get := func(tx engine.Transaction, k string) []byte {
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		v, err := st.Get([]byte(k))
+		if err == engine.ErrKeyNotFound {
+			return nil
+		}
+		require.NoError(t, err)
+		return v
+	}
+
+	// count returns the number of items of the store.
+	count := func(tx engine.Transaction) int {
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		it := st.NewIterator(engine.IteratorConfig{})
+		defer it.Close()
+
+		var n int
+		for it.Seek(nil); it.Valid(); it.Next() {
+			n++
+		}
+		return n
+	}
+
+	wtx, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer wtx.Rollback()
+
+	st, err = wtx.GetStore([]byte("test"))
+	require.NoError(t, err)
+	err = st.Put([]byte("a"), []byte("2"))
+	require.NoError(t, err)
+	err = st.Put([]byte("b"), []byte("3"))
+	require.NoError(t, err)
+
+	// readers are not blocked by the writer and don't see its changes.
+	rtx, err := ng.Begin(false)
+	require.NoError(t, err)
+	defer rtx.Rollback()
+	require.Equal(t, []byte("1"), get(rtx, "a"))
+	require.Nil(t, get(rtx, "b"))
+	require.Equal(t, 1, count(rtx))
+
+	// the writer sees its own changes.
+	require.Equal(t, []byte("2"), get(wtx, "a"))
+	require.Equal(t, 2, count(wtx))
+
+	// iterators of the writer are not affected by the changes made
+	// after they seek, which are seen by the next seek.
+	err = st.Put([]byte("c"), []byte("4"))
+	require.NoError(t, err)
+	it := st.NewIterator(engine.IteratorConfig{})
+	it.Seek(nil)
+	err = st.Delete([]byte("c"))
+	require.NoError(t, err)
+	var n int
+	for ; it.Valid(); it.Next() {
+		n++
+	}
+	require.Equal(t, 3, n)
+	it.Seek(nil)
+	n = 0
+	for ; it.Valid(); it.Next() {
+		n++
+	}
+	require.Equal(t, 2, n)
+	require.NoError(t, it.Close())
+
+	err = wtx.Commit()
+	require.NoError(t, err)
+
+	// transactions started before the commit keep their snapshot.
+	require.Equal(t, []byte("1"), get(rtx, "a"))
+	require.Equal(t, 1, count(rtx))
+
+	rtx2, err := ng.Begin(false)
+	require.NoError(t, err)
+	defer rtx2.Rollback()
+	require.Equal(t, []byte("2"), get(rtx2, "a"))
+	require.Equal(t, []byte("3"), get(rtx2, "b"))
+
+	// rolled back changes are never visible.
+	wtx, err = ng.Begin(true)
+	require.NoError(t, err)
+	st, err = wtx.GetStore([]byte("test"))
+	require.NoError(t, err)
+	err = st.Delete([]byte("a"))
+	require.NoError(t, err)
+	err = st.Truncate()
+	require.NoError(t, err)
+	err = wtx.Rollback()
+	require.NoError(t, err)
+
+	rtx3, err := ng.Begin(false)
+	require.NoError(t, err)
+	defer rtx3.Rollback()
+	require.Equal(t, 2, count(rtx3))
+}
+
 func BenchmarkMemoryEngineStorePut(b *testing.B) {
 	enginetest.BenchmarkStorePut(b, builder)
 }
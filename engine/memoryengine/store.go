@@ -11,12 +11,9 @@ import (
 
 // item implements an engine.Item.
 // it is also used as a btree.Item.
+// Since items can be shared by multiple btrees, they must never be modified.
 type item struct {
 	k, v []byte
-	// set to true if the item has been deleted
-	// during the current transaction
-	// but before rollback or commit.
-	deleted bool
 }
 
 func (i *item) Key() []byte {
@@ -51,33 +48,8 @@ func (s *storeTx) Put(k, v []byte) error {
 		return errors.New("empty keys are forbidden")
 	}
 
-	it := &item{k: k}
-	// if there is an existing value, fetch it
-	// and overwrite it directly using the pointer.
-	if i := s.tr.Get(it); i != nil {
-		cur := i.(*item)
-
-		oldv, oldDeleted := cur.v, cur.deleted
-		cur.v = v
-		cur.deleted = false
-
-		// on rollback replace the new value by the old value
-		s.tx.onRollback = append(s.tx.onRollback, func() {
-			cur.v = oldv
-			cur.deleted = oldDeleted
-		})
-
-		return nil
-	}
-
-	it.v = v
-	s.tr.ReplaceOrInsert(it)
-
-	// on rollback delete the new item
-	s.tx.onRollback = append(s.tx.onRollback, func() {
-		s.tr.Delete(it)
-	})
-
+	s.tr.ReplaceOrInsert(&item{k: k, v: v})
+	s.tx.invalidateSnapshot(s.name)
 	return nil
 }
 
@@ -88,74 +60,36 @@ func (s *storeTx) Get(k []byte) ([]byte, error) {
 		return nil, engine.ErrKeyNotFound
 	}
 
-	i := it.(*item)
-	// don't return items that have been deleted during
-	// this transaction.
-	if i.deleted {
-		return nil, engine.ErrKeyNotFound
-	}
-
 	return it.(*item).v, nil
 }
 
-// Delete marks k for deletion. The item will be actually
-// deleted during the commit phase of the current transaction.
-// The deletion is delayed to avoid a rebalancing of the tree
-// every time we remove an item from it,
-// which causes iterators to behave incorrectly when looping
-// and deleting at the same time.
+// Delete removes k from the btree of the transaction.
+// Iterators work on a snapshot of the btree, which allows
+// deleting items while iterating.
 func (s *storeTx) Delete(k []byte) error {
 	if !s.tx.writable {
 		return engine.ErrTransactionReadOnly
 	}
 
-	it := s.tr.Get(&item{k: k})
+	it := s.tr.Delete(&item{k: k})
 	if it == nil {
 		return engine.ErrKeyNotFound
 	}
 
-	i := it.(*item)
-	// items that have been deleted during
-	// this transaction must be ignored.
-	if i.deleted {
-		return engine.ErrKeyNotFound
-	}
-
-	// set the deleted flag to true.
-	// this makes the item invisible during this
-	// transaction without actually deleting it
-	// from the tree.
-	// once the transaction is commited, actually
-	// remove it from the tree.
-	i.deleted = true
-
-	// on rollback set the deleted flag to false.
-	s.tx.onRollback = append(s.tx.onRollback, func() {
-		i.deleted = false
-	})
-
-	// on commit, remove the item from the tree.
-	s.tx.onCommit = append(s.tx.onCommit, func() {
-		s.tr.Delete(i)
-	})
+	s.tx.invalidateSnapshot(s.name)
 	return nil
 }
 
-// Truncate replaces the current tree by a new
-// one. The current tree will be garbage collected
-// once the transaction is commited.
+// Truncate replaces the current tree by a new one.
 func (s *storeTx) Truncate() error {
 	if !s.tx.writable {
 		return engine.ErrTransactionReadOnly
 	}
 
-	old := s.tr
 	s.tr = btree.New(btreeDegree)
-
-	// on rollback replace the new tree by the old one.
-	s.tx.onRollback = append(s.tx.onRollback, func() {
-		s.tr = old
-	})
+	s.tx.stores[s.name] = s.tr
+	s.tx.cloned[s.name] = true
+	s.tx.invalidateSnapshot(s.name)
 
 	return nil
 }
@@ -166,26 +100,55 @@ func (s *storeTx) NextSequence() (uint64, error) {
 		return 0, engine.ErrTransactionReadOnly
 	}
 
-	s.tx.ng.sequences[s.name]++
+	s.tx.sequences[s.name]++
+
+	return s.tx.sequences[s.name], nil
+}
+
+// SetSequence sets the last number returned by NextSequence.
+func (s *storeTx) SetSequence(seq uint64) error {
+	if !s.tx.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	s.tx.sequences[s.name] = seq
 
-	return s.tx.ng.sequences[s.name], nil
+	return nil
 }
 
 func (s *storeTx) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
 	return &iterator{
 		tx:      s.tx,
-		tr:      s.tr,
+		store:   s,
 		reverse: cfg.Reverse,
 		ch:      make(chan *item),
 		closed:  make(chan struct{}),
 	}
 }
 
+// snapshot returns the btree read by iterators.
+// Iterators of a write transaction read a clone of its btree, which allows the
+// transaction to modify the btree while iterating. The clone is shared by the iterators
+// until the btree is modified, so that it is only cloned once if it isn't.
+func (s *storeTx) snapshot() *btree.BTree {
+	if !s.tx.writable {
+		return s.tr
+	}
+
+	tr, ok := s.tx.snapshots[s.name]
+	if !ok {
+		tr = s.tr.Clone()
+		s.tx.snapshots[s.name] = tr
+	}
+
+	return tr
+}
+
 // iterator uses a goroutine to read from the tree on demand.
 type iterator struct {
 	tx      *transaction
 	reverse bool
-	tr      *btree.BTree
+	store   *storeTx
 	item    *item // current item
 	ch      chan *item
 	closed  chan struct{} // closed by the goroutine when it's shutdown
@@ -205,7 +168,7 @@ func (it *iterator) Seek(pivot []byte) {
 
 	it.ctx, it.cancel = context.WithCancel(context.Background())
 
-	it.runIterator(pivot)
+	it.runIterator(pivot, it.store.snapshot())
 
 	it.Next()
 }
@@ -213,7 +176,7 @@ func (it *iterator) Seek(pivot []byte) {
 // runIterator creates a goroutine that reads from the tree.
 // Once the goroutine is done reading or if the context is canceled,
 // both ch and closed channels will be closed.
-func (it *iterator) runIterator(pivot []byte) {
+func (it *iterator) runIterator(pivot []byte, tr *btree.BTree) {
 	it.tx.wg.Add(1)
 
 	go func(ctx context.Context, ch chan *item, tr *btree.BTree) {
@@ -228,15 +191,10 @@ func (it *iterator) runIterator(pivot []byte) {
 			default:
 			}
 
-			itm := i.(*item)
-			if itm.deleted {
-				return true
-			}
-
 			select {
 			case <-ctx.Done():
 				return false
-			case ch <- itm:
+			case ch <- i.(*item):
 				return true
 			}
 		})
@@ -254,7 +212,7 @@ func (it *iterator) runIterator(pivot []byte) {
 				tr.AscendGreaterOrEqual(&item{k: pivot}, iter)
 			}
 		}
-	}(it.ctx, it.ch, it.tr)
+	}(it.ctx, it.ch, tr)
 }
 
 func (it *iterator) Valid() bool {
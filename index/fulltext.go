@@ -0,0 +1,516 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// A FullTextQuery selects the texts containing some words.
+type FullTextQuery struct {
+	// the query matches a text if all the phrases
+	// of one of the groups are found in that text.
+	groups [][]phrase
+}
+
+// A phrase is a list of words that must be found next to each other, in order.
+// Most phrases contain a single word.
+type phrase []string
+
+// ParseFullTextQuery parses a full-text query. Words separated by spaces must all be found
+// in the text, unless they are separated by OR: "a b OR c" matches the texts containing both a and b,
+// or c. Words enclosed in double quotes form a phrase, whose words must be found next to each other,
+// in the same order. The words of the query are normalized like the words of the texts.
+func ParseFullTextQuery(s string) (*FullTextQuery, error) {
+	var q FullTextQuery
+	var group []phrase
+
+	add := func(s string) {
+		if words := Tokenize(s); len(words) > 0 {
+			group = append(group, words)
+		}
+	}
+
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, errors.New("full-text query: unterminated phrase")
+			}
+
+			add(s[1 : end+1])
+			s = s[end+2:]
+			continue
+		}
+
+		end := strings.IndexFunc(s, func(r rune) bool {
+			return r == '"' || unicode.IsSpace(r)
+		})
+		if end < 0 {
+			end = len(s)
+		}
+
+		switch word := s[:end]; word {
+		case "OR":
+			if len(group) == 0 {
+				return nil, errors.New("full-text query: OR must be placed between words")
+			}
+
+			q.groups = append(q.groups, group)
+			group = nil
+		case "AND":
+			// words must all be found by default.
+		default:
+			// words containing punctuation, such as "e-mail",
+			// are split into phrases.
+			add(word)
+		}
+
+		s = s[end:]
+	}
+
+	if len(group) == 0 {
+		if len(q.groups) > 0 {
+			return nil, errors.New("full-text query: OR must be placed between words")
+		}
+
+		return nil, errors.New("full-text query: no words to search")
+	}
+
+	q.groups = append(q.groups, group)
+	return &q, nil
+}
+
+// Match reports whether a text, split into words by Tokenize, matches the query.
+func (q *FullTextQuery) Match(words []string) bool {
+	return q.match(wordPositions(words))
+}
+
+// MatchText reports whether a text matches the query.
+func (q *FullTextQuery) MatchText(text string) bool {
+	return q.Match(Tokenize(text))
+}
+
+// match reports whether the query matches a text, given the positions
+// of the words of the text, in increasing order.
+func (q *FullTextQuery) match(positions map[string][]int) bool {
+	for _, g := range q.groups {
+		found := true
+		for _, p := range g {
+			if !p.foundIn(positions) {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// words returns the distinct words of the query.
+func (q *FullTextQuery) words() []string {
+	var words []string
+	seen := make(map[string]bool)
+
+	for _, g := range q.groups {
+		for _, p := range g {
+			for _, w := range p {
+				if !seen[w] {
+					seen[w] = true
+					words = append(words, w)
+				}
+			}
+		}
+	}
+
+	return words
+}
+
+// foundIn reports whether the words of the phrase are found next to each other,
+// given the positions of the words of a text, in increasing order.
+func (p phrase) foundIn(positions map[string][]int) bool {
+	for _, start := range positions[p[0]] {
+		found := true
+		for i := 1; i < len(p) && found; i++ {
+			ps := positions[p[i]]
+			j := sort.SearchInts(ps, start+i)
+			found = j < len(ps) && ps[j] == start+i
+		}
+
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parameters of the Okapi BM25 ranking function.
+const (
+	// saturation of the frequency of words.
+	bm25K1 = 1.2
+	// normalization of the length of texts.
+	bm25B = 0.75
+)
+
+// The keys of the store of a full-text index start with one of these prefixes.
+const (
+	// word + 0 + key of the document: positions of the word in the text of the document.
+	postingPrefix = 'p'
+	// key of the document: number of words of its text.
+	lengthPrefix = 'l'
+	// number of indexed texts and total number of words.
+	statsPrefix = 's'
+)
+
+// A FullTextIndex is an inverted index: it associates every word of the indexed texts with
+// the keys of the documents containing it, and the positions of the word in their text.
+// It uses the same store as an Index with the same name, which can be used to truncate it.
+type FullTextIndex struct {
+	tx        engine.Transaction
+	storeName []byte
+}
+
+// NewFullTextIndex creates a full-text index.
+func NewFullTextIndex(tx engine.Transaction, idxName string) *FullTextIndex {
+	return &FullTextIndex{
+		tx:        tx,
+		storeName: append([]byte(storePrefix), idxName...),
+	}
+}
+
+// Set indexes the words of a text with the key of its document.
+// Values that are not texts are ignored.
+func (idx *FullTextIndex) Set(v document.Value, k []byte) error {
+	if len(k) == 0 {
+		return errors.New("cannot index value without a key")
+	}
+
+	if v.Type != document.TextValue {
+		return nil
+	}
+
+	st, err := getOrCreateStore(idx.tx, idx.storeName)
+	if err != nil {
+		return err
+	}
+
+	words := Tokenize(v.V.(string))
+	for w, positions := range wordPositions(words) {
+		var buf []byte
+		var prev int
+		for _, p := range positions {
+			buf = appendUvarint(buf, uint64(p-prev))
+			prev = p
+		}
+
+		err = st.Put(postingKey(w, k), buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = st.Put(append([]byte{lengthPrefix}, k...), appendUvarint(nil, uint64(len(words))))
+	if err != nil {
+		return err
+	}
+
+	return updateFullTextStats(st, 1, len(words))
+}
+
+// Delete removes the words of a text associated with the key of its document.
+// Values that are not texts are ignored.
+func (idx *FullTextIndex) Delete(v document.Value, k []byte) error {
+	if v.Type != document.TextValue {
+		return nil
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return engine.ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	words := Tokenize(v.V.(string))
+	for w := range wordPositions(words) {
+		err = st.Delete(postingKey(w, k))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = st.Delete(append([]byte{lengthPrefix}, k...))
+	if err != nil {
+		return err
+	}
+
+	return updateFullTextStats(st, -1, -len(words))
+}
+
+// Search calls fn with the key of every document whose text matches the query, and its
+// relevance score, computed using the Okapi BM25 ranking function: the more a document
+// contains the words of the query, the higher its score, especially if the words are rare
+// and the text is short. Documents are returned by decreasing score.
+// The postings of the words of the query are read in parallel, in the order of the keys
+// of the documents, so that only the matching documents and their score are kept in memory.
+// If the given function returns an error, the search stops and returns that error.
+func (idx *FullTextIndex) Search(q *FullTextQuery, fn func(k []byte, score float64) error) error {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	docCount, wordCount, err := fullTextStats(st)
+	if err != nil || docCount == 0 {
+		return err
+	}
+	avgLength := float64(wordCount) / float64(docCount)
+
+	words := q.words()
+	cursors := make([]*postingCursor, len(words))
+	for i, w := range words {
+		c, err := newPostingCursor(st, w)
+		if err != nil {
+			return err
+		}
+		defer c.close()
+
+		cursors[i] = c
+	}
+
+	type result struct {
+		key   []byte
+		score float64
+	}
+
+	var results []result
+	// positions of the words of the query in the current document.
+	positions := make(map[string][]int, len(words))
+	for {
+		// the current document is the one with the lowest key.
+		var k []byte
+		for _, c := range cursors {
+			if c.valid() && (k == nil || bytes.Compare(c.key, k) < 0) {
+				k = c.key
+			}
+		}
+		if k == nil {
+			break
+		}
+		k = append([]byte(nil), k...)
+
+		for w := range positions {
+			delete(positions, w)
+		}
+		for _, c := range cursors {
+			if c.valid() && bytes.Equal(c.key, k) {
+				positions[c.word] = c.positions
+			}
+		}
+
+		if q.match(positions) {
+			v, err := st.Get(append([]byte{lengthPrefix}, k...))
+			if err != nil {
+				return err
+			}
+			length, _ := binary.Uvarint(v)
+
+			var score float64
+			for _, c := range cursors {
+				ps, ok := positions[c.word]
+				if !ok {
+					continue
+				}
+
+				n := float64(c.docs)
+				idf := math.Log(1 + (float64(docCount)-n+0.5)/(n+0.5))
+				tf := float64(len(ps))
+				score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(length)/avgLength))
+			}
+
+			results = append(results, result{key: k, score: score})
+		}
+
+		for _, c := range cursors {
+			if c.valid() && bytes.Equal(c.key, k) {
+				err = c.next()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+
+		return bytes.Compare(results[i].key, results[j].key) < 0
+	})
+
+	for _, r := range results {
+		err = fn(r.key, r.score)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wordPositions returns the positions of every word in the given list.
+func wordPositions(words []string) map[string][]int {
+	positions := make(map[string][]int)
+	for i, w := range words {
+		positions[w] = append(positions[w], i)
+	}
+
+	return positions
+}
+
+func postingKey(word string, k []byte) []byte {
+	buf := make([]byte, 0, len(word)+len(k)+2)
+	buf = append(buf, postingPrefix)
+	buf = append(buf, word...)
+	buf = append(buf, 0)
+	return append(buf, k...)
+}
+
+// A postingCursor reads the postings of a word, in the order of the keys of the documents.
+type postingCursor struct {
+	word   string
+	prefix []byte
+	it     engine.Iterator
+	// number of documents containing the word.
+	docs int
+
+	// key of the current document and positions of the word in its text.
+	key       []byte
+	positions []int
+	buf       []byte
+}
+
+// newPostingCursor creates a cursor positioned on the first document containing the word.
+func newPostingCursor(st engine.Store, word string) (*postingCursor, error) {
+	c := postingCursor{
+		word:   word,
+		prefix: postingKey(word, nil),
+		it:     st.NewIterator(engine.IteratorConfig{}),
+	}
+
+	// the number of documents is needed to score the documents
+	// before the postings are read.
+	for c.it.Seek(c.prefix); c.valid(); c.it.Next() {
+		c.docs++
+	}
+
+	c.it.Seek(c.prefix)
+	err := c.read()
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// valid reports whether the cursor is positioned on a posting of the word.
+func (c *postingCursor) valid() bool {
+	return c.it.Valid() && bytes.HasPrefix(c.it.Item().Key(), c.prefix)
+}
+
+// next moves the cursor to the next document containing the word.
+func (c *postingCursor) next() error {
+	c.it.Next()
+	return c.read()
+}
+
+// read decodes the current posting, if any.
+func (c *postingCursor) read() error {
+	if !c.valid() {
+		return nil
+	}
+
+	itm := c.it.Item()
+	c.key = itm.Key()[len(c.prefix):]
+
+	var err error
+	c.buf, err = itm.ValueCopy(c.buf[:0])
+	if err != nil {
+		return err
+	}
+
+	c.positions = c.positions[:0]
+	var p int
+	for b := c.buf; len(b) > 0; {
+		delta, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("cannot decode full-text index positions")
+		}
+
+		p += int(delta)
+		c.positions = append(c.positions, p)
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func (c *postingCursor) close() {
+	c.it.Close()
+}
+
+// fullTextStats returns the number of indexed texts and their total number of words.
+func fullTextStats(st engine.Store) (docs, words uint64, err error) {
+	v, err := st.Get([]byte{statsPrefix})
+	if err == engine.ErrKeyNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	docs, n := binary.Uvarint(v)
+	words, _ = binary.Uvarint(v[n:])
+	return docs, words, nil
+}
+
+// updateFullTextStats adds the given numbers of texts and words to the statistics of the index.
+func updateFullTextStats(st engine.Store, docs, words int) error {
+	d, w, err := fullTextStats(st)
+	if err != nil {
+		return err
+	}
+
+	buf := appendUvarint(nil, uint64(int64(d)+int64(docs)))
+	buf = appendUvarint(buf, uint64(int64(w)+int64(words)))
+	return st.Put([]byte{statsPrefix}, buf)
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
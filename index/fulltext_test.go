@@ -0,0 +1,167 @@
+package index_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text  string
+		words []string
+	}{
+		{"", nil},
+		{"  -- ", nil},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"e-mail 42nd", []string{"e", "mail", "42nd"}},
+		{"Crème Brûlée", []string{"creme", "brulee"}},
+		// the accents are combining marks.
+		{"Cre\u0300me", []string{"creme"}},
+		{"Œuvre STRAßE", []string{"oeuvre", "strasse"}},
+		// only the diacritics of Latin letters are removed.
+		{"Ελληνικά 日本語", []string{"ελληνικά", "日本語"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			require.Equal(t, test.words, index.Tokenize(test.text))
+		})
+	}
+}
+
+func TestParseFullTextQuery(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog"
+
+	tests := []struct {
+		query string
+		match bool
+		fails bool
+	}{
+		{"fox", true, false},
+		{"FOX", true, false},
+		{"cat", false, false},
+		{"quick fox", true, false},
+		{"quick AND fox", true, false},
+		{"quick cat", false, false},
+		{"cat OR fox", true, false},
+		{"cat OR mouse", false, false},
+		{"quick cat OR lazy dog", true, false},
+		{`"quick brown fox"`, true, false},
+		{`"brown quick"`, false, false},
+		{`"lazy dog" OR cat`, true, false},
+		{"over-the-lazy", true, false},
+		{"lazy-over", false, false},
+		{`"fox`, false, true},
+		{"OR fox", false, true},
+		{"fox OR", false, true},
+		{"fox OR OR dog", false, true},
+		{"", false, true},
+		{"--", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := index.ParseFullTextQuery(test.query)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.match, q.Match(index.Tokenize(text)))
+		})
+	}
+}
+
+func TestFullTextIndexSearch(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	idx := index.NewFullTextIndex(tx, "foo")
+
+	texts := map[string]string{
+		"a": "The quick brown fox",
+		"b": "The lazy dog sleeps all day long and never wakes up",
+		"c": "A dog and a fox",
+		"d": "Foxes are not dogs",
+	}
+	for k, text := range texts {
+		require.NoError(t, idx.Set(document.NewTextValue(text), []byte(k)))
+	}
+	// other values are ignored.
+	require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("e")))
+	require.Error(t, idx.Set(document.NewTextValue("fox"), nil))
+
+	search := func(query string) ([]string, []float64) {
+		q, err := index.ParseFullTextQuery(query)
+		require.NoError(t, err)
+
+		var keys []string
+		var scores []float64
+		err = idx.Search(q, func(k []byte, score float64) error {
+			keys = append(keys, string(k))
+			scores = append(scores, score)
+			return nil
+		})
+		require.NoError(t, err)
+		return keys, scores
+	}
+
+	t.Run("Words", func(t *testing.T) {
+		keys, _ := search("fox")
+		require.ElementsMatch(t, []string{"a", "c"}, keys)
+
+		keys, _ = search("dog fox")
+		require.Equal(t, []string{"c"}, keys)
+
+		keys, _ = search("brown OR lazy")
+		require.ElementsMatch(t, []string{"a", "b"}, keys)
+
+		keys, _ = search(`"dog and"`)
+		require.Equal(t, []string{"c"}, keys)
+
+		keys, _ = search("cat")
+		require.Empty(t, keys)
+	})
+
+	t.Run("Score", func(t *testing.T) {
+		// both contain "dog" once, c is shorter than b.
+		keys, scores := search("dog")
+		require.Equal(t, []string{"c", "b"}, keys)
+		require.Greater(t, scores[0], scores[1])
+
+		// b contains both words.
+		keys, scores = search("dog OR sleeps")
+		require.Equal(t, []string{"b", "c"}, keys)
+		require.Greater(t, scores[0], scores[1])
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, idx.Delete(document.NewTextValue(texts["c"]), []byte("c")))
+
+		keys, _ := search("fox")
+		require.Equal(t, []string{"a"}, keys)
+
+		keys, _ = search("dog")
+		require.Equal(t, []string{"b"}, keys)
+	})
+
+	t.Run("Empty index", func(t *testing.T) {
+		idx := index.NewFullTextIndex(tx, "bar")
+		require.Equal(t, engine.ErrKeyNotFound, idx.Delete(document.NewTextValue("fox"), []byte("a")))
+
+		q, err := index.ParseFullTextQuery("fox")
+		require.NoError(t, err)
+		err = idx.Search(q, func(k []byte, score float64) error {
+			t.Fatal("no document should be found")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
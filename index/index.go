@@ -20,6 +20,7 @@ var valueTypes = []document.ValueType{
 	document.NullValue,
 	document.BoolValue,
 	document.DoubleValue,
+	document.TimestampValue,
 	document.TextValue,
 	document.BlobValue,
 	document.ArrayValue,
@@ -157,6 +158,33 @@ func (idx *Index) Delete(v document.Value, k []byte) error {
 	return engine.ErrKeyNotFound
 }
 
+// Get returns the key associated with v. It must only be used on unique indexes.
+// If v is not indexed, it returns engine.ErrKeyNotFound.
+func (idx *Index) Get(v document.Value) ([]byte, error) {
+	if !idx.Unique {
+		return nil, errors.New("cannot get a value from a non-unique index")
+	}
+
+	if idx.Type != 0 && idx.Type != v.Type {
+		return nil, engine.ErrKeyNotFound
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return nil, engine.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := idx.encodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return st.Get(buf)
+}
+
 // AscendGreaterOrEqual seeks for the pivot and then goes through all the subsequent key value pairs in increasing order and calls the given function for each pair.
 // If the given function returns an error, the iteration stops and returns that error.
 // If the pivot is empty, starts from the beginning.
@@ -214,6 +242,74 @@ func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val
 	})
 }
 
+// AscendPrefix goes through, in increasing order, all the key value pairs whose value is an array
+// starting with the elements of prefix and containing at least one more element, and calls
+// the given function for each pair.
+// If the pivot is not empty, the iteration starts at the first array whose element following the prefix
+// is greater or equal to the pivot. If the pivot only has a type, the iteration starts at the first
+// element of that type.
+// If the given function returns an error, the iteration stops and returns that error.
+func (idx *Index) AscendPrefix(prefix []document.Value, pivot document.Value, fn func(val, key []byte) error) error {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+	if st == nil {
+		return nil
+	}
+
+	enc, err := key.AppendArrayPrefix(nil, prefix...)
+	if err != nil {
+		return err
+	}
+
+	seek := enc
+	if pivot.V != nil {
+		seek, err = key.AppendValue(seek, pivot)
+		if err != nil {
+			return err
+		}
+	} else if pivot.Type != 0 {
+		if pivot.Type == document.IntegerValue {
+			pivot.Type = document.DoubleValue
+		}
+
+		seek = append(seek, byte(pivot.Type))
+	}
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(seek); it.Valid(); it.Next() {
+		itm := it.Item()
+
+		k := itm.Key()
+		if !bytes.HasPrefix(k, enc) {
+			return nil
+		}
+
+		// the last byte of the key of a non-unique index is the size of the varint.
+		// if that byte is 0, it means that key is not duplicated.
+		if !idx.Unique {
+			n := k[len(k)-1]
+			k = k[:len(k)-int(n)-1]
+		}
+
+		buf, err = itm.ValueCopy(buf[:0])
+		if err != nil {
+			return err
+		}
+
+		err = fn(k, buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Truncate deletes all the index data.
 func (idx *Index) Truncate() error {
 	err := idx.tx.DropStore(idx.storeName)
@@ -274,7 +370,9 @@ func (idx *Index) iterate(st engine.Store, pivot document.Value, reverse bool, f
 		pivot.Type = document.DoubleValue
 	}
 
-	if pivot.Type != 0 && pivot.V == nil {
+	// the values of typed indexes are encoded without their type,
+	// the iteration starts at the beginning of the index.
+	if idx.Type == 0 && pivot.Type != 0 && pivot.V == nil {
 		seek = []byte{byte(pivot.Type)}
 
 		if reverse {
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/memoryengine"
 	"github.com/genjidb/genji/index"
 	"github.com/genjidb/genji/key"
@@ -63,6 +64,39 @@ func TestIndexSet(t *testing.T) {
 	})
 }
 
+func TestIndexGet(t *testing.T) {
+	t.Run("Unique: false fails", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("key")))
+		_, err := idx.Get(document.NewIntegerValue(10))
+		require.Error(t, err)
+	})
+
+	t.Run("Unique: true", func(t *testing.T) {
+		idx, cleanup := getIndex(t, true)
+		defer cleanup()
+
+		_, err := idx.Get(document.NewIntegerValue(10))
+		require.Equal(t, engine.ErrKeyNotFound, err)
+
+		require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("key1")))
+		require.NoError(t, idx.Set(document.NewTextValue("foo"), []byte("key2")))
+
+		k, err := idx.Get(document.NewIntegerValue(10))
+		require.NoError(t, err)
+		require.Equal(t, []byte("key1"), k)
+
+		k, err = idx.Get(document.NewTextValue("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("key2"), k)
+
+		_, err = idx.Get(document.NewTextValue("bar"))
+		require.Equal(t, engine.ErrKeyNotFound, err)
+	})
+}
+
 func TestIndexDelete(t *testing.T) {
 	t.Run("Unique: false, Delete valid key succeeds", func(t *testing.T) {
 		idx, cleanup := getIndex(t, false)
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits a text into normalized words, in order. Words are sequences of letters
+// and digits: every other character separates them. They are normalized by lowercasing them
+// and removing the diacritics of Latin letters, so that "Café" and "cafe" are the same word.
+func Tokenize(text string) []string {
+	var words []string
+	var sb strings.Builder
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining marks, such as the accents of decomposed letters,
+			// belong to the word but are removed.
+			continue
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if sb.Len() > 0 {
+				words = append(words, sb.String())
+				sb.Reset()
+			}
+			continue
+		}
+
+		r = unicode.ToLower(r)
+		if s, ok := foldedLetters[r]; ok {
+			sb.WriteString(s)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	if sb.Len() > 0 {
+		words = append(words, sb.String())
+	}
+
+	return words
+}
+
+// foldedLetters maps the lowercase letters of the Latin-1 Supplement and Latin Extended-A
+// Unicode blocks to the letters they are made of, without their diacritics.
+var foldedLetters = func() map[rune]string {
+	m := make(map[rune]string)
+
+	for folded, letters := range map[string]string{
+		"a":  "àáâãäåāăą",
+		"c":  "çćĉċč",
+		"d":  "ðďđ",
+		"e":  "èéêëēĕėęě",
+		"g":  "ĝğġģ",
+		"h":  "ĥħ",
+		"i":  "ìíîïĩīĭįı",
+		"ij": "ĳ",
+		"j":  "ĵ",
+		"k":  "ķĸ",
+		"l":  "ĺļľŀł",
+		"n":  "ñńņňŉŋ",
+		"o":  "òóôõöøōŏő",
+		"ae": "æ",
+		"oe": "œ",
+		"r":  "ŕŗř",
+		"s":  "śŝşšſ",
+		"ss": "ß",
+		"t":  "ţťŧ",
+		"th": "þ",
+		"u":  "ùúûüũūŭůűų",
+		"w":  "ŵ",
+		"y":  "ýÿŷ",
+		"z":  "źżž",
+	} {
+		for _, r := range letters {
+			m[r] = folded
+		}
+	}
+
+	return m
+}()
@@ -13,6 +13,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"math"
+	"time"
 
 	"github.com/genjidb/genji/document"
 )
@@ -93,6 +94,22 @@ func DecodeFloat64(buf []byte) (float64, error) {
 	return math.Float64frombits(x), nil
 }
 
+// AppendTimestamp takes a time and returns its binary representation,
+// the number of microseconds elapsed since January 1, 1970 UTC encoded using AppendInt64.
+func AppendTimestamp(buf []byte, x time.Time) []byte {
+	return AppendInt64(buf, x.Unix()*1e6+int64(x.Nanosecond()/1e3))
+}
+
+// DecodeTimestamp takes a byte slice and decodes it into a time.
+func DecodeTimestamp(buf []byte) (time.Time, error) {
+	x, err := DecodeInt64(buf)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(x/1e6, (x%1e6)*1e3).UTC(), nil
+}
+
 // AppendBase64 encodes data into a custom base64 encoding. The resulting slice respects
 // natural sort-ordering.
 func AppendBase64(buf []byte, data []byte) ([]byte, error) {
@@ -171,6 +188,27 @@ func AppendArray(buf []byte, a document.Array) ([]byte, error) {
 	return buf, nil
 }
 
+// AppendArrayPrefix encodes the given values the same way AppendValue encodes an array
+// whose first elements are these values, including the type of the array.
+// Each value is followed by a delimiter and the end of the array is not encoded, which means the
+// result is a prefix of the encoding of any array starting with these values and containing
+// at least one more element.
+func AppendArrayPrefix(buf []byte, values ...document.Value) ([]byte, error) {
+	var err error
+
+	buf = append(buf, byte(document.ArrayValue))
+	for _, v := range values {
+		buf, err = AppendValue(buf, v)
+		if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, arrayValueDelim)
+	}
+
+	return buf, nil
+}
+
 func decodeValue(data []byte, delim, end byte) (document.Value, int, error) {
 	t := document.ValueType(data[0])
 	i := 1
@@ -195,6 +233,8 @@ func decodeValue(data []byte, delim, end byte) (document.Value, int, error) {
 		i++
 	case document.DoubleValue:
 		i += 16
+	case document.TimestampValue:
+		i += 8
 	case document.BlobValue, document.TextValue:
 		for i < len(data) && data[i] != delim && data[i] != end {
 			i++
@@ -349,6 +389,8 @@ func AppendValue(buf []byte, v document.Value) ([]byte, error) {
 		return AppendBool(buf, v.V.(bool)), nil
 	case document.IntegerValue, document.DoubleValue:
 		return AppendNumber(buf, v)
+	case document.TimestampValue:
+		return AppendTimestamp(buf, v.V.(time.Time)), nil
 	case document.NullValue:
 		return buf, nil
 	case document.ArrayValue:
@@ -393,6 +435,12 @@ func DecodeValue(data []byte) (document.Value, error) {
 			return document.Value{}, err
 		}
 		return document.NewDoubleValue(x), nil
+	case document.TimestampValue:
+		x, err := DecodeTimestamp(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewTimestampValue(x), nil
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	case document.ArrayValue:
@@ -426,6 +474,8 @@ func Append(buf []byte, t document.ValueType, v interface{}) ([]byte, error) {
 		return AppendInt64(buf, v.(int64)), nil
 	case document.DoubleValue:
 		return AppendFloat64(buf, v.(float64)), nil
+	case document.TimestampValue:
+		return AppendTimestamp(buf, v.(time.Time)), nil
 	case document.NullValue:
 		return buf, nil
 	case document.ArrayValue:
@@ -459,6 +509,12 @@ func Decode(t document.ValueType, data []byte) (document.Value, error) {
 			return document.Value{}, err
 		}
 		return document.NewDoubleValue(x), nil
+	case document.TimestampValue:
+		x, err := DecodeTimestamp(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewTimestampValue(x), nil
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	case document.ArrayValue:
@@ -5,6 +5,7 @@ import (
 	"math"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
@@ -21,8 +22,10 @@ func TestAppendDecode(t *testing.T) {
 		{"double", document.NewDoubleValue(-3.14)},
 		{"text", document.NewTextValue("foo")},
 		{"blob", document.NewBlobValue([]byte("bar"))},
+		{"timestamp", document.NewTimestampValue(time.Date(1969, 7, 20, 20, 17, 40, 123456000, time.UTC))},
 		{"array", document.NewArrayValue(document.NewValueBuffer(
 			document.NewBoolValue(true),
+			document.NewTimestampValue(time.Date(2020, 10, 5, 12, 30, 10, 0, time.UTC)),
 			document.NewIntegerValue(55),
 			document.NewDoubleValue(789.58),
 			document.NewArrayValue(document.NewValueBuffer(
@@ -87,8 +90,10 @@ func TestAppendValueDecodeValue(t *testing.T) {
 		{"double", document.NewDoubleValue(-3.14)},
 		{"text", document.NewTextValue("foo")},
 		{"blob", document.NewBlobValue([]byte("bar"))},
+		{"timestamp", document.NewTimestampValue(time.Date(1969, 7, 20, 20, 17, 40, 123456000, time.UTC))},
 		{"array", document.NewArrayValue(document.NewValueBuffer(
 			document.NewBoolValue(true),
+			document.NewTimestampValue(time.Date(2020, 10, 5, 12, 30, 10, 0, time.UTC)),
 			document.NewIntegerValue(55),
 			document.NewDoubleValue(789.58),
 			document.NewArrayValue(document.NewValueBuffer(
@@ -153,6 +158,9 @@ func TestOrdering(t *testing.T) {
 		{"uint64", 0, 1000, func(buf []byte, i int) []byte { return AppendUint64(buf, uint64(i)) }},
 		{"int64", -1000, 1000, func(buf []byte, i int) []byte { return AppendInt64(buf, int64(i)) }},
 		{"float64", -1000, 1000, func(buf []byte, i int) []byte { return AppendFloat64(buf, float64(i)) }},
+		{"timestamp", -1000, 1000, func(buf []byte, i int) []byte {
+			return AppendTimestamp(buf, time.Unix(int64(i)*1e5, int64(i)*1e3))
+		}},
 		{"text", -1000, 1000, func(buf []byte, i int) []byte {
 			b, err := AppendValue(nil, document.NewTextValue(string(AppendInt64(buf, int64(i)))))
 			require.NoError(t, err)
@@ -224,3 +232,28 @@ func TestOrdering(t *testing.T) {
 		}
 	})
 }
+
+func TestAppendArrayPrefix(t *testing.T) {
+	prefix, err := AppendArrayPrefix(nil, document.NewIntegerValue(1), document.NewTextValue("a"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		values   []document.Value
+		expected bool
+	}{
+		{"Longer array", []document.Value{document.NewIntegerValue(1), document.NewTextValue("a"), document.NewBoolValue(true)}, true},
+		{"Longer array with null", []document.Value{document.NewIntegerValue(1), document.NewTextValue("a"), document.NewNullValue()}, true},
+		{"Same array", []document.Value{document.NewIntegerValue(1), document.NewTextValue("a")}, false},
+		{"Different values", []document.Value{document.NewIntegerValue(1), document.NewTextValue("b"), document.NewBoolValue(true)}, false},
+		{"Longer text", []document.Value{document.NewIntegerValue(1), document.NewTextValue("ab"), document.NewBoolValue(true)}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := AppendValue(nil, document.NewArrayValue(document.NewValueBuffer(test.values...)))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, bytes.HasPrefix(enc, prefix))
+		})
+	}
+}
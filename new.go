@@ -6,11 +6,12 @@ import (
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document/encoding/msgpack"
 	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/sql/parser"
 )
 
 // New initializes the DB using the given engine.
 func New(ng engine.Engine) (*DB, error) {
-	db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec(), ParseExpr: parser.ParseStoredExpr})
 	if err != nil {
 		return nil, err
 	}
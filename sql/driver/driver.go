@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/planner"
@@ -63,7 +64,7 @@ type connector struct {
 }
 
 func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
-	return &conn{db: c.db}, nil
+	return &conn{db: c.db, session: c.db.DB.NewSession()}, nil
 }
 
 func (c *connector) Driver() driver.Driver {
@@ -80,9 +81,12 @@ func (c *connector) Close() error {
 
 // conn represents a connection to the Genji database.
 // It implements the database/sql/driver.Conn interface.
+// Each connection has its own session, which allows running
+// BEGIN, COMMIT and ROLLBACK statements on multiple connections concurrently.
 type conn struct {
-	db *genji.DB
-	tx *genji.Tx
+	db      *genji.DB
+	session *database.Session
+	tx      *genji.Tx
 }
 
 // Prepare returns a prepared statement, bound to this connection.
@@ -98,19 +102,27 @@ func (c *conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error
 	}
 
 	return stmt{
-		db: c.db,
-		tx: c.tx,
-		q:  pq,
+		db:      c.db,
+		session: c.session,
+		tx:      c.tx,
+		q:       pq,
 	}, nil
 }
 
-// Close closes any ongoing transaction.
+// Close rolls back any ongoing transaction and closes the session.
 func (c *conn) Close() error {
+	var err error
 	if c.tx != nil {
-		return c.tx.Rollback()
+		err = c.tx.Rollback()
+		c.tx = nil
 	}
 
-	return nil
+	serr := c.session.Close()
+	if err == nil {
+		err = serr
+	}
+
+	return err
 }
 
 // Begin starts and returns a new transaction.
@@ -154,9 +166,10 @@ func (c *conn) Rollback() error {
 // Stmt is a prepared statement. It is bound to a Conn and not
 // used by multiple goroutines concurrently.
 type stmt struct {
-	db *genji.DB
-	tx *genji.Tx
-	q  query.Query
+	db      *genji.DB
+	session *database.Session
+	tx      *genji.Tx
+	q       query.Query
 }
 
 // NumInput returns the number of placeholder parameters.
@@ -207,7 +220,7 @@ func (s stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver
 	if s.tx != nil {
 		res, err = s.q.Exec(ctx, s.tx.Transaction, driverNamedValueToParams(args))
 	} else {
-		res, err = s.q.Run(ctx, s.db.DB, driverNamedValueToParams(args))
+		res, err = s.q.Run(ctx, s.session, driverNamedValueToParams(args))
 	}
 
 	if err != nil {
@@ -257,7 +270,7 @@ func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (drive
 	if s.tx != nil {
 		res, err = s.q.Exec(ctx, s.tx.Transaction, driverNamedValueToParams(args))
 	} else {
-		res, err = s.q.Run(ctx, s.db.DB, driverNamedValueToParams(args))
+		res, err = s.q.Run(ctx, s.session, driverNamedValueToParams(args))
 	}
 
 	if err != nil {
@@ -421,7 +434,7 @@ func (v valueScanner) Scan(src interface{}) error {
 	case document.Array:
 		return document.SliceScan(t, v.v)
 	case document.Value:
-		return document.ScanValue(t, src)
+		return document.ScanValue(t, v.v)
 	}
 
 	vv, err := document.NewValue(src)
@@ -429,7 +442,7 @@ func (v valueScanner) Scan(src interface{}) error {
 		return err
 	}
 
-	return document.ScanValue(vv, &src)
+	return document.ScanValue(vv, v.v)
 }
 
 // Scanner turns a variable into a sql.Scanner.
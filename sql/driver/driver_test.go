@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji/engine"
 	"github.com/stretchr/testify/require"
@@ -98,6 +99,36 @@ func TestDriver(t *testing.T) {
 		require.Equal(t, 10, count)
 	})
 
+	t.Run("Timestamps", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Microsecond)
+
+		_, err := db.Exec("CREATE TABLE ts(t TIMESTAMP)")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO ts (t) VALUES (?)", now)
+		require.NoError(t, err)
+
+		var ts time.Time
+		err = db.QueryRow("SELECT t FROM ts").Scan(&ts)
+		require.NoError(t, err)
+		require.Equal(t, now, ts)
+
+		err = db.QueryRow("SELECT t FROM ts").Scan(Scanner(&ts))
+		require.NoError(t, err)
+		require.Equal(t, now, ts)
+	})
+
+	t.Run("Returning", func(t *testing.T) {
+		var a, pk int
+		err := db.QueryRow("INSERT INTO test (a) VALUES (?) RETURNING a, pk()", 100).Scan(&a, &pk)
+		require.NoError(t, err)
+		require.Equal(t, 100, a)
+		require.Equal(t, 11, pk)
+
+		err = db.QueryRow("DELETE FROM test WHERE a = 100 RETURNING a").Scan(&a)
+		require.NoError(t, err)
+		require.Equal(t, 100, a)
+	})
+
 	t.Run("Params", func(t *testing.T) {
 		rows, err := db.Query("SELECT a FROM test WHERE a = ?", 5)
 		require.NoError(t, err)
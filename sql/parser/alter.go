@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -33,7 +34,8 @@ func (p *Parser) parseAlterTableAddFieldStatement(tableName string) (_ query.Alt
 	}
 
 	// Parse new field definition.
-	err = p.parseFieldDefinition(&stmt.Constraint)
+	var checks []database.CheckConstraint
+	err = p.parseFieldDefinition(&stmt.Constraint, &checks)
 	if err != nil {
 		return stmt, err
 	}
@@ -42,6 +44,11 @@ func (p *Parser) parseAlterTableAddFieldStatement(tableName string) (_ query.Alt
 		return stmt, &ParseError{Message: "cannot add a PRIMARY KEY constraint"}
 	}
 
+	// existing documents would not be checked.
+	if len(checks) > 0 {
+		return stmt, &ParseError{Message: "cannot add a CHECK constraint"}
+	}
+
 	return stmt, nil
 }
 
@@ -62,6 +62,7 @@ func TestParserAlterTableAddColumn(t *testing.T) {
 			},
 		}, false},
 		{"With primary key", "ALTER TABLE foo ADD FIELD bar PRIMARY KEY", query.AlterTableAddField{}, true},
+		{"With check", "ALTER TABLE foo ADD FIELD bar CHECK (bar > 0)", query.AlterTableAddField{}, true},
 		{"With multiple constraints", "ALTER TABLE foo ADD FIELD bar integer NOT NULL DEFAULT 0", query.AlterTableAddField{TableName: "foo",
 			Constraint: database.FieldConstraint{
 				Path:         parsePath(t, "bar"),
@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseAnalyzeStatement parses an analyze statement.
+// This function assumes the ANALYZE identifier has already been consumed.
+func (p *Parser) parseAnalyzeStatement() (query.Statement, error) {
+	var stmt query.AnalyzeStmt
+
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	if tok == scanner.IDENT {
+		stmt.TableName = lit
+	} else {
+		p.Unscan()
+	}
+
+	return stmt, nil
+}
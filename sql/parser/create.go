@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -24,6 +27,19 @@ func (p *Parser) parseCreateStatement() (query.Statement, error) {
 		return p.parseCreateIndexStatement(true)
 	case scanner.INDEX:
 		return p.parseCreateIndexStatement(false)
+	case scanner.IDENT:
+		// FULLTEXT is not a keyword, to allow using it as an identifier.
+		if !strings.EqualFold(lit, "fulltext") {
+			break
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.INDEX {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"INDEX"}, pos)
+		}
+
+		stmt, err := p.parseCreateIndexStatement(false)
+		stmt.FullText = true
+		return stmt, err
 	}
 
 	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX"}, pos)
@@ -76,7 +92,9 @@ func (p *Parser) parseIfNotExists() (bool, error) {
 	return true, nil
 }
 
-func (p *Parser) parseFieldDefinition(fc *database.FieldConstraint) (err error) {
+// parseFieldDefinition parses a field, its type and its constraints.
+// Its check constraints are appended to checks.
+func (p *Parser) parseFieldDefinition(fc *database.FieldConstraint, checks *[]database.CheckConstraint) (err error) {
 	fc.Path, err = p.parsePath()
 	if err != nil {
 		return err
@@ -87,7 +105,7 @@ func (p *Parser) parseFieldDefinition(fc *database.FieldConstraint) (err error)
 		return err
 	}
 
-	return p.parseFieldConstraint(fc)
+	return p.parseFieldConstraint(fc, checks)
 }
 
 func (p *Parser) parseFieldConstraints(info *database.TableInfo) error {
@@ -101,14 +119,24 @@ func (p *Parser) parseFieldConstraints(info *database.TableInfo) error {
 
 	// Parse constraints.
 	for {
-		var fc database.FieldConstraint
+		// table constraints are listed with the fields.
+		if p.isTableConstraint() {
+			cc, err := p.parseCheckConstraint()
+			if err != nil {
+				return err
+			}
 
-		err = p.parseFieldDefinition(&fc)
-		if err != nil {
-			return err
-		}
+			info.CheckConstraints = append(info.CheckConstraints, cc)
+		} else {
+			var fc database.FieldConstraint
+
+			err = p.parseFieldDefinition(&fc, &info.CheckConstraints)
+			if err != nil {
+				return err
+			}
 
-		info.FieldConstraints = append(info.FieldConstraints, fc)
+			info.FieldConstraints = append(info.FieldConstraints, fc)
+		}
 
 		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
 			p.Unscan()
@@ -135,11 +163,52 @@ func (p *Parser) parseFieldConstraints(info *database.TableInfo) error {
 	return nil
 }
 
-func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
+// isTableConstraint reports whether the next tokens start a table constraint rather than
+// the definition of a field. CHECK and CONSTRAINT are not keywords and can be used as field
+// names: CHECK must be followed by a parenthesis and CONSTRAINT by the name of the constraint.
+func (p *Parser) isTableConstraint() bool {
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.IDENT {
+		p.Unscan()
+		return false
+	}
+
+	// read the next token, after the whitespace if any.
+	n := 2
+	next, _, _ := p.Scan()
+	if next == scanner.WS {
+		n++
+		next, _, _ = p.Scan()
+	}
+	for i := 0; i < n; i++ {
+		p.Unscan()
+	}
+
+	switch {
+	case strings.EqualFold(lit, "check"):
+		return next == scanner.LPAREN
+	case strings.EqualFold(lit, "constraint"):
+		return next == scanner.IDENT
+	}
+
+	return false
+}
+
+func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint, checks *[]database.CheckConstraint) error {
 	for {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
-		switch tok {
-		case scanner.PRIMARY:
+		switch {
+		case tok == scanner.IDENT && (strings.EqualFold(lit, "constraint") || strings.EqualFold(lit, "check")):
+			p.Unscan()
+
+			cc, err := p.parseCheckConstraint()
+			if err != nil {
+				return err
+			}
+
+			cc.Path = fc.Path
+			*checks = append(*checks, cc)
+		case tok == scanner.PRIMARY:
 			// Parse "KEY"
 			if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.KEY {
 				return newParseError(scanner.Tokstr(tok, lit), []string{"KEY"}, pos)
@@ -151,7 +220,7 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 			}
 
 			fc.IsPrimaryKey = true
-		case scanner.NOT:
+		case tok == scanner.NOT:
 			// Parse "NULL"
 			if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.NULL {
 				return newParseError(scanner.Tokstr(tok, lit), []string{"NULL"}, pos)
@@ -163,7 +232,7 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 			}
 
 			fc.IsNotNull = true
-		case scanner.DEFAULT:
+		case tok == scanner.DEFAULT:
 			// Parse default value expression.
 			e, err := p.parseUnaryExpr()
 			if err != nil {
@@ -192,8 +261,47 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 	}
 }
 
+// parseCheckConstraint parses a check constraint, optionally
+// preceded by CONSTRAINT and the name of the constraint.
+func (p *Parser) parseCheckConstraint() (database.CheckConstraint, error) {
+	var cc database.CheckConstraint
+	var err error
+
+	// Parse optional "CONSTRAINT name"
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == scanner.IDENT && strings.EqualFold(lit, "constraint") {
+		cc.Name, err = p.parseIdent()
+		if err != nil {
+			return cc, err
+		}
+	} else {
+		p.Unscan()
+	}
+
+	// Parse "CHECK"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "check") {
+		return cc, newParseError(scanner.Tokstr(tok, lit), []string{"CHECK"}, pos)
+	}
+
+	// Parse ( token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return cc, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	_, cc.Expr, err = p.parseStoredExpr()
+	if err != nil {
+		return cc, err
+	}
+
+	// Parse required ) token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return cc, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return cc, nil
+}
+
 // parseCreateIndexStatement parses a create index string and returns a Statement AST object.
-// This function assumes the CREATE INDEX or CREATE UNIQUE INDEX tokens have already been consumed.
+// This function assumes the CREATE INDEX, CREATE UNIQUE INDEX or CREATE FULLTEXT INDEX words have already been consumed.
 func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt, error) {
 	var err error
 	stmt := query.CreateIndexStmt{
@@ -217,15 +325,19 @@ func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt,
 		p.Unscan()
 	}
 
-	// Parse index name
-	stmt.IndexName, err = p.parseIdent()
-	if err != nil {
-		return stmt, err
-	}
+	// Parse index name, which is optional unless IF NOT EXISTS is used.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ON || stmt.IfNotExists {
+		p.Unscan()
+
+		stmt.IndexName, err = p.parseIdent()
+		if err != nil {
+			return stmt, err
+		}
 
-	// Parse "ON"
-	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ON {
-		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"ON"}, pos)
+		// Parse "ON"
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"ON"}, pos)
+		}
 	}
 
 	// Parse table name
@@ -234,20 +346,94 @@ func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt,
 		return stmt, err
 	}
 
-	paths, err := p.parsePathList()
+	err = p.parseIndexedList(&stmt)
 	if err != nil {
 		return stmt, err
 	}
-	if len(paths) == 0 {
-		tok, pos, lit := p.ScanIgnoreWhitespace()
-		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
-	}
 
-	if len(paths) != 1 {
-		return stmt, &ParseError{Message: "indexes on more than one path are not supported"}
+	// Parse optional "WHERE"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.WHERE {
+		p.Unscan()
+		return stmt, nil
 	}
 
-	stmt.Path = paths[0]
+	_, stmt.Where, err = p.parseIndexExpr()
+	if err != nil {
+		return stmt, err
+	}
 
 	return stmt, nil
 }
+
+// parseIndexedList parses the paths indexed by an index, or the expression
+// indexed by an expression index, surrounded by parentheses.
+// The path of a multi-valued index is followed by [*].
+func (p *Parser) parseIndexedList(stmt *query.CreateIndexStmt) error {
+	// Parse ( token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	var exprs []string
+	for {
+		e, lit, err := p.parseIndexExpr()
+		if err != nil {
+			return err
+		}
+
+		if fs, ok := e.(expr.FieldSelector); ok {
+			stmt.Paths = append(stmt.Paths, document.ValuePath(fs))
+
+			multi, err := p.parseArrayWildcard()
+			if err != nil {
+				return err
+			}
+			stmt.Multi = stmt.Multi || multi
+		} else {
+			exprs = append(exprs, lit)
+		}
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			break
+		}
+	}
+
+	// Parse required ) token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	if stmt.Multi && len(stmt.Paths)+len(exprs) > 1 {
+		return errors.New("a multi-valued index must be created on a single path")
+	}
+
+	if len(exprs) == 0 {
+		return nil
+	}
+
+	if len(exprs) > 1 || len(stmt.Paths) > 0 {
+		return errors.New("an expression index must be created on a single expression")
+	}
+
+	stmt.Expr = exprs[0]
+	return nil
+}
+
+// parseArrayWildcard parses an optional [*] and reports whether it was found.
+func (p *Parser) parseArrayWildcard() (bool, error) {
+	if tok, _, _ := p.Scan(); tok != scanner.LSBRACKET {
+		p.Unscan()
+		return false, nil
+	}
+
+	if tok, pos, lit := p.Scan(); tok != scanner.MUL {
+		return false, newParseError(scanner.Tokstr(tok, lit), []string{"*"}, pos)
+	}
+
+	if tok, pos, lit := p.Scan(); tok != scanner.RSBRACKET {
+		return false, newParseError(scanner.Tokstr(tok, lit), []string{"]"}, pos)
+	}
+
+	return true, nil
+}
@@ -19,6 +19,8 @@ func TestParserCreateTable(t *testing.T) {
 	}{
 		{"Basic", "CREATE TABLE test", query.CreateTableStmt{TableName: "test"}, false},
 		{"If not exists", "CREATE TABLE IF NOT EXISTS test", query.CreateTableStmt{TableName: "test", IfNotExists: true}, false},
+		{"Named analyze", "CREATE TABLE analyze", query.CreateTableStmt{TableName: "analyze"}, false},
+		{"Named fulltext", "CREATE TABLE fulltext", query.CreateTableStmt{TableName: "fulltext"}, false},
 		{"With primary key", "CREATE TABLE test(foo INTEGER PRIMARY KEY)",
 			query.CreateTableStmt{
 				TableName: "test",
@@ -127,6 +129,15 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, false},
+		{"With timestamp field", "CREATE TABLE test(timestamp TIMESTAMP)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "timestamp"), Type: document.TimestampValue},
+					},
+				},
+			}, false},
 		{"With integer aliases types",
 			"CREATE TABLE test(i int, ii int2, ei int8, m mediumint, s smallint, b bigint, t tinyint)",
 			query.CreateTableStmt{
@@ -179,6 +190,55 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, true},
+
+		{"With field check", "CREATE TABLE test(price DOUBLE NOT NULL CHECK (price >= 0), qty CONSTRAINT positive_qty CHECK (qty > 0))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "price"), Type: document.DoubleValue, IsNotNull: true},
+						{Path: parsePath(t, "qty")},
+					},
+					CheckConstraints: []database.CheckConstraint{
+						{Path: parsePath(t, "price"), Expr: "price >= 0"},
+						{Name: "positive_qty", Path: parsePath(t, "qty"), Expr: "qty > 0"},
+					},
+				},
+			}, false},
+		{"With table check", "CREATE TABLE test(a INTEGER, CHECK (a < b), b INTEGER, CONSTRAINT c CHECK (a + b < 10))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "a"), Type: document.IntegerValue},
+						{Path: parsePath(t, "b"), Type: document.IntegerValue},
+					},
+					CheckConstraints: []database.CheckConstraint{
+						{Expr: "a < b"},
+						{Name: "c", Expr: "a + b < 10"},
+					},
+				},
+			}, false},
+		{"With fields named check and constraint", "CREATE TABLE test(check INTEGER CHECK(check > 0), constraint, check.constraint TEXT, CHECK(check < constraint), constraint c CHECK (constraint > 0))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "check"), Type: document.IntegerValue},
+						{Path: parsePath(t, "constraint")},
+						{Path: parsePath(t, "check.constraint"), Type: document.TextValue},
+					},
+					CheckConstraints: []database.CheckConstraint{
+						{Path: parsePath(t, "check"), Expr: "check > 0"},
+						{Expr: "check < constraint"},
+						{Name: "c", Expr: "constraint > 0"},
+					},
+				},
+			}, false},
+		{"With check without parentheses", "CREATE TABLE test(a CHECK a > 0)", nil, true},
+		{"With constraint without check", "CREATE TABLE test(a CONSTRAINT c NOT NULL)", nil, true},
+		{"With check with params", "CREATE TABLE test(a CHECK (a > ?))", nil, true},
+		{"With check with subquery", "CREATE TABLE test(a CHECK (a IN (SELECT a FROM b)))", nil, true},
 	}
 
 	for _, test := range tests {
@@ -202,11 +262,32 @@ func TestParserCreateIndex(t *testing.T) {
 		expected query.Statement
 		errored  bool
 	}{
-		{"Basic", "CREATE INDEX idx ON test (foo)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo")}, false},
-		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar[1])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo.bar[1]"), IfNotExists: true}, false},
-		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[3].baz)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo[3].baz"), IfNotExists: true, Unique: true}, false},
+		{"Basic", "CREATE INDEX idx ON test (foo)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")}}, false},
+		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar[1])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo.bar[1]")}, IfNotExists: true}, false},
+		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[3].baz)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo[3].baz")}, IfNotExists: true, Unique: true}, false},
 		{"No fields", "CREATE INDEX idx ON test", nil, true},
-		{"More than 1 path", "CREATE INDEX idx ON test (foo, bar)", nil, true},
+		{"More than 1 path", "CREATE INDEX idx ON test (foo, bar[1])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo"), parsePath(t, "bar[1]")}}, false},
+		{"Partial", "CREATE INDEX idx ON test (foo) WHERE status = 'pending' AND bar > 10", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")}, Where: "status = 'pending' AND bar > 10"}, false},
+		{"Partial with params", "CREATE INDEX idx ON test (foo) WHERE bar > ?", nil, true},
+		{"Partial with subquery", "CREATE INDEX idx ON test (foo) WHERE bar IN (SELECT a FROM b)", nil, true},
+		{"Partial with now", "CREATE INDEX idx ON test (foo) WHERE at > NOW()", nil, true},
+		{"Without name", "CREATE INDEX ON test (foo)", query.CreateIndexStmt{TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo")}}, false},
+		{"If not exists without name", "CREATE INDEX IF NOT EXISTS ON test (foo)", nil, true},
+		{"Expression", "CREATE UNIQUE INDEX ON test (lower(email))", query.CreateIndexStmt{TableName: "test", Expr: "lower(email)", Unique: true}, false},
+		{"Partial expression", "CREATE INDEX idx ON test (a + b) WHERE a > 0", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Expr: "a + b", Where: "a > 0"}, false},
+		{"Expression and path", "CREATE INDEX idx ON test (lower(email), foo)", nil, true},
+		{"Expression with params", "CREATE INDEX idx ON test (a + ?)", nil, true},
+		{"Expression with now", "CREATE INDEX inow ON test (NOW())", nil, true},
+		{"Nested expression with now", "CREATE INDEX idx ON test (date_trunc('day', NOW()))", nil, true},
+		{"Multi-valued", "CREATE INDEX idx ON test (foo.tags[*])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "foo.tags")}, Multi: true}, false},
+		{"Multi-valued on array element", "CREATE INDEX idx ON test (tags[0][*])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "tags[0]")}, Multi: true}, false},
+		{"Multi-valued composite", "CREATE INDEX idx ON test (tags[*], foo)", nil, true},
+		{"Invalid wildcard", "CREATE INDEX idx ON test (tags[*)", nil, true},
+		{"Full-text", "CREATE FULLTEXT INDEX idx ON test (description)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "description")}, FullText: true}, false},
+		{"Full-text without name", "CREATE FULLTEXT INDEX ON test (a.b)", query.CreateIndexStmt{TableName: "test", Paths: []document.ValuePath{parsePath(t, "a.b")}, FullText: true}, false},
+		{"Full-text without INDEX", "CREATE FULLTEXT idx ON test (description)", nil, true},
+		{"Keyword as path", "CREATE INDEX idx ON test (returning)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Paths: []document.ValuePath{parsePath(t, "returning")}}, false},
+		{"Full-text lowercase", "create fulltext index on fulltext (fulltext)", query.CreateIndexStmt{TableName: "fulltext", Paths: []document.ValuePath{parsePath(t, "fulltext")}, FullText: true}, false},
 	}
 
 	for _, test := range tests {
@@ -24,6 +24,7 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 		pErr.Expected = []string{"table_name"}
 		return nil, pErr
 	}
+	p.addTables(cfg.TableName)
 
 	// Parse condition: "WHERE EXPR".
 	cfg.WhereExpr, err = p.parseCondition()
@@ -31,6 +32,12 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 		return nil, err
 	}
 
+	// Parse "RETURNING"
+	cfg.Returning, err = p.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg.ToTree(), nil
 }
 
@@ -38,6 +45,7 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 type deleteConfig struct {
 	TableName string
 	WhereExpr expr.Expr
+	Returning []planner.ProjectedField
 }
 
 // ToTree turns the statement into an expression tree.
@@ -50,5 +58,9 @@ func (cfg deleteConfig) ToTree() *planner.Tree {
 
 	t = planner.NewDeletionNode(t, cfg.TableName)
 
+	if cfg.Returning != nil {
+		t = planner.NewReturningNode(t, cfg.Returning, cfg.TableName)
+	}
+
 	return &planner.Tree{Root: t}
 }
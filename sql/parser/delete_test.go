@@ -25,6 +25,24 @@ func TestParserDelete(t *testing.T) {
 					planner.NewTableInputNode("test"),
 					expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10))),
 				"test"))},
+		{"Returning", "DELETE FROM test WHERE age = 10 RETURNING *, pk() AS k",
+			planner.NewTree(planner.NewReturningNode(
+				planner.NewDeletionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("test"),
+						expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10))),
+					"test"),
+				[]planner.ProjectedField{planner.Wildcard{}, planner.ProjectedExpr{Expr: &expr.PKFunc{}, ExprName: "k"}},
+				"test"))},
+		{"Returning field named returning", "DELETE FROM test WHERE returning = 10 returning returning",
+			planner.NewTree(planner.NewReturningNode(
+				planner.NewDeletionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("test"),
+						expr.Eq(expr.FieldSelector(parsePath(t, "returning")), expr.IntegerValue(10))),
+					"test"),
+				[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "returning")), ExprName: "returning"}},
+				"test"))},
 	}
 
 	for _, test := range tests {
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/scanner"
@@ -8,7 +10,15 @@ import (
 
 // parseExplainStatement parses any statement and returns an ExplainStmt object.
 // This function assumes the EXPLAIN token has already been consumed.
+// If it is followed by the ANALYZE keyword, the statement will be executed.
 func (p *Parser) parseExplainStatement() (query.Statement, error) {
+	var analyze bool
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == scanner.IDENT && strings.EqualFold(lit, "analyze") {
+		analyze = true
+	} else {
+		p.Unscan()
+	}
+
 	// ensure we don't have multiple EXPLAIN keywords
 	tok, pos, lit := p.ScanIgnoreWhitespace()
 	if tok == scanner.EXPLAIN {
@@ -21,5 +31,5 @@ func (p *Parser) parseExplainStatement() (query.Statement, error) {
 		return nil, err
 	}
 
-	return &planner.ExplainStmt{Statement: innerStmt}, nil
+	return &planner.ExplainStmt{Statement: innerStmt, Analyze: analyze}, nil
 }
@@ -17,7 +17,9 @@ func TestParserExplain(t *testing.T) {
 		errored  bool
 	}{
 		{"Explain create table", "EXPLAIN CREATE TABLE test", &planner.ExplainStmt{Statement: query.CreateTableStmt{TableName: "test"}}, false},
+		{"Explain analyze", "EXPLAIN ANALYZE CREATE TABLE test", &planner.ExplainStmt{Statement: query.CreateTableStmt{TableName: "test"}, Analyze: true}, false},
 		{"Multiple Explains", "EXPLAIN EXPLAIN CREATE TABLE test", nil, true},
+		{"Multiple Explains with analyze", "EXPLAIN ANALYZE EXPLAIN CREATE TABLE test", nil, true},
 	}
 
 	for _, test := range tests {
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -61,6 +62,11 @@ func (p *Parser) ParseExpr() (e expr.Expr, lit string, err error) {
 			return nil, "", err
 		}
 
+		// the IN operator compares the left operand with every value returned by a subquery.
+		if sq, ok := rhs.(*planner.Subquery); ok && (tok == scanner.IN || tok == scanner.NOT) {
+			sq.Kind = planner.ArraySubquery
+		}
+
 		// Find the right spot in the tree to add the new expression by
 		// descending the RHS of the expression tree until we reach the last
 		// BinaryExpr or a BinaryExpr whose RHS has an operator with
@@ -84,12 +90,6 @@ func (p *Parser) parseOperator() (func(lhs, rhs expr.Expr) expr.Expr, scanner.To
 		return nil, 0, nil
 	}
 
-	// Ignore currently unused operators.
-	if op == scanner.EQREGEX || op == scanner.NEQREGEX {
-		p.Unscan()
-		return nil, 0, nil
-	}
-
 	switch op {
 	case scanner.EQ:
 		return expr.Eq, op, nil
@@ -103,6 +103,10 @@ func (p *Parser) parseOperator() (func(lhs, rhs expr.Expr) expr.Expr, scanner.To
 		return expr.Lt, op, nil
 	case scanner.LTE:
 		return expr.Lte, op, nil
+	case scanner.EQREGEX:
+		return expr.EqRegex, op, nil
+	case scanner.NEQREGEX:
+		return expr.NeqRegex, op, nil
 	case scanner.AND:
 		return expr.And, op, nil
 	case scanner.OR:
@@ -161,6 +165,9 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
+		if len(field) > 1 {
+			p.addQualifier(field[0].FieldName)
+		}
 		fs := expr.FieldSelector(field)
 		return fs, nil
 	case scanner.NAMEDPARAM:
@@ -207,7 +214,26 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 	case scanner.LSBRACKET:
 		p.Unscan()
 		return p.parseExprList(scanner.LSBRACKET, scanner.RSBRACKET)
+	case scanner.EXISTS:
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+		}
+		return p.parseSubquery(planner.ExistsSubquery)
+	case scanner.NOT:
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EXISTS {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"EXISTS"}, pos)
+		}
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+		}
+		return p.parseSubquery(planner.NotExistsSubquery)
 	case scanner.LPAREN:
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.SELECT {
+			p.Unscan()
+			return p.parseSubquery(planner.ScalarSubquery)
+		}
+		p.Unscan()
+
 		e, _, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
@@ -293,7 +319,7 @@ func (p *Parser) parseParam() (expr.Expr, error) {
 }
 
 func (p *Parser) parseType() (document.ValueType, error) {
-	tok, _, _ := p.ScanIgnoreWhitespace()
+	tok, _, lit := p.ScanIgnoreWhitespace()
 	switch tok {
 	case scanner.TYPEARRAY:
 		return document.ArrayValue, nil
@@ -319,6 +345,11 @@ func (p *Parser) parseType() (document.ValueType, error) {
 		return document.IntegerValue, nil
 	case scanner.TYPETEXT:
 		return document.TextValue, nil
+	case scanner.IDENT:
+		// TIMESTAMP is not a keyword, to allow using it as a field name.
+		if strings.EqualFold(lit, "timestamp") {
+			return document.TimestampValue, nil
+		}
 	case scanner.TYPEVARCHAR, scanner.TYPECHARACTER:
 		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
 			return 0, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
@@ -433,6 +464,13 @@ LOOP:
 		case scanner.LSBRACKET:
 			// scan the next token for an integer
 			tok, pos, lit := p.Scan()
+			// [*] is not part of the path, it selects every element
+			// of the array in the definition of multi-valued indexes.
+			if tok == scanner.MUL {
+				p.Unscan()
+				p.Unscan()
+				break LOOP
+			}
 			if tok != scanner.INTEGER || lit[0] == '-' {
 				return nil, newParseError(lit, []string{"array index"}, pos)
 			}
@@ -521,7 +559,11 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 
 	// Check if the function is called without arguments.
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.RPAREN {
-		return p.functions.GetFunc(fname)
+		e, err := p.functions.GetFunc(fname)
+		if _, ok := e.(expr.NowFunc); ok {
+			p.nowCalls++
+		}
+		return e, err
 	}
 	p.Unscan()
 
@@ -592,3 +634,190 @@ func (p *Parser) parseCastExpression() (expr.Expr, error) {
 
 	return expr.CastFunc{Expr: e, CastAs: tp}, nil
 }
+
+// parseSubquery parses a SELECT statement followed by a closing parenthesis.
+// This function assumes the opening parenthesis has already been consumed.
+func (p *Parser) parseSubquery(kind planner.SubqueryKind) (expr.Expr, error) {
+	// the literal representation of the subquery is stored in its own buffer
+	// and the expressions of the subquery use their own buffers as well.
+	buf := p.buf
+	p.buf = nil
+	sbuf := new(bytes.Buffer)
+	p.subqueryBufs = append(p.subqueryBufs, sbuf)
+	p.pushScope()
+
+	t, err := p.parseSubqueryStatement()
+
+	s := p.popScope()
+	p.subqueryBufs = p.subqueryBufs[:len(p.subqueryBufs)-1]
+	p.buf = buf
+	if p.buf != nil {
+		p.buf.Write(sbuf.Bytes())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse required ) token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	t.Subqueries = s.allSubqueries()
+	sq := planner.Subquery{
+		Tree: t,
+		Kind: kind,
+		Text: strings.TrimSpace(sbuf.String()),
+	}
+
+	// paths qualified by the name of a table the subquery doesn't read
+	// may reference the documents of an enclosing statement.
+	qualifiers := make(map[string]struct{})
+	for q := range s.qualifiers {
+		if !s.hasTable(q) {
+			qualifiers[q] = struct{}{}
+		}
+	}
+
+	if cur := p.currentScope(); cur != nil {
+		cur.subqueries = append(cur.subqueries, &sq)
+		cur.pending = append(cur.pending, pendingSubquery{sq: &sq, qualifiers: qualifiers})
+		for q := range qualifiers {
+			cur.addQualifier(q)
+		}
+	}
+
+	return &sq, nil
+}
+
+func (p *Parser) parseSubqueryStatement() (*planner.Tree, error) {
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	return p.parseSelectStatement()
+}
+
+// A scope keeps track of the tables read by a statement and of the qualified paths
+// used by its expressions, to determine which subqueries reference the documents
+// of an enclosing statement.
+type scope struct {
+	tables []string
+	// first field of every path composed of more than one field.
+	qualifiers map[string]struct{}
+	// subqueries used by the expressions of the statement.
+	subqueries []*planner.Subquery
+	// subqueries used by the select statements combined with the statement.
+	combined []*planner.Subquery
+	// subqueries, used by the statement or by one of its subqueries, whose
+	// correlation is not known yet.
+	pending []pendingSubquery
+}
+
+type pendingSubquery struct {
+	sq         *planner.Subquery
+	qualifiers map[string]struct{}
+}
+
+func (s *scope) addQualifier(q string) {
+	if s.qualifiers == nil {
+		s.qualifiers = make(map[string]struct{})
+	}
+
+	s.qualifiers[q] = struct{}{}
+}
+
+// allSubqueries returns the subqueries of the statement and of the statements
+// combined with it.
+func (s *scope) allSubqueries() []*planner.Subquery {
+	return append(s.subqueries, s.combined...)
+}
+
+func (s *scope) hasTable(name string) bool {
+	for _, t := range s.tables {
+		if t == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Parser) pushScope() {
+	p.scopes = append(p.scopes, new(scope))
+}
+
+// popScope removes the innermost scope. Pending subqueries referencing one
+// of its tables are marked as correlated, the others are passed to the enclosing scope.
+func (p *Parser) popScope() *scope {
+	s := p.scopes[len(p.scopes)-1]
+	p.scopes = p.scopes[:len(p.scopes)-1]
+
+	if len(s.tables) == 1 {
+		for _, sq := range s.subqueries {
+			sq.OuterTable = s.tables[0]
+		}
+	}
+
+	var pending []pendingSubquery
+	for _, ps := range s.pending {
+		var correlated bool
+		for q := range ps.qualifiers {
+			if s.hasTable(q) {
+				correlated = true
+				break
+			}
+		}
+
+		if correlated {
+			ps.sq.Correlated = true
+		} else {
+			pending = append(pending, ps)
+		}
+	}
+
+	if cur := p.currentScope(); cur != nil {
+		cur.pending = append(cur.pending, pending...)
+	}
+
+	return s
+}
+
+// popCompoundScope removes the scope of a select statement combined with
+// the statement of the enclosing scope. Its subqueries belong to the enclosing
+// statement and the paths it qualifies by the name of a table it doesn't read
+// may reference the documents of an enclosing statement.
+func (p *Parser) popCompoundScope() {
+	s := p.popScope()
+
+	if cur := p.currentScope(); cur != nil {
+		cur.combined = append(cur.combined, s.allSubqueries()...)
+		for q := range s.qualifiers {
+			if !s.hasTable(q) {
+				cur.addQualifier(q)
+			}
+		}
+	}
+}
+
+func (p *Parser) currentScope() *scope {
+	if len(p.scopes) == 0 {
+		return nil
+	}
+
+	return p.scopes[len(p.scopes)-1]
+}
+
+// addTables registers the tables read by the statement being parsed.
+func (p *Parser) addTables(names ...string) {
+	if cur := p.currentScope(); cur != nil {
+		cur.tables = append(cur.tables, names...)
+	}
+}
+
+// addQualifier registers the first field of a path used by the statement being parsed.
+func (p *Parser) addQualifier(q string) {
+	if cur := p.currentScope(); cur != nil {
+		cur.addQualifier(q)
+	}
+}
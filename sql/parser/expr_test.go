@@ -121,6 +121,8 @@ func TestParserExpr(t *testing.T) {
 		{">=", "age >= 10", expr.Gte(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
 		{"<", "age < 10", expr.Lt(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
 		{"<=", "age <= 10", expr.Lte(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
+		{"=~", "name =~ '^a'", expr.EqRegex(expr.FieldSelector(parsePath(t, "name")), expr.TextValue("^a")), false},
+		{"!~", "name !~ ?", expr.NeqRegex(expr.FieldSelector(parsePath(t, "name")), expr.PositionalParam(1)), false},
 		{"+", "age + 10", expr.Add(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
 		{"-", "age - 10", expr.Sub(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
 		{"*", "age * 10", expr.Mul(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
@@ -160,6 +162,7 @@ func TestParserExpr(t *testing.T) {
 		{"count(expr) function", "count(a)", &expr.CountFunc{Expr: expr.FieldSelector(parsePath(t, "a"))}, false},
 		{"count(*) function", "count(*)", &expr.CountFunc{Wildcard: true}, false},
 		{"CAST", "CAST(a.b[1][0] AS TEXT)", expr.CastFunc{Expr: expr.FieldSelector(parsePath(t, "a.b[1][0]")), CastAs: document.TextValue}, false},
+		{"CAST AS TIMESTAMP", "CAST(timestamp AS TIMESTAMP)", expr.CastFunc{Expr: expr.FieldSelector(parsePath(t, "timestamp")), CastAs: document.TimestampValue}, false},
 	}
 
 	for _, test := range tests {
@@ -2,15 +2,18 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
 
 // parseInsertStatement parses an insert string and returns a Statement AST object.
+// If the statement has a RETURNING clause, it returns a tree that projects the inserted documents.
 // This function assumes the INSERT token has already been consumed.
-func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
+func (p *Parser) parseInsertStatement() (query.Statement, error) {
 	var stmt query.InsertStmt
 	var err error
 
@@ -59,9 +62,86 @@ func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
 	}
 
 	stmt.Values = values
+
+	// Parse optional ON CONFLICT clause
+	stmt.OnConflict, err = p.parseOnConflictClause()
+	if err != nil {
+		return stmt, err
+	}
+
+	// Parse "RETURNING"
+	returning, err := p.parseReturning()
+	if err != nil {
+		return stmt, err
+	}
+	if returning != nil {
+		return planner.NewTree(planner.NewReturningNode(planner.NewInsertionNode(stmt), returning, stmt.TableName)), nil
+	}
+
 	return stmt, nil
 }
 
+// parseOnConflictClause parses the "ON CONFLICT" clause of the query, if it exists:
+//
+//	ON CONFLICT [(path, ...)] DO NOTHING
+//	ON CONFLICT [(path, ...)] DO UPDATE SET path = expr, ... [WHERE expr]
+func (p *Parser) parseOnConflictClause() (*query.OnConflictClause, error) {
+	// Parse "ON"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+		p.Unscan()
+		return nil, nil
+	}
+
+	// Parse "CONFLICT". CONFLICT, DO and NOTHING are not keywords,
+	// they can be used as identifiers.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "conflict") {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"CONFLICT"}, pos)
+	}
+
+	var clause query.OnConflictClause
+	var err error
+
+	// Parse optional path list: (a, b.c)
+	clause.Paths, err = p.parsePathList()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse "DO"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "do") {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"DO"}, pos)
+	}
+
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.IDENT && strings.EqualFold(lit, "nothing"):
+		clause.DoNothing = true
+		return &clause, nil
+	case tok == scanner.UPDATE:
+	default:
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"NOTHING", "UPDATE"}, pos)
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SET {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SET"}, pos)
+	}
+
+	pairs, err := p.parseSetClause()
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		clause.SetPairs = append(clause.SetPairs, query.SetPair{Path: pair.path, Expr: pair.e})
+	}
+
+	clause.WhereExpr, err = p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	return &clause, nil
+}
+
 // parseFieldList parses a list of fields in the form: (path, path, ...), if exists
 func (p *Parser) parseFieldList() ([]string, bool, error) {
 	// Parse ( token.
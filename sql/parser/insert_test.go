@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
@@ -61,6 +63,30 @@ func TestParserInsert(t *testing.T) {
 					expr.LiteralExprList{expr.TextValue("c"), expr.TextValue("d")},
 				},
 			}, false},
+		{"Values / With timestamp field", "INSERT INTO test (timestamp, a) VALUES ('c', 'd')",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"timestamp", "a"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.TextValue("c"), expr.TextValue("d")},
+				},
+			}, false},
+		{"Values / With join keywords as fields", "INSERT INTO test (left, inner, outer) VALUES (1, 2, 3)",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"left", "inner", "outer"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1), expr.IntegerValue(2), expr.IntegerValue(3)},
+				},
+			}, false},
+		{"Values / With all field", "INSERT INTO test (all) VALUES (1)",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"all"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1)},
+				},
+			}, false},
 		{"Values / With too many values", "INSERT INTO test (a, b) VALUES ('c', 'd', 'e')",
 			nil, true},
 		{"Values / Multiple", "INSERT INTO test (a, b) VALUES ('c', 'd'), ('e', 'f')",
@@ -76,6 +102,114 @@ func TestParserInsert(t *testing.T) {
 			nil, true},
 		{"Values / Without fields / Wrong values", "INSERT INTO test VALUES {a: 1}, ('e', 'f')",
 			nil, true},
+		{"On conflict / Do nothing", "INSERT INTO test (a) VALUES (1) ON CONFLICT DO NOTHING",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"a"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1)},
+				},
+				OnConflict: &query.OnConflictClause{DoNothing: true},
+			}, false},
+		{"On conflict / With paths", "INSERT INTO test (a) VALUES (1) ON CONFLICT (a, b.c) DO NOTHING",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"a"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1)},
+				},
+				OnConflict: &query.OnConflictClause{
+					Paths:     []document.ValuePath{parsePath(t, "a"), parsePath(t, "b.c")},
+					DoNothing: true,
+				},
+			}, false},
+		{"On conflict / Do update", "INSERT INTO test VALUES {a: 1} ON CONFLICT (a) DO UPDATE SET b = excluded.b, c = 2 WHERE c > 1",
+			query.InsertStmt{
+				TableName: "test",
+				Values: expr.LiteralExprList{
+					expr.KVPairs{expr.KVPair{K: "a", V: expr.IntegerValue(1)}},
+				},
+				OnConflict: &query.OnConflictClause{
+					Paths: []document.ValuePath{parsePath(t, "a")},
+					SetPairs: []query.SetPair{
+						{Path: parsePath(t, "b"), Expr: expr.FieldSelector(parsePath(t, "excluded.b"))},
+						{Path: parsePath(t, "c"), Expr: expr.IntegerValue(2)},
+					},
+					WhereExpr: expr.Gt(expr.FieldSelector(parsePath(t, "c")), expr.IntegerValue(1)),
+				},
+			}, false},
+		{"On conflict / Keywords as fields", "INSERT INTO test (conflict, do, nothing) VALUES (1, 2, 3) ON conflict (conflict) do nothing",
+			query.InsertStmt{
+				TableName:  "test",
+				FieldNames: []string{"conflict", "do", "nothing"},
+				Values: expr.LiteralExprList{
+					expr.LiteralExprList{expr.IntegerValue(1), expr.IntegerValue(2), expr.IntegerValue(3)},
+				},
+				OnConflict: &query.OnConflictClause{
+					Paths:     []document.ValuePath{parsePath(t, "conflict")},
+					DoNothing: true,
+				},
+			}, false},
+		{"On conflict / Do update keywords", "INSERT INTO test VALUES {do: 1} ON CONFLICT (do) DO UPDATE SET nothing = excluded.nothing",
+			query.InsertStmt{
+				TableName: "test",
+				Values: expr.LiteralExprList{
+					expr.KVPairs{expr.KVPair{K: "do", V: expr.IntegerValue(1)}},
+				},
+				OnConflict: &query.OnConflictClause{
+					Paths: []document.ValuePath{parsePath(t, "do")},
+					SetPairs: []query.SetPair{
+						{Path: parsePath(t, "nothing"), Expr: expr.FieldSelector(parsePath(t, "excluded.nothing"))},
+					},
+				},
+			}, false},
+		{"Returning", "INSERT INTO test (a) VALUES (1) RETURNING *",
+			planner.NewTree(planner.NewReturningNode(
+				planner.NewInsertionNode(query.InsertStmt{
+					TableName:  "test",
+					FieldNames: []string{"a"},
+					Values: expr.LiteralExprList{
+						expr.LiteralExprList{expr.IntegerValue(1)},
+					},
+				}),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				"test",
+			)), false},
+		{"Returning / On conflict", "INSERT INTO test (a) VALUES (1) ON CONFLICT DO NOTHING RETURNING a",
+			planner.NewTree(planner.NewReturningNode(
+				planner.NewInsertionNode(query.InsertStmt{
+					TableName:  "test",
+					FieldNames: []string{"a"},
+					Values: expr.LiteralExprList{
+						expr.LiteralExprList{expr.IntegerValue(1)},
+					},
+					OnConflict: &query.OnConflictClause{DoNothing: true},
+				}),
+				[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+				"test",
+			)), false},
+		{"Returning / Field named returning", "INSERT INTO test (returning) VALUES (1) RETURNING returning",
+			planner.NewTree(planner.NewReturningNode(
+				planner.NewInsertionNode(query.InsertStmt{
+					TableName:  "test",
+					FieldNames: []string{"returning"},
+					Values: expr.LiteralExprList{
+						expr.LiteralExprList{expr.IntegerValue(1)},
+					},
+				}),
+				[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "returning")), ExprName: "returning"}},
+				"test",
+			)), false},
+		{"Returning / Missing fields", "INSERT INTO test (a) VALUES (1) RETURNING",
+			nil, true},
+		{"On conflict / Missing action", "INSERT INTO test (a) VALUES (1) ON CONFLICT",
+			nil, true},
+		{"On conflict / Do update without set", "INSERT INTO test (a) VALUES (1) ON CONFLICT DO UPDATE",
+			nil, true},
+		{"On conflict / Unknown action", "INSERT INTO test (a) VALUES (1) ON CONFLICT DO something",
+			nil, true},
+		{"On conflict / Unclosed paths", "INSERT INTO test (a) VALUES (1) ON CONFLICT (a DO NOTHING",
+			nil, true},
 	}
 
 	for _, test := range tests {
@@ -1,6 +1,9 @@
 package parser
 
-import "github.com/genjidb/genji/sql/query/expr"
+import (
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+)
 
 // Options of the SQL parser.
 type Options struct {
@@ -9,7 +12,11 @@ type Options struct {
 }
 
 func defaultOptions() *Options {
+	functions := expr.NewFunctions()
+	// full-text queries are parsed by the index package, which expr doesn't depend on.
+	functions.AddFunc("match", planner.NewMatchFunc)
+
 	return &Options{
-		Functions: expr.NewFunctions(),
+		Functions: functions,
 	}
 }
@@ -7,7 +7,9 @@ import (
 	"io"
 	"strings"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -18,8 +20,13 @@ type Parser struct {
 	s             *scanner.BufScanner
 	orderedParams int
 	namedParams   int
+	nowCalls      int // number of calls to NOW() parsed so far
 	buf           *bytes.Buffer
 	functions     expr.Functions
+	// literal representation of the subqueries being parsed, the innermost last.
+	subqueryBufs []*bytes.Buffer
+	// scopes of the statements being parsed, the innermost last.
+	scopes []*scope
 }
 
 // NewParser returns a new instance of Parser.
@@ -46,6 +53,67 @@ func ParsePath(s string) (document.ValuePath, error) {
 	return NewParser(strings.NewReader(s)).parsePath()
 }
 
+// ParseStoredExpr parses an expression stored in the catalog of a database,
+// such as the predicate of a partial index. It is meant to be used as the
+// ParseExpr option of the database.
+func ParseStoredExpr(s string) (database.Expr, error) {
+	p := NewParser(strings.NewReader(s))
+
+	e, _, err := p.parseStoredExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EOF {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"EOF"}, pos)
+	}
+
+	return expr.StoredExpr{E: e}, nil
+}
+
+// parseStoredExpr parses an expression stored in the catalog. Such expressions
+// are evaluated against a single document, outside of any statement, and thus
+// can't use parameters or subqueries.
+func (p *Parser) parseStoredExpr() (expr.Expr, string, error) {
+	params := p.orderedParams + p.namedParams
+
+	p.pushScope()
+	e, lit, err := p.ParseExpr()
+	s := p.popScope()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if p.orderedParams+p.namedParams != params {
+		return nil, "", fmt.Errorf("%s: parameters are not allowed in this expression", lit)
+	}
+
+	if len(s.allSubqueries()) > 0 {
+		return nil, "", fmt.Errorf("%s: subqueries are not allowed in this expression", lit)
+	}
+
+	return e, lit, nil
+}
+
+// parseIndexExpr parses an expression stored in the catalog and evaluated
+// when a document is indexed, such as an indexed expression or the predicate
+// of a partial index. Its result must not change between the indexation of
+// a document and its removal, thus it can't call non-deterministic functions.
+func (p *Parser) parseIndexExpr() (expr.Expr, string, error) {
+	calls := p.nowCalls
+
+	e, lit, err := p.parseStoredExpr()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if p.nowCalls != calls {
+		return nil, "", fmt.Errorf("%s: non-deterministic functions are not allowed in this expression", lit)
+	}
+
+	return e, lit, nil
+}
+
 // ParseQuery parses a Genji SQL string and returns a Query.
 func (p *Parser) ParseQuery(ctx context.Context) (query.Query, error) {
 	var statements []query.Statement
@@ -79,6 +147,21 @@ func (p *Parser) ParseQuery(ctx context.Context) (query.Query, error) {
 
 // ParseStatement parses a Genji SQL string and returns a Statement AST object.
 func (p *Parser) ParseStatement() (query.Statement, error) {
+	p.pushScope()
+	stmt, err := p.parseStatement()
+	s := p.popScope()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := stmt.(*planner.Tree); ok {
+		t.Subqueries = s.allSubqueries()
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseStatement() (query.Statement, error) {
 	tok, pos, lit := p.ScanIgnoreWhitespace()
 	switch tok {
 	case scanner.ALTER:
@@ -105,10 +188,15 @@ func (p *Parser) ParseStatement() (query.Statement, error) {
 		return p.parseReIndexStatement()
 	case scanner.ROLLBACK:
 		return p.parseRollbackStatement()
+	case scanner.IDENT:
+		// ANALYZE is not a keyword, to allow using it as an identifier.
+		if strings.EqualFold(lit, "analyze") {
+			return p.parseAnalyzeStatement()
+		}
 	}
 
 	return nil, newParseError(scanner.Tokstr(tok, lit), []string{
-		"ALTER", "BEGIN", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "ROLLBACK",
+		"ALTER", "ANALYZE", "BEGIN", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "ROLLBACK",
 	}, pos)
 }
 
@@ -129,6 +217,18 @@ func (p *Parser) parseCondition() (expr.Expr, error) {
 	return expr, nil
 }
 
+// parseReturning parses the "RETURNING" clause of the query, if it exists.
+func (p *Parser) parseReturning() ([]planner.ProjectedField, error) {
+	// Check if the RETURNING token exists.
+	// RETURNING is not a keyword, it can be used as an identifier.
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "returning") {
+		p.Unscan()
+		return nil, nil
+	}
+
+	return p.parseResultFields()
+}
+
 // parsePathList parses a list of paths in the form: (path, path, ...), if exists
 func (p *Parser) parsePathList() ([]document.ValuePath, error) {
 	// Parse ( token.
@@ -176,6 +276,9 @@ func (p *Parser) Scan() (tok scanner.Token, pos scanner.Pos, lit string) {
 	if p.buf != nil {
 		p.buf.WriteString(ti.Raw)
 	}
+	for _, b := range p.subqueryBufs {
+		b.WriteString(ti.Raw)
+	}
 
 	tok, pos, lit = ti.Tok, ti.Pos, ti.Lit
 	return
@@ -194,10 +297,13 @@ func (p *Parser) ScanIgnoreWhitespace() (tok scanner.Token, pos scanner.Pos, lit
 
 // Unscan pushes the previously read token back onto the buffer.
 func (p *Parser) Unscan() {
+	ti := p.s.Curr()
 	if p.buf != nil {
-		ti := p.s.Curr()
 		p.buf.Truncate(p.buf.Len() - len(ti.Raw))
 	}
+	for _, b := range p.subqueryBufs {
+		b.Truncate(b.Len() - len(ti.Raw))
+	}
 	p.s.Unscan()
 }
 
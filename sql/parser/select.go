@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -11,56 +12,157 @@ import (
 // parseSelectStatement parses a select string and returns a Statement AST object.
 // This function assumes the SELECT token has already been consumed.
 func (p *Parser) parseSelectStatement() (*planner.Tree, error) {
+	cfg, err := p.parseSelectCore()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse compound operators: "UNION [ALL] | INTERSECT | EXCEPT SELECT ..."
+	cfg.Compounds, err = p.parseCompounds(cfg.ProjectionExprs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse order by: "ORDER BY expr [ASC|DESC]? [NULLS FIRST|NULLS LAST]?, ..."
+	cfg.OrderBy, err = p.parseOrderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse limit: "LIMIT expr"
+	cfg.LimitExpr, err = p.parseLimit()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse offset: "OFFSET expr"
+	cfg.OffsetExpr, err = p.parseOffset()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.ToTree()
+}
+
+// parseSelectCore parses the part of a select statement that can be combined
+// with other select statements, from the result fields to the GROUP BY clause.
+// This function assumes the SELECT token has already been consumed.
+func (p *Parser) parseSelectCore() (selectConfig, error) {
 	var cfg selectConfig
 	var err error
 
+	// Parse optional DISTINCT
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.DISTINCT {
+		cfg.Distinct = true
+	} else {
+		p.Unscan()
+	}
+
 	// Parse path list or query.Wildcard
 	cfg.ProjectionExprs, err = p.parseResultFields()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
 	// Parse "FROM".
 	var found bool
 	cfg.TableName, found, err = p.parseFrom()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 	if !found {
-		return cfg.ToTree()
+		return cfg, nil
 	}
+	p.addTables(cfg.TableName)
 
-	// Parse condition: "WHERE expr".
-	cfg.WhereExpr, err = p.parseCondition()
+	// Parse joins: "[INNER | LEFT [OUTER]] JOIN table_name ON expr"
+	cfg.Joins, err = p.parseJoins()
 	if err != nil {
-		return nil, err
+		return cfg, err
+	}
+	for _, jc := range cfg.Joins {
+		p.addTables(jc.TableName)
 	}
 
-	// Parse group by: "GROUP BY expr"
-	cfg.GroupByExpr, err = p.parseGroupBy()
+	// Parse condition: "WHERE expr".
+	cfg.WhereExpr, err = p.parseCondition()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
-	// Parse order by: "ORDER BY path [ASC|DESC]?"
-	cfg.OrderBy, cfg.OrderByDirection, err = p.parseOrderBy()
+	// Parse group by: "GROUP BY expr [, expr]*"
+	cfg.GroupByExprs, err = p.parseGroupBy()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
-	// Parse limit: "LIMIT expr"
-	cfg.LimitExpr, err = p.parseLimit()
-	if err != nil {
-		return nil, err
+	// Parse having: "HAVING expr"
+	cfg.HavingExpr, err = p.parseHaving()
+	return cfg, err
+}
+
+// parseCompounds parses the list of select statements combined with the first one
+// using UNION [ALL], INTERSECT or EXCEPT. They are evaluated from left to right.
+// Every statement must return the same number of fields, unless one of them uses a wildcard.
+func (p *Parser) parseCompounds(fields []planner.ProjectedField) ([]compoundClause, error) {
+	var compounds []compoundClause
+
+	width := resultWidth(fields)
+	for {
+		cc := compoundClause{}
+
+		tok, _, _ := p.ScanIgnoreWhitespace()
+		switch tok {
+		case scanner.UNION:
+			cc.Kind = tok
+			// ALL is not a keyword, it can be used as an identifier.
+			if tok, _, lit := p.ScanIgnoreWhitespace(); tok == scanner.IDENT && strings.EqualFold(lit, "all") {
+				cc.All = true
+			} else {
+				p.Unscan()
+			}
+		case scanner.INTERSECT, scanner.EXCEPT:
+			cc.Kind = tok
+		default:
+			p.Unscan()
+			return compounds, nil
+		}
+
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		if tok != scanner.SELECT {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+		}
+
+		// each statement reads its own tables.
+		p.pushScope()
+		var err error
+		cc.Select, err = p.parseSelectCore()
+		p.popCompoundScope()
+		if err != nil {
+			return nil, err
+		}
+
+		w := resultWidth(cc.Select.ProjectionExprs)
+		if width != -1 && w != -1 && w != width {
+			return nil, &ParseError{Message: fmt.Sprintf("SELECTs combined by %s must have the same number of result fields", cc.Kind)}
+		}
+		if width == -1 {
+			width = w
+		}
+
+		compounds = append(compounds, cc)
 	}
+}
 
-	// Parse offset: "OFFSET expr"
-	cfg.OffsetExpr, err = p.parseOffset()
-	if err != nil {
-		return nil, err
+// resultWidth returns the number of result fields or -1 if it contains a wildcard.
+func resultWidth(fields []planner.ProjectedField) int {
+	for _, f := range fields {
+		if _, ok := f.(planner.Wildcard); ok {
+			return -1
+		}
 	}
 
-	return cfg.ToTree()
+	return len(fields)
 }
 
 // parseResultFields parses the list of result fields.
@@ -139,7 +241,61 @@ func (p *Parser) parseFrom() (string, bool, error) {
 	return ident, true, nil
 }
 
-func (p *Parser) parseGroupBy() (expr.Expr, error) {
+// parseJoins parses the list of join clauses following the FROM clause, if any.
+func (p *Parser) parseJoins() ([]joinClause, error) {
+	var joins []joinClause
+
+	for {
+		var jc joinClause
+
+		// INNER, LEFT and OUTER are not keywords, they can be used as identifiers.
+		tok, _, lit := p.ScanIgnoreWhitespace()
+		switch {
+		case tok == scanner.JOIN:
+		case tok == scanner.IDENT && strings.EqualFold(lit, "inner"):
+			if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.JOIN {
+				return nil, newParseError(scanner.Tokstr(tok, lit), []string{"JOIN"}, pos)
+			}
+		case tok == scanner.IDENT && strings.EqualFold(lit, "left"):
+			jc.Kind = planner.LeftJoin
+
+			// OUTER is optional
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok == scanner.IDENT && strings.EqualFold(lit, "outer") {
+				tok, pos, lit = p.ScanIgnoreWhitespace()
+			}
+			if tok != scanner.JOIN {
+				return nil, newParseError(scanner.Tokstr(tok, lit), []string{"JOIN"}, pos)
+			}
+		default:
+			p.Unscan()
+			return joins, nil
+		}
+
+		// Parse table name
+		var err error
+		jc.TableName, err = p.parseIdent()
+		if err != nil {
+			pErr := err.(*ParseError)
+			pErr.Expected = []string{"table_name"}
+			return nil, pErr
+		}
+
+		// Parse "ON"
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"ON"}, pos)
+		}
+
+		jc.On, _, err = p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		joins = append(joins, jc)
+	}
+}
+
+func (p *Parser) parseGroupBy() ([]expr.Expr, error) {
 	// parse GROUP token
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.GROUP {
 		p.Unscan()
@@ -151,36 +307,98 @@ func (p *Parser) parseGroupBy() (expr.Expr, error) {
 		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
 	}
 
-	// parse expr
+	// parse expressions
+	var exprs []expr.Expr
+	for {
+		e, _, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, e)
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			return exprs, nil
+		}
+	}
+}
+
+func (p *Parser) parseHaving() (expr.Expr, error) {
+	// parse HAVING token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.HAVING {
+		p.Unscan()
+		return nil, nil
+	}
+
 	e, _, err := p.ParseExpr()
 	return e, err
 }
 
-func (p *Parser) parseOrderBy() (expr.FieldSelector, scanner.Token, error) {
+func (p *Parser) parseOrderBy() ([]planner.SortKey, error) {
 	// parse ORDER token
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ORDER {
 		p.Unscan()
-		return nil, 0, nil
+		return nil, nil
 	}
 
 	// parse BY token
 	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.BY {
-		return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
 	}
 
-	// parse path
-	ref, err := p.parsePath()
+	var keys []planner.SortKey
+	for {
+		k, err := p.parseSortKey()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			return keys, nil
+		}
+	}
+}
+
+// parseSortKey parses a sort key of the form "expr [ASC|DESC] [NULLS FIRST|NULLS LAST]".
+// NULLS, FIRST and LAST are not keywords, to allow using them as field names.
+func (p *Parser) parseSortKey() (planner.SortKey, error) {
+	var k planner.SortKey
+	var err error
+
+	// parse expression
+	k.Expr, _, err = p.ParseExpr()
 	if err != nil {
-		return nil, 0, err
+		return k, err
 	}
 
 	// parse optional ASC or DESC
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ASC || tok == scanner.DESC {
-		return expr.FieldSelector(ref), tok, nil
+		k.Direction = tok
+	} else {
+		p.Unscan()
+	}
+
+	// parse optional NULLS FIRST or NULLS LAST
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "NULLS") {
+		p.Unscan()
+		return k, nil
+	}
+
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.IDENT && strings.EqualFold(lit, "FIRST"):
+		k.Nulls = planner.NullsFirst
+	case tok == scanner.IDENT && strings.EqualFold(lit, "LAST"):
+		k.Nulls = planner.NullsLast
+	default:
+		return k, newParseError(scanner.Tokstr(tok, lit), []string{"FIRST", "LAST"}, pos)
 	}
-	p.Unscan()
 
-	return expr.FieldSelector(ref), 0, nil
+	return k, nil
 }
 
 func (p *Parser) parseLimit() (expr.Expr, error) {
@@ -205,38 +423,47 @@ func (p *Parser) parseOffset() (expr.Expr, error) {
 	return e, err
 }
 
+// joinClause holds the configuration of a JOIN clause.
+type joinClause struct {
+	Kind      planner.JoinKind
+	TableName string
+	On        expr.Expr
+}
+
+// compoundClause holds the configuration of a select statement
+// combined with the previous ones.
+type compoundClause struct {
+	// UNION, INTERSECT or EXCEPT
+	Kind   scanner.Token
+	All    bool
+	Select selectConfig
+}
+
 // SelectConfig holds SELECT configuration.
 type selectConfig struct {
-	TableName        string
-	WhereExpr        expr.Expr
-	GroupByExpr      expr.Expr
-	OrderBy          expr.FieldSelector
-	OrderByDirection scanner.Token
-	OffsetExpr       expr.Expr
-	LimitExpr        expr.Expr
-	ProjectionExprs  []planner.ProjectedField
+	Distinct        bool
+	TableName       string
+	Joins           []joinClause
+	WhereExpr       expr.Expr
+	GroupByExprs    []expr.Expr
+	HavingExpr      expr.Expr
+	OrderBy         []planner.SortKey
+	OffsetExpr      expr.Expr
+	LimitExpr       expr.Expr
+	ProjectionExprs []planner.ProjectedField
+	Compounds       []compoundClause
 }
 
 // ToTree turns the statement into an expression tree.
 func (cfg selectConfig) ToTree() (*planner.Tree, error) {
-	var n planner.Node
+	n := cfg.coreNode()
 
-	if cfg.TableName != "" {
-		n = planner.NewTableInputNode(cfg.TableName)
+	for _, cc := range cfg.Compounds {
+		n = planner.NewCombinationNode(planner.NewTree(n), planner.NewTree(cc.Select.coreNode()), cc.Kind, cc.All)
 	}
 
-	if cfg.WhereExpr != nil {
-		n = planner.NewSelectionNode(n, cfg.WhereExpr)
-	}
-
-	if cfg.GroupByExpr != nil {
-		n = planner.NewGroupingNode(n, cfg.GroupByExpr)
-	}
-
-	n = planner.NewProjectionNode(n, cfg.ProjectionExprs, cfg.TableName)
-
 	if cfg.OrderBy != nil {
-		n = planner.NewSortNode(n, cfg.OrderBy, cfg.OrderByDirection)
+		n = planner.NewSortNode(n, cfg.OrderBy...)
 	}
 
 	if cfg.OffsetExpr != nil {
@@ -277,3 +504,101 @@ func (cfg selectConfig) ToTree() (*planner.Tree, error) {
 
 	return &planner.Tree{Root: n}, nil
 }
+
+// coreNode returns the node producing the documents of the statement,
+// before they are combined with other statements, sorted or limited.
+func (cfg selectConfig) coreNode() planner.Node {
+	var n planner.Node
+
+	if cfg.TableName != "" {
+		n = planner.NewTableInputNode(cfg.TableName)
+	}
+
+	for _, jc := range cfg.Joins {
+		n = planner.NewJoinNode(n, planner.NewTableInputNode(jc.TableName), jc.Kind, jc.On)
+	}
+
+	if cfg.WhereExpr != nil {
+		n = planner.NewSelectionNode(n, cfg.WhereExpr)
+	}
+
+	if cfg.GroupByExprs != nil {
+		n = planner.NewGroupingNode(n, cfg.GroupByExprs...)
+	}
+
+	// aggregate functions are computed once the documents are grouped,
+	// before the HAVING clause filters the groups.
+	if builders := cfg.aggregators(); len(builders) > 0 || cfg.GroupByExprs != nil || cfg.HavingExpr != nil {
+		n = planner.NewAggregationNode(n, builders...)
+	}
+
+	if cfg.HavingExpr != nil {
+		n = planner.NewSelectionNode(n, cfg.HavingExpr)
+	}
+
+	// documents produced by a join don't belong to a single table
+	tableName := cfg.TableName
+	if len(cfg.Joins) > 0 {
+		tableName = ""
+	}
+
+	n = planner.NewProjectionNode(n, cfg.ProjectionExprs, tableName)
+
+	if cfg.Distinct {
+		n = planner.NewDistinctNode(n)
+	}
+
+	return n
+}
+
+// aggregators returns the aggregate functions used by the projected fields
+// and the HAVING clause. Aggregate functions projected at the top level are
+// named after their projected field. Equal functions are only returned once.
+func (cfg selectConfig) aggregators() []planner.AggregatorBuilder {
+	var builders []planner.AggregatorBuilder
+
+	for _, f := range cfg.ProjectionExprs {
+		pe, ok := f.(planner.ProjectedExpr)
+		if !ok {
+			continue
+		}
+
+		if ab, ok := pe.Expr.(planner.AggregatorBuilder); ok && pe.ExprName != "" {
+			ab.SetAlias(pe.ExprName)
+		}
+
+		builders = appendAggregators(builders, pe.Expr)
+	}
+
+	return appendAggregators(builders, cfg.HavingExpr)
+}
+
+// appendAggregators appends the aggregate functions found in e to builders,
+// unless an equal function was already found.
+func appendAggregators(builders []planner.AggregatorBuilder, e expr.Expr) []planner.AggregatorBuilder {
+	switch t := e.(type) {
+	case planner.AggregatorBuilder:
+		for _, ab := range builders {
+			if expr.Equal(ab.(expr.Expr), e) {
+				// the function is computed once: its result is read
+				// using the name of the first one.
+				t.SetAlias(fmt.Sprintf("%v", ab))
+				return builders
+			}
+		}
+		return append(builders, t)
+	case expr.Operator:
+		builders = appendAggregators(builders, t.LeftHand())
+		return appendAggregators(builders, t.RightHand())
+	case expr.Parentheses:
+		return appendAggregators(builders, t.E)
+	case expr.CastFunc:
+		return appendAggregators(builders, t.Expr)
+	case expr.LiteralExprList:
+		for _, e := range t {
+			builders = appendAggregators(builders, e)
+		}
+	}
+
+	return builders
+}
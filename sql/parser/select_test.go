@@ -74,6 +74,14 @@ func TestParserSelect(t *testing.T) {
 					"test",
 				)),
 			false},
+		{"WithTimestampField", "SELECT timestamp FROM test",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewTableInputNode("test"),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "timestamp")), ExprName: "timestamp"}},
+					"test",
+				)),
+			false},
 		{"WithFieldsWithQuotes", "SELECT `long \"path\"` FROM test",
 			planner.NewTree(
 				planner.NewProjectionNode(
@@ -120,17 +128,61 @@ func TestParserSelect(t *testing.T) {
 		{"WithGroupBy", "SELECT * FROM test WHERE age = 10 GROUP BY a.b.c",
 			planner.NewTree(
 				planner.NewProjectionNode(
-					planner.NewGroupingNode(
-						planner.NewSelectionNode(
-							planner.NewTableInputNode("test"),
-							expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+					planner.NewAggregationNode(
+						planner.NewGroupingNode(
+							planner.NewSelectionNode(
+								planner.NewTableInputNode("test"),
+								expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+							),
+							expr.FieldSelector(parsePath(t, "a.b.c")),
 						),
-						expr.FieldSelector(parsePath(t, "a.b.c")),
 					),
 					[]planner.ProjectedField{planner.Wildcard{}},
 					"test",
 				)),
 			false},
+		{"WithGroupByMultipleExprsAndHaving", "SELECT a, COUNT(*) AS c FROM test GROUP BY a, b HAVING c > 1 AND MAX(d) < 10",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewAggregationNode(
+							planner.NewGroupingNode(
+								planner.NewTableInputNode("test"),
+								expr.FieldSelector(parsePath(t, "a")),
+								expr.FieldSelector(parsePath(t, "b")),
+							),
+							&expr.CountFunc{Wildcard: true, Alias: "c"},
+							&expr.MaxFunc{Expr: expr.FieldSelector(parsePath(t, "d"))},
+						),
+						expr.And(
+							expr.Gt(expr.FieldSelector(parsePath(t, "c")), expr.IntegerValue(1)),
+							expr.Lt(&expr.MaxFunc{Expr: expr.FieldSelector(parsePath(t, "d"))}, expr.IntegerValue(10)),
+						),
+					),
+					[]planner.ProjectedField{
+						planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"},
+						planner.ProjectedExpr{Expr: &expr.CountFunc{Wildcard: true, Alias: "c"}, ExprName: "c"},
+					},
+					"test",
+				)),
+			false},
+		{"WithHavingOnAliasedAggregator", "SELECT COUNT(*) AS c FROM test HAVING COUNT(*) > 1",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewAggregationNode(
+							planner.NewTableInputNode("test"),
+							&expr.CountFunc{Wildcard: true, Alias: "c"},
+						),
+						expr.Gt(&expr.CountFunc{Wildcard: true, Alias: "c"}, expr.IntegerValue(1)),
+					),
+					[]planner.ProjectedField{
+						planner.ProjectedExpr{Expr: &expr.CountFunc{Wildcard: true, Alias: "c"}, ExprName: "c"},
+					},
+					"test",
+				)),
+			false},
+		{"WithGroupByTrailingComma", "SELECT * FROM test GROUP BY a,", nil, true},
 		{"WithOrderBy", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c",
 			planner.NewTree(
 				planner.NewSortNode(
@@ -142,8 +194,7 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					expr.FieldSelector(parsePath(t, "a.b.c")),
-					scanner.ASC,
+					planner.SortKey{Expr: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.ASC},
 				)),
 			false},
 		{"WithOrderBy ASC", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c ASC",
@@ -157,8 +208,7 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					expr.FieldSelector(parsePath(t, "a.b.c")),
-					scanner.ASC,
+					planner.SortKey{Expr: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.ASC},
 				)),
 			false},
 		{"WithOrderBy DESC", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c DESC",
@@ -172,10 +222,35 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					expr.FieldSelector(parsePath(t, "a.b.c")),
-					scanner.DESC,
+					planner.SortKey{Expr: expr.FieldSelector(parsePath(t, "a.b.c")), Direction: scanner.DESC},
 				)),
 			false},
+		{"WithMultipleOrderBy", "SELECT * FROM test ORDER BY a DESC, b ASC NULLS LAST, lower(c) NULLS FIRST",
+			planner.NewTree(
+				planner.NewSortNode(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					planner.SortKey{Expr: expr.FieldSelector(parsePath(t, "a")), Direction: scanner.DESC},
+					planner.SortKey{Expr: expr.FieldSelector(parsePath(t, "b")), Direction: scanner.ASC, Nulls: planner.NullsLast},
+					planner.SortKey{Expr: expr.LowerFunc{Expr: expr.FieldSelector(parsePath(t, "c"))}, Direction: scanner.ASC, Nulls: planner.NullsFirst},
+				)),
+			false},
+		{"WithOrderByExpr", "SELECT * FROM test ORDER BY a + 1 DESC",
+			planner.NewTree(
+				planner.NewSortNode(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					planner.SortKey{Expr: expr.Add(expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1)), Direction: scanner.DESC},
+				)),
+			false},
+		{"WithOrderBy NULLS without FIRST or LAST", "SELECT * FROM test ORDER BY a NULLS", nil, true},
+		{"WithOrderBy trailing comma", "SELECT * FROM test ORDER BY a,", nil, true},
 		{"WithLimit", "SELECT * FROM test WHERE age = 10 LIMIT 20",
 			planner.NewTree(
 				planner.NewLimitNode(
@@ -222,6 +297,210 @@ func TestParserSelect(t *testing.T) {
 				)),
 			false},
 		{"WithOffsetThenLimit", "SELECT * FROM test WHERE age = 10 OFFSET 20 LIMIT 10", nil, true},
+		{"WithJoin", "SELECT a.x, b.y FROM a JOIN b ON a.x = b.y",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewJoinNode(
+						planner.NewTableInputNode("a"),
+						planner.NewTableInputNode("b"),
+						planner.InnerJoin,
+						expr.Eq(expr.FieldSelector(parsePath(t, "a.x")), expr.FieldSelector(parsePath(t, "b.y"))),
+					),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a.x")), ExprName: "a.x"}, planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b.y")), ExprName: "b.y"}},
+					"",
+				)),
+			false},
+		{"WithInnerJoin", "SELECT * FROM a INNER JOIN b ON a.x = b.y WHERE a.z > 10",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewJoinNode(
+							planner.NewTableInputNode("a"),
+							planner.NewTableInputNode("b"),
+							planner.InnerJoin,
+							expr.Eq(expr.FieldSelector(parsePath(t, "a.x")), expr.FieldSelector(parsePath(t, "b.y"))),
+						),
+						expr.Gt(expr.FieldSelector(parsePath(t, "a.z")), expr.IntegerValue(10)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"",
+				)),
+			false},
+		{"WithMultipleJoins", "SELECT * FROM a LEFT JOIN b ON a.x = b.y LEFT OUTER JOIN c ON b.y = c.z",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewJoinNode(
+						planner.NewJoinNode(
+							planner.NewTableInputNode("a"),
+							planner.NewTableInputNode("b"),
+							planner.LeftJoin,
+							expr.Eq(expr.FieldSelector(parsePath(t, "a.x")), expr.FieldSelector(parsePath(t, "b.y"))),
+						),
+						planner.NewTableInputNode("c"),
+						planner.LeftJoin,
+						expr.Eq(expr.FieldSelector(parsePath(t, "b.y")), expr.FieldSelector(parsePath(t, "c.z"))),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"",
+				)),
+			false},
+		{"WithJoinWithoutOn", "SELECT * FROM a JOIN b", nil, true},
+		{"WithLeftWithoutJoin", "SELECT * FROM a LEFT b ON a.x = b.y", nil, true},
+		{"WithCheckConstraintKeywordsAsFields", "SELECT check, constraint FROM test WHERE check > constraint",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(planner.NewTableInputNode("test"),
+						expr.Gt(expr.FieldSelector(parsePath(t, "check")), expr.FieldSelector(parsePath(t, "constraint")))),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "check")), ExprName: "check"}, planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "constraint")), ExprName: "constraint"}},
+					"test",
+				)),
+			false},
+		{"WithJoinKeywordsAsFields", "SELECT left, inner FROM a LEFT OUTER JOIN b ON a.outer = b.inner",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewJoinNode(
+						planner.NewTableInputNode("a"),
+						planner.NewTableInputNode("b"),
+						planner.LeftJoin,
+						expr.Eq(expr.FieldSelector(parsePath(t, "a.outer")), expr.FieldSelector(parsePath(t, "b.inner"))),
+					),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "left")), ExprName: "left"}, planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "inner")), ExprName: "inner"}},
+					"",
+				)),
+			false},
+		{"WithINSubquery", "SELECT * FROM test WHERE a IN (SELECT b FROM foo)",
+			func() *planner.Tree {
+				sq := &planner.Subquery{
+					Tree: planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("foo"),
+						[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+						"foo")),
+					Kind:       planner.ArraySubquery,
+					OuterTable: "test",
+					Text:       "SELECT b FROM foo",
+				}
+				tree := planner.NewTree(
+					planner.NewProjectionNode(
+						planner.NewSelectionNode(planner.NewTableInputNode("test"),
+							expr.In(expr.FieldSelector(parsePath(t, "a")), sq)),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					))
+				tree.Subqueries = []*planner.Subquery{sq}
+				return tree
+			}(),
+			false},
+		{"WithCorrelatedEXISTSSubquery", "SELECT * FROM test WHERE NOT EXISTS (SELECT * FROM foo WHERE foo.b = test.a)",
+			func() *planner.Tree {
+				sq := &planner.Subquery{
+					Tree: planner.NewTree(planner.NewProjectionNode(
+						planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+							expr.Eq(expr.FieldSelector(parsePath(t, "foo.b")), expr.FieldSelector(parsePath(t, "test.a")))),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"foo")),
+					Kind:       planner.NotExistsSubquery,
+					Correlated: true,
+					OuterTable: "test",
+					Text:       "SELECT * FROM foo WHERE foo.b = test.a",
+				}
+				tree := planner.NewTree(
+					planner.NewProjectionNode(
+						planner.NewSelectionNode(planner.NewTableInputNode("test"), sq),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					))
+				tree.Subqueries = []*planner.Subquery{sq}
+				return tree
+			}(),
+			false},
+		{"WithScalarSubquery", "SELECT (SELECT b FROM foo WHERE c = test.c LIMIT 1) AS b FROM test",
+			func() *planner.Tree {
+				sq := &planner.Subquery{
+					Tree: planner.NewTree(planner.NewLimitNode(
+						planner.NewProjectionNode(
+							planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+								expr.Eq(expr.FieldSelector(parsePath(t, "c")), expr.FieldSelector(parsePath(t, "test.c")))),
+							[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+							"foo"),
+						1)),
+					Kind:       planner.ScalarSubquery,
+					Correlated: true,
+					OuterTable: "test",
+					Text:       "SELECT b FROM foo WHERE c = test.c LIMIT 1",
+				}
+				tree := planner.NewTree(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.ProjectedExpr{Expr: sq, ExprName: "b"}},
+						"test",
+					))
+				tree.Subqueries = []*planner.Subquery{sq}
+				return tree
+			}(),
+			false},
+		{"WithSubqueryWithoutParenthesis", "SELECT * FROM test WHERE a IN (SELECT b FROM foo", nil, true},
+		{"WithEXISTSWithoutSubquery", "SELECT * FROM test WHERE EXISTS (1)", nil, true},
+		{"WithDistinct", "SELECT DISTINCT a FROM test",
+			planner.NewTree(
+				planner.NewDistinctNode(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+						"test",
+					))),
+			false},
+		{"WithUnionAndOrderBy", "SELECT a FROM foo UNION SELECT DISTINCT b FROM bar WHERE b > 1 ORDER BY a LIMIT 10",
+			planner.NewTree(
+				planner.NewLimitNode(
+					planner.NewSortNode(
+						planner.NewCombinationNode(
+							planner.NewTree(planner.NewProjectionNode(
+								planner.NewTableInputNode("foo"),
+								[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+								"foo",
+							)),
+							planner.NewTree(planner.NewDistinctNode(planner.NewProjectionNode(
+								planner.NewSelectionNode(planner.NewTableInputNode("bar"), expr.Gt(expr.FieldSelector(parsePath(t, "b")), expr.IntegerValue(1))),
+								[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+								"bar",
+							))),
+							scanner.UNION, false,
+						),
+						planner.SortKey{Expr: expr.FieldSelector(parsePath(t, "a"))},
+					),
+					10,
+				)),
+			false},
+		{"WithUnionAllThenExcept", "SELECT * FROM foo UNION ALL SELECT * FROM bar EXCEPT SELECT * FROM baz",
+			planner.NewTree(
+				planner.NewCombinationNode(
+					planner.NewTree(planner.NewCombinationNode(
+						planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("foo"), []planner.ProjectedField{planner.Wildcard{}}, "foo")),
+						planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("bar"), []planner.ProjectedField{planner.Wildcard{}}, "bar")),
+						scanner.UNION, true,
+					)),
+					planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("baz"), []planner.ProjectedField{planner.Wildcard{}}, "baz")),
+					scanner.EXCEPT, false,
+				)),
+			false},
+		{"WithUnionAllOfFieldsNamedAll", "SELECT all FROM foo union all SELECT all FROM bar",
+			planner.NewTree(
+				planner.NewCombinationNode(
+					planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("foo"), []planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "all")), ExprName: "all"}}, "foo")),
+					planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("bar"), []planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "all")), ExprName: "all"}}, "bar")),
+					scanner.UNION, true,
+				)),
+			false},
+		{"WithIntersect", "SELECT 1 INTERSECT SELECT 2",
+			planner.NewTree(
+				planner.NewCombinationNode(
+					planner.NewTree(planner.NewProjectionNode(nil, []planner.ProjectedField{planner.ProjectedExpr{Expr: expr.IntegerValue(1), ExprName: "1"}}, "")),
+					planner.NewTree(planner.NewProjectionNode(nil, []planner.ProjectedField{planner.ProjectedExpr{Expr: expr.IntegerValue(2), ExprName: "2"}}, "")),
+					scanner.INTERSECT, false,
+				)),
+			false},
+		{"WithUnionWithoutSelect", "SELECT a FROM foo UNION b", nil, true},
+		{"WithUnionOfDifferentSizes", "SELECT a FROM foo UNION SELECT a, b FROM bar", nil, true},
+		{"WithIntersectAll", "SELECT a FROM foo INTERSECT ALL SELECT a FROM bar", nil, true},
 	}
 
 	for _, test := range tests {
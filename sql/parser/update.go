@@ -20,6 +20,7 @@ func (p *Parser) parseUpdateStatement() (*planner.Tree, error) {
 		pErr.Expected = []string{"table_name"}
 		return nil, pErr
 	}
+	p.addTables(cfg.TableName)
 
 	// Parse clause: SET or UNSET.
 	tok, pos, lit := p.ScanIgnoreWhitespace()
@@ -41,6 +42,12 @@ func (p *Parser) parseUpdateStatement() (*planner.Tree, error) {
 		return nil, err
 	}
 
+	// Parse "RETURNING"
+	cfg.Returning, err = p.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg.ToTree(), nil
 }
 
@@ -125,6 +132,10 @@ type updateConfig struct {
 	UnsetFields []string
 
 	WhereExpr expr.Expr
+
+	// Returning holds the fields of the updated documents
+	// returned by the statement, if any.
+	Returning []planner.ProjectedField
 }
 
 type updateSetPair struct {
@@ -152,5 +163,9 @@ func (cfg updateConfig) ToTree() *planner.Tree {
 
 	t = planner.NewReplacementNode(t, cfg.TableName)
 
+	if cfg.Returning != nil {
+		t = planner.NewReturningNode(t, cfg.Returning, cfg.TableName)
+	}
+
 	return &planner.Tree{Root: t}
 }
@@ -42,6 +42,23 @@ func TestParserUpdate(t *testing.T) {
 					"test",
 				)),
 			false},
+		{"SET/Returning", "UPDATE test SET a = 1 RETURNING a, b + 1",
+			planner.NewTree(
+				planner.NewReturningNode(
+					planner.NewReplacementNode(
+						planner.NewSetNode(
+							planner.NewTableInputNode("test"),
+							parsePath(t, "a"), expr.IntegerValue(1),
+						),
+						"test",
+					),
+					[]planner.ProjectedField{
+						planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"},
+						planner.ProjectedExpr{Expr: expr.Add(expr.FieldSelector(parsePath(t, "b")), expr.IntegerValue(1)), ExprName: "b + 1"},
+					},
+					"test",
+				)),
+			false},
 		{"SET/No cond path with backquotes", "UPDATE test SET `   some \"path\" ` = 1",
 			planner.NewTree(
 				planner.NewReplacementNode(
@@ -38,3 +38,41 @@ func bindNode(n Node, tx *database.Transaction, params []expr.Param) error {
 
 	return nil
 }
+
+// evalCache is embedded by the nodes that evaluate expressions.
+// It holds the cache of the current execution, see expr.EvalStack.
+type evalCache struct {
+	cache map[expr.Expr]interface{}
+}
+
+func (c *evalCache) setCache(cache map[expr.Expr]interface{}) {
+	c.cache = cache
+}
+
+// A cacheSetter is a node that needs the cache of the current execution.
+type cacheSetter interface {
+	setCache(cache map[expr.Expr]interface{})
+}
+
+// bindCache passes the cache of the current execution to every node of t
+// that needs it. Nodes pass it to the streams they build, which must therefore
+// be built right after.
+func bindCache(t *Tree, cache map[expr.Expr]interface{}) {
+	if t.Root != nil {
+		bindNodeCache(t.Root, cache)
+	}
+}
+
+func bindNodeCache(n Node, cache map[expr.Expr]interface{}) {
+	if cs, ok := n.(cacheSetter); ok {
+		cs.setCache(cache)
+	}
+
+	if n.Left() != nil {
+		bindNodeCache(n.Left(), cache)
+	}
+
+	if n.Right() != nil {
+		bindNodeCache(n.Right(), cache)
+	}
+}
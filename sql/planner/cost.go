@@ -0,0 +1,278 @@
+package planner
+
+import (
+	"bytes"
+	"math"
+	"unicode/utf8"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// Cost of reading a document, relative to the cost of reading
+// a document during a full table scan.
+const (
+	// documents read using the primary key are stored next to each other.
+	pkReadCost = 1
+	// documents read using an index are fetched one by one
+	// after reading the index.
+	indexReadCost = 2
+)
+
+// Selectivity of the conditions whose selectivity can't be
+// estimated using the statistics.
+const (
+	defaultEqSelectivity    = 0.1
+	defaultRangeSelectivity = 1.0 / 3
+)
+
+// A scanCandidate is a way of reading the documents of a table
+// using an index or the primary key.
+type scanCandidate struct {
+	// selection nodes to remove from the tree
+	nodes []Node
+	in    inputNode
+	// the candidate reads at most one document per value,
+	// because it uses the primary key or a unique index.
+	unique bool
+}
+
+// cheapestCandidate estimates the cost of every candidate using the statistics of the table
+// and returns the cheapest one. It returns nil if reading the entire table is cheaper.
+// Candidates reading at most one document are always preferred to a full table scan,
+// since the statistics may have been collected when the table was smaller.
+func cheapestCandidate(candidates []scanCandidate, ts *database.TableStatistics) *scanCandidate {
+	for i, c := range candidates {
+		if isUniqueLookup(c) {
+			return &candidates[i]
+		}
+	}
+
+	var selected *scanCandidate
+	minCost := math.Max(float64(ts.RowCount), 1)
+
+	for i, c := range candidates {
+		_, cost := estimateScan(c.in, c.nodes[0].(*selectionNode).cond, ts)
+		if cost < minCost {
+			selected = &candidates[i]
+			minCost = cost
+		}
+	}
+
+	return selected
+}
+
+// isUniqueLookup returns true if the candidate looks up a single value
+// of the primary key or of a unique index.
+func isUniqueLookup(c scanCandidate) bool {
+	if !c.unique {
+		return false
+	}
+
+	switch in := c.in.(type) {
+	case *pkInputNode:
+		return in.op == scanner.EQ
+	case *indexInputNode:
+		if op, ok := in.iop.(compositeIndexOperator); ok {
+			return op.rangeOp == 0 && op.eq == len(in.index.Opts.Paths)
+		}
+
+		if in.index.Opts.Multi || in.index.Opts.FullText {
+			return false
+		}
+
+		return comparisonToken(c.nodes[0].(*selectionNode).cond.(expr.Operator)) == scanner.EQ
+	}
+
+	return false
+}
+
+// estimateScan estimates the number of documents read by an input node, selected by
+// the given condition, and the cost of reading them.
+func estimateScan(in inputNode, cond expr.Expr, ts *database.TableStatistics) (rows, cost float64) {
+	switch in := in.(type) {
+	case *pkInputNode:
+		rows = estimateComparison(ts.PrimaryKey, ts.RowCount, in.op, in.e, in.op == scanner.EQ)
+		return rows, rows * pkReadCost
+	case *indexInputNode:
+		vs := ts.Indexes[in.indexName]
+
+		if op, ok := in.iop.(compositeIndexOperator); ok {
+			rows = estimateComposite(vs, ts.RowCount, op, len(in.index.Opts.Paths))
+		} else if in.index.Opts.Multi || in.index.Opts.FullText {
+			// multi-valued indexes are read by looking up a single element,
+			// full-text indexes have no statistics.
+			rows = estimateComparison(vs, ts.RowCount, scanner.EQ, in.e, false)
+		} else {
+			rows = estimateComparison(vs, ts.RowCount, comparisonToken(cond.(expr.Operator)), in.e, in.index.Unique)
+		}
+
+		return rows, rows * indexReadCost
+	case *indexUnionNode:
+		for i, input := range in.inputs {
+			r, c := estimateScan(input, in.conds[i], ts)
+			// the documents are fetched again once their keys are deduplicated.
+			rows += r
+			cost += c + r*pkReadCost
+		}
+
+		return math.Min(rows, float64(ts.RowCount)), cost
+	}
+
+	return float64(ts.RowCount), float64(ts.RowCount)
+}
+
+// estimateComparison estimates the number of values that match a comparison
+// with the result of e, using the given operator.
+// If vs is nil or describes no values, the estimation is based on the number of documents of the table.
+func estimateComparison(vs *database.ValueStatistics, rowCount int64, tok scanner.Token, e expr.Expr, unique bool) float64 {
+	if vs == nil || vs.DistinctCount == 0 {
+		switch {
+		case tok == scanner.EQ && unique:
+			return 1
+		case tok == scanner.GT, tok == scanner.GTE, tok == scanner.LT, tok == scanner.LTE:
+			return float64(rowCount) * defaultRangeSelectivity
+		}
+
+		return float64(rowCount) * defaultEqSelectivity
+	}
+
+	count := float64(vs.Count)
+	// number of values equal to a given value, assuming
+	// every value is repeated as many times.
+	perValue := count / float64(vs.DistinctCount)
+
+	switch tok {
+	case scanner.EQ:
+		return perValue
+	case scanner.IN:
+		n, ok := listLength(e)
+		if !ok {
+			return count * defaultEqSelectivity
+		}
+
+		return math.Min(count, float64(n)*perValue)
+	case scanner.EQREGEX:
+		lit := e.(expr.LiteralValue)
+		prefix := expr.RegexLiteralPrefix(lit.V.(string))
+
+		lo := lesserFraction(vs, document.NewTextValue(prefix), perValue)
+		hi := lesserFraction(vs, document.NewTextValue(prefix+string(utf8.MaxRune)), perValue)
+		return count * (hi - lo)
+	}
+
+	// the value of parameters is unknown until the tree is run.
+	lit, ok := e.(expr.LiteralValue)
+	if !ok {
+		return count * defaultRangeSelectivity
+	}
+
+	lesser := count * lesserFraction(vs, document.Value(lit), perValue)
+	switch tok {
+	case scanner.GT:
+		return math.Max(0, count-lesser-perValue)
+	case scanner.GTE:
+		return count - lesser
+	case scanner.LT:
+		return lesser
+	}
+
+	return math.Min(count, lesser+perValue)
+}
+
+// estimateComposite estimates the number of values of a composite index read by op.
+// Its fields are assumed to be independent.
+func estimateComposite(vs *database.ValueStatistics, rowCount int64, op compositeIndexOperator, fields int) float64 {
+	var count, sel float64
+
+	if vs == nil || vs.DistinctCount == 0 {
+		count = float64(rowCount)
+		sel = math.Pow(defaultEqSelectivity, float64(op.eq))
+	} else {
+		count = float64(vs.Count)
+		sel = math.Pow(1/float64(vs.DistinctCount), float64(op.eq)/float64(fields))
+	}
+
+	if op.rangeOp != 0 {
+		sel *= defaultRangeSelectivity
+	}
+
+	return count * sel
+}
+
+// lesserFraction uses the histogram to estimate the fraction of the values that are lesser than v.
+// Values are compared using their key encoding, which follows the order of the indexes.
+func lesserFraction(vs *database.ValueStatistics, v document.Value, perValue float64) float64 {
+	if vs.Count == 0 {
+		return 0
+	}
+
+	enc, err := key.AppendValue(nil, v)
+	if err != nil {
+		return defaultRangeSelectivity
+	}
+
+	var lesser float64
+	for _, b := range vs.Histogram {
+		ub, err := key.AppendValue(nil, b.UpperBound)
+		if err != nil {
+			return defaultRangeSelectivity
+		}
+
+		switch c := bytes.Compare(ub, enc); {
+		case c < 0:
+			lesser += float64(b.Count)
+			continue
+		case c == 0:
+			// v is the greatest value of the bucket.
+			lesser += math.Max(0, float64(b.Count)-perValue)
+		default:
+			// assume v is in the middle of the bucket.
+			lesser += float64(b.Count) / 2
+		}
+
+		break
+	}
+
+	return lesser / float64(vs.Count)
+}
+
+// listLength returns the number of values of a literal list or array.
+func listLength(e expr.Expr) (int, bool) {
+	switch t := e.(type) {
+	case expr.LiteralExprList:
+		return len(t), true
+	case expr.LiteralValue:
+		if t.Type == document.ArrayValue {
+			n, err := document.ArrayLength(t.V.(document.Array))
+			return n, err == nil
+		}
+	}
+
+	return 0, false
+}
+
+// comparisonToken returns the token of a comparison operator, as if the
+// selected field was its left operand: a > 1 and 1 < a both return GT.
+func comparisonToken(op expr.Operator) scanner.Token {
+	tok := op.Token()
+	if _, ok := op.RightHand().(expr.FieldSelector); !ok {
+		return tok
+	}
+
+	switch tok {
+	case scanner.GT:
+		return scanner.LT
+	case scanner.GTE:
+		return scanner.LTE
+	case scanner.LT:
+		return scanner.GT
+	case scanner.LTE:
+		return scanner.GTE
+	}
+
+	return tok
+}
@@ -1,11 +1,14 @@
 package planner
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/sql/query/expr"
 )
 
@@ -17,6 +20,10 @@ type deletionNode struct {
 
 	tableName string
 	table     *database.Table
+	// if true, the node returns a copy of the deleted documents.
+	returning bool
+	// the deleted documents, kept until the result is closed.
+	deleted *sorter
 }
 
 var _ operationNode = (*deletionNode)(nil)
@@ -45,11 +52,22 @@ func (n *deletionNode) Bind(tx *database.Transaction, params []expr.Param) (err
 // to a buffer and delete them after the iteration is complete, and it will do that until there is no document
 // left to delete.
 // Increasing deleteBufferSize will occasionate less key searches (O(log n) for most engines) but will take more memory.
+// Documents are deleted when the stream is built, not when it is iterated. When returning,
+// the deleted documents are encoded and kept in memory up to the sort buffer size of the database,
+// beyond which they are written to temporary files, removed when the result of the tree is closed.
 func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 	st = st.Limit(deleteBufferSize)
 
 	keys := make([][]byte, deleteBufferSize)
 
+	n.deleted = nil
+	var deleted *sorter
+	if n.returning {
+		deleted = &sorter{bufferSize: n.table.Tx().DB().SortBufferSize}
+	}
+	codec := n.table.Tx().DB().Codec
+	var buf bytes.Buffer
+
 	for {
 		var i int
 
@@ -61,9 +79,28 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 			// copy the key and reuse the buffer
 			keys[i] = append(keys[i][0:0], k.Key()...)
 			i++
-			return nil
+
+			if deleted == nil {
+				return nil
+			}
+
+			// the key of the document is encoded before the document itself.
+			// Every document is added with the same sort key, which keeps them in order.
+			var l [binary.MaxVarintLen64]byte
+			buf.Reset()
+			buf.Write(l[:binary.PutUvarint(l[:], uint64(len(k.Key())))])
+			buf.Write(k.Key())
+			err := codec.NewEncoder(&buf).EncodeDocument(d)
+			if err != nil {
+				return err
+			}
+
+			return deleted.add(nil, append([]byte(nil), buf.Bytes()...))
 		})
 		if err != nil {
+			if deleted != nil {
+				deleted.close()
+			}
 			return document.Stream{}, err
 		}
 
@@ -72,6 +109,9 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 		for _, key := range keys {
 			err = n.table.Delete(key)
 			if err != nil {
+				if deleted != nil {
+					deleted.close()
+				}
 				return document.Stream{}, err
 			}
 		}
@@ -81,9 +121,46 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 		}
 	}
 
+	if deleted != nil {
+		n.deleted = deleted
+		return document.NewStream(&deletedIterator{sorter: deleted, codec: codec}), nil
+	}
+
 	return document.Stream{}, nil
 }
 
+// release removes the temporary files containing the deleted documents.
+func (n *deletionNode) release() {
+	if n.deleted != nil {
+		n.deleted.close()
+		n.deleted = nil
+	}
+}
+
 func (n *deletionNode) String() string {
 	return fmt.Sprintf("Delete(%s)", n.tableName)
 }
+
+// deletedIterator returns the documents deleted by a deletion node,
+// in the order they were deleted.
+type deletedIterator struct {
+	sorter *sorter
+	codec  encoding.Codec
+}
+
+func (it *deletedIterator) Iterate(fn func(d document.Document) error) error {
+	defer it.sorter.close()
+
+	return it.sorter.iterate(func(_, data []byte) error {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < l {
+			return errors.New("malformed deleted document")
+		}
+		data = data[n:]
+
+		return fn(&encodedDocumentWithKey{
+			Document: it.codec.NewDocument(data[l:]),
+			key:      data[:l],
+		})
+	})
+}
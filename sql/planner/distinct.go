@@ -0,0 +1,488 @@
+package planner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+type distinctNode struct {
+	node
+
+	tx *database.Transaction
+}
+
+var _ operationNode = (*distinctNode)(nil)
+
+// NewDistinctNode creates a node that removes duplicate documents from a stream.
+// Two documents are duplicates if they have the same fields, in the same order,
+// with equal values.
+func NewDistinctNode(n Node) Node {
+	return &distinctNode{
+		node: node{
+			op:   Distinct,
+			left: n,
+		},
+	}
+}
+
+func (n *distinctNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+	return
+}
+
+func (n *distinctNode) toStream(st document.Stream) (document.Stream, error) {
+	return document.NewStream(&distinctIterator{
+		st:         st,
+		codec:      n.tx.DB().Codec,
+		bufferSize: n.tx.DB().SortBufferSize,
+	}), nil
+}
+
+func (n *distinctNode) String() string {
+	return "Distinct()"
+}
+
+type distinctIterator struct {
+	st         document.Stream
+	codec      encoding.Codec
+	bufferSize int
+}
+
+func (it *distinctIterator) Iterate(fn func(d document.Document) error) error {
+	dd := newDeduplicator(it.bufferSize, it.codec, false)
+	defer dd.close()
+
+	err := it.st.Iterate(func(d document.Document) error {
+		h, err := hashDocument(d)
+		if err != nil {
+			return err
+		}
+
+		ok, err := dd.add(d, h)
+		if err != nil || !ok {
+			return err
+		}
+
+		return fn(d)
+	})
+	if err != nil {
+		return err
+	}
+
+	return dd.flush(fn)
+}
+
+type combinationNode struct {
+	node
+
+	// UNION, INTERSECT or EXCEPT
+	kind scanner.Token
+	// if true, duplicates are kept. Only used by UNION.
+	all      bool
+	lhs, rhs *Tree
+	// names of the fields returned by lhs, nil if they are only known at runtime.
+	fields []string
+
+	tx *database.Transaction
+}
+
+var _ inputNode = (*combinationNode)(nil)
+
+// NewCombinationNode creates a node that combines the documents returned by two SELECT statements.
+// UNION returns the documents of both statements, INTERSECT the documents returned by both of them
+// and EXCEPT the documents of lhs that rhs doesn't return. Duplicates are removed unless all is true,
+// which is only supported by UNION.
+// Documents returned by rhs are renamed positionally after the fields projected by lhs.
+func NewCombinationNode(lhs, rhs *Tree, kind scanner.Token, all bool) Node {
+	return &combinationNode{
+		node: node{
+			op: Combination,
+		},
+		kind:   kind,
+		all:    all && kind == scanner.UNION,
+		lhs:    lhs,
+		rhs:    rhs,
+		fields: resultFields(lhs),
+	}
+}
+
+// resultFields returns the names of the fields of the documents returned by t,
+// or nil if they depend on the documents read by t.
+func resultFields(t *Tree) []string {
+	for n := t.Root; n != nil; n = n.Left() {
+		switch nn := n.(type) {
+		case *combinationNode:
+			return nn.fields
+		case *ProjectionNode:
+			fields := make([]string, len(nn.Expressions))
+			for i, e := range nn.Expressions {
+				if _, ok := e.(Wildcard); ok {
+					return nil
+				}
+				fields[i] = e.Name()
+			}
+			return fields
+		}
+	}
+
+	return nil
+}
+
+func (n *combinationNode) Bind(tx *database.Transaction, params []expr.Param) error {
+	n.tx = tx
+
+	err := Bind(n.lhs, tx, params)
+	if err != nil {
+		return err
+	}
+
+	return Bind(n.rhs, tx, params)
+}
+
+func (n *combinationNode) setCache(cache map[expr.Expr]interface{}) {
+	bindCache(n.lhs, cache)
+	bindCache(n.rhs, cache)
+}
+
+// optimize optimizes both statements separately.
+func (n *combinationNode) optimize() (err error) {
+	n.lhs, err = Optimize(n.lhs)
+	if err != nil {
+		return err
+	}
+
+	n.rhs, err = Optimize(n.rhs)
+	return err
+}
+
+func (n *combinationNode) buildStream() (document.Stream, error) {
+	lres, err := n.lhs.execute()
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	rres, err := n.rhs.execute()
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	return document.NewStream(&combinationIterator{
+		combinationNode: n,
+		lhs:             lres.Stream,
+		rhs:             rres.Stream,
+		codec:           n.tx.DB().Codec,
+		bufferSize:      n.tx.DB().SortBufferSize,
+	}), nil
+}
+
+func (n *combinationNode) String() string {
+	name := "Union"
+	switch {
+	case n.all:
+		name = "UnionAll"
+	case n.kind == scanner.INTERSECT:
+		name = "Intersect"
+	case n.kind == scanner.EXCEPT:
+		name = "Except"
+	}
+
+	return fmt.Sprintf("%s(%s, %s)", name, n.lhs, n.rhs)
+}
+
+type combinationIterator struct {
+	*combinationNode
+
+	lhs, rhs   document.Stream
+	codec      encoding.Codec
+	bufferSize int
+}
+
+func (it *combinationIterator) Iterate(fn func(d document.Document) error) error {
+	dd := newDeduplicator(it.bufferSize, it.codec, it.kind == scanner.INTERSECT)
+	defer dd.close()
+
+	// emit passes the documents that were not returned yet to fn.
+	emit := func(d document.Document, h [sha256.Size]byte) error {
+		if it.all {
+			return fn(d)
+		}
+
+		ok, err := dd.add(d, h)
+		if err != nil || !ok {
+			return err
+		}
+
+		return fn(d)
+	}
+
+	if it.kind == scanner.UNION {
+		err := it.iterate(it.lhs, false, emit)
+		if err != nil {
+			return err
+		}
+
+		err = it.iterate(it.rhs, true, emit)
+		if err != nil {
+			return err
+		}
+
+		return dd.flush(fn)
+	}
+
+	// INTERSECT and EXCEPT need to know every document returned
+	// by rhs before reading lhs.
+	err := it.iterate(it.rhs, true, func(d document.Document, h [sha256.Size]byte) error {
+		return dd.addHash(h)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = it.iterate(it.lhs, false, emit)
+	if err != nil {
+		return err
+	}
+
+	return dd.flush(fn)
+}
+
+// iterate calls fn with every document of st and its hash.
+// If rename is true, documents are renamed after the fields of lhs first.
+func (it *combinationIterator) iterate(st document.Stream, rename bool, fn func(d document.Document, h [sha256.Size]byte) error) error {
+	var fb document.FieldBuffer
+
+	return st.Iterate(func(d document.Document) error {
+		if rename && it.fields != nil {
+			fb.Reset()
+
+			var i int
+			err := d.Iterate(func(_ string, v document.Value) error {
+				if i < len(it.fields) {
+					fb.Add(it.fields[i], v)
+				}
+				i++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if i != len(it.fields) {
+				return fmt.Errorf("documents combined by %s must have the same number of fields, got %d and %d", it.kind, len(it.fields), i)
+			}
+
+			d = &fb
+		}
+
+		h, err := hashDocument(d)
+		if err != nil {
+			return err
+		}
+
+		return fn(d, h)
+	})
+}
+
+// hashDocument returns the hash of the group key of d, see document.AppendGroupKey.
+// Documents with the same fields and equal values have the same hash,
+// integers being equal to doubles with the same value.
+func hashDocument(d document.Document) ([sha256.Size]byte, error) {
+	buf, err := document.AppendGroupKey(nil, document.NewDocumentValue(d))
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(buf), nil
+}
+
+// hashState describes what is known about a hash kept in memory by a deduplicator.
+type hashState uint8
+
+const (
+	// the hash was added using addHash and no document with this hash was returned.
+	hashAdded hashState = iota + 1
+	// a document with this hash was returned, or will be returned by flush.
+	hashReturned
+)
+
+// tags appended to the hashes sorted by a deduplicator, so that hashes added using
+// addHash are sorted before the documents with the same hash.
+const (
+	hashTag byte = iota
+	documentTag
+)
+
+// A deduplicator returns the first occurrence of every document of a stream,
+// using the hashes of the documents.
+// With INTERSECT and EXCEPT, the hashes of the documents of the right-hand side
+// are added first, using addHash, and the documents of the left-hand side with
+// the same hashes are respectively the only ones returned, or never returned.
+//
+// Hashes are kept in memory until their size exceeds the buffer size. From then on,
+// documents whose hash is not in memory can't be checked right away: they are encoded
+// and sorted by hash, along with the hashes added using addHash, using an external
+// sort which writes them to temporary files if needed. Once the stream has been read,
+// flush removes the duplicates and returns the remaining documents in their original order.
+type deduplicator struct {
+	bufferSize int
+	codec      encoding.Codec
+	// if true, only the documents whose hash was added using addHash are returned.
+	intersect bool
+
+	hashes map[[sha256.Size]byte]hashState
+	size   int
+
+	// documents and hashes that didn't fit in memory, sorted by hash.
+	pending *sorter
+	seq     uint64
+	buf     bytes.Buffer
+}
+
+func newDeduplicator(bufferSize int, codec encoding.Codec, intersect bool) *deduplicator {
+	return &deduplicator{
+		bufferSize: bufferSize,
+		codec:      codec,
+		intersect:  intersect,
+		hashes:     make(map[[sha256.Size]byte]hashState),
+	}
+}
+
+// addHash adds the hash of a document of the right-hand side of INTERSECT or EXCEPT.
+func (dd *deduplicator) addHash(h [sha256.Size]byte) error {
+	if _, ok := dd.hashes[h]; ok {
+		return nil
+	}
+
+	if dd.pending == nil {
+		dd.hashes[h] = hashAdded
+		dd.grow()
+		return nil
+	}
+
+	return dd.pending.add(append(h[:], hashTag), nil)
+}
+
+// add reports whether d must be returned right away. Otherwise, d is either a duplicate,
+// or its hash is not in memory, in which case it is kept to be returned by flush if needed.
+func (dd *deduplicator) add(d document.Document, h [sha256.Size]byte) (bool, error) {
+	if st, ok := dd.hashes[h]; ok {
+		if st == hashReturned || !dd.intersect {
+			return false, nil
+		}
+
+		dd.hashes[h] = hashReturned
+		if dd.pending == nil {
+			return true, nil
+		}
+
+		// some hashes of the right-hand side are not in memory, so some documents
+		// are returned by flush: this one must be returned by flush as well to
+		// preserve the order of the documents.
+		err := dd.pending.add(append(h[:], hashTag), nil)
+		if err != nil {
+			return false, err
+		}
+
+		return dd.addPending(d, h)
+	}
+
+	if dd.pending == nil {
+		// every hash added using addHash is in memory.
+		if dd.intersect {
+			return false, nil
+		}
+
+		dd.hashes[h] = hashReturned
+		dd.grow()
+		return true, nil
+	}
+
+	return dd.addPending(d, h)
+}
+
+// addPending keeps d to be checked by flush.
+func (dd *deduplicator) addPending(d document.Document, h [sha256.Size]byte) (bool, error) {
+	// the document is encoded after its position in the stream,
+	// which is used to sort the documents back in order.
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], dd.seq)
+	dd.seq++
+
+	dd.buf.Reset()
+	dd.buf.Write(seq[:])
+	err := dd.codec.NewEncoder(&dd.buf).EncodeDocument(d)
+	if err != nil {
+		return false, err
+	}
+
+	return false, dd.pending.add(append(h[:], documentTag), append([]byte(nil), dd.buf.Bytes()...))
+}
+
+// grow accounts for a new hash kept in memory. Once the buffer size is exceeded,
+// no new hash is kept in memory.
+func (dd *deduplicator) grow() {
+	dd.size += sha256.Size + 1
+	if dd.size > dd.bufferSize {
+		dd.pending = &sorter{bufferSize: dd.bufferSize}
+	}
+}
+
+// flush calls fn with the documents kept by add that must be returned,
+// in the order they were added.
+func (dd *deduplicator) flush(fn func(d document.Document) error) error {
+	if dd.pending == nil {
+		return nil
+	}
+
+	ordered := sorter{bufferSize: dd.bufferSize}
+	defer ordered.close()
+
+	var cur []byte
+	var added, done bool
+	err := dd.pending.iterate(func(k, data []byte) error {
+		h, tag := k[:len(k)-1], k[len(k)-1]
+		if !bytes.Equal(h, cur) {
+			cur = h
+			added, done = false, false
+		}
+
+		if tag == hashTag {
+			added = true
+			return nil
+		}
+
+		// only the first document with a given hash can be returned.
+		if done {
+			return nil
+		}
+		done = true
+
+		if added != dd.intersect {
+			return nil
+		}
+
+		return ordered.add(data[:8], data[8:])
+	})
+	if err != nil {
+		return err
+	}
+
+	return ordered.iterate(func(_, data []byte) error {
+		return fn(dd.codec.NewDocument(data))
+	})
+}
+
+// close removes the temporary files, if any.
+func (dd *deduplicator) close() {
+	if dd.pending != nil {
+		dd.pending.close()
+	}
+}
@@ -3,6 +3,9 @@ package planner
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -13,8 +16,11 @@ import (
 // ExplainStmt is a query.Statement that
 // displays information about how a statement
 // is going to be executed, without executing it.
+// If Analyze is true, the statement is executed and the
+// runtime statistics of every node are displayed as well.
 type ExplainStmt struct {
 	Statement query.Statement
+	Analyze   bool
 }
 
 // Run analyses the inner statement and displays its execution plan.
@@ -24,6 +30,10 @@ type ExplainStmt struct {
 func (s *ExplainStmt) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
 	switch t := s.Statement.(type) {
 	case *Tree:
+		if s.Analyze {
+			return s.analyze(ctx, t, tx, params)
+		}
+
 		err := Bind(t, tx, params)
 		if err != nil {
 			return query.Result{}, err
@@ -34,23 +44,217 @@ func (s *ExplainStmt) Run(ctx context.Context, tx *database.Transaction, params
 			return query.Result{}, err
 		}
 
-		return s.createResult(t.String())
+		return s.createResult(document.NewFieldBuffer().Add("plan", document.NewTextValue(t.String())))
 	}
 
 	return query.Result{}, errors.New("EXPLAIN only works on SELECT, UPDATE AND DELETE statements")
 }
 
-func (s *ExplainStmt) createResult(text string) (query.Result, error) {
+// analyze runs the tree, discarding the documents it returns, and returns its plan
+// annotated with the statistics of every node, as text and as a list of documents.
+// The execution stops if ctx is canceled.
+func (s *ExplainStmt) analyze(ctx context.Context, t *Tree, tx *database.Transaction, params []expr.Param) (query.Result, error) {
+	var kc database.KeyCounter
+	// the keys read by the tables and indexes bound from now on are counted.
+	tx.CountKeys(&kc)
+	defer tx.CountKeys(nil)
+
+	t, err := t.prepare(tx, params)
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	if t.Root == nil {
+		return s.createResult(document.NewFieldBuffer().Add("plan", document.NewTextValue("")))
+	}
+
+	var stats []*NodeStats
+	p := profiler{kc: &kc}
+	st, err := analyzeNodeToStream(t.Root, &p, &stats)
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	err = st.Iterate(func(d document.Document) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		return nil
+	})
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	var text strings.Builder
+	var vb document.ValueBuffer
+	for i, ns := range stats {
+		if i > 0 {
+			ns.DocsIn = stats[i-1].DocsOut
+			text.WriteString("\n-> ")
+		}
+
+		text.WriteString(ns.String())
+		vb = vb.Append(document.NewDocumentValue(ns.ToDocument()))
+	}
+
+	return s.createResult(document.NewFieldBuffer().
+		Add("plan", document.NewTextValue(text.String())).
+		Add("nodes", document.NewArrayValue(vb)))
+}
+
+func (s *ExplainStmt) createResult(d document.Document) (query.Result, error) {
 	return query.Result{
-		Stream: document.NewStream(
-			document.NewIterator(
-				document.NewFieldBuffer().
-					Add("plan", document.NewTextValue(text)))),
+		Stream: document.NewStream(document.NewIterator(d)),
 	}, nil
 }
 
 // IsReadOnly indicates that this statement doesn't write anything into
-// the database.
+// the database, unless it executes the inner statement.
 func (s *ExplainStmt) IsReadOnly() bool {
-	return true
+	return !s.Analyze
+}
+
+// NodeStats are the runtime statistics of a node of a tree, collected by EXPLAIN ANALYZE.
+type NodeStats struct {
+	// Node is the string representation of the node.
+	Node string
+	// DocsIn is the number of documents received from the node below.
+	DocsIn int64
+	// DocsOut is the number of documents returned by the node.
+	DocsOut int64
+	// Duration is the time spent by the node, excluding the nodes below.
+	Duration time.Duration
+	// TableKeys and IndexKeys are the number of keys read by the node from the stores
+	// of the tables and of the indexes, excluding the nodes below.
+	TableKeys int64
+	IndexKeys int64
+
+	input bool
+}
+
+func (ns *NodeStats) String() string {
+	var b strings.Builder
+
+	b.WriteString(ns.Node)
+	b.WriteString(" (")
+	if !ns.input {
+		fmt.Fprintf(&b, "in: %d, ", ns.DocsIn)
+	}
+	fmt.Fprintf(&b, "out: %d, time: %s", ns.DocsOut, ns.Duration)
+	if ns.TableKeys > 0 {
+		fmt.Fprintf(&b, ", table keys: %d", ns.TableKeys)
+	}
+	if ns.IndexKeys > 0 {
+		fmt.Fprintf(&b, ", index keys: %d", ns.IndexKeys)
+	}
+	b.WriteString(")")
+
+	return b.String()
+}
+
+// ToDocument returns a document representing the statistics.
+// The duration is expressed in microseconds.
+func (ns *NodeStats) ToDocument() document.Document {
+	return document.NewFieldBuffer().
+		Add("node", document.NewTextValue(ns.Node)).
+		Add("in", document.NewIntegerValue(ns.DocsIn)).
+		Add("out", document.NewIntegerValue(ns.DocsOut)).
+		Add("time_us", document.NewIntegerValue(ns.Duration.Microseconds())).
+		Add("table_keys", document.NewIntegerValue(ns.TableKeys)).
+		Add("index_keys", document.NewIntegerValue(ns.IndexKeys))
+}
+
+// analyzeNodeToStream works like nodeToStream but every stream is wrapped to collect
+// the statistics of its node, which are appended to stats, from the input node to n.
+func analyzeNodeToStream(n Node, p *profiler, stats *[]*NodeStats) (st document.Stream, err error) {
+	l := n.Left()
+	if l != nil {
+		st, err = analyzeNodeToStream(l, p, stats)
+		if err != nil {
+			return
+		}
+	}
+
+	ns := NodeStats{
+		Node: fmt.Sprintf("%v", n),
+	}
+
+	// some nodes, like the deletion node, do their work while
+	// creating their stream.
+	prev := p.switchTo(&ns)
+	switch t := n.(type) {
+	case inputNode:
+		ns.input = true
+		st, err = t.buildStream()
+	case operationNode:
+		st, err = t.toStream(st)
+	default:
+		panic(fmt.Sprintf("incorrect node type %#v", n))
+	}
+	p.switchTo(prev)
+	if err != nil {
+		return
+	}
+
+	*stats = append(*stats, &ns)
+
+	return document.NewStream(&analyzeIterator{st: st, stats: &ns, p: p}), nil
+}
+
+// A profiler measures the time spent and the keys read by each node of a tree.
+// Only one node runs at a time: the time and the keys are attributed to the running
+// node until another one starts running, so that the statistics of a node
+// never include those of the nodes below or above it.
+type profiler struct {
+	kc *database.KeyCounter
+
+	current              *NodeStats
+	since                time.Time
+	tableKeys, indexKeys int64
+}
+
+// switchTo attributes the time spent and the keys read since the last call to the
+// current node, and makes ns the current node. It returns the previous current node.
+// A nil ns means that no node is running.
+func (p *profiler) switchTo(ns *NodeStats) *NodeStats {
+	now := time.Now()
+	if p.current != nil {
+		p.current.Duration += now.Sub(p.since)
+		p.current.TableKeys += p.kc.TableKeys - p.tableKeys
+		p.current.IndexKeys += p.kc.IndexKeys - p.indexKeys
+	}
+
+	prev := p.current
+	p.current = ns
+	p.since = now
+	p.tableKeys = p.kc.TableKeys
+	p.indexKeys = p.kc.IndexKeys
+	return prev
+}
+
+// analyzeIterator makes its node the running node of the profiler while
+// iterating over its stream, except while the function receiving the documents runs.
+type analyzeIterator struct {
+	st    document.Stream
+	stats *NodeStats
+	p     *profiler
+}
+
+func (it *analyzeIterator) Iterate(fn func(d document.Document) error) error {
+	caller := it.p.switchTo(it.stats)
+
+	err := it.st.Iterate(func(d document.Document) error {
+		it.stats.DocsOut++
+		it.p.switchTo(caller)
+
+		err := fn(d)
+		it.p.switchTo(it.stats)
+		return err
+	})
+
+	it.p.switchTo(caller)
+	return err
 }
@@ -2,9 +2,12 @@ package planner_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
 	"github.com/stretchr/testify/require"
 )
 
@@ -24,14 +27,60 @@ func TestExplainStmt(t *testing.T) {
 		{"EXPLAIN SELECT a + 1 FROM test WHERE c IN [1 + 1, 2 + 2]", false, `"Table(test) -> σ(cond: c IN [2, 4]) -> ∏(a + 1)"`},
 		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10", false, `"Index(idx_a) -> ∏(a + 1)"`},
 		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND b > 20 AND c > 30", false, `"Index(idx_b) -> σ(cond: c > 30) -> σ(cond: a > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 GROUP BY b ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> G(b) -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a =~ '^abc'", false, `"Index(idx_a) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a =~ 'abc'", false, `"Table(test) -> σ(cond: a =~ \"abc\") -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a !~ '^abc'", false, `"Table(test) -> σ(cond: a !~ \"^abc\") -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Index(idx_a, DESC) -> σ(cond: c > 30) -> ∏(a + 1) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 GROUP BY b ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> G(b) -> Aggregate() -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN SELECT b, COUNT(*) FROM test GROUP BY b, c HAVING COUNT(*) > 1", false, `"Table(test) -> G(b, c) -> Aggregate(COUNT(*)) -> σ(cond: COUNT(*) > 1) -> ∏(b, COUNT(*))"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY a DESC, lower(b), c NULLS LAST", false, `"Table(test) -> ∏(*) -> Sort(a DESC, LOWER(b) ASC, c ASC NULLS LAST)"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY a LIMIT 10", false, `"Index(idx_a) -> ∏(*) -> Limit(10)"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY a NULLS LAST", false, `"Table(test) -> ∏(*) -> Sort(a ASC NULLS LAST)"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY b DESC, c", false, `"Index(idx_b, DESC) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY k DESC", false, `"Table(test, DESC) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY k, a", false, `"Table(test) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE a > 10 ORDER BY a", false, `"Index(idx_a) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE a > 10 ORDER BY a DESC", false, `"Index(idx_a) -> ∏(*) -> Sort(a DESC)"`},
+		{"EXPLAIN SELECT * FROM test WHERE k > 10 ORDER BY k", false, `"PrimaryKey(test) -> ∏(*)"`},
+		{"EXPLAIN SELECT c AS a FROM test ORDER BY a", false, `"Table(test) -> ∏(c) -> Sort(a ASC)"`},
+		{"EXPLAIN SELECT MIN(a) FROM test", false, `"Index(idx_a) -> σ(cond: a IS NOT NULL) -> Limit(1) -> Aggregate(MIN(a)) -> ∏(MIN(a))"`},
+		{"EXPLAIN SELECT MAX(a) FROM test WHERE c = 1", false, `"Index(idx_a, DESC) -> σ(cond: c = 1) -> σ(cond: a IS NOT NULL) -> Limit(1) -> Aggregate(MAX(a)) -> ∏(MAX(a))"`},
+		{"EXPLAIN SELECT MAX(k) FROM test", false, `"Table(test, DESC) -> Limit(1) -> Aggregate(MAX(k)) -> ∏(MAX(k))"`},
+		{"EXPLAIN SELECT MIN(a), MAX(a) FROM test", false, `"Table(test) -> Aggregate(MIN(a), MAX(a)) -> ∏(MIN(a), MAX(a))"`},
+		{"EXPLAIN SELECT MIN(c) FROM test", false, `"Table(test) -> Aggregate(MIN(c)) -> ∏(MIN(c))"`},
 		{"EXPLAIN UPDATE test SET a = 10", false, `"Table(test) -> Set(a = 10) -> Replace(test)"`},
 		{"EXPLAIN UPDATE test SET a = 10 WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> Set(a = 10) -> Replace(test)"`},
 		{"EXPLAIN UPDATE test SET a = 10 WHERE a > 10", false, `"Index(idx_a) -> Set(a = 10) -> Replace(test)"`},
 		{"EXPLAIN DELETE FROM test", false, `"Table(test) -> Delete(test)"`},
 		{"EXPLAIN DELETE FROM test WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> Delete(test)"`},
 		{"EXPLAIN DELETE FROM test WHERE a > 10", false, `"Index(idx_a) -> Delete(test)"`},
+		{"EXPLAIN SELECT * FROM test JOIN other ON test.c = other.c", false, `"Table(test) -> InnerJoin(Table(other), cond: test.c = other.c, strategy: nested loop) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test LEFT JOIN other ON test.c = other.a", false, `"Table(test) -> LeftJoin(Table(other), cond: test.c = other.a, strategy: index(idx_other_a)) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test JOIN other ON other.k = test.a WHERE test.a > 10", false, `"Index(idx_a) -> InnerJoin(Table(other), cond: other.k = test.a, strategy: pk) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test JOIN other ON other.k = test.a WHERE other.a > 10 AND test.c > 10", false, `"Table(test) -> InnerJoin(Table(other), cond: other.k = test.a, strategy: pk) -> σ(cond: test.c > 10) -> σ(cond: other.a > 10) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE b = 1 AND c > 2 AND d = 3", false, `"Index(idx_other_b_c) -> σ(cond: d = 3) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE c > 2", false, `"Table(other) -> σ(cond: c > 2) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE d = 1", false, `"Table(other) -> σ(cond: d = 1) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE d = 1 AND status = 'pending'", false, `"Index(idx_other_d) -> σ(cond: status = \"pending\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE e > 20", false, `"Index(idx_other_e) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE e > 5", false, `"Table(other) -> σ(cond: e > 5) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE lower(f) = 'foo'", false, `"Index(other_lower_f_idx) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE 'foo' = lower(f)", false, `"Table(other) -> σ(cond: \"foo\" = LOWER(f)) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE upper(f) = 'FOO'", false, `"Table(other) -> σ(cond: UPPER(f) = \"FOO\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE 'foo' IN tags", false, `"Index(idx_other_tags) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE contains(tags, 'foo') AND a > 1", false, `"Index(idx_other_tags) -> σ(cond: a > 1) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE tags IN ['foo']", false, `"Table(other) -> σ(cond: tags IN [\"foo\"]) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE 1 IN a", false, `"Table(test) -> σ(cond: 1 IN a) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE match(g, 'foo bar') AND a > 1", false, `"Index(idx_other_g) -> σ(cond: a > 1) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE match(f, 'foo')", false, `"Table(other) -> σ(cond: MATCH(f, \"foo\")) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM other WHERE g = 'foo'", false, `"Table(other) -> σ(cond: g = \"foo\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE a > 10 OR b = 5", false, `"IndexUnion(Index(idx_a), Index(idx_b)) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE a > 10 OR (b = 5 OR k = 1)", false, `"IndexUnion(Index(idx_a), Index(idx_b), PrimaryKey(test)) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE a > 10 OR c = 5", false, `"Table(test) -> σ(cond: a > 10 OR c = 5) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE (a > 10 OR b = 5) AND c = 5", false, `"IndexUnion(Index(idx_a), Index(idx_b)) -> σ(cond: c = 5) -> ∏(*)"`},
+		{"EXPLAIN DELETE FROM test WHERE a > 10 OR b = 5", false, `"IndexUnion(Index(idx_a), Index(idx_b)) -> Delete(test)"`},
+		{"EXPLAIN SELECT DISTINCT c FROM test", false, `"Table(test) -> ∏(c) -> Distinct()"`},
+		{"EXPLAIN SELECT c FROM test WHERE a > 10 UNION SELECT c FROM other WHERE c > 2 ORDER BY c", false, `"Union(Index(idx_a) -> ∏(c), Table(other) -> σ(cond: c > 2) -> ∏(c)) -> Sort(c ASC)"`},
 	}
 
 	for _, test := range tests {
@@ -47,6 +96,14 @@ func TestExplainStmt(t *testing.T) {
 			err = db.Exec(ctx, `
 						CREATE INDEX idx_a ON test (a);
 						CREATE UNIQUE INDEX idx_b ON test (b);
+						CREATE TABLE other (k INTEGER PRIMARY KEY);
+						CREATE INDEX idx_other_a ON other (a);
+						CREATE INDEX idx_other_b_c ON other (b, c);
+						CREATE INDEX idx_other_d ON other (d) WHERE status = 'pending';
+						CREATE INDEX idx_other_e ON other (e) WHERE e >= 10;
+						CREATE INDEX ON other (lower(f));
+						CREATE INDEX idx_other_tags ON other (tags[*]);
+						CREATE FULLTEXT INDEX idx_other_g ON other (g);
 					`)
 			require.NoError(t, err)
 
@@ -64,3 +121,234 @@ func TestExplainStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainStmtWithStatistics(t *testing.T) {
+	tests := []struct {
+		query    string
+		analyze  bool
+		expected string
+	}{
+		{"EXPLAIN SELECT * FROM users WHERE status = 'active' AND email = 'a@b.c'", false, `"Index(idx_users_status) -> σ(cond: email = \"a@b.c\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE status = 'active' AND email = 'a@b.c'", true, `"Index(idx_users_email) -> σ(cond: status = \"active\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE status = 'active'", false, `"Index(idx_users_status) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE status = 'active'", true, `"Table(users) -> σ(cond: status = \"active\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE k = 10", false, `"PrimaryKey(users) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE k = 10 AND email = 'a@b.c'", true, `"PrimaryKey(users) -> σ(cond: email = \"a@b.c\") -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE k > 90", true, `"PrimaryKey(users) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE k > 90 AND age > 80", true, `"PrimaryKey(users) -> σ(cond: age > 80) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE k > 10 AND age > 95", true, `"Index(idx_users_age) -> σ(cond: k > 10) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE age < 95", true, `"Table(users) -> σ(cond: age < 95) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE age < 95", false, `"Index(idx_users_age) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE email = 'a@b.c' OR age > 95", true, `"IndexUnion(Index(idx_users_email), Index(idx_users_age)) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM users WHERE status = 'active' OR age > 95", true, `"Table(users) -> σ(cond: status = \"active\" OR age > 95) -> ∏(*)"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE users (k INTEGER PRIMARY KEY);
+				CREATE INDEX idx_users_status ON users (status);
+				CREATE INDEX idx_users_email ON users (email);
+				CREATE INDEX idx_users_age ON users (age);
+			`)
+			require.NoError(t, err)
+
+			for i := 0; i < 100; i++ {
+				status := "active"
+				if i%2 == 0 {
+					status = "disabled"
+				}
+
+				err = db.Exec(ctx, "INSERT INTO users (k, email, status, age) VALUES (?, ?, ?, ?)", i, fmt.Sprintf("user%d@example.com", i), status, i)
+				require.NoError(t, err)
+			}
+
+			if test.analyze {
+				err = db.Exec(ctx, "ANALYZE users")
+				require.NoError(t, err)
+			}
+
+			d, err := db.QueryDocument(ctx, test.query)
+			require.NoError(t, err)
+
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+
+			require.JSONEq(t, test.expected, v.String())
+		})
+	}
+}
+
+func TestExplainStmtWithStaleStatistics(t *testing.T) {
+	tests := []struct {
+		query string
+		// number of documents of the table when it is analyzed
+		analyzed int
+		expected string
+	}{
+		{"EXPLAIN SELECT * FROM test WHERE id = 42", 1, `"PrimaryKey(test) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE name = 'n42'", 1, `"Index(idx_test_name) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE id = 42", 0, `"PrimaryKey(test) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test WHERE name = 'n42'", 0, `"Index(idx_test_name) -> ∏(*)"`},
+		// statistics of an empty table are ignored.
+		{"EXPLAIN SELECT * FROM test WHERE age = 42", 0, `"Index(idx_test_age) -> ∏(*)"`},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%d", test.query, test.analyzed), func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test (id INTEGER PRIMARY KEY);
+				CREATE UNIQUE INDEX idx_test_name ON test (name);
+				CREATE INDEX idx_test_age ON test (age);
+			`)
+			require.NoError(t, err)
+
+			insert := func(from, to int) {
+				for i := from; i < to; i++ {
+					err = db.Exec(ctx, "INSERT INTO test (id, name, age) VALUES (?, ?, ?)", i, fmt.Sprintf("n%d", i), i)
+					require.NoError(t, err)
+				}
+			}
+
+			// the table is analyzed while it contains only a few documents.
+			insert(0, test.analyzed)
+			err = db.Exec(ctx, "ANALYZE test")
+			require.NoError(t, err)
+			insert(test.analyzed, 300)
+
+			d, err := db.QueryDocument(ctx, test.query)
+			require.NoError(t, err)
+
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+
+			require.JSONEq(t, test.expected, v.String())
+		})
+	}
+}
+
+func TestExplainAnalyzeStmt(t *testing.T) {
+	type nodeStats struct {
+		Node      string `genji:"node"`
+		In        int64  `genji:"in"`
+		Out       int64  `genji:"out"`
+		TableKeys int64  `genji:"table_keys"`
+		IndexKeys int64  `genji:"index_keys"`
+	}
+
+	tests := []struct {
+		query    string
+		expected []nodeStats
+		// number of documents left in the table
+		count int
+	}{
+		{"EXPLAIN ANALYZE SELECT * FROM test", []nodeStats{
+			{Node: "Table(test)", Out: 10, TableKeys: 10},
+			{Node: "∏(*)", In: 10, Out: 10},
+		}, 10},
+		{"EXPLAIN ANALYZE SELECT a FROM test WHERE b > 5", []nodeStats{
+			{Node: "Table(test)", Out: 10, TableKeys: 10},
+			{Node: "σ(cond: b > 5)", In: 10, Out: 4},
+			{Node: "∏(a)", In: 4, Out: 4},
+		}, 10},
+		{"EXPLAIN ANALYZE SELECT * FROM test WHERE a > 5", []nodeStats{
+			// the index is positioned on the first value equal to 5.
+			{Node: "Index(idx_a)", Out: 4, TableKeys: 4, IndexKeys: 5},
+			{Node: "∏(*)", In: 4, Out: 4},
+		}, 10},
+		{"EXPLAIN ANALYZE SELECT * FROM test WHERE b < 5 LIMIT 2", []nodeStats{
+			// the limit node stops the stream when it receives a third document.
+			{Node: "Table(test)", Out: 3, TableKeys: 3},
+			{Node: "σ(cond: b < 5)", In: 3, Out: 3},
+			{Node: "∏(*)", In: 3, Out: 3},
+			{Node: "Limit(2)", In: 3, Out: 2},
+		}, 10},
+		{"EXPLAIN ANALYZE DELETE FROM test WHERE a >= 7", []nodeStats{
+			{Node: "Index(idx_a)", Out: 3, TableKeys: 3, IndexKeys: 3},
+			// deleting a document reads it again and reads its index entry.
+			{Node: "Delete(test)", In: 3, TableKeys: 3, IndexKeys: 3},
+		}, 7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+
+			err = db.Exec(ctx, "CREATE TABLE test (k INTEGER PRIMARY KEY); CREATE INDEX idx_a ON test (a)")
+			require.NoError(t, err)
+
+			for i := 0; i < 10; i++ {
+				err = db.Exec(ctx, "INSERT INTO test (k, a, b) VALUES (?, ?, ?)", i, i, i)
+				require.NoError(t, err)
+			}
+
+			d, err := db.QueryDocument(ctx, test.query)
+			require.NoError(t, err)
+
+			v, err := d.GetByField("nodes")
+			require.NoError(t, err)
+
+			var nodes []nodeStats
+			err = v.V.(document.Array).Iterate(func(i int, v document.Value) error {
+				var ns nodeStats
+				err := document.StructScan(v.V.(document.Document), &ns)
+				nodes = append(nodes, ns)
+				return err
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.expected, nodes)
+
+			v, err = d.GetByField("plan")
+			require.NoError(t, err)
+			for _, ns := range test.expected {
+				require.Contains(t, v.V.(string), ns.Node+" (")
+			}
+
+			d, err = db.QueryDocument(ctx, "SELECT COUNT(*) FROM test")
+			require.NoError(t, err)
+
+			var count int
+			err = document.Scan(d, &count)
+			require.NoError(t, err)
+			require.Equal(t, test.count, count)
+		})
+	}
+}
+
+func TestExplainAnalyzeStmtCanceled(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(context.Background(), "CREATE TABLE test; INSERT INTO test (a) VALUES (1), (2)")
+	require.NoError(t, err)
+
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	q, err := parser.ParseQuery(context.Background(), "EXPLAIN ANALYZE SELECT * FROM test")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = q.Statements[0].Run(ctx, tx.Transaction, nil)
+	require.Equal(t, context.Canceled, err)
+}
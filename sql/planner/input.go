@@ -1,11 +1,16 @@
 package planner
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index"
+	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -17,6 +22,8 @@ type tableInputNode struct {
 	table     *database.Table
 	tx        *database.Transaction
 	params    []expr.Param
+	// documents are read in decreasing order of keys if set to DESC.
+	orderByDirection scanner.Token
 }
 
 var _ inputNode = (*tableInputNode)(nil)
@@ -39,16 +46,39 @@ func (n *tableInputNode) Bind(tx *database.Transaction, params []expr.Param) (er
 	return
 }
 
+// TableName returns the name of the table read by this node.
+func (n *tableInputNode) TableName() string {
+	return n.tableName
+}
+
 func (n *tableInputNode) String() string {
+	if n.orderByDirection == scanner.DESC {
+		return fmt.Sprintf("Table(%s, DESC)", n.tableName)
+	}
+
 	return fmt.Sprintf("Table(%s)", n.tableName)
 }
 
 func (n *tableInputNode) buildStream() (document.Stream, error) {
+	if n.orderByDirection == scanner.DESC {
+		return document.NewStream(descendingTable{n.table}), nil
+	}
+
 	return document.NewStream(n.table), nil
 }
 
+// descendingTable reads the documents of a table in decreasing order of keys.
+type descendingTable struct {
+	*database.Table
+}
+
+func (t descendingTable) Iterate(fn func(d document.Document) error) error {
+	return t.DescendLessOrEqual(nil, fn)
+}
+
 type indexInputNode struct {
 	node
+	evalCache
 
 	tableName string
 	indexName string
@@ -79,18 +109,16 @@ func NewIndexInputNode(tableName, indexName string, iop IndexIteratorOperator, f
 }
 
 func (n *indexInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
-	if n.table == nil {
-		n.table, err = tx.GetTable(n.tableName)
-		if err != nil {
-			return
-		}
+	// the node may be bound again with another transaction
+	// if the tree is executed more than once.
+	n.table, err = tx.GetTable(n.tableName)
+	if err != nil {
+		return
 	}
 
-	if n.index == nil {
-		n.index, err = tx.GetIndex(n.indexName)
-		if err != nil {
-			return
-		}
+	n.index, err = tx.GetIndex(n.indexName)
+	if err != nil {
+		return
 	}
 
 	n.tx = tx
@@ -100,16 +128,27 @@ func (n *indexInputNode) Bind(tx *database.Transaction, params []expr.Param) (er
 
 func (n *indexInputNode) buildStream() (document.Stream, error) {
 	return document.NewStream(&indexIterator{
-		tx:     n.tx,
-		tb:     n.table,
-		params: n.params,
-		index:  n.index,
-		e:      n.e,
-		iop:    n.iop,
+		tx:               n.tx,
+		tb:               n.table,
+		params:           n.params,
+		cache:            n.cache,
+		index:            n.index,
+		e:                n.e,
+		iop:              n.iop,
+		orderByDirection: n.orderByDirection,
 	}), nil
 }
 
+// TableName returns the name of the table read by this node.
+func (n *indexInputNode) TableName() string {
+	return n.tableName
+}
+
 func (n *indexInputNode) String() string {
+	if n.orderByDirection == scanner.DESC {
+		return fmt.Sprintf("Index(%s, DESC)", n.indexName)
+	}
+
 	return fmt.Sprintf("Index(%s)", n.indexName)
 }
 
@@ -119,10 +158,223 @@ type IndexIteratorOperator interface {
 	IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error
 }
 
+// compositeIndexOperator reads documents from a composite index.
+// It is used with an array containing the values the first eq fields of the index
+// must be equal to, followed, if rangeOp is set, by the value the next field
+// is compared to using rangeOp.
+type compositeIndexOperator struct {
+	eq      int
+	rangeOp scanner.Token
+}
+
+var _ IndexIteratorOperator = compositeIndexOperator{}
+
+func (op compositeIndexOperator) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	var values []document.Value
+	err := v.V.(document.Array).Iterate(func(_ int, v document.Value) error {
+		values = append(values, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// NULL never matches anything
+	for _, v := range values {
+		if v.Type == document.NullValue {
+			return nil
+		}
+	}
+
+	getDocument := func(k []byte) error {
+		d, err := tb.GetDocument(k)
+		if err != nil {
+			return err
+		}
+
+		return fn(d)
+	}
+
+	// if every field is compared using equality, only the arrays
+	// that are equal to the given values match.
+	if op.rangeOp == 0 && op.eq == len(idx.Opts.Paths) {
+		err = idx.AscendGreaterOrEqual(document.NewArrayValue(document.NewValueBuffer(values...)), func(val, key []byte, isEqual bool) error {
+			if !isEqual {
+				return errStop
+			}
+
+			return getDocument(key)
+		})
+	} else {
+		err = idx.AscendPrefix(values[:op.eq], op.pivot(values), func(val, key []byte) error {
+			if op.rangeOp != 0 {
+				ok, err := op.inRange(val, values[op.eq])
+				if err != nil || !ok {
+					return err
+				}
+			}
+
+			return getDocument(key)
+		})
+	}
+	if err != nil && err != errStop {
+		return err
+	}
+
+	return nil
+}
+
+// pivot returns the value from which the iteration of the index must start.
+func (op compositeIndexOperator) pivot(values []document.Value) document.Value {
+	switch op.rangeOp {
+	case scanner.GT, scanner.GTE:
+		v := values[op.eq]
+		// integers are encoded before the doubles that have the same integer part.
+		if v.Type == document.DoubleValue {
+			if x := v.V.(float64); x >= math.MinInt64 && x < math.MaxInt64 {
+				return document.NewIntegerValue(int64(x))
+			}
+		}
+
+		return v
+	case scanner.LT, scanner.LTE:
+		return document.Value{Type: values[op.eq].Type}
+	}
+
+	return document.Value{}
+}
+
+// inRange decodes the indexed array and compares the element following the prefix
+// with v. It returns false if the element doesn't match and errStop if no other
+// element can match.
+func (op compositeIndexOperator) inRange(val []byte, v document.Value) (bool, error) {
+	av, err := key.DecodeValue(val)
+	if err != nil {
+		return false, err
+	}
+
+	elem, err := av.V.(document.Array).GetByIndex(op.eq)
+	if err != nil {
+		return false, err
+	}
+
+	// elements are sorted by type first, all the elements of the type of v
+	// have been read.
+	if elem.Type != v.Type && !(elem.Type.IsNumber() && v.Type.IsNumber()) {
+		return false, errStop
+	}
+
+	switch op.rangeOp {
+	case scanner.GT:
+		return elem.IsGreaterThan(v)
+	case scanner.GTE:
+		return elem.IsGreaterThanOrEqual(v)
+	case scanner.LT:
+		ok, err := elem.IsLesserThan(v)
+		if err == nil && !ok {
+			err = errStop
+		}
+		return ok, err
+	case scanner.LTE:
+		ok, err := elem.IsLesserThanOrEqual(v)
+		if err == nil && !ok {
+			err = errStop
+		}
+		return ok, err
+	}
+
+	return false, nil
+}
+
+// NewMatchFunc creates the MATCH function, whose queries are parsed by the index package.
+// See index.ParseFullTextQuery for the syntax of queries.
+func NewMatchFunc(args ...expr.Expr) (expr.Expr, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("MATCH() takes 2 arguments")
+	}
+
+	return expr.MatchFunc{Field: args[0], Query: args[1], Parser: fullTextParser{}}, nil
+}
+
+// fullTextParser parses full-text queries using the index package.
+type fullTextParser struct{}
+
+func (fullTextParser) ParseFullTextQuery(q string) (expr.FullTextQuery, error) {
+	fq, err := index.ParseFullTextQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return fq, nil
+}
+
+// multiIndexOperator reads the documents whose indexed array contains a value
+// from a multi-valued index.
+type multiIndexOperator struct{}
+
+var _ IndexIteratorOperator = multiIndexOperator{}
+
+func (op multiIndexOperator) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	// numbers are stored in the index the same way they must be looked up.
+	v = database.MultiIndexValue(v)
+
+	return expr.Eq(nil, nil).(IndexIteratorOperator).IterateIndex(idx, tb, v, fn)
+}
+
+// matchOperator reads the documents matching a full-text query from a full-text index,
+// by decreasing relevance score.
+type matchOperator struct{}
+
+var _ IndexIteratorOperator = matchOperator{}
+
+func (op matchOperator) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	// NULL never matches anything
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	if v.Type != document.TextValue {
+		return errors.New("MATCH(): the query must be a text")
+	}
+
+	q, err := index.ParseFullTextQuery(v.V.(string))
+	if err != nil {
+		return err
+	}
+
+	return idx.FullText().Search(q, func(k []byte, score float64) error {
+		d, err := tb.GetDocument(k)
+		if err != nil {
+			return err
+		}
+
+		return fn(scoredDocument{Document: d, score: score})
+	})
+}
+
+// A scoredDocument is a document selected by a full-text index,
+// associated with its relevance score.
+type scoredDocument struct {
+	document.Document
+
+	score float64
+}
+
+// Key returns the key of the underlying document.
+func (d scoredDocument) Key() []byte {
+	return d.Document.(document.Keyer).Key()
+}
+
+// Score returns the relevance score of the document.
+func (d scoredDocument) Score() (float64, bool) {
+	return d.score, true
+}
+
 type indexIterator struct {
 	tx               *database.Transaction
 	tb               *database.Table
 	params           []expr.Param
+	cache            map[expr.Expr]interface{}
 	index            *database.Index
 	iop              IndexIteratorOperator
 	e                expr.Expr
@@ -161,6 +413,7 @@ func (it indexIterator) Iterate(fn func(d document.Document) error) error {
 	v, err := it.e.Eval(expr.EvalStack{
 		Tx:     it.tx,
 		Params: it.params,
+		Cache:  it.cache,
 	})
 	if err != nil {
 		return err
@@ -168,3 +421,216 @@ func (it indexIterator) Iterate(fn func(d document.Document) error) error {
 
 	return it.iop.IterateIndex(it.index, it.tb, v, fn)
 }
+
+type pkInputNode struct {
+	node
+	evalCache
+
+	tableName string
+	pop       PKIteratorOperator
+	op        scanner.Token
+	e         expr.Expr
+
+	tx     *database.Transaction
+	params []expr.Param
+	table  *database.Table
+	pk     *database.FieldConstraint
+}
+
+var _ inputNode = (*pkInputNode)(nil)
+
+// NewPrimaryKeyInputNode creates a node that only reads the documents of a table whose primary key
+// is compared successfully to the result of e, using pop.
+// The primary key of the table must be typed.
+func NewPrimaryKeyInputNode(tableName string, pop PKIteratorOperator, e expr.Expr) Node {
+	return &pkInputNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		pop:       pop,
+		op:        pop.Token(),
+		e:         e,
+	}
+}
+
+func (n *pkInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.table, err = tx.GetTable(n.tableName)
+	if err != nil {
+		return
+	}
+
+	info, err := n.table.Info()
+	if err != nil {
+		return
+	}
+
+	n.pk = info.GetPrimaryKey()
+	if n.pk == nil || n.pk.Type == 0 {
+		return fmt.Errorf("table %q doesn't have a typed primary key", n.tableName)
+	}
+
+	n.tx = tx
+	n.params = params
+	return
+}
+
+func (n *pkInputNode) buildStream() (document.Stream, error) {
+	return document.NewStream(pkIterator{pkInputNode: n, cache: n.cache}), nil
+}
+
+// TableName returns the name of the table read by this node.
+func (n *pkInputNode) TableName() string {
+	return n.tableName
+}
+
+func (n *pkInputNode) String() string {
+	return fmt.Sprintf("PrimaryKey(%s)", n.tableName)
+}
+
+// PKIteratorOperator is an operator that can be used
+// to read documents using the primary key of a table.
+type PKIteratorOperator interface {
+	Token() scanner.Token
+	IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error
+}
+
+type pkIterator struct {
+	*pkInputNode
+
+	cache map[expr.Expr]interface{}
+}
+
+func (it pkIterator) Iterate(fn func(d document.Document) error) error {
+	v, err := it.e.Eval(expr.EvalStack{
+		Tx:     it.tx,
+		Params: it.params,
+		Cache:  it.cache,
+	})
+	if err != nil {
+		return err
+	}
+
+	return it.pop.IteratePK(it.table, v, it.pk.Type, fn)
+}
+
+type indexUnionNode struct {
+	node
+
+	tableName string
+	inputs    []inputNode
+	// condition read by each input, only used to estimate their cost.
+	conds []expr.Expr
+
+	tx    *database.Transaction
+	table *database.Table
+}
+
+var _ inputNode = (*indexUnionNode)(nil)
+
+// NewIndexUnionNode creates a node that reads the documents of a table returned by any of the given
+// input nodes, which must read the same table using an index or the primary key.
+// The keys of the documents returned by the inputs are deduplicated first, then the documents
+// are fetched in the order of their keys.
+func NewIndexUnionNode(tableName string, inputs ...Node) Node {
+	n := indexUnionNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		inputs:    make([]inputNode, len(inputs)),
+	}
+
+	for i, in := range inputs {
+		n.inputs[i] = in.(inputNode)
+	}
+
+	return &n
+}
+
+func (n *indexUnionNode) setCache(cache map[expr.Expr]interface{}) {
+	for _, in := range n.inputs {
+		bindNodeCache(in, cache)
+	}
+}
+
+func (n *indexUnionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.table, err = tx.GetTable(n.tableName)
+	if err != nil {
+		return
+	}
+
+	for _, in := range n.inputs {
+		err = in.Bind(tx, params)
+		if err != nil {
+			return
+		}
+	}
+
+	n.tx = tx
+	return
+}
+
+func (n *indexUnionNode) buildStream() (document.Stream, error) {
+	return document.NewStream(indexUnionIterator{n}), nil
+}
+
+// TableName returns the name of the table read by this node.
+func (n *indexUnionNode) TableName() string {
+	return n.tableName
+}
+
+func (n *indexUnionNode) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("IndexUnion(")
+	for i, in := range n.inputs {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%v", in)
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+type indexUnionIterator struct {
+	*indexUnionNode
+}
+
+func (it indexUnionIterator) Iterate(fn func(d document.Document) error) error {
+	// the keys are sorted using an external sort,
+	// so that every document is read once, in the order of the table.
+	keys := sorter{bufferSize: it.tx.DB().SortBufferSize}
+	defer keys.close()
+
+	for _, in := range it.inputs {
+		st, err := in.buildStream()
+		if err != nil {
+			return err
+		}
+
+		err = st.Iterate(func(d document.Document) error {
+			return keys.add(append([]byte(nil), d.(document.Keyer).Key()...), nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var prev []byte
+	return keys.iterate(func(k, _ []byte) error {
+		if prev != nil && bytes.Equal(k, prev) {
+			return nil
+		}
+		prev = k
+
+		d, err := it.table.GetDocument(k)
+		if err != nil {
+			return err
+		}
+
+		return fn(d)
+	})
+}
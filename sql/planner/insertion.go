@@ -0,0 +1,84 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+type insertionNode struct {
+	node
+
+	stmt   query.InsertStmt
+	tx     *database.Transaction
+	params []expr.Param
+	table  *database.Table
+}
+
+var _ inputNode = (*insertionNode)(nil)
+
+// NewInsertionNode creates an input node that runs the given insert statement
+// and returns a stream of the documents it inserted or updated.
+func NewInsertionNode(stmt query.InsertStmt) Node {
+	return &insertionNode{
+		node: node{
+			op: Insertion,
+		},
+		stmt: stmt,
+	}
+}
+
+func (n *insertionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+	n.params = params
+	n.table, err = tx.GetTable(n.stmt.TableName)
+	return
+}
+
+// buildStream inserts the documents when called, and returns a stream
+// of copies of the stored documents, taken after each write.
+func (n *insertionNode) buildStream() (document.Stream, error) {
+	var docs []document.Document
+
+	_, err := n.stmt.Insert(n.tx, n.params, func(key []byte) error {
+		d, err := n.table.GetDocument(key)
+		if err != nil {
+			return err
+		}
+
+		var fb document.FieldBuffer
+		err = fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		docs = append(docs, &encodedDocumentWithKey{Document: &fb, key: append([]byte(nil), key...)})
+		return nil
+	})
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	return document.NewStream(document.NewIterator(docs...)), nil
+}
+
+func (n *insertionNode) String() string {
+	return fmt.Sprintf("Insert(%s)", n.stmt.TableName)
+}
+
+// NewReturningNode creates a projection node that returns the documents written by n,
+// which must be a node created by NewInsertionNode, NewReplacementNode or NewDeletionNode.
+// Deleted documents are returned as they were before being deleted.
+func NewReturningNode(n Node, expressions []ProjectedField, tableName string) Node {
+	switch t := n.(type) {
+	case *replacementNode:
+		t.returning = true
+	case *deletionNode:
+		t.returning = true
+	}
+
+	return NewProjectionNode(n, expressions, tableName)
+}
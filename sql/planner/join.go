@@ -0,0 +1,315 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A joinStrategy describes how the join node reads the documents of its inner table.
+type joinStrategy int
+
+const (
+	// nestedLoopJoin reads the entire inner table for every document of the outer stream.
+	nestedLoopJoin joinStrategy = iota
+	// indexJoin uses an index of the inner table to fetch matching documents.
+	indexJoin
+	// pkJoin uses the primary key of the inner table to fetch matching documents.
+	pkJoin
+)
+
+// JoinKind describes which documents are returned by a join node.
+type JoinKind int
+
+const (
+	// InnerJoin returns the combinations of documents for which the condition is truthy.
+	InnerJoin JoinKind = iota
+	// LeftJoin also returns the documents of the stream that don't match any document
+	// of the right node, combined with NULL.
+	LeftJoin
+)
+
+type joinNode struct {
+	node
+	evalCache
+
+	kind JoinKind
+	cond expr.Expr
+
+	// names used to qualify the documents of each side of the join.
+	leftName, rightName string
+
+	strategy joinStrategy
+	// when using an index or the primary key, the inner table
+	// is looked up using the result of outerExpr.
+	outerExpr expr.Expr
+	table     *database.Table
+	index     *database.Index
+	pk        *database.FieldConstraint
+
+	tx     *database.Transaction
+	params []expr.Param
+}
+
+var _ operationNode = (*joinNode)(nil)
+
+// NewJoinNode creates a node that combines every document of the stream with the documents
+// of the right node for which cond is truthy.
+// With a left join, documents of the stream that don't match any document
+// of the right node are kept and combined with NULL.
+// Documents produced by this node are composed of one field per table, named after
+// the table and containing the table document, so that fields can be selected using
+// qualified paths (i.e. "table.field").
+// To avoid allocations, the same document is passed to the next node for every
+// combination of documents: it must be copied to be used after the call.
+func NewJoinNode(left, right Node, kind JoinKind, cond expr.Expr) Node {
+	return &joinNode{
+		node: node{
+			op:    Join,
+			left:  left,
+			right: right,
+		},
+		kind: kind,
+		cond: cond,
+	}
+}
+
+// A tableNamer is a node that reads documents from a table.
+type tableNamer interface {
+	TableName() string
+}
+
+func (n *joinNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+	n.params = params
+
+	if tn, ok := n.left.(tableNamer); ok {
+		n.leftName = tn.TableName()
+	}
+
+	if tn, ok := n.right.(tableNamer); ok {
+		n.rightName = tn.TableName()
+	}
+
+	return
+}
+
+func (n *joinNode) toStream(st document.Stream) (document.Stream, error) {
+	inner, err := nodeToStream(n.right)
+	if err != nil {
+		return st, err
+	}
+
+	cache := n.cache
+
+	return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+		// jd is reused for every joined document, like the documents read from tables.
+		var jd joinedDocument
+		stack := expr.EvalStack{
+			Tx:     n.tx,
+			Params: n.params,
+			Cache:  cache,
+		}
+
+		return st.Iterate(func(outer document.Document) error {
+			var matched bool
+
+			err := n.iterateInner(inner, outer, cache, func(d document.Document) error {
+				jd.join(n.leftName, outer, n.rightName, d)
+
+				if n.cond != nil {
+					stack.Document = &jd
+					v, err := n.cond.Eval(stack)
+					if err != nil {
+						return err
+					}
+
+					ok, err := v.IsTruthy()
+					if err != nil || !ok {
+						return err
+					}
+				}
+
+				matched = true
+				return fn(&jd)
+			})
+			if err != nil {
+				return err
+			}
+
+			if !matched && n.kind == LeftJoin {
+				jd.join(n.leftName, outer, n.rightName, nil)
+				return fn(&jd)
+			}
+
+			return nil
+		})
+	})), nil
+}
+
+// iterateInner calls fn for every document of the inner stream that might
+// match the outer document, depending on the selected strategy.
+func (n *joinNode) iterateInner(inner document.Stream, outer document.Document, cache map[expr.Expr]interface{}, fn func(d document.Document) error) error {
+	if n.strategy == nestedLoopJoin {
+		return inner.Iterate(fn)
+	}
+
+	var jd joinedDocument
+	jd.join(n.leftName, outer, "", nil)
+
+	v, err := n.outerExpr.Eval(expr.EvalStack{
+		Tx:       n.tx,
+		Params:   n.params,
+		Document: &jd,
+		Cache:    cache,
+	})
+	if err != nil {
+		return err
+	}
+
+	// NULL never matches anything
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	if n.strategy == pkJoin {
+		return n.lookupPK(v, fn)
+	}
+
+	if n.index.Type != 0 {
+		v, err = v.CastAs(n.index.Type)
+		if err != nil {
+			return err
+		}
+	}
+
+	return n.cond.(IndexIteratorOperator).IterateIndex(n.index, n.table, v, fn)
+}
+
+func (n *joinNode) lookupPK(v document.Value, fn func(d document.Document) error) error {
+	var k []byte
+	var err error
+
+	if n.pk.Type != 0 {
+		v, err = v.CastAs(n.pk.Type)
+		if err != nil {
+			return err
+		}
+
+		k, err = key.Append(nil, v.Type, v.V)
+	} else {
+		k, err = key.AppendValue(nil, v)
+	}
+	if err != nil {
+		return err
+	}
+
+	d, err := n.table.GetDocument(k)
+	if err == database.ErrDocumentNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return fn(d)
+}
+
+func (n *joinNode) String() string {
+	kind := "InnerJoin"
+	if n.kind == LeftJoin {
+		kind = "LeftJoin"
+	}
+
+	var strategy string
+	switch n.strategy {
+	case nestedLoopJoin:
+		strategy = "nested loop"
+	case indexJoin:
+		strategy = fmt.Sprintf("index(%s)", n.index.Opts.IndexName)
+	case pkJoin:
+		strategy = "pk"
+	}
+
+	return fmt.Sprintf("%s(%s, cond: %s, strategy: %s)", kind, nodeToString(n.right), n.cond, strategy)
+}
+
+// joinedDocument is the document produced by a join node.
+// It contains one field per table, whose value is the document of that table,
+// or NULL if no document was matched by a left join.
+// Unqualified fields are looked up in the documents of every table, in order.
+type joinedDocument struct {
+	names []string
+	docs  []document.Document
+}
+
+var _ document.Document = (*joinedDocument)(nil)
+
+// join resets jd with the outer and inner documents.
+// If the outer document is itself a joined document, its tables are
+// added to jd before the inner one.
+func (jd *joinedDocument) join(outerName string, outer document.Document, innerName string, inner document.Document) {
+	jd.names = jd.names[:0]
+	jd.docs = jd.docs[:0]
+
+	if ojd, ok := outer.(*joinedDocument); ok {
+		jd.names = append(jd.names, ojd.names...)
+		jd.docs = append(jd.docs, ojd.docs...)
+	} else {
+		jd.names = append(jd.names, outerName)
+		jd.docs = append(jd.docs, outer)
+	}
+
+	if innerName != "" {
+		jd.names = append(jd.names, innerName)
+		jd.docs = append(jd.docs, inner)
+	}
+}
+
+func (jd *joinedDocument) GetByField(field string) (document.Value, error) {
+	for i, name := range jd.names {
+		if name == field {
+			return jd.value(i), nil
+		}
+	}
+
+	for _, d := range jd.docs {
+		if d == nil {
+			continue
+		}
+
+		v, err := d.GetByField(field)
+		if err == nil || err != document.ErrFieldNotFound {
+			return v, err
+		}
+	}
+
+	return document.Value{}, document.ErrFieldNotFound
+}
+
+func (jd *joinedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	for i, name := range jd.names {
+		err := fn(name, jd.value(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (jd *joinedDocument) value(i int) document.Value {
+	if jd.docs[i] == nil {
+		return document.NewNullValue()
+	}
+
+	return document.NewDocumentValue(jd.docs[i])
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (jd *joinedDocument) MarshalJSON() ([]byte, error) {
+	return document.MarshalJSON(jd)
+}
@@ -19,11 +19,17 @@ func _() {
 	_ = x[Sort-8]
 	_ = x[Set-9]
 	_ = x[Unset-10]
+	_ = x[Join-11]
+	_ = x[Insertion-12]
+	_ = x[Correlation-13]
+	_ = x[Distinct-14]
+	_ = x[Combination-15]
+	_ = x[Aggregation-16]
 }
 
-const _Operation_name = "InputSelectionProjectionRenameDeletionReplacementLimitSkipSortSetUnset"
+const _Operation_name = "InputSelectionProjectionRenameDeletionReplacementLimitSkipSortSetUnsetJoinInsertionCorrelationDistinctCombinationAggregation"
 
-var _Operation_index = [...]uint8{0, 5, 14, 24, 30, 38, 49, 54, 58, 62, 65, 70}
+var _Operation_index = [...]uint8{0, 5, 14, 24, 30, 38, 49, 54, 58, 62, 65, 70, 74, 83, 94, 102, 113, 124}
 
 func (i Operation) String() string {
 	if i < 0 || i >= Operation(len(_Operation_index)-1) {
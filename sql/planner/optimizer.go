@@ -1,6 +1,8 @@
 package planner
 
 import (
+	"sort"
+
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -12,6 +14,10 @@ var optimizerRules = []func(t *Tree) (*Tree, error){
 	PrecalculateExprRule,
 	RemoveUnnecessarySelectionNodesRule,
 	UseIndexBasedOnSelectionNodeRule,
+	UseIndexForJoinRule,
+	UseIndexBasedOnSortNodeRule,
+	UseIndexForMinMaxRule,
+	UseBoundedSortRule,
 }
 
 // Optimize takes a tree, applies a list of optimization rules
@@ -28,6 +34,16 @@ func Optimize(t *Tree) (*Tree, error) {
 		}
 	}
 
+	// statements combined by UNION, INTERSECT or EXCEPT are optimized separately.
+	for n := t.Root; n != nil; n = n.Left() {
+		if cn, ok := n.(*combinationNode); ok {
+			err = cn.optimize()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return t, nil
 }
 
@@ -252,15 +268,29 @@ func RemoveUnnecessarySelectionNodesRule(t *Tree) (*Tree, error) {
 // operator that satisfies the following criterias:
 // - implements the indexIteratorOperator interface
 // - one of its operands is path selector that is indexed
-// - the other operand is a literal value or a parameter
-// If found, it will replace the input node by an indexInputNode using this index.
+// - the other operand is a literal value, a parameter or an uncorrelated subquery
+// Composite indexes are used by a set of selection nodes comparing the first fields of the index
+// to a literal value or a parameter, using equality, optionally followed by a range on the next field.
+// Typed primary keys are used by selection nodes comparing them to a literal value or a parameter
+// using one of the =, >, >=, < and <= operators.
+// If the table has been analyzed, the number of documents read by each candidate is estimated
+// using the statistics of the table and the cheapest candidate is selected, unless reading the
+// entire table is cheaper.
+// Disjunctions whose operands can all use an index or the primary key read the documents
+// matching each operand, using an indexUnionNode.
+// If found, it will replace the input node by an indexInputNode, a pkInputNode or an indexUnionNode.
 func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 	n := t.Root
 	var prev Node
 	var inputNode Node
+	var joins int
 
 	// first we lookup for the input node
 	for n != nil {
+		if n.Operation() == Join {
+			joins++
+		}
+
 		if n.Operation() == Input {
 			inputNode = n
 			break
@@ -281,48 +311,139 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return nil, err
 	}
 
-	type candidate struct {
-		prevNode, nextNode Node
-		in                 *indexInputNode
+	info, err := inpn.table.Info()
+	if err != nil {
+		return nil, err
 	}
+	pk := info.GetPrimaryKey()
+
+	// selection nodes located above a join are evaluated against the joined documents:
+	// they can only be used to select an index of the input table if they only refer
+	// to fields of that table using qualified paths. They are replaced by selection nodes
+	// whose paths are relative to the documents of the table, which are mapped
+	// to the original nodes.
+	originals := make(map[Node]Node)
+
+	var selectionNodes []*selectionNode
+	var conds []expr.Expr
+	for n = t.Root; n != nil; n = n.Left() {
+		if n.Operation() == Join {
+			joins--
+		}
 
-	var candidates []candidate
-
-	n = t.Root
-	// look for all selection nodes that satisfy our requirements
-	for n != nil {
 		if n.Operation() == Selection {
 			sn := n.(*selectionNode)
-			indexedNode := selectionNodeValidForIndex(sn, inpn.tableName, indexes)
-			if indexedNode != nil {
-				candidates = append(candidates, candidate{
-					prevNode: prev,
-					nextNode: n.Left(),
-					in:       indexedNode,
-				})
+			if joins > 0 {
+				cond := unqualifiedCondition(sn.cond, inpn.tableName)
+				if cond == nil {
+					continue
+				}
+
+				sn = &selectionNode{node: sn.node, cond: cond}
+				originals[sn] = n
 			}
-		}
 
-		prev = n
-		n = n.Left()
+			selectionNodes = append(selectionNodes, sn)
+			conds = append(conds, sn.cond)
+		}
 	}
 
-	// determine which index is the most interesting and replace it in the tree.
-	// we will assume that unique indexes are more interesting than list indexes
-	// because they usually have less elements.
-	var selectedCandidate *candidate
+	// partial indexes can only be used if the conditions imply their predicate.
+	indexes = usableIndexes(indexes, conds)
 
-	for i, candidate := range candidates {
-		if selectedCandidate == nil {
-			selectedCandidate = &candidates[i]
-			continue
+	// candidates using the primary key come first so that, without statistics,
+	// they are only selected if no index is as interesting, unless they are unique.
+	var pkCandidates, candidates, unionCandidates []scanCandidate
+
+	// look for all selection nodes that satisfy our requirements
+	for _, sn := range selectionNodes {
+		indexedNode := selectionNodeValidForIndex(sn, inpn.tableName, indexes)
+		if indexedNode != nil {
+			candidates = append(candidates, scanCandidate{
+				nodes:  []Node{sn},
+				in:     indexedNode,
+				unique: indexedNode.index.Unique,
+			})
+		}
+
+		pkNode := selectionNodeValidForPK(sn, inpn.tableName, pk)
+		if pkNode != nil {
+			pkCandidates = append(pkCandidates, scanCandidate{
+				nodes:  []Node{sn},
+				in:     pkNode,
+				unique: pkNode.op == scanner.EQ,
+			})
+		}
+
+		unionNode := selectionNodeValidForIndexUnion(sn, inpn.tableName, indexes, pk)
+		if unionNode != nil {
+			unionCandidates = append(unionCandidates, scanCandidate{
+				nodes: []Node{sn},
+				in:    unionNode,
+			})
+		}
+	}
+
+	// then look for composite indexes that can be used by one or more
+	// of these selection nodes.
+	// indexes are sorted by name so that the same index is always selected.
+	var composites []database.Index
+	for _, idx := range indexes {
+		if idx.Opts.IsComposite() {
+			composites = append(composites, idx)
+		}
+	}
+	sort.Slice(composites, func(i, j int) bool {
+		return composites[i].Opts.IndexName < composites[j].Opts.IndexName
+	})
+
+	for i := range composites {
+		nodes, in := selectionNodesValidForCompositeIndex(selectionNodes, inpn.tableName, &composites[i])
+		if in != nil {
+			candidates = append(candidates, scanCandidate{
+				nodes:  nodes,
+				in:     in,
+				unique: composites[i].Unique,
+			})
 		}
+	}
+
+	// unions come last, they read more documents than an index
+	// used by the same number of selection nodes.
+	candidates = append(pkCandidates, candidates...)
+	candidates = append(candidates, unionCandidates...)
+
+	stats, err := inpn.tx.GetStatistics(inpn.tableName)
+	if err != nil && err != database.ErrStatisticsNotFound {
+		return nil, err
+	}
 
-		// if the candidate's related index is a unique index,
-		// select it.
-		idx := candidate.in.index
-		if idx.Unique {
-			selectedCandidate = &candidates[i]
+	var selectedCandidate *scanCandidate
+
+	// statistics of an empty table can't tell which candidate is the cheapest.
+	if stats != nil && stats.RowCount > 0 {
+		selectedCandidate = cheapestCandidate(candidates, stats)
+	} else {
+		// without statistics, determine which index is the most interesting.
+		// we will assume that indexes used by more selection nodes are more interesting,
+		// then that unique indexes are more interesting than list indexes
+		// because they usually have less elements.
+		for i, candidate := range candidates {
+			if selectedCandidate == nil {
+				selectedCandidate = &candidates[i]
+				continue
+			}
+
+			if len(candidate.nodes) > len(selectedCandidate.nodes) {
+				selectedCandidate = &candidates[i]
+				continue
+			}
+
+			// if the candidate reads a unique index or the primary key,
+			// select it.
+			if candidate.unique && len(candidate.nodes) == len(selectedCandidate.nodes) {
+				selectedCandidate = &candidates[i]
+			}
 		}
 	}
 
@@ -335,11 +456,25 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return nil, err
 	}
 
-	// we remove the selection node from the tree
-	if selectedCandidate.prevNode == nil {
-		t.Root = selectedCandidate.nextNode
-	} else {
-		selectedCandidate.prevNode.SetLeft(selectedCandidate.nextNode)
+	// we remove the selection nodes from the tree
+	for _, sn := range selectedCandidate.nodes {
+		if orig, ok := originals[sn]; ok {
+			sn = orig
+		}
+
+		prev = nil
+		for n = t.Root; n != nil; n = n.Left() {
+			if n == sn {
+				if prev == nil {
+					t.Root = n.Left()
+				} else {
+					prev.SetLeft(n.Left())
+				}
+				break
+			}
+
+			prev = n
+		}
 	}
 
 	n = t.Root
@@ -369,6 +504,14 @@ func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map
 		return nil
 	}
 
+	if in := selectionNodeValidForMultiIndex(sn, tableName, indexes); in != nil {
+		return in
+	}
+
+	if in := selectionNodeValidForFullTextIndex(sn, tableName, indexes); in != nil {
+		return in
+	}
+
 	// the root of the condition must be an operator
 	op, ok := sn.cond.(expr.Operator)
 	if !ok {
@@ -376,33 +519,770 @@ func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map
 	}
 
 	iop, ok := op.(IndexIteratorOperator)
-	if !ok {
+	if !ok || expr.IsNotInOperator(op) {
+		return nil
+	}
+
+	// the right operand of the IN operator is an array: a regular index
+	// on that operand can't be used to look up one of its elements.
+	if _, ok := op.RightHand().(expr.FieldSelector); ok && expr.IsInOperator(op) {
 		return nil
 	}
 
 	// determine if the operator can benefit from an index
-	ok, field, e := opCanUseIndex(op)
+	idx, e := indexForOperator(op, indexes)
+	if idx == nil {
+		return nil
+	}
+
+	// analyse the other operand to make sure it's a literal, a param
+	// or a subquery whose result doesn't depend on the document being filtered.
+	if !isLiteralOrParam(e) && !isUncorrelatedSubquery(e) {
+		return nil
+	}
+
+	// regular expressions can only use an index if they are literals
+	// with a literal prefix, in which case only the indexed values starting
+	// with that prefix are read.
+	if expr.IsEqRegexOperator(op) {
+		if _, ok := op.RightHand().(expr.FieldSelector); ok {
+			return nil
+		}
+
+		lit, ok := e.(expr.LiteralValue)
+		if !ok || lit.Type != document.TextValue || expr.RegexLiteralPrefix(lit.V.(string)) == "" {
+			return nil
+		}
+	}
+
+	in := NewIndexInputNode(tableName, idx.Opts.IndexName, iop, e, scanner.ASC).(*indexInputNode)
+	in.index = idx
+
+	return in
+}
+
+// selectionNodeValidForMultiIndex returns an indexInputNode reading a multi-valued index
+// if the condition of sn checks whether the indexed array contains a literal value, a param
+// or the result of an uncorrelated subquery, using the IN operator or the CONTAINS function.
+// Each document containing the value is read once, by looking up that value in the index.
+func selectionNodeValidForMultiIndex(sn *selectionNode, tableName string, indexes map[string]database.Index) *indexInputNode {
+	var array, e expr.Expr
+
+	switch t := sn.cond.(type) {
+	case expr.ContainsFunc:
+		array, e = t.Array, t.Value
+	case expr.Operator:
+		if !expr.IsInOperator(t) {
+			return nil
+		}
+		array, e = t.RightHand(), t.LeftHand()
+	default:
+		return nil
+	}
+
+	fs, ok := array.(expr.FieldSelector)
+	if !ok || (!isLiteralOrParam(e) && !isUncorrelatedSubquery(e)) {
+		return nil
+	}
+
+	// multi-valued indexes are keyed by their path followed by [*].
+	idx, ok := indexes[fs.Name()+"[*]"]
 	if !ok {
 		return nil
 	}
 
-	// analyse the other operand to make sure it's a literal or a param
-	if !isLiteralOrParam(e) {
+	in := NewIndexInputNode(tableName, idx.Opts.IndexName, multiIndexOperator{}, e, scanner.ASC).(*indexInputNode)
+	in.index = &idx
+
+	return in
+}
+
+// selectionNodeValidForFullTextIndex returns an indexInputNode if the condition of sn
+// is MATCH(path, query), the query being a literal, a param or an uncorrelated subquery,
+// and a full-text index was created on that path.
+func selectionNodeValidForFullTextIndex(sn *selectionNode, tableName string, indexes map[string]database.Index) *indexInputNode {
+	m, ok := sn.cond.(expr.MatchFunc)
+	if !ok {
+		return nil
+	}
+
+	fs, ok := m.Field.(expr.FieldSelector)
+	if !ok || (!isLiteralOrParam(m.Query) && !isUncorrelatedSubquery(m.Query)) {
 		return nil
 	}
 
-	// now, we look if an index exists for that path
-	idx, ok := indexes[field.Name()]
+	// full-text indexes are keyed by their path preceded by FULLTEXT.
+	idx, ok := indexes["FULLTEXT "+fs.Name()]
 	if !ok {
 		return nil
 	}
 
-	in := NewIndexInputNode(tableName, idx.Opts.IndexName, iop, e, scanner.ASC).(*indexInputNode)
+	in := NewIndexInputNode(tableName, idx.Opts.IndexName, matchOperator{}, m.Query, scanner.ASC).(*indexInputNode)
 	in.index = &idx
 
 	return in
 }
 
+// selectionNodeValidForPK returns a pkInputNode if the condition of sn compares the primary key
+// to a literal value or a parameter using one of the =, >, >=, < and <= operators.
+// Only typed primary keys are used: their encoding follows the order of the values.
+func selectionNodeValidForPK(sn *selectionNode, tableName string, pk *database.FieldConstraint) *pkInputNode {
+	if pk == nil || pk.Type == 0 {
+		return nil
+	}
+
+	op, ok := sn.cond.(expr.Operator)
+	if !ok {
+		return nil
+	}
+
+	switch op.Token() {
+	case scanner.EQ, scanner.GT, scanner.GTE, scanner.LT, scanner.LTE:
+	default:
+		return nil
+	}
+
+	ok, field, e := opCanUseIndex(op)
+	if !ok || !isLiteralOrParam(e) || !document.ValuePath(field).IsEqual(pk.Path) {
+		return nil
+	}
+
+	// the primary key is kept on the left-hand side of the operator.
+	var pop expr.Expr
+	switch comparisonToken(op) {
+	case scanner.EQ:
+		pop = expr.Eq(field, e)
+	case scanner.GT:
+		pop = expr.Gt(field, e)
+	case scanner.GTE:
+		pop = expr.Gte(field, e)
+	case scanner.LT:
+		pop = expr.Lt(field, e)
+	case scanner.LTE:
+		pop = expr.Lte(field, e)
+	}
+
+	return NewPrimaryKeyInputNode(tableName, pop.(PKIteratorOperator), e).(*pkInputNode)
+}
+
+// selectionNodeValidForIndexUnion returns an indexUnionNode if the condition of sn is a disjunction
+// whose operands can all use an index or the primary key, as if they were the condition
+// of a selection node.
+func selectionNodeValidForIndexUnion(sn *selectionNode, tableName string, indexes map[string]database.Index, pk *database.FieldConstraint) *indexUnionNode {
+	conds := splitORExpr(sn.cond)
+	if len(conds) < 2 {
+		return nil
+	}
+
+	inputs := make([]Node, len(conds))
+	for i, cond := range conds {
+		branch := selectionNode{cond: cond}
+
+		if in := selectionNodeValidForPK(&branch, tableName, pk); in != nil {
+			inputs[i] = in
+			continue
+		}
+
+		if in := selectionNodeValidForIndex(&branch, tableName, indexes); in != nil {
+			inputs[i] = in
+			continue
+		}
+
+		return nil
+	}
+
+	un := NewIndexUnionNode(tableName, inputs...).(*indexUnionNode)
+	un.conds = conds
+	return un
+}
+
+// splitORExpr takes an expression and splits it by OR operator,
+// ignoring parentheses.
+func splitORExpr(cond expr.Expr) (exprs []expr.Expr) {
+	if p, ok := cond.(expr.Parentheses); ok {
+		return splitORExpr(p.E)
+	}
+
+	if expr.IsOrOperator(cond) {
+		op := cond.(expr.Operator)
+		exprs = append(exprs, splitORExpr(op.LeftHand())...)
+		exprs = append(exprs, splitORExpr(op.RightHand())...)
+		return
+	}
+
+	exprs = append(exprs, cond)
+	return
+}
+
+// selectionNodesValidForCompositeIndex determines which selection nodes can be used to read
+// documents from the given composite index. The index can be used if the first fields
+// of the index are compared to a literal or a parameter using the equal operator,
+// optionally followed by a field compared using one of the >, >=, < and <= operators.
+// It returns the selection nodes used and an indexInputNode reading the index,
+// or nil if the index can't be used.
+func selectionNodesValidForCompositeIndex(nodes []*selectionNode, tableName string, idx *database.Index) ([]Node, *indexInputNode) {
+	type indexedCond struct {
+		node  *selectionNode
+		path  document.ValuePath
+		tok   scanner.Token
+		value expr.Expr
+	}
+
+	var conds []indexedCond
+	for _, sn := range nodes {
+		op, ok := sn.cond.(expr.Operator)
+		if !ok {
+			continue
+		}
+
+		tok := op.Token()
+		switch tok {
+		case scanner.EQ, scanner.GT, scanner.GTE, scanner.LT, scanner.LTE:
+		default:
+			continue
+		}
+
+		ok, field, e := opCanUseIndex(op)
+		if !ok || !isLiteralOrParam(e) {
+			continue
+		}
+
+		// expr OP path: the operator must be reversed
+		if _, ok := op.RightHand().(expr.FieldSelector); ok {
+			switch tok {
+			case scanner.GT:
+				tok = scanner.LT
+			case scanner.GTE:
+				tok = scanner.LTE
+			case scanner.LT:
+				tok = scanner.GT
+			case scanner.LTE:
+				tok = scanner.GTE
+			}
+		}
+
+		conds = append(conds, indexedCond{node: sn, path: document.ValuePath(field), tok: tok, value: e})
+	}
+
+	lookup := func(path document.ValuePath, eq bool) *indexedCond {
+		for i, c := range conds {
+			if c.path.IsEqual(path) && (c.tok == scanner.EQ) == eq {
+				return &conds[i]
+			}
+		}
+
+		return nil
+	}
+
+	var used []Node
+	var values expr.LiteralExprList
+	var op compositeIndexOperator
+
+	for _, path := range idx.Opts.Paths {
+		if c := lookup(path, true); c != nil {
+			used = append(used, c.node)
+			values = append(values, c.value)
+			op.eq++
+			continue
+		}
+
+		if c := lookup(path, false); c != nil {
+			used = append(used, c.node)
+			values = append(values, c.value)
+			op.rangeOp = c.tok
+		}
+
+		break
+	}
+
+	if len(used) == 0 {
+		return nil, nil
+	}
+
+	in := NewIndexInputNode(tableName, idx.Opts.IndexName, op, values, scanner.ASC).(*indexInputNode)
+	in.index = idx
+
+	return used, in
+}
+
+// UseIndexForJoinRule looks for join nodes whose inner node is a table and whose condition
+// is an equality between a qualified field of that table and a qualified field
+// of another table, or a literal value or a parameter.
+// If the field of the inner table is its primary key or is indexed, the join node
+// is configured to fetch the matching inner documents using the primary key or the index
+// instead of reading the entire inner table for every outer document.
+// Example:
+//   this:
+//     Table(a) -> InnerJoin(Table(b), cond: a.x = b.y, strategy: nested loop)
+//   becomes this, if b.y is indexed:
+//     Table(a) -> InnerJoin(Table(b), cond: a.x = b.y, strategy: index(idx_b_y))
+func UseIndexForJoinRule(t *Tree) (*Tree, error) {
+	for n := t.Root; n != nil; n = n.Left() {
+		if n.Operation() != Join {
+			continue
+		}
+
+		jn := n.(*joinNode)
+		in, ok := jn.right.(*tableInputNode)
+		if !ok || jn.cond == nil {
+			continue
+		}
+
+		op, ok := jn.cond.(expr.Operator)
+		if !ok || op.Token() != scanner.EQ {
+			continue
+		}
+
+		if _, ok := op.(IndexIteratorOperator); !ok {
+			continue
+		}
+
+		path, outerExpr := joinOperands(op, in.tableName)
+		if path == nil {
+			continue
+		}
+
+		info, err := in.table.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		if pk := info.GetPrimaryKey(); pk != nil && pk.Path.IsEqual(path) {
+			jn.strategy = pkJoin
+			jn.pk = pk
+			jn.table = in.table
+			jn.outerExpr = outerExpr
+			continue
+		}
+
+		indexes, err := in.table.Indexes()
+		if err != nil {
+			return nil, err
+		}
+
+		idx, ok := indexes[path.String()]
+		if !ok {
+			continue
+		}
+
+		jn.strategy = indexJoin
+		jn.index = &idx
+		jn.table = in.table
+		jn.outerExpr = outerExpr
+	}
+
+	return t, nil
+}
+
+// unqualifiedCondition returns a copy of the condition of a selection node located above a join,
+// whose paths are relative to the documents of the given table. The paths of the condition must
+// all be qualified with the name of that table. Only the conditions that may use an index or
+// the primary key are copied. It returns nil if the condition can't be copied.
+func unqualifiedCondition(cond expr.Expr, tableName string) expr.Expr {
+	var copyExpr func(e expr.Expr) expr.Expr
+	copyExpr = func(e expr.Expr) expr.Expr {
+		switch t := e.(type) {
+		case expr.FieldSelector:
+			if len(t) < 2 || t[0].FieldName != tableName {
+				return nil
+			}
+			return t[1:]
+		case expr.LiteralValue, expr.NamedParam, expr.PositionalParam:
+			return e
+		case *Subquery:
+			if t.Correlated {
+				return nil
+			}
+			return e
+		case expr.Parentheses:
+			if inner := copyExpr(t.E); inner != nil {
+				return expr.Parentheses{E: inner}
+			}
+			return nil
+		case expr.ContainsFunc:
+			a, v := copyExpr(t.Array), copyExpr(t.Value)
+			if a == nil || v == nil {
+				return nil
+			}
+			return expr.ContainsFunc{Array: a, Value: v}
+		case expr.MatchFunc:
+			f, q := copyExpr(t.Field), copyExpr(t.Query)
+			if f == nil || q == nil {
+				return nil
+			}
+			t.Field, t.Query = f, q
+			return t
+		case expr.Operator:
+			var newOp func(a, b expr.Expr) expr.Expr
+			switch {
+			case expr.IsInOperator(t):
+				newOp = expr.In
+			case expr.IsOrOperator(t):
+				newOp = expr.Or
+			case expr.IsEqRegexOperator(t):
+				newOp = expr.EqRegex
+			case expr.IsComparisonOperator(t):
+				// IS and IS NOT share the token of IN, they are never copied.
+				switch t.Token() {
+				case scanner.EQ:
+					newOp = expr.Eq
+				case scanner.NEQ:
+					newOp = expr.Neq
+				case scanner.GT:
+					newOp = expr.Gt
+				case scanner.GTE:
+					newOp = expr.Gte
+				case scanner.LT:
+					newOp = expr.Lt
+				case scanner.LTE:
+					newOp = expr.Lte
+				}
+			}
+
+			if newOp == nil {
+				return nil
+			}
+
+			a, b := copyExpr(t.LeftHand()), copyExpr(t.RightHand())
+			if a == nil || b == nil {
+				return nil
+			}
+			return newOp(a, b)
+		}
+
+		return nil
+	}
+
+	return copyExpr(cond)
+}
+
+// joinOperands determines which operand of op selects a field of the inner table
+// and returns the path of that field, relative to the table documents, and the other operand.
+// The other operand must either select a field of another table, or be a literal value or a parameter.
+func joinOperands(op expr.Operator, innerTable string) (document.ValuePath, expr.Expr) {
+	isInner := func(e expr.Expr) bool {
+		fs, ok := e.(expr.FieldSelector)
+		return ok && len(fs) > 1 && fs[0].FieldName == innerTable
+	}
+
+	isOuter := func(e expr.Expr) bool {
+		if fs, ok := e.(expr.FieldSelector); ok {
+			return len(fs) > 1 && fs[0].FieldName != innerTable
+		}
+
+		return isLiteralOrParam(e)
+	}
+
+	lh, rh := op.LeftHand(), op.RightHand()
+
+	if isInner(lh) && isOuter(rh) {
+		return document.ValuePath(lh.(expr.FieldSelector)[1:]), rh
+	}
+
+	if isInner(rh) && isOuter(lh) {
+		return document.ValuePath(rh.(expr.FieldSelector)[1:]), lh
+	}
+
+	return nil, nil
+}
+
+// UseIndexBasedOnSortNodeRule removes the sort node if the documents can be read in the order
+// of the first sort key, using the primary key or an index.
+// The sort key must select a field of the documents of the table, which must only be filtered,
+// projected or deduplicated before being sorted.
+// If the entire table is read, the table is read in the direction of the sort if the field is the
+// primary key, otherwise it is replaced by an index on that field, read in the direction of the sort,
+// provided NULL values are placed where the index stores them.
+// If the documents are read using the primary key or an index on that field to satisfy a selection,
+// they are already returned in increasing order.
+// The other sort keys are only ignored if the field is the primary key or is uniquely indexed.
+// Example:
+//   this:
+//     Table(test) -> ∏(*) -> Sort(a DESC) -> Limit(10)
+//   becomes this, if a is indexed:
+//     Index(idx_test_a, DESC) -> ∏(*) -> Limit(10)
+func UseIndexBasedOnSortNodeRule(t *Tree) (*Tree, error) {
+	var prev Node
+	var sn *sortNode
+
+	n := t.Root
+	for n != nil {
+		if nn, ok := n.(*sortNode); ok {
+			sn = nn
+			break
+		}
+
+		prev = n
+		n = n.Left()
+	}
+
+	if sn == nil {
+		return t, nil
+	}
+
+	key := sn.keys[0]
+	fs, ok := key.Expr.(expr.FieldSelector)
+	if !ok {
+		return t, nil
+	}
+	path := document.ValuePath(fs)
+
+	// the documents must reach the sort node in the order they are read.
+	inputParent := Node(sn)
+	n = sn.Left()
+	for n != nil && n.Operation() != Input {
+		switch nn := n.(type) {
+		case *selectionNode, *distinctNode:
+		case *ProjectionNode:
+			if !projectionKeepsPath(nn, path) {
+				return t, nil
+			}
+		default:
+			return t, nil
+		}
+
+		inputParent = n
+		n = n.Left()
+	}
+
+	// the other sort keys can only be ignored if the values of the field are unique.
+	singleKey := len(sn.keys) == 1
+
+	switch in := n.(type) {
+	case *tableInputNode:
+		info, err := in.table.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		if pk := info.GetPrimaryKey(); pk != nil && pk.Path.IsEqual(path) {
+			in.orderByDirection = key.Direction
+			break
+		}
+
+		indexes, err := in.table.Indexes()
+		if err != nil {
+			return nil, err
+		}
+
+		idx, ok := indexes[path.String()]
+		if !ok || !(singleKey || idx.Unique) {
+			return t, nil
+		}
+
+		// NULL values are stored at the beginning of the index.
+		if key.nullsFirst() != (key.Direction != scanner.DESC) {
+			return t, nil
+		}
+
+		// we make sure the new indexInputNode is bound
+		iin := NewIndexInputNode(in.tableName, idx.Opts.IndexName, nil, nil, key.Direction)
+		err = iin.Bind(in.tx, in.params)
+		if err != nil {
+			return nil, err
+		}
+
+		inputParent.SetLeft(iin)
+	case *pkInputNode:
+		if key.Direction == scanner.DESC || !in.pk.Path.IsEqual(path) {
+			return t, nil
+		}
+	case *indexInputNode:
+		if key.Direction == scanner.DESC || !indexReadInOrder(in, path) || !(singleKey || in.index.Unique) {
+			return t, nil
+		}
+	default:
+		return t, nil
+	}
+
+	if prev == nil {
+		t.Root = sn.Left()
+	} else {
+		prev.SetLeft(sn.Left())
+	}
+
+	return t, nil
+}
+
+// projectionKeepsPath reports whether selecting path from the projected documents
+// returns the same value as selecting it from the documents of the table.
+func projectionKeepsPath(pn *ProjectionNode, path document.ValuePath) bool {
+	for _, f := range pn.Expressions {
+		pe, ok := f.(ProjectedExpr)
+		if !ok || pe.Name() != path[0].FieldName {
+			continue
+		}
+
+		fs, ok := pe.Expr.(expr.FieldSelector)
+		if !ok || len(fs) != 1 || fs[0] != path[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// indexReadInOrder reports whether the documents selected by an index on path are read
+// in increasing order of their value.
+// Documents selected by the IN operator are read in the order of the list of values.
+func indexReadInOrder(in *indexInputNode, path document.ValuePath) bool {
+	if len(in.index.Opts.Paths) != 1 || in.index.Opts.Multi || in.index.Opts.FullText || !in.index.Opts.Paths[0].IsEqual(path) {
+		return false
+	}
+
+	op, ok := in.iop.(expr.Operator)
+	if !ok {
+		return false
+	}
+
+	switch op.Token() {
+	case scanner.EQ, scanner.GT, scanner.GTE, scanner.LT, scanner.LTE, scanner.EQREGEX:
+		return true
+	}
+
+	return false
+}
+
+// UseIndexForMinMaxRule answers the MIN and MAX aggregate functions by reading one end of the
+// primary key or of an index on the aggregated field, if it is the only aggregate function of a
+// statement that doesn't group its documents.
+// The documents are read in increasing order for MIN, decreasing order for MAX, and only the first
+// document that is not filtered out and whose value is not NULL is aggregated.
+// Example:
+//   this:
+//     Table(test) -> Aggregate(MIN(a)) -> ∏(MIN(a))
+//   becomes this, if a is indexed:
+//     Index(idx_test_a) -> σ(cond: a IS NOT NULL) -> Limit(1) -> Aggregate(MIN(a)) -> ∏(MIN(a))
+func UseIndexForMinMaxRule(t *Tree) (*Tree, error) {
+	var an *AggregationNode
+
+	n := t.Root
+	for n != nil {
+		if nn, ok := n.(*AggregationNode); ok {
+			an = nn
+			break
+		}
+
+		n = n.Left()
+	}
+
+	if an == nil || len(an.Builders) != 1 {
+		return t, nil
+	}
+
+	var e expr.Expr
+	direction := scanner.ASC
+	switch fn := an.Builders[0].(type) {
+	case *expr.MinFunc:
+		e = fn.Expr
+	case *expr.MaxFunc:
+		e = fn.Expr
+		direction = scanner.DESC
+	default:
+		return t, nil
+	}
+
+	fs, ok := e.(expr.FieldSelector)
+	if !ok {
+		return t, nil
+	}
+	path := document.ValuePath(fs)
+
+	// only selection nodes may filter the documents before they are aggregated.
+	inputParent := Node(an)
+	n = an.Left()
+	for n != nil && n.Operation() == Selection {
+		inputParent = n
+		n = n.Left()
+	}
+
+	in, ok := n.(*tableInputNode)
+	if !ok {
+		return t, nil
+	}
+
+	info, err := in.table.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := an.Left()
+	if pk := info.GetPrimaryKey(); pk != nil && pk.Path.IsEqual(path) {
+		in.orderByDirection = direction
+	} else {
+		indexes, err := in.table.Indexes()
+		if err != nil {
+			return nil, err
+		}
+
+		idx, ok := indexes[path.String()]
+		if !ok {
+			return t, nil
+		}
+
+		iin := NewIndexInputNode(in.tableName, idx.Opts.IndexName, nil, nil, direction)
+		err = iin.Bind(in.tx, in.params)
+		if err != nil {
+			return nil, err
+		}
+		inputParent.SetLeft(iin)
+		if inputParent == an {
+			nodes = iin
+		}
+
+		// NULL values are ignored by MIN and MAX, but they are indexed.
+		nodes = NewSelectionNode(nodes, expr.IsNot(fs, expr.NullValue()))
+		err = nodes.Bind(in.tx, in.params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limit := NewLimitNode(nodes, 1)
+	err = limit.Bind(in.tx, in.params)
+	if err != nil {
+		return nil, err
+	}
+	an.SetLeft(limit)
+
+	return t, nil
+}
+
+// UseBoundedSortRule looks for a sort node that is only followed by limit and offset nodes
+// and tells it how many documents will be read from the sorted stream, which
+// is the sum of the limit and the offset.
+// This allows the sort node to only keep the best documents in memory instead of sorting the
+// entire stream.
+// Example:
+//   this:
+//     Sort(a ASC) -> Offset(10) -> Limit(5)
+//   tells the sort node that only the first 15 documents will be read.
+func UseBoundedSortRule(t *Tree) (*Tree, error) {
+	need := -1
+
+	for n := t.Root; n != nil; n = n.Left() {
+		switch nn := n.(type) {
+		case *limitNode:
+			if need == -1 || nn.limit < need {
+				need = nn.limit
+			}
+		case *offsetNode:
+			if need != -1 {
+				need += nn.offset
+			}
+		case *sortNode:
+			if need > 0 {
+				nn.limit = need
+			}
+			return t, nil
+		default:
+			return t, nil
+		}
+	}
+
+	return t, nil
+}
+
 func opCanUseIndex(op expr.Operator) (bool, expr.FieldSelector, expr.Expr) {
 	lf, leftIsField := op.LeftHand().(expr.FieldSelector)
 	rf, rightIsField := op.RightHand().(expr.FieldSelector)
@@ -420,6 +1300,49 @@ func opCanUseIndex(op expr.Operator) (bool, expr.FieldSelector, expr.Expr) {
 	return false, nil, nil
 }
 
+// indexForOperator returns the index containing the values of one of the operands of op,
+// and the other operand. Expression indexes can only be used if the left operand of op
+// is the indexed expression.
+// It returns nil if there is no such index.
+func indexForOperator(op expr.Operator, indexes map[string]database.Index) (*database.Index, expr.Expr) {
+	if ok, field, e := opCanUseIndex(op); ok {
+		idx, ok := indexes[field.Name()]
+		if !ok {
+			return nil, nil
+		}
+
+		return &idx, e
+	}
+
+	lhs := stripParentheses(op.LeftHand())
+
+	// if several indexes match, the one with the smallest name is selected
+	// so that the same index is always used.
+	var selected *database.Index
+	for _, idx := range indexes {
+		se, ok := idx.Expr().(expr.StoredExpr)
+		if !ok || !expr.Equal(se.E, lhs) {
+			continue
+		}
+
+		if selected == nil || idx.Opts.IndexName < selected.Opts.IndexName {
+			idx := idx
+			selected = &idx
+		}
+	}
+
+	if selected == nil {
+		return nil, nil
+	}
+
+	return selected, op.RightHand()
+}
+
+func isUncorrelatedSubquery(e expr.Expr) bool {
+	sq, ok := e.(*Subquery)
+	return ok && !sq.Correlated
+}
+
 func isLiteralOrParam(e expr.Expr) (ok bool) {
 	switch e.(type) {
 	case expr.LiteralValue, expr.NamedParam, expr.PositionalParam:
@@ -428,3 +1351,191 @@ func isLiteralOrParam(e expr.Expr) (ok bool) {
 
 	return false
 }
+
+// usableIndexes returns the indexes that can be used to read the documents matching
+// all the given conditions, keyed like Table.Indexes keys regular indexes.
+// Partial indexes can only be used if the conditions imply their predicate, in which
+// case they replace the regular index created on the same paths, if any, because
+// they contain fewer documents.
+func usableIndexes(indexes map[string]database.Index, conds []expr.Expr) map[string]database.Index {
+	usable := make(map[string]database.Index, len(indexes))
+	var partials []database.Index
+
+	for k, idx := range indexes {
+		if idx.Opts.Where == "" {
+			usable[k] = idx
+			continue
+		}
+
+		partials = append(partials, idx)
+	}
+
+	// partial indexes are sorted by name so that the same index is always selected.
+	sort.Slice(partials, func(i, j int) bool {
+		return partials[i].Opts.IndexName < partials[j].Opts.IndexName
+	})
+
+	selected := make(map[string]bool)
+	for _, idx := range partials {
+		k := idx.Opts.PathsString()
+		if selected[k] || !impliesPredicate(conds, idx.Predicate()) {
+			continue
+		}
+
+		usable[k] = idx
+		selected[k] = true
+	}
+
+	return usable
+}
+
+// impliesPredicate reports whether every document matching all the conditions
+// matches the predicate of a partial index. Each operand of the AND operators
+// of the predicate must be implied by one of the conditions.
+func impliesPredicate(conds []expr.Expr, pred database.Expr) bool {
+	se, ok := pred.(expr.StoredExpr)
+	if !ok {
+		return false
+	}
+
+	for _, p := range splitANDExpr(stripParentheses(se.E)) {
+		var implied bool
+		for _, c := range conds {
+			if implies(c, p) {
+				implied = true
+				break
+			}
+		}
+
+		if !implied {
+			return false
+		}
+	}
+
+	return true
+}
+
+// implies reports whether every document matching c matches p.
+// Besides identical expressions, it detects comparisons of a path with a literal
+// value implying a comparison of the same path, e.g. "a > 10" implies "a >= 5"
+// and "a IS NOT NULL", as well as conditions implying an operand of an OR operator.
+func implies(c, p expr.Expr) bool {
+	c, p = stripParentheses(c), stripParentheses(p)
+
+	if expr.Equal(c, p) {
+		return true
+	}
+
+	if ps := splitORExpr(p); len(ps) > 1 {
+		for _, pp := range ps {
+			if implies(c, pp) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	cpath, ctok, cv, ok := literalComparison(c)
+	if !ok {
+		return false
+	}
+
+	// a comparison with a value other than NULL can only be true if the path isn't NULL.
+	if expr.IsIsNotOperator(p) {
+		op := p.(expr.Operator)
+		path, ok := op.LeftHand().(expr.FieldSelector)
+		lit, isLit := op.RightHand().(expr.LiteralValue)
+		return ok && isLit && lit.Type == document.NullValue && document.ValuePath(path).IsEqual(cpath)
+	}
+
+	ppath, ptok, pv, ok := literalComparison(p)
+	if !ok || !ppath.IsEqual(cpath) {
+		return false
+	}
+
+	switch ctok {
+	case scanner.EQ:
+		// the only value matching c must match p.
+		return compareValues(cv, ptok, pv)
+	case scanner.GT, scanner.GTE:
+		if ptok != scanner.GT && ptok != scanner.GTE {
+			return false
+		}
+		if ctok == scanner.GTE && ptok == scanner.GT {
+			return compareValues(cv, scanner.GT, pv)
+		}
+		return compareValues(cv, scanner.GTE, pv)
+	case scanner.LT, scanner.LTE:
+		if ptok != scanner.LT && ptok != scanner.LTE {
+			return false
+		}
+		if ctok == scanner.LTE && ptok == scanner.LT {
+			return compareValues(cv, scanner.LT, pv)
+		}
+		return compareValues(cv, scanner.LTE, pv)
+	}
+
+	return false
+}
+
+// literalComparison returns the path, the operator and the value of a comparison
+// of a path with a literal value other than NULL, using one of the =, >, >=, < and <= operators.
+// The operator is returned as if the path was its left operand.
+func literalComparison(e expr.Expr) (document.ValuePath, scanner.Token, document.Value, bool) {
+	op, ok := e.(expr.Operator)
+	if !ok {
+		return nil, 0, document.Value{}, false
+	}
+
+	switch op.Token() {
+	case scanner.EQ, scanner.GT, scanner.GTE, scanner.LT, scanner.LTE:
+	default:
+		return nil, 0, document.Value{}, false
+	}
+
+	ok, path, e := opCanUseIndex(op)
+	if !ok {
+		return nil, 0, document.Value{}, false
+	}
+
+	lit, ok := e.(expr.LiteralValue)
+	if !ok || lit.Type == document.NullValue {
+		return nil, 0, document.Value{}, false
+	}
+
+	return document.ValuePath(path), comparisonToken(op), document.Value(lit), true
+}
+
+// compareValues compares a and b using the given comparison operator.
+// Values that can't be compared don't match.
+func compareValues(a document.Value, tok scanner.Token, b document.Value) bool {
+	var ok bool
+	var err error
+
+	switch tok {
+	case scanner.EQ:
+		ok, err = a.IsEqual(b)
+	case scanner.GT:
+		ok, err = a.IsGreaterThan(b)
+	case scanner.GTE:
+		ok, err = a.IsGreaterThanOrEqual(b)
+	case scanner.LT:
+		ok, err = a.IsLesserThan(b)
+	case scanner.LTE:
+		ok, err = a.IsLesserThanOrEqual(b)
+	}
+
+	return ok && err == nil
+}
+
+func stripParentheses(e expr.Expr) expr.Expr {
+	for {
+		p, ok := e.(expr.Parentheses)
+		if !ok {
+			return e
+		}
+
+		e = p.E
+	}
+}
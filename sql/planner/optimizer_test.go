@@ -207,6 +207,21 @@ func TestRemoveUnnecessarySelectionNodesRule(t *testing.T) {
 }
 
 func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
+	subquery := &planner.Subquery{
+		Tree: planner.NewTree(planner.NewProjectionNode(planner.NewTableInputNode("bar"),
+			[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}}}},
+			"bar")),
+		Kind: planner.ArraySubquery,
+		Text: "SELECT b FROM bar",
+	}
+	correlatedSubquery := &planner.Subquery{
+		Tree:       subquery.Tree,
+		Kind:       planner.ArraySubquery,
+		Correlated: true,
+		OuterTable: "foo",
+		Text:       "SELECT b FROM bar WHERE bar.c = foo.c",
+	}
+
 	tests := []struct {
 		name           string
 		root, expected planner.Node
@@ -335,6 +350,214 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 				"foo",
 			),
 		},
+		{
+			"FROM bar WHERE a = 1 AND b = 2",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("bar"),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+					expr.IntegerValue(2),
+				),
+			),
+			planner.NewIndexInputNode(
+				"bar",
+				"idx_bar_a_b_c",
+				expr.Eq(nil, nil).(planner.IndexIteratorOperator),
+				expr.LiteralExprList{expr.IntegerValue(1), expr.IntegerValue(2)},
+				scanner.ASC,
+			),
+		},
+		{
+			"FROM bar WHERE a = 1 AND b > 2 AND c = 3",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(
+					planner.NewSelectionNode(planner.NewTableInputNode("bar"),
+						expr.Eq(
+							expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+							expr.IntegerValue(1),
+						),
+					),
+					expr.Gt(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+						expr.IntegerValue(2),
+					),
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+					expr.IntegerValue(3),
+				),
+			),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode(
+					"bar",
+					"idx_bar_a_b_c",
+					expr.Eq(nil, nil).(planner.IndexIteratorOperator),
+					expr.LiteralExprList{expr.IntegerValue(1), expr.IntegerValue(2)},
+					scanner.ASC,
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+					expr.IntegerValue(3),
+				),
+			),
+		},
+		{
+			"FROM bar WHERE a = 1 AND c = 3",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("bar"),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+					expr.IntegerValue(3),
+				),
+			),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode(
+					"bar",
+					"idx_bar_a",
+					expr.Eq(nil, nil).(planner.IndexIteratorOperator),
+					expr.IntegerValue(1),
+					scanner.ASC,
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+					expr.IntegerValue(3),
+				),
+			),
+		},
+		{
+			"FROM foo WHERE a = 1 OR c > 2",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Or(
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+					expr.Gt(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+						expr.IntegerValue(2),
+					),
+				)),
+			planner.NewIndexUnionNode(
+				"foo",
+				planner.NewIndexInputNode(
+					"foo",
+					"idx_foo_a",
+					expr.Eq(nil, nil).(planner.IndexIteratorOperator),
+					expr.IntegerValue(1),
+					scanner.ASC,
+				),
+				planner.NewIndexInputNode(
+					"foo",
+					"idx_foo_c",
+					expr.Gt(nil, nil).(planner.IndexIteratorOperator),
+					expr.IntegerValue(2),
+					scanner.ASC,
+				),
+			),
+		},
+		{
+			"FROM foo WHERE a = 1 OR d = 2",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Or(
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "d"}},
+						expr.IntegerValue(2),
+					),
+				)),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Or(
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "d"}},
+						expr.IntegerValue(2),
+					),
+				)),
+		},
+		{
+			"FROM foo WHERE a IN (SELECT b FROM bar)",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					subquery,
+				)),
+			planner.NewIndexInputNode(
+				"foo",
+				"idx_foo_a",
+				expr.In(nil, nil).(planner.IndexIteratorOperator),
+				subquery,
+				scanner.ASC,
+			),
+		},
+		{
+			"FROM foo WHERE a NOT IN (SELECT b FROM bar)",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.NotIn(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					subquery,
+				)),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.NotIn(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					subquery,
+				)),
+		},
+		{
+			"FROM foo WHERE a IN (SELECT b FROM bar WHERE bar.c = foo.c)",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					correlatedSubquery,
+				)),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					correlatedSubquery,
+				)),
+		},
+		{
+			"FROM bar WHERE b = 2 AND c = 3",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("bar"),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+						expr.IntegerValue(2),
+					),
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+					expr.IntegerValue(3),
+				),
+			),
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("bar"),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+						expr.IntegerValue(2),
+					),
+				),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "c"}},
+					expr.IntegerValue(3),
+				),
+			),
+		},
 	}
 
 	for _, test := range tests {
@@ -350,6 +573,9 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 			err = tx.Exec(context.Background(), `
 				CREATE TABLE foo;
 				CREATE INDEX idx_foo_a ON foo(a);
+				CREATE TABLE bar;
+				CREATE INDEX idx_bar_a ON bar(a);
+				CREATE INDEX idx_bar_a_b_c ON bar(a, b, c);
 				CREATE INDEX idx_foo_b ON foo(b);
 				CREATE UNIQUE INDEX idx_foo_c ON foo(c);
 				INSERT INTO foo (a, b, c, d) VALUES
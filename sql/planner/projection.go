@@ -15,12 +15,14 @@ import (
 // document, call functions, execute arithmetic operations. etc.
 type ProjectionNode struct {
 	node
+	evalCache
 
 	Expressions []ProjectedField
 	tableName   string
 
-	info *database.TableInfo
-	tx   *database.Transaction
+	info   *database.TableInfo
+	tx     *database.Transaction
+	params []expr.Param
 }
 
 var _ operationNode = (*ProjectionNode)(nil)
@@ -40,6 +42,7 @@ func NewProjectionNode(n Node, expressions []ProjectedField, tableName string) N
 // Bind database resources to this node.
 func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
+	n.params = params
 	if n.tableName == "" {
 		return
 	}
@@ -60,28 +63,11 @@ type AggregatorBuilder interface {
 }
 
 func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
-	var aggBuilders []document.AggregatorBuilder
-
-	for _, e := range n.Expressions {
-		pe, ok := e.(ProjectedExpr)
-		if !ok {
-			continue
-		}
-		if builder, ok := pe.Expr.(AggregatorBuilder); ok {
-			aggBuilders = append(aggBuilders, builder)
-
-			if pe.ExprName != "" {
-				builder.SetAlias(pe.ExprName)
-			}
-		}
-	}
-
-	if len(aggBuilders) > 0 {
-		st = st.Aggregate(aggBuilders...)
-	}
-
 	if st.IsEmpty() {
 		d := documentMask{
+			tx:           n.tx,
+			params:       n.params,
+			cache:        n.cache,
 			resultFields: n.Expressions,
 		}
 		var fb document.FieldBuffer
@@ -93,8 +79,12 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 		st = document.NewStream(document.NewIterator(&fb))
 	} else {
 		var dm documentMask
+		cache := n.cache
 		st = st.Map(func(d document.Document) (document.Document, error) {
 			dm.info = n.info
+			dm.tx = n.tx
+			dm.params = n.params
+			dm.cache = cache
 			dm.d = d
 			dm.resultFields = n.Expressions
 
@@ -120,6 +110,9 @@ func (n *ProjectionNode) String() string {
 
 type documentMask struct {
 	info         *database.TableInfo
+	tx           *database.Transaction
+	params       []expr.Param
+	cache        map[expr.Expr]interface{}
 	d            document.Document
 	resultFields []ProjectedField
 }
@@ -128,9 +121,21 @@ var _ document.Document = documentMask{}
 
 func (r documentMask) GetByField(field string) (document.Value, error) {
 	for _, rf := range r.resultFields {
-		if rf.Name() == field || rf.Name() == "*" {
+		if rf.Name() == "*" {
 			return r.d.GetByField(field)
 		}
+
+		// projected expressions are evaluated, to allow
+		// selecting them using their alias.
+		if pe, ok := rf.(ProjectedExpr); ok && pe.ExprName == field {
+			return pe.Expr.Eval(expr.EvalStack{
+				Tx:       r.tx,
+				Document: r.d,
+				Params:   r.params,
+				Info:     r.info,
+				Cache:    r.cache,
+			})
+		}
 	}
 
 	return document.Value{}, document.ErrFieldNotFound
@@ -138,8 +143,11 @@ func (r documentMask) GetByField(field string) (document.Value, error) {
 
 func (r documentMask) Iterate(fn func(field string, value document.Value) error) error {
 	stack := expr.EvalStack{
+		Tx:       r.tx,
 		Document: r.d,
+		Params:   r.params,
 		Info:     r.info,
+		Cache:    r.cache,
 	}
 
 	for _, rf := range r.resultFields {
@@ -152,6 +160,15 @@ func (r documentMask) Iterate(fn func(field string, value document.Value) error)
 	return nil
 }
 
+// Score returns the relevance score of the original document, if any.
+func (r documentMask) Score() (float64, bool) {
+	if sc, ok := r.d.(expr.Scorer); ok {
+		return sc.Score()
+	}
+
+	return 0, false
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (r documentMask) MarshalJSON() ([]byte, error) {
 	return document.MarshalJSON(r)
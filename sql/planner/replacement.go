@@ -20,6 +20,8 @@ type replacementNode struct {
 	tableName string
 	table     *database.Table
 	codec     encoding.Codec
+	// if true, the node returns the replaced documents.
+	returning bool
 }
 
 var _ operationNode = (*replacementNode)(nil)
@@ -58,6 +60,7 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 
 	keys := make([][]byte, replaceBufferSize)
 	docs := make([]document.FieldBuffer, replaceBufferSize)
+	var replaced [][]byte
 
 	var err error
 	for {
@@ -87,6 +90,10 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 			if err != nil {
 				return document.Stream{}, err
 			}
+
+			if n.returning {
+				replaced = append(replaced, append([]byte(nil), keys[j]...))
+			}
 		}
 
 		if i < replaceBufferSize {
@@ -96,6 +103,10 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 		rit.curKey = keys[i-1]
 	}
 
+	if n.returning && err == nil {
+		return document.NewStream(keysIterator{table: n.table, keys: replaced}), nil
+	}
+
 	return document.Stream{}, err
 }
 
@@ -103,6 +114,28 @@ func (n *replacementNode) String() string {
 	return fmt.Sprintf("Replace(%s)", n.tableName)
 }
 
+// keysIterator iterates over the documents of a table stored at the given keys.
+type keysIterator struct {
+	table *database.Table
+	keys  [][]byte
+}
+
+func (it keysIterator) Iterate(fn func(d document.Document) error) error {
+	for _, k := range it.keys {
+		d, err := it.table.GetDocument(k)
+		if err != nil {
+			return err
+		}
+
+		err = fn(d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // storeFromKey implements an engine.Store which iterates from a certain key.
 // it is used to resume iteration.
 type resumableIterator struct {
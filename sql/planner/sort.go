@@ -1,31 +1,93 @@
 package planner
 
 import (
+	"bufio"
 	"bytes"
 	"container/heap"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
 
+// NullsOrder describes where NULL values are placed by a sort.
+type NullsOrder int
+
+const (
+	// NullsDefault places NULL values first when sorting in ascending order
+	// and last when sorting in descending order, since NULL is the smallest value.
+	NullsDefault NullsOrder = iota
+	// NullsFirst places NULL values before any other value.
+	NullsFirst
+	// NullsLast places NULL values after any other value.
+	NullsLast
+)
+
+// A SortKey is an expression used to sort a stream, with its own
+// direction and placement of NULL values.
+type SortKey struct {
+	Expr      expr.Expr
+	Direction scanner.Token
+	Nulls     NullsOrder
+}
+
+func (k SortKey) String() string {
+	dir := "ASC"
+	if k.Direction == scanner.DESC {
+		dir = "DESC"
+	}
+
+	switch k.Nulls {
+	case NullsFirst:
+		return fmt.Sprintf("%s %s NULLS FIRST", k.Expr, dir)
+	case NullsLast:
+		return fmt.Sprintf("%s %s NULLS LAST", k.Expr, dir)
+	}
+
+	return fmt.Sprintf("%s %s", k.Expr, dir)
+}
+
+// nullsFirst reports whether NULL values must be placed before other values.
+func (k SortKey) nullsFirst() bool {
+	if k.Nulls == NullsDefault {
+		return k.Direction != scanner.DESC
+	}
+
+	return k.Nulls == NullsFirst
+}
+
 type sortNode struct {
 	node
+	evalCache
+
+	keys []SortKey
+	// if greater than zero, only the first limit documents
+	// of the sorted stream will be read.
+	limit int
 
-	sortField expr.FieldSelector
-	direction scanner.Token
+	tx     *database.Transaction
+	params []expr.Param
 }
 
 var _ operationNode = (*sortNode)(nil)
 
-// NewSortNode creates a node that sorts a stream according to a given
-// document path and a sort direction.
-func NewSortNode(n Node, sortField expr.FieldSelector, direction scanner.Token) Node {
-	if direction == 0 {
-		direction = scanner.ASC
+// NewSortNode creates a node that sorts a stream according to a list of keys.
+// Documents are compared using the first key, then the second key if they are equal, and so on.
+func NewSortNode(n Node, keys ...SortKey) Node {
+	for i := range keys {
+		if keys[i].Direction == 0 {
+			keys[i].Direction = scanner.ASC
+		}
 	}
 
 	return &sortNode{
@@ -33,100 +95,118 @@ func NewSortNode(n Node, sortField expr.FieldSelector, direction scanner.Token)
 			op:   Sort,
 			left: n,
 		},
-		sortField: sortField,
-		direction: direction,
+		keys: keys,
 	}
 }
 
 func (n *sortNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+	n.params = params
 	return
 }
 
 func (n *sortNode) toStream(st document.Stream) (document.Stream, error) {
 	return document.NewStream(&sortIterator{
-		st:        st,
-		sortField: n.sortField,
-		direction: n.direction,
+		st:         st,
+		keys:       n.keys,
+		limit:      n.limit,
+		codec:      n.tx.DB().Codec,
+		bufferSize: n.tx.DB().SortBufferSize,
+		stack: expr.EvalStack{
+			Tx:     n.tx,
+			Params: n.params,
+			Cache:  n.cache,
+		},
 	}), nil
 }
 
 func (n *sortNode) String() string {
-	dir := "ASC"
-	if n.direction == scanner.DESC {
-		dir = "DESC"
+	var b strings.Builder
+
+	for i, k := range n.keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k.String())
 	}
 
-	return fmt.Sprintf("Sort(%s %s)", n.sortField, dir)
+	return fmt.Sprintf("Sort(%s)", b.String())
 }
 
 type sortIterator struct {
-	st        document.Stream
-	sortField expr.FieldSelector
-	direction scanner.Token
+	st         document.Stream
+	keys       []SortKey
+	limit      int
+	codec      encoding.Codec
+	bufferSize int
+	stack      expr.EvalStack
 }
 
+// Iterate sorts the stream using an external merge sort.
+// Documents are encoded and kept in memory until their size exceeds the buffer size,
+// at which point they are sorted and written to a temporary file, called a run.
+// Once the stream is entirely read, the runs and the documents remaining in memory
+// are merged and returned in order.
+// If only the first k documents are read, where k is the sum of the OFFSET and
+// LIMIT clauses, the documents are kept in a bounded heap of size k, which ensures
+// a O(n log k) time complexity and avoids writing runs, unless the k documents
+// don't fit in memory.
 func (it *sortIterator) Iterate(fn func(d document.Document) error) error {
-	h, err := it.sortStream(it.st)
-	if err != nil {
-		return err
+	s := sorter{
+		limit:      it.limit,
+		bufferSize: it.bufferSize,
 	}
+	defer s.close()
 
-	for h.Len() > 0 {
-		node := heap.Pop(h).(heapNode)
-		err := fn(&(node.data))
+	var buf bytes.Buffer
+	err := it.st.Iterate(func(d document.Document) error {
+		k, err := it.sortKey(d)
 		if err != nil {
 			return err
 		}
+
+		buf.Reset()
+		err = it.codec.NewEncoder(&buf).EncodeDocument(d)
+		if err != nil {
+			return err
+		}
+
+		return s.add(k, append([]byte(nil), buf.Bytes()...))
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return s.iterate(func(_, data []byte) error {
+		return fn(it.codec.NewDocument(data))
+	})
 }
 
-// sortStream operates a partial sort on the iterator using a heap.
-// This ensures a O(k+n log n) time complexity, where k is the sum of
-// OFFSET + LIMIT clauses, if provided, otherwise k = n.
-// If the sorting is in ascending order, a min-heap will be used
-// otherwise a max-heap will be used instead.
-// Once the heap is filled entirely with the content of the table a stream is returned.
-// During iteration, the stream will pop the k-smallest or k-largest elements, depending on
-// the chosen sorting order (ASC or DESC).
-// This function is not memory efficient as it's loading the entire stream in memory before
-// returning the k-smallest or k-largest elements.
-func (it *sortIterator) sortStream(st document.Stream) (heap.Interface, error) {
-	path := document.ValuePath(it.sortField)
-
-	var h heap.Interface
-	if it.direction == scanner.ASC {
-		h = new(minHeap)
-	} else {
-		h = new(maxHeap)
-	}
-
-	heap.Init(h)
-
-	return h, st.Iterate(func(d document.Document) error {
-		// It is possible to sort by any projected field
-		// or field of the original document.
-		v, err := path.GetValue(d)
-		if err != nil && err != document.ErrFieldNotFound {
-			return err
+// sortKey evaluates the sort keys against d and encodes the results
+// so that sorting the keys using bytes.Compare sorts the documents.
+// Each value is encoded, escaped and terminated so that it never is the prefix
+// of another value, allowing its bytes to be inverted to sort in descending order.
+func (it *sortIterator) sortKey(d document.Document) ([]byte, error) {
+	it.stack.Document = sortDocument{d}
+
+	var buf []byte
+	for _, k := range it.keys {
+		v, err := k.Expr.Eval(it.stack)
+		if err != nil {
+			return nil, err
 		}
 
-		// If a field is not found in the projected fields
-		// Look for fields in the original document.
-		if err == document.ErrFieldNotFound {
-			if dm, ok := d.(*documentMask); ok {
-				v, err = path.GetValue(dm.d)
-				if err != nil && err != document.ErrFieldNotFound {
-					return err
-				}
-				if err == document.ErrFieldNotFound {
-					v = document.NewNullValue()
-				}
+		// NULL values are placed before or after any other value
+		// regardless of the direction.
+		if v.Type == document.NullValue {
+			if k.nullsFirst() {
+				buf = append(buf, 0)
 			} else {
-				v = document.NewNullValue()
+				buf = append(buf, 2)
 			}
+			continue
 		}
+		buf = append(buf, 1)
 
 		// We need to make sure sort behaviour
 		// if the same with or without indexes.
@@ -135,15 +215,7 @@ func (it *sortIterator) sortStream(st document.Stream) (heap.Interface, error) {
 		if v.Type == document.IntegerValue {
 			v, err = v.CastAsDouble()
 			if err != nil {
-				return err
-			}
-		}
-
-		var value []byte
-		if v.Type != document.ArrayValue && v.Type != document.DocumentValue {
-			value, err = key.AppendValue(nil, v)
-			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -153,49 +225,348 @@ func (it *sortIterator) sortStream(st document.Stream) (heap.Interface, error) {
 		// we will prepend the encoded value with one byte
 		// representing the type of the value.
 		// integer will be considered as double
-		value = append([]byte{byte(v.Type)}, value...)
+		value := []byte{byte(v.Type)}
+		if v.Type != document.ArrayValue && v.Type != document.DocumentValue {
+			value, err = key.AppendValue(value, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		start := len(buf)
+		buf = appendEscaped(buf, value)
+		if k.Direction == scanner.DESC {
+			for i := start; i < len(buf); i++ {
+				buf[i] = ^buf[i]
+			}
+		}
+	}
+
+	return buf, nil
+}
+
+// appendEscaped appends b to buf, followed by the 0x00 0x01 terminator.
+// Every 0x00 byte of b is escaped as 0x00 0xFF, which ensures the
+// escaped value preserves the ordering of b and is never the prefix
+// of another escaped value.
+func appendEscaped(buf, b []byte) []byte {
+	for _, c := range b {
+		if c == 0 {
+			buf = append(buf, 0, 0xFF)
+		} else {
+			buf = append(buf, c)
+		}
+	}
+
+	return append(buf, 0, 1)
+}
+
+// sortDocument is the document used to evaluate the sort keys.
+// It is possible to sort by any projected field
+// or field of the original document.
+type sortDocument struct {
+	document.Document
+}
+
+func (d sortDocument) GetByField(field string) (document.Value, error) {
+	v, err := d.Document.GetByField(field)
+	if err != document.ErrFieldNotFound {
+		return v, err
+	}
+
+	// If a field is not found in the projected fields
+	// Look for fields in the original document.
+	if dm, ok := d.Document.(*documentMask); ok {
+		return dm.d.GetByField(field)
+	}
+
+	return v, err
+}
+
+// Score returns the relevance score of the original document, if any.
+func (d sortDocument) Score() (float64, bool) {
+	if sc, ok := d.Document.(expr.Scorer); ok {
+		return sc.Score()
+	}
+
+	return 0, false
+}
+
+// A sortItem is an encoded document associated with its sort key.
+type sortItem struct {
+	key []byte
+	doc []byte
+	// position of the document in the stream, used to keep
+	// documents with the same key in their original order.
+	seq int
+}
+
+// sorter sorts items using at most bufferSize bytes of memory, writing
+// sorted runs to temporary files when needed.
+type sorter struct {
+	limit      int
+	bufferSize int
+
+	items []sortItem
+	size  int
+	seq   int
+	runs  []*os.File
+}
+
+// less reports whether a must be returned before b.
+func (s *sorter) less(a, b *sortItem) bool {
+	c := bytes.Compare(a.key, b.key)
+	if c == 0 {
+		return a.seq < b.seq
+	}
+
+	return c < 0
+}
+
+// the following methods implement the heap.Interface interface.
+// When limit is set, items are kept in a heap whose root is the item
+// that must be returned last, so that it can be replaced when a
+// better item is added.
+func (s *sorter) Len() int           { return len(s.items) }
+func (s *sorter) Less(i, j int) bool { return s.less(&s.items[j], &s.items[i]) }
+func (s *sorter) Swap(i, j int)      { s.items[i], s.items[j] = s.items[j], s.items[i] }
+
+func (s *sorter) Push(x interface{}) {
+	s.items = append(s.items, x.(sortItem))
+}
+
+func (s *sorter) Pop() interface{} {
+	n := len(s.items)
+	x := s.items[n-1]
+	s.items = s.items[:n-1]
+	return x
+}
+
+func (s *sorter) add(k, doc []byte) error {
+	item := sortItem{key: k, doc: doc, seq: s.seq}
+	s.seq++
+
+	switch {
+	case s.limit <= 0:
+		s.items = append(s.items, item)
+	case len(s.items) < s.limit:
+		heap.Push(s, item)
+	case s.less(&item, &s.items[0]):
+		s.size -= len(s.items[0].key) + len(s.items[0].doc)
+		s.items[0] = item
+		heap.Fix(s, 0)
+	default:
+		return nil
+	}
+
+	s.size += len(k) + len(doc)
+	if s.size > s.bufferSize {
+		return s.spill()
+	}
+
+	return nil
+}
+
+// sortItems sorts the items kept in memory.
+func (s *sorter) sortItems() {
+	sort.Slice(s.items, func(i, j int) bool {
+		return s.less(&s.items[i], &s.items[j])
+	})
+}
+
+// spill sorts the items kept in memory and writes them to a temporary file.
+func (s *sorter) spill() error {
+	s.sortItems()
+
+	f, err := ioutil.TempFile("", "genji-sort-")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f)
+
+	w := bufio.NewWriter(f)
+	var buf [binary.MaxVarintLen64]byte
+	for _, item := range s.items {
+		for _, b := range [][]byte{item.key, item.doc} {
+			n := binary.PutUvarint(buf[:], uint64(len(b)))
+			_, err = w.Write(buf[:n])
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(b)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
 
-		node := heapNode{
-			value: value,
+	s.items = s.items[:0]
+	s.size = 0
+	return nil
+}
+
+// iterate calls fn with every document and its key, in order.
+func (s *sorter) iterate(fn func(k, doc []byte) error) error {
+	s.sortItems()
+
+	// if everything fits in memory, no need to merge.
+	if len(s.runs) == 0 {
+		for i := range s.items {
+			err := fn(s.items[i].key, s.items[i].doc)
+			if err != nil {
+				return err
+			}
 		}
-		err = node.data.Copy(d)
+
+		return nil
+	}
+
+	// otherwise, merge the runs and the items kept in memory.
+	// runs and items are given a sequence number corresponding to their
+	// order of creation, to keep items with the same key in order.
+	m := merger{sorter: s}
+	for i, f := range s.runs {
+		_, err := f.Seek(0, io.SeekStart)
 		if err != nil {
 			return err
 		}
 
-		heap.Push(h, node)
+		m.cursors = append(m.cursors, &runCursor{r: bufio.NewReader(f), seq: i})
+	}
+	m.cursors = append(m.cursors, &runCursor{items: s.items, seq: len(s.runs)})
+
+	return m.merge(fn)
+}
+
+// close removes the temporary files.
+func (s *sorter) close() {
+	for _, f := range s.runs {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	s.runs = nil
+	s.items = nil
+}
+
+// A runCursor reads the items of a run, either from a temporary file
+// or from memory.
+type runCursor struct {
+	r     *bufio.Reader
+	items []sortItem
+	seq   int
+
+	cur sortItem
+}
+
+// next reads the next item of the run. It returns io.EOF
+// if the run has been entirely read.
+func (c *runCursor) next() error {
+	if c.r == nil {
+		if len(c.items) == 0 {
+			return io.EOF
+		}
 
+		c.cur = c.items[0]
+		c.cur.seq = c.seq
+		c.items = c.items[1:]
 		return nil
-	})
+	}
+
+	k, err := c.readBytes()
+	if err != nil {
+		return err
+	}
+
+	doc, err := c.readBytes()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	c.cur = sortItem{key: k, doc: doc, seq: c.seq}
+	return nil
 }
 
-type heapNode struct {
-	value []byte
-	data  document.FieldBuffer
+func (c *runCursor) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, n)
+	_, err = io.ReadFull(c.r, b)
+	return b, err
 }
 
-type minHeap []heapNode
+// merger merges sorted runs using a heap of cursors.
+type merger struct {
+	*sorter
+
+	cursors []*runCursor
+}
 
-func (h minHeap) Len() int           { return len(h) }
-func (h minHeap) Less(i, j int) bool { return bytes.Compare(h[i].value, h[j].value) < 0 }
-func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (m *merger) Len() int           { return len(m.cursors) }
+func (m *merger) Less(i, j int) bool { return m.less(&m.cursors[i].cur, &m.cursors[j].cur) }
+func (m *merger) Swap(i, j int)      { m.cursors[i], m.cursors[j] = m.cursors[j], m.cursors[i] }
 
-func (h *minHeap) Push(x interface{}) {
-	*h = append(*h, x.(heapNode))
+func (m *merger) Push(x interface{}) {
+	m.cursors = append(m.cursors, x.(*runCursor))
 }
 
-func (h *minHeap) Pop() interface{} {
-	old := *h
-	n := len(old)
-	x := old[n-1]
-	*h = old[0 : n-1]
+func (m *merger) Pop() interface{} {
+	n := len(m.cursors)
+	x := m.cursors[n-1]
+	m.cursors = m.cursors[:n-1]
 	return x
 }
 
-type maxHeap struct {
-	minHeap
-}
+func (m *merger) merge(fn func(k, doc []byte) error) error {
+	cursors := m.cursors
+	m.cursors = m.cursors[:0]
+	for _, c := range cursors {
+		err := c.next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		m.cursors = append(m.cursors, c)
+	}
+	heap.Init(m)
+
+	var count int
+	for m.Len() > 0 {
+		if m.limit > 0 && count == m.limit {
+			return nil
+		}
+
+		c := m.cursors[0]
+		err := fn(c.cur.key, c.cur.doc)
+		if err != nil {
+			return err
+		}
+		count++
 
-func (h maxHeap) Less(i, j int) bool {
-	return bytes.Compare(h.minHeap[i].value, h.minHeap[j].value) > 0
+		err = c.next()
+		if err == io.EOF {
+			heap.Pop(m)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		heap.Fix(m, 0)
+	}
+
+	return nil
 }
@@ -0,0 +1,351 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// SubqueryKind determines how the documents returned by a subquery are turned into a value.
+type SubqueryKind int
+
+const (
+	// ScalarSubquery evaluates to the value of the only field of the document returned
+	// by the subquery, or to NULL if it doesn't return any document.
+	ScalarSubquery SubqueryKind = iota
+	// ArraySubquery evaluates to an array containing the value of the only field
+	// of every document returned by the subquery. It is used by the IN operator.
+	ArraySubquery
+	// ExistsSubquery evaluates to true if the subquery returns at least one document.
+	ExistsSubquery
+	// NotExistsSubquery evaluates to true if the subquery doesn't return any document.
+	NotExistsSubquery
+)
+
+// A Subquery is an expression that evaluates a SELECT statement nested in another statement.
+// An uncorrelated subquery is evaluated once per execution of the tree that uses it and its result is cached.
+// A correlated subquery references the document currently evaluated by the enclosing statement,
+// using the name of the table it belongs to (i.e. "table.field"), and is evaluated for every document.
+// A Subquery is not modified by its evaluation: the state of each execution is stored in the
+// cache of the evaluation stack.
+type Subquery struct {
+	Tree *Tree
+	Kind SubqueryKind
+	// Correlated is true if the subquery references the documents of an enclosing statement.
+	Correlated bool
+	// OuterTable is the name of the table read by the enclosing statement.
+	// It is empty if the enclosing statement reads the documents of a join,
+	// which are already qualified by table name.
+	OuterTable string
+	// Text is the SQL representation of the nested statement.
+	Text string
+}
+
+// A subqueryRun holds the state of a subquery during one execution
+// of the tree that uses it.
+type subqueryRun struct {
+	tree         *Tree
+	correlations []*correlationNode
+	result       *document.Value
+}
+
+// prepare binds and optimizes the tree of the subquery for a new execution.
+// Like the trees of statements, the tree is optimized every time it is run.
+func (s *Subquery) prepare(tx *database.Transaction, params []expr.Param) (*subqueryRun, error) {
+	err := Bind(s.Tree, tx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := Optimize(s.Tree)
+	if err != nil {
+		return nil, err
+	}
+
+	run := subqueryRun{tree: t}
+	if s.Correlated && t.Root != nil {
+		run.correlations = correlate(t)
+	}
+
+	return &run, nil
+}
+
+// Eval runs the subquery and turns its result into a value, depending on its kind.
+// It implements the expr.Expr interface.
+func (s *Subquery) Eval(stack expr.EvalStack) (document.Value, error) {
+	if stack.Tx == nil {
+		return document.Value{}, errors.New("subqueries can only be evaluated within a transaction")
+	}
+
+	run, ok := stack.Cache[s].(*subqueryRun)
+	if !ok {
+		var err error
+		run, err = s.prepare(stack.Tx, stack.Params)
+		if err != nil {
+			return document.Value{}, err
+		}
+
+		if stack.Cache != nil {
+			stack.Cache[s] = run
+		}
+	}
+
+	if run.result != nil {
+		return *run.result, nil
+	}
+
+	st, err := s.stream(run, stack)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	var v document.Value
+	switch s.Kind {
+	case ExistsSubquery, NotExistsSubquery:
+		var found bool
+		err = st.Iterate(func(d document.Document) error {
+			found = true
+			return errStop
+		})
+		if err != nil && err != errStop {
+			return document.Value{}, err
+		}
+
+		v = document.NewBoolValue(found == (s.Kind == ExistsSubquery))
+	case ArraySubquery:
+		var vb document.ValueBuffer
+		err = st.Iterate(func(d document.Document) error {
+			fv, err := onlyFieldValue(d)
+			if err != nil {
+				return err
+			}
+
+			vb = vb.Append(fv)
+			return nil
+		})
+		if err != nil {
+			return document.Value{}, err
+		}
+
+		v = document.NewArrayValue(vb)
+	default:
+		v = document.NewNullValue()
+		var found bool
+		err = st.Iterate(func(d document.Document) error {
+			if found {
+				return errors.New("subquery used as an expression returned more than one document")
+			}
+			found = true
+
+			v, err = onlyFieldValue(d)
+			return err
+		})
+		if err != nil {
+			return document.Value{}, err
+		}
+	}
+
+	if !s.Correlated {
+		run.result = &v
+	}
+
+	return v, nil
+}
+
+// stream binds the nested statement and returns its stream of documents.
+func (s *Subquery) stream(run *subqueryRun, stack expr.EvalStack) (document.Stream, error) {
+	t := run.tree
+	if t.Root == nil {
+		return document.Stream{}, nil
+	}
+
+	err := Bind(t, stack.Tx, stack.Params)
+	if err != nil {
+		return document.Stream{}, err
+	}
+	bindCache(t, stack.Cache)
+
+	outer := stack.Document
+	if s.OuterTable != "" && stack.Document != nil {
+		outer = qualifiedDocument{tableName: s.OuterTable, d: stack.Document}
+	}
+	for _, cn := range run.correlations {
+		cn.outer = outer
+	}
+
+	res, err := t.execute()
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	return res.Stream, nil
+}
+
+func (s *Subquery) String() string {
+	switch s.Kind {
+	case ExistsSubquery:
+		return fmt.Sprintf("EXISTS (%s)", s.Text)
+	case NotExistsSubquery:
+		return fmt.Sprintf("NOT EXISTS (%s)", s.Text)
+	}
+
+	return fmt.Sprintf("(%s)", s.Text)
+}
+
+// onlyFieldValue returns a copy of the value of the only field of d.
+func onlyFieldValue(d document.Document) (document.Value, error) {
+	var fb document.FieldBuffer
+	err := fb.Copy(d)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	if fb.Len() != 1 {
+		return document.Value{}, errors.New("subquery used as an expression must return only one field")
+	}
+
+	var v document.Value
+	err = fb.Iterate(func(_ string, value document.Value) error {
+		v = value
+		return nil
+	})
+	return v, err
+}
+
+// correlate inserts a correlation node right above the input node of the tree,
+// or above the last join node if the tree reads the documents of a join,
+// and returns the correlation nodes of the tree.
+// Statements combined by UNION, INTERSECT or EXCEPT are correlated separately.
+func correlate(t *Tree) []*correlationNode {
+	var prev Node
+
+	n := t.Root
+	for n != nil && n.Operation() != Input && n.Operation() != Join {
+		switch nn := n.(type) {
+		case *combinationNode:
+			return append(correlate(nn.lhs), correlate(nn.rhs)...)
+		case *correlationNode:
+			// the tree was already correlated by a previous execution.
+			return []*correlationNode{nn}
+		}
+
+		prev = n
+		n = n.Left()
+	}
+
+	cn := &correlationNode{
+		node: node{
+			op:   Correlation,
+			left: n,
+		},
+	}
+
+	if tn, ok := n.(tableNamer); ok {
+		cn.tableName = tn.TableName()
+	}
+
+	if prev == nil {
+		t.Root = cn
+	} else {
+		prev.SetLeft(cn)
+	}
+
+	return []*correlationNode{cn}
+}
+
+// A correlationNode is used by correlated subqueries to make the document
+// evaluated by the enclosing statement available to every document of the stream.
+type correlationNode struct {
+	node
+
+	tableName string
+	// document of the enclosing statement, set right before
+	// the stream is built.
+	outer document.Document
+}
+
+var _ operationNode = (*correlationNode)(nil)
+
+func (n *correlationNode) Bind(tx *database.Transaction, params []expr.Param) error {
+	return nil
+}
+
+func (n *correlationNode) toStream(st document.Stream) (document.Stream, error) {
+	var cd correlatedDocument
+	outer := n.outer
+
+	return st.Map(func(d document.Document) (document.Document, error) {
+		cd.Document = d
+		cd.tableName = n.tableName
+		cd.outer = outer
+
+		return &cd, nil
+	}), nil
+}
+
+func (n *correlationNode) String() string {
+	return "Correlation()"
+}
+
+// A correlatedDocument is a document read by a correlated subquery.
+// Fields that it doesn't contain are looked up in the document of the enclosing statement.
+// The document can also be selected by the name of its table.
+type correlatedDocument struct {
+	document.Document
+
+	tableName string
+	outer     document.Document
+}
+
+func (d *correlatedDocument) GetByField(field string) (document.Value, error) {
+	v, err := d.Document.GetByField(field)
+	if err != document.ErrFieldNotFound {
+		return v, err
+	}
+
+	if d.tableName != "" && field == d.tableName {
+		return document.NewDocumentValue(d.Document), nil
+	}
+
+	if d.outer == nil {
+		return v, err
+	}
+
+	return d.outer.GetByField(field)
+}
+
+// Key returns the key of the underlying document, if any.
+func (d *correlatedDocument) Key() []byte {
+	if k, ok := d.Document.(document.Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
+
+// A qualifiedDocument exposes a document as a field named after its table.
+// If the document belongs to a correlated subquery itself, the documents
+// of its enclosing statements remain accessible.
+type qualifiedDocument struct {
+	tableName string
+	d         document.Document
+}
+
+func (q qualifiedDocument) GetByField(field string) (document.Value, error) {
+	if field == q.tableName {
+		return document.NewDocumentValue(q.d), nil
+	}
+
+	if cd, ok := q.d.(*correlatedDocument); ok && cd.outer != nil {
+		return cd.outer.GetByField(field)
+	}
+
+	return document.Value{}, document.ErrFieldNotFound
+}
+
+func (q qualifiedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return fn(q.tableName, document.NewDocumentValue(q.d))
+}
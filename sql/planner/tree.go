@@ -8,6 +8,7 @@ package planner
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -42,6 +43,20 @@ const (
 	Set
 	// Unset is an operation that removes a path from every document of a stream
 	Unset
+	// Join is an operation that combines the documents of two streams based on a given condition.
+	Join
+	// Insertion is an operation that inserts documents in a table and returns them as a stream.
+	Insertion
+	// Correlation is an operation that gives the documents of a stream access to the document
+	// currently evaluated by an enclosing statement.
+	Correlation
+	// Distinct is an operation that removes duplicate documents from a stream.
+	Distinct
+	// Combination is an operation that combines the documents of two statements
+	// using UNION, INTERSECT or EXCEPT.
+	Combination
+	// Aggregation is an operation that computes aggregate functions for each group of documents.
+	Aggregation
 	// Group is an operation that groups documents based on a given path.
 )
 
@@ -49,6 +64,9 @@ const (
 // Each node will manipulate the stream using relational algebra operations.
 type Tree struct {
 	Root Node
+	// Subqueries used by the expressions of the tree.
+	// They are prepared every time the tree is run.
+	Subqueries []*Subquery
 }
 
 // NewTree creates a new tree with n as root.
@@ -59,12 +77,7 @@ func NewTree(n Node) *Tree {
 // Run implements the query.Statement interface.
 // It binds the tree to the database resources and executes it.
 func (t *Tree) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
-	err := Bind(t, tx, params)
-	if err != nil {
-		return query.Result{}, err
-	}
-
-	t, err = Optimize(t)
+	t, err := t.prepare(tx, params)
 	if err != nil {
 		return query.Result{}, err
 	}
@@ -72,25 +85,51 @@ func (t *Tree) Run(ctx context.Context, tx *database.Transaction, params []expr.
 	return t.execute()
 }
 
-func (t *Tree) execute() (query.Result, error) {
-	var st document.Stream
-	var err error
-
-	if t.Root.Left() != nil {
-		st, err = nodeToStream(t.Root.Left())
+// prepare binds and optimizes the tree and its subqueries for a new execution.
+// The state of the subqueries is stored in a cache created for this execution,
+// which is passed to the nodes of the optimized tree.
+func (t *Tree) prepare(tx *database.Transaction, params []expr.Param) (*Tree, error) {
+	cache := make(map[expr.Expr]interface{})
+	for _, sq := range t.Subqueries {
+		run, err := sq.prepare(tx, params)
 		if err != nil {
-			return query.Result{}, err
+			return nil, err
 		}
+
+		cache[sq] = run
+	}
+
+	err := Bind(t, tx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err = Optimize(t)
+	if err != nil {
+		return nil, err
 	}
 
-	st, err = t.Root.(operationNode).toStream(st)
+	bindCache(t, cache)
+	return t, nil
+}
+
+func (t *Tree) execute() (query.Result, error) {
+	st, err := nodeToStream(t.Root)
 	if err != nil {
 		return query.Result{}, err
 	}
 
-	return query.Result{
+	res := query.Result{
 		Stream: st,
-	}, nil
+	}
+
+	for n := t.Root; n != nil; n = n.Left() {
+		if rn, ok := n.(releaserNode); ok {
+			res.OnClose(rn.release)
+		}
+	}
+
+	return res, nil
 }
 
 func (t *Tree) String() string {
@@ -165,6 +204,14 @@ type operationNode interface {
 	toStream(st document.Stream) (document.Stream, error)
 }
 
+// A releaserNode holds resources needed by its stream, which are
+// released when the result is closed, whether the stream was read or not.
+type releaserNode interface {
+	Node
+
+	release()
+}
+
 type node struct {
 	op          Operation
 	left, right Node
@@ -192,6 +239,7 @@ func (n *node) SetRight(rn Node) {
 
 type selectionNode struct {
 	node
+	evalCache
 
 	cond   expr.Expr
 	tx     *database.Transaction
@@ -226,6 +274,7 @@ func (n *selectionNode) toStream(st document.Stream) (document.Stream, error) {
 	stack := expr.EvalStack{
 		Tx:     n.tx,
 		Params: n.params,
+		Cache:  n.cache,
 	}
 
 	return st.Filter(func(d document.Document) (bool, error) {
@@ -319,6 +368,7 @@ func (n *offsetNode) toStream(st document.Stream) (document.Stream, error) {
 
 type setNode struct {
 	node
+	evalCache
 
 	path document.ValuePath
 	e    expr.Expr
@@ -357,6 +407,7 @@ func (n *setNode) toStream(st document.Stream) (document.Stream, error) {
 	stack := expr.EvalStack{
 		Tx:     n.tx,
 		Params: n.params,
+		Cache:  n.cache,
 	}
 
 	return st.Map(func(d document.Document) (document.Document, error) {
@@ -438,25 +489,27 @@ func (n *unsetNode) String() string {
 	return fmt.Sprintf("Unset(%s)", n.field)
 }
 
-// A GroupingNode is a node that groups documents by a given path.
+// A GroupingNode is a node that groups documents by the values of a list of expressions.
 type GroupingNode struct {
 	node
+	evalCache
 
-	Expr   expr.Expr
+	Exprs  []expr.Expr
 	Tx     *database.Transaction
 	Params []expr.Param
 }
 
 var _ operationNode = (*GroupingNode)(nil)
 
-// NewGroupingNode creates a GroupingNode.
-func NewGroupingNode(n Node, e expr.Expr) Node {
+// NewGroupingNode creates a GroupingNode. Documents for which every expression
+// evaluates to the same value belong to the same group.
+func NewGroupingNode(n Node, exprs ...expr.Expr) Node {
 	return &GroupingNode{
 		node: node{
 			op:   Projection,
 			left: n,
 		},
-		Expr: e,
+		Exprs: exprs,
 	}
 }
 
@@ -468,15 +521,81 @@ func (n *GroupingNode) Bind(tx *database.Transaction, params []expr.Param) (err
 }
 
 func (n *GroupingNode) toStream(st document.Stream) (document.Stream, error) {
-	return st.GroupBy(func(d document.Document) (document.Value, error) {
-		return n.Expr.Eval(expr.EvalStack{
-			Tx:       n.Tx,
-			Params:   n.Params,
-			Document: d,
-		})
-	}), nil
+	groupFns := make([]func(d document.Document) (document.Value, error), len(n.Exprs))
+	cache := n.cache
+	for i := range n.Exprs {
+		e := n.Exprs[i]
+		groupFns[i] = func(d document.Document) (document.Value, error) {
+			return e.Eval(expr.EvalStack{
+				Tx:       n.Tx,
+				Params:   n.Params,
+				Document: d,
+				Cache:    cache,
+			})
+		}
+	}
+
+	return st.GroupBy(groupFns...), nil
 }
 
 func (n *GroupingNode) String() string {
-	return fmt.Sprintf("G(%s)", n.Expr)
+	var b strings.Builder
+
+	for i, e := range n.Exprs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%v", e)
+	}
+
+	return fmt.Sprintf("G(%s)", b.String())
+}
+
+// An AggregationNode is a node that computes aggregate functions, like COUNT or MAX,
+// for each group of documents of the stream, and returns one document per group.
+type AggregationNode struct {
+	node
+
+	Builders []AggregatorBuilder
+}
+
+var _ operationNode = (*AggregationNode)(nil)
+
+// NewAggregationNode creates an AggregationNode. If the documents were not
+// grouped by a GroupingNode, they all belong to the same group.
+func NewAggregationNode(n Node, builders ...AggregatorBuilder) Node {
+	return &AggregationNode{
+		node: node{
+			op:   Aggregation,
+			left: n,
+		},
+		Builders: builders,
+	}
+}
+
+// Bind database resources to this node.
+func (n *AggregationNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	return
+}
+
+func (n *AggregationNode) toStream(st document.Stream) (document.Stream, error) {
+	builders := make([]document.AggregatorBuilder, len(n.Builders))
+	for i := range n.Builders {
+		builders[i] = n.Builders[i]
+	}
+
+	return st.Aggregate(builders...), nil
+}
+
+func (n *AggregationNode) String() string {
+	var b strings.Builder
+
+	for i, ab := range n.Builders {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%v", ab)
+	}
+
+	return fmt.Sprintf("Aggregate(%s)", b.String())
 }
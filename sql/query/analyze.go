@@ -0,0 +1,31 @@
+package query
+
+import (
+	"context"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// AnalyzeStmt is a DSL that allows creating a full ANALYZE statement.
+type AnalyzeStmt struct {
+	TableName string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt AnalyzeStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run computes the statistics of the table, or of every table if no table name was given,
+// and stores them. They are used by the query planner to select the best way to read a table.
+// It implements the Statement interface.
+func (stmt AnalyzeStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.TableName == "" {
+		return res, tx.AnalyzeAll()
+	}
+
+	return res, tx.Analyze(stmt.TableName)
+}
@@ -0,0 +1,79 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectAnalyzed []string
+		fails          bool
+	}{
+		{"Analyze all", `ANALYZE`, []string{"test1", "test2"}, false},
+		{"Analyze table", `ANALYZE test2`, []string{"test2"}, false},
+		{"Analyze unknown", `ANALYZE doesntexist`, nil, true},
+		{"Analyze read-only", `ANALYZE __genji_tables`, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test1;
+				CREATE TABLE test2;
+				CREATE INDEX idx_test2_a ON test2(a);
+
+				INSERT INTO test1(a, b) VALUES (1, 'a'), (2, 'b');
+				INSERT INTO test2(a, b) VALUES (3, 'c'), (4, 'd'), (4, 'e');
+			`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, test.query)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			err = db.View(func(tx *genji.Tx) error {
+				for _, name := range []string{"test1", "test2"} {
+					ts, err := tx.GetStatistics(name)
+
+					var analyzed bool
+					for _, n := range test.expectAnalyzed {
+						analyzed = analyzed || n == name
+					}
+					if !analyzed {
+						require.Equal(t, database.ErrStatisticsNotFound, err)
+						continue
+					}
+					require.NoError(t, err)
+
+					if name == "test1" {
+						require.EqualValues(t, 2, ts.RowCount)
+						require.Empty(t, ts.Indexes)
+					} else {
+						require.EqualValues(t, 3, ts.RowCount)
+						require.EqualValues(t, 3, ts.Indexes["idx_test2_a"].Count)
+						require.EqualValues(t, 2, ts.Indexes["idx_test2_a"].DistinctCount)
+					}
+				}
+
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
@@ -43,9 +43,20 @@ func (stmt CreateTableStmt) Run(ctx context.Context, tx *database.Transaction, a
 type CreateIndexStmt struct {
 	IndexName   string
 	TableName   string
-	Path        document.ValuePath
+	Paths       []document.ValuePath
 	IfNotExists bool
 	Unique      bool
+	// if true, one entry is stored per element of the array
+	// found at the only path of the index.
+	Multi bool
+	// if true, the words of the texts found at the only path
+	// of the index are indexed, to be searched with MATCH.
+	FullText bool
+	// text of the expression indexed by expression indexes,
+	// which are not created on paths.
+	Expr string
+	// text of the predicate of partial indexes.
+	Where string
 }
 
 // IsReadOnly always returns false. It implements the Statement interface.
@@ -62,19 +73,20 @@ func (stmt CreateIndexStmt) Run(ctx context.Context, tx *database.Transaction, a
 		return res, errors.New("missing table name")
 	}
 
-	if stmt.IndexName == "" {
-		return res, errors.New("missing index name")
-	}
-
-	if len(stmt.Path) == 0 {
+	if len(stmt.Paths) == 0 && stmt.Expr == "" {
 		return res, errors.New("missing path")
 	}
 
+	// if the index name is empty, a name is generated.
 	err := tx.CreateIndex(database.IndexConfig{
 		Unique:    stmt.Unique,
 		IndexName: stmt.IndexName,
 		TableName: stmt.TableName,
-		Path:      stmt.Path,
+		Paths:     stmt.Paths,
+		Multi:     stmt.Multi,
+		FullText:  stmt.FullText,
+		Expr:      stmt.Expr,
+		Where:     stmt.Where,
 	})
 	if stmt.IfNotExists && err == database.ErrIndexAlreadyExists {
 		err = nil
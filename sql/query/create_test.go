@@ -32,6 +32,8 @@ func TestCreateTable(t *testing.T) {
 		{"With primary key", "CREATE TABLE test(foo TEXT PRIMARY KEY)", false},
 		{"With field constraints", "CREATE TABLE test(foo.a[1][2] TEXT primary key, bar[4][0].bat INTEGER not null, baz not null)", false},
 		{"With no constraints", "CREATE TABLE test(a, b)", false},
+		{"With check constraints", "CREATE TABLE test(a INTEGER CHECK (a > 0), CONSTRAINT c CHECK (a < b))", false},
+		{"With duplicate check constraints", "CREATE TABLE test(a CONSTRAINT c CHECK (a > 0), CONSTRAINT c CHECK (a < b))", true},
 	}
 
 	for _, test := range tests {
@@ -118,6 +120,35 @@ func TestCreateTable(t *testing.T) {
 
 		})
 
+		t.Run("with check constraints", func(t *testing.T) {
+			err = db.Exec(ctx, `
+				CREATE TABLE test3(
+					a.b INTEGER CHECK (a.b > 0) CHECK (a.b < 10), CHECK (c IS NOT NULL), CONSTRAINT test3_check1 CHECK (c != 'foo')
+				)
+			`)
+			require.NoError(t, err)
+
+			err = db.View(func(tx *genji.Tx) error {
+				tb, err := tx.GetTable("test3")
+				if err != nil {
+					return err
+				}
+				info, err := tb.Info()
+				if err != nil {
+					return err
+				}
+
+				require.Equal(t, []database.CheckConstraint{
+					{Name: "test3_a_b_check", Path: parsePath(t, "a.b"), Expr: "a.b > 0"},
+					{Name: "test3_a_b_check1", Path: parsePath(t, "a.b"), Expr: "a.b < 10"},
+					{Name: "test3_check", Expr: "c IS NOT NULL"},
+					{Name: "test3_check1", Expr: "c != 'foo'"},
+				}, info.CheckConstraints)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
 		t.Run("with variable aliases data types", func(t *testing.T) {
 			ctx := context.Background()
 
@@ -169,7 +200,18 @@ func TestCreateIndex(t *testing.T) {
 		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar)", false},
 		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[1])", false},
 		{"No fields", "CREATE INDEX idx ON test", true},
-		{"More than 1 field", "CREATE INDEX idx ON test (foo, bar)", true},
+		{"More than 1 field", "CREATE INDEX idx ON test (foo, bar)", false},
+		{"Partial", "CREATE INDEX idx ON test (foo) WHERE bar > 10", false},
+		{"Partial with invalid predicate", "CREATE INDEX idx ON test (foo) WHERE bar >", true},
+		{"Without name", "CREATE INDEX ON test (foo)", false},
+		{"Expression", "CREATE INDEX idx ON test (lower(email))", false},
+		{"Invalid expression", "CREATE INDEX idx ON test (lower(email), foo)", true},
+		{"Multi-valued", "CREATE INDEX idx ON test (tags[*])", false},
+		{"Full-text", "CREATE FULLTEXT INDEX idx ON test (description)", false},
+		{"Full-text composite", "CREATE FULLTEXT INDEX idx ON test (title, description)", true},
+		{"Full-text expression", "CREATE FULLTEXT INDEX idx ON test (lower(description))", true},
+		{"Full-text partial", "CREATE FULLTEXT INDEX idx ON test (description) WHERE a > 1", true},
+		{"Full-text multi-valued", "CREATE FULLTEXT INDEX idx ON test (tags[*])", true},
 	}
 
 	for _, test := range tests {
@@ -3,9 +3,13 @@ package query_test
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
 )
@@ -62,4 +66,78 @@ func TestDeleteStmt(t *testing.T) {
 			}
 		})
 	}
+
+	// small buffer sizes force the deleted documents to be written to disk
+	for _, size := range []int{database.DefaultSortBufferSize, 1} {
+		t.Run(fmt.Sprintf("returning/buffer size %d", size), func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			db.DB.SortBufferSize = size
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test;
+				INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar'), (3, 'baz');
+			`)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, "DELETE FROM test WHERE a > 1 RETURNING pk(), b")
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, `[{"pk()": 2, "b": "bar"}, {"pk()": 3, "b": "baz"}]`, buf.String())
+			require.NoError(t, st.Close())
+
+			d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS n FROM test")
+			require.NoError(t, err)
+			var n int
+			err = document.Scan(d, &n)
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+		})
+	}
+
+	t.Run("returning/exec", func(t *testing.T) {
+		// the temporary files are created in a dedicated directory.
+		dir, err := ioutil.TempDir("", "genji-delete-")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		tmpdir, ok := os.LookupEnv("TMPDIR")
+		os.Setenv("TMPDIR", dir)
+		defer func() {
+			if ok {
+				os.Setenv("TMPDIR", tmpdir)
+			} else {
+				os.Unsetenv("TMPDIR")
+			}
+		}()
+
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		db.DB.SortBufferSize = 64
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+		for i := 0; i < 500; i++ {
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+			require.NoError(t, err)
+		}
+
+		// the returned documents are never read.
+		err = db.Exec(ctx, "DELETE FROM test RETURNING *")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test (a) VALUES (1); DELETE FROM test RETURNING *; SELECT * FROM test")
+		require.NoError(t, err)
+
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
 }
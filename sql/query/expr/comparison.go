@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
-	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -34,6 +38,11 @@ func Eq(a, b Expr) Expr {
 var errStop = errors.New("errStop")
 
 func (op eqOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	v, ok := indexLookupValue(idx, v)
+	if !ok {
+		return nil
+	}
+
 	err := idx.AscendGreaterOrEqual(v, func(val, key []byte, isEqual bool) error {
 		if isEqual {
 			d, err := tb.GetDocument(key)
@@ -54,26 +63,9 @@ func (op eqOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 	return nil
 }
 
+// IteratePK implements the planner.PKIteratorOperator interface.
 func (op eqOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
-	v, err := v.CastAs(pkType)
-	if err != nil {
-		return nil
-	}
-
-	data, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-
-	val, err := tb.Store.Get(data)
-	if err != nil {
-		if err == engine.ErrKeyNotFound {
-			return nil
-		}
-
-		return err
-	}
-	return fn(tb.Tx().DB().Codec.NewDocument(val))
+	return iteratePK(tb, scanner.EQ, v, pkType, fn)
 }
 
 func (op eqOp) String() string {
@@ -103,6 +95,11 @@ func Gt(a, b Expr) Expr {
 }
 
 func (op gtOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	v, ok := indexLookupValue(idx, v)
+	if !ok {
+		return nil
+	}
+
 	err := idx.AscendGreaterOrEqual(v, func(val, key []byte, isEqual bool) error {
 		if isEqual {
 			return nil
@@ -123,37 +120,9 @@ func (op gtOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 	return nil
 }
 
+// IteratePK implements the planner.PKIteratorOperator interface.
 func (op gtOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
-	v, err := v.CastAs(pkType)
-	if err != nil {
-		return err
-	}
-
-	data, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-
-	it := tb.Store.NewIterator(engine.IteratorConfig{})
-	defer it.Close()
-
-	var buf []byte
-	for it.Seek(data); it.Valid(); it.Next() {
-		buf, err = it.Item().ValueCopy(buf)
-		if err != nil {
-			return err
-		}
-		if bytes.Equal(data, buf) {
-			return nil
-		}
-
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return iteratePK(tb, scanner.GT, v, pkType, fn)
 }
 
 func (op gtOp) String() string {
@@ -170,6 +139,11 @@ func Gte(a, b Expr) Expr {
 }
 
 func (op gteOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	v, ok := indexLookupValue(idx, v)
+	if !ok {
+		return nil
+	}
+
 	err := idx.AscendGreaterOrEqual(v, func(val, key []byte, isEqual bool) error {
 		d, err := tb.GetDocument(key)
 		if err != nil {
@@ -186,34 +160,9 @@ func (op gteOp) IterateIndex(idx *database.Index, tb *database.Table, v document
 	return nil
 }
 
+// IteratePK implements the planner.PKIteratorOperator interface.
 func (op gteOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
-	v, err := v.CastAs(pkType)
-	if err != nil {
-		return err
-	}
-
-	data, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-
-	it := tb.Store.NewIterator(engine.IteratorConfig{})
-	defer it.Close()
-
-	var buf []byte
-	for it.Seek(data); it.Valid(); it.Next() {
-		buf, err = it.Item().ValueCopy(buf)
-		if err != nil {
-			return err
-		}
-
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return iteratePK(tb, scanner.GTE, v, pkType, fn)
 }
 
 func (op gteOp) String() string {
@@ -232,14 +181,20 @@ func Lt(a, b Expr) Expr {
 func (op ltOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
 	var err error
 
-	if v.Type == document.IntegerValue {
+	v, ok := indexLookupValue(idx, v)
+	if !ok {
+		return nil
+	}
+
+	// integers are stored as doubles, unless the index only contains integers.
+	if v.Type == document.IntegerValue && idx.Type != document.IntegerValue {
 		v, err = v.CastAsDouble()
 		if err != nil {
 			return err
 		}
 	}
 
-	enc, err := key.AppendValue(nil, v)
+	enc, err := appendIndexValue(idx, v)
 	if err != nil {
 		return err
 	}
@@ -263,37 +218,9 @@ func (op ltOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 	return nil
 }
 
+// IteratePK implements the planner.PKIteratorOperator interface.
 func (op ltOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
-	v, err := v.CastAs(pkType)
-	if err != nil {
-		return err
-	}
-
-	data, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-
-	it := tb.Store.NewIterator(engine.IteratorConfig{})
-	defer it.Close()
-
-	var buf []byte
-	for it.Seek(nil); it.Valid(); it.Next() {
-		buf, err = it.Item().ValueCopy(buf)
-		if err != nil {
-			return err
-		}
-		if bytes.Compare(data, buf) <= 0 {
-			break
-		}
-
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return iteratePK(tb, scanner.LT, v, pkType, fn)
 }
 
 func (op ltOp) String() string {
@@ -312,14 +239,20 @@ func Lte(a, b Expr) Expr {
 func (op lteOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
 	var err error
 
-	if v.Type == document.IntegerValue {
+	v, ok := indexLookupValue(idx, v)
+	if !ok {
+		return nil
+	}
+
+	// integers are stored as doubles, unless the index only contains integers.
+	if v.Type == document.IntegerValue && idx.Type != document.IntegerValue {
 		v, err = v.CastAsDouble()
 		if err != nil {
 			return err
 		}
 	}
 
-	enc, err := key.AppendValue(nil, v)
+	enc, err := appendIndexValue(idx, v)
 	if err != nil {
 		return err
 	}
@@ -344,37 +277,9 @@ func (op lteOp) IterateIndex(idx *database.Index, tb *database.Table, v document
 	return nil
 }
 
+// IteratePK implements the planner.PKIteratorOperator interface.
 func (op lteOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
-	v, err := v.CastAs(pkType)
-	if err != nil {
-		return err
-	}
-
-	data, err := key.AppendValue(nil, v)
-	if err != nil {
-		return err
-	}
-
-	it := tb.Store.NewIterator(engine.IteratorConfig{})
-	defer it.Close()
-
-	var buf []byte
-	for it.Seek(nil); it.Valid(); it.Next() {
-		buf, err = it.Item().ValueCopy(buf)
-		if err != nil {
-			return err
-		}
-		if bytes.Compare(data, buf) < 0 {
-			break
-		}
-
-		err = fn(tb.Tx().DB().Codec.NewDocument(buf))
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return iteratePK(tb, scanner.LTE, v, pkType, fn)
 }
 
 func (op lteOp) String() string {
@@ -422,11 +327,11 @@ func (op cmpOp) compare(l, r document.Value) (bool, error) {
 }
 
 // IsComparisonOperator returns true if e is one of
-// =, !=, >, >=, <, <=, IS, IS NOT, IN, or NOT IN operators.
+// =, !=, >, >=, <, <=, IS, IS NOT, IN, NOT IN, =~ or !~ operators.
 func IsComparisonOperator(op Operator) bool {
 	switch op.(type) {
 	case eqOp, neqOp, gtOp, gteOp, ltOp, lteOp,
-		isOp, isNotOp, inOp, notInOp:
+		isOp, isNotOp, inOp, notInOp, eqRegexOp, neqRegexOp:
 		return true
 	}
 
@@ -451,6 +356,18 @@ func IsInOperator(e Expr) bool {
 	return ok
 }
 
+// IsNotInOperator reports if e is the NOT IN operator.
+func IsNotInOperator(e Expr) bool {
+	_, ok := e.(*notInOp)
+	return ok
+}
+
+// IsIsNotOperator reports if e is the IS NOT operator.
+func IsIsNotOperator(e Expr) bool {
+	_, ok := e.(*isNotOp)
+	return ok
+}
+
 type inOp struct {
 	*simpleOperator
 }
@@ -490,39 +407,44 @@ func (op inOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 		return errors.New("IN operator takes an array")
 	}
 
+	// the array may contain duplicates, for example if it is the result
+	// of a subquery. each value must only be looked up once.
+	seen := make(map[string]struct{})
 	var eq eqOp
 	return v.V.(document.Array).Iterate(func(i int, value document.Value) error {
+		k, err := key.AppendValue(nil, value)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[string(k)]; ok {
+			return nil
+		}
+		seen[string(k)] = struct{}{}
+
 		return eq.IterateIndex(idx, tb, value, fn)
 	})
 }
 
-// IteratePK implements the query.pkIterator interface. It expects v to be an array,
-// iterates over it, and for each value, gets it from the underlying store of tb.
+// IteratePK implements the planner.PKIteratorOperator interface. It expects v to be an array,
+// and reads the documents whose primary key is equal to one of its values.
 func (op inOp) IteratePK(tb *database.Table, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
 	if v.Type != document.ArrayValue {
 		return errors.New("IN operator takes an array")
 	}
 
+	// each value must only be looked up once.
+	seen := make(map[string]struct{})
 	return v.V.(document.Array).Iterate(func(i int, value document.Value) error {
-		val, err := value.CastAs(pkType)
-		if err != nil {
-			return nil
-		}
-
-		data, err := key.AppendValue(nil, val)
+		k, err := key.AppendValue(nil, value)
 		if err != nil {
 			return err
 		}
-
-		v, err := tb.Store.Get(data)
-		if err != nil {
-			if err == engine.ErrKeyNotFound {
-				return nil
-			}
-
-			return err
+		if _, ok := seen[string(k)]; ok {
+			return nil
 		}
-		return fn(tb.Tx().DB().Codec.NewDocument(v))
+		seen[string(k)] = struct{}{}
+
+		return iteratePK(tb, scanner.EQ, value, pkType, fn)
 	})
 }
 
@@ -616,3 +538,336 @@ func (op isNotOp) Eval(ctx EvalStack) (document.Value, error) {
 func (op isNotOp) String() string {
 	return fmt.Sprintf("%v IS NOT %v", op.a, op.b)
 }
+
+// regexCache holds the last regular expression compiled by a regex operator.
+// Patterns are usually literals or parameters bound to the same value,
+// which allows compiling them only once per statement.
+type regexCache struct {
+	mu      sync.Mutex
+	pattern string
+	re      *regexp.Regexp
+}
+
+func (c *regexCache) compile(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.re != nil && c.pattern == pattern {
+		return c.re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.pattern = pattern
+	c.re = re
+	return re, nil
+}
+
+// regexOp is the base of the regex operators.
+type regexOp struct {
+	*simpleOperator
+
+	cache *regexCache
+}
+
+func newRegexOp(a, b Expr, t scanner.Token) regexOp {
+	return regexOp{&simpleOperator{a, b, t}, new(regexCache)}
+}
+
+// match reports whether a matches the pattern b.
+// It returns NULL if any of the operands is NULL and false if a is not a text.
+func (op regexOp) match(a, b document.Value) (document.Value, error) {
+	if a.Type == document.NullValue || b.Type == document.NullValue {
+		return nullLitteral, nil
+	}
+
+	if b.Type != document.TextValue {
+		return nullLitteral, fmt.Errorf("regular expression must be a text, got %s", b.Type)
+	}
+
+	re, err := op.cache.compile(b.V.(string))
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	if a.Type != document.TextValue {
+		return falseLitteral, nil
+	}
+
+	if re.MatchString(a.V.(string)) {
+		return trueLitteral, nil
+	}
+
+	return falseLitteral, nil
+}
+
+type eqRegexOp struct {
+	regexOp
+}
+
+// EqRegex creates an expression that evaluates to true if a is a text matching the regular expression b.
+// The pattern must be a text and follows the syntax of the Go regexp package.
+func EqRegex(a, b Expr) Expr {
+	return eqRegexOp{newRegexOp(a, b, scanner.EQREGEX)}
+}
+
+func (op eqRegexOp) Eval(ctx EvalStack) (document.Value, error) {
+	a, b, err := op.simpleOperator.eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return op.match(a, b)
+}
+
+// IterateIndex reads the documents whose indexed text value matches the regular expression v.
+// If the pattern starts with a literal prefix, only the values starting with that prefix are read.
+func (op eqRegexOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	if v.Type != document.TextValue {
+		return fmt.Errorf("regular expression must be a text, got %s", v.Type)
+	}
+
+	prefix := RegexLiteralPrefix(v.V.(string))
+
+	err := idx.AscendGreaterOrEqual(document.NewTextValue(prefix), func(val, k []byte, isEqual bool) error {
+		var tv document.Value
+		var err error
+
+		if idx.Type != 0 {
+			tv, err = key.Decode(idx.Type, val)
+		} else {
+			tv, err = key.DecodeValue(val)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(tv.V.(string), prefix) {
+			return errStop
+		}
+
+		ok, err := op.match(tv, v)
+		if err != nil || ok != trueLitteral {
+			return err
+		}
+
+		d, err := tb.GetDocument(k)
+		if err != nil {
+			return err
+		}
+
+		return fn(d)
+	})
+
+	if err != nil && err != errStop {
+		return err
+	}
+
+	return nil
+}
+
+func (op eqRegexOp) String() string {
+	return fmt.Sprintf("%v =~ %v", op.a, op.b)
+}
+
+type neqRegexOp struct {
+	regexOp
+}
+
+// NeqRegex creates an expression that evaluates to true if a is not a text matching the regular expression b.
+func NeqRegex(a, b Expr) Expr {
+	return neqRegexOp{newRegexOp(a, b, scanner.NEQREGEX)}
+}
+
+func (op neqRegexOp) Eval(ctx EvalStack) (document.Value, error) {
+	a, b, err := op.simpleOperator.eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	v, err := op.match(a, b)
+	if err != nil {
+		return v, err
+	}
+	if v == trueLitteral {
+		return falseLitteral, nil
+	}
+	if v == falseLitteral {
+		return trueLitteral, nil
+	}
+	return v, nil
+}
+
+func (op neqRegexOp) String() string {
+	return fmt.Sprintf("%v !~ %v", op.a, op.b)
+}
+
+// IsEqRegexOperator reports if e is the =~ operator.
+func IsEqRegexOperator(e Expr) bool {
+	_, ok := e.(eqRegexOp)
+	return ok
+}
+
+// RegexLiteralPrefix returns the literal text any string matching the regular expression
+// must start with. It returns an empty string if the pattern is not anchored at the beginning
+// of the text, doesn't start with a literal, or is invalid.
+func RegexLiteralPrefix(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) == 0 || re.Sub[0].Op != syntax.OpBeginText {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+
+		sb.WriteString(string(sub.Rune))
+	}
+
+	return sb.String()
+}
+
+// indexLookupValue returns the value that must be looked up in the index to find
+// the values compared successfully with v. Texts are compared with timestamps as timestamps:
+// a text is converted if the index only contains timestamps.
+// It returns false if no value of the index can match v.
+func indexLookupValue(idx *database.Index, v document.Value) (document.Value, bool) {
+	if idx.Type == document.TimestampValue && v.Type == document.TextValue {
+		v, err := v.CastAsTimestamp()
+		return v, err == nil
+	}
+
+	return v, true
+}
+
+// appendIndexValue encodes v the way values are encoded in the index:
+// the values of a typed index are encoded without their type.
+func appendIndexValue(idx *database.Index, v document.Value) ([]byte, error) {
+	if idx.Type != 0 {
+		return key.Append(nil, v.Type, v.V)
+	}
+
+	return key.AppendValue(nil, v)
+}
+
+// iteratePK calls fn with every document of tb whose primary key is compared
+// successfully to v using op, which must be one of the =, >, >=, < and <= operators.
+// The primary key must be typed.
+func iteratePK(tb *database.Table, op scanner.Token, v document.Value, pkType document.ValueType, fn func(d document.Document) error) error {
+	op, v, ok := pkBound(op, v, pkType)
+	if !ok {
+		return nil
+	}
+
+	// there is no bound, every document matches.
+	if op == 0 {
+		return tb.Iterate(fn)
+	}
+
+	k, err := key.Append(nil, v.Type, v.V)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case scanner.EQ:
+		d, err := tb.GetDocument(k)
+		if err == database.ErrDocumentNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return fn(d)
+	case scanner.GT, scanner.GTE:
+		return tb.AscendGreaterOrEqual(k, func(d document.Document) error {
+			if op == scanner.GT && bytes.Equal(d.(document.Keyer).Key(), k) {
+				return nil
+			}
+
+			return fn(d)
+		})
+	}
+
+	err = tb.Iterate(func(d document.Document) error {
+		c := bytes.Compare(d.(document.Keyer).Key(), k)
+		if c > 0 || (c == 0 && op == scanner.LT) {
+			return errStop
+		}
+
+		return fn(d)
+	})
+	if err != nil && err != errStop {
+		return err
+	}
+
+	return nil
+}
+
+// pkBound converts v to the type of the primary key, so that it can be compared to the
+// encoded keys of the table. Comparing an integer primary key to a double may require
+// changing the operator, or reading the entire table, in which case the returned operator is 0.
+// It returns false if no document can match.
+func pkBound(op scanner.Token, v document.Value, pkType document.ValueType) (scanner.Token, document.Value, bool) {
+	if v.Type == pkType {
+		return op, v, true
+	}
+
+	// texts are compared with timestamps as timestamps.
+	if pkType == document.TimestampValue && v.Type == document.TextValue {
+		v, err := v.CastAsTimestamp()
+		return op, v, err == nil
+	}
+
+	// values of different types, or NULL, are never equal, greater or lesser than the primary key.
+	if !v.Type.IsNumber() || !pkType.IsNumber() {
+		return op, v, false
+	}
+
+	if pkType == document.DoubleValue {
+		return op, document.NewDoubleValue(float64(v.V.(int64))), true
+	}
+
+	x := v.V.(float64)
+	f := math.Floor(x)
+	switch {
+	case math.IsNaN(x):
+		return op, v, false
+	case f >= math.MaxInt64:
+		// x is greater than every integer.
+		if op == scanner.LT || op == scanner.LTE {
+			return 0, v, true
+		}
+		return op, v, false
+	case f < math.MinInt64:
+		// x is lesser than every integer.
+		if op == scanner.GT || op == scanner.GTE {
+			return 0, v, true
+		}
+		return op, v, false
+	case f == x:
+		return op, document.NewIntegerValue(int64(x)), true
+	}
+
+	// x has a fractional part: k > x and k >= x are equivalent to k > floor(x),
+	// k < x and k <= x to k <= floor(x).
+	switch op {
+	case scanner.GT, scanner.GTE:
+		return scanner.GT, document.NewIntegerValue(int64(f)), true
+	case scanner.LT, scanner.LTE:
+		return scanner.LTE, document.NewIntegerValue(int64(f)), true
+	}
+
+	return op, v, false
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
 )
 
 func TestComparisonExpr(t *testing.T) {
@@ -158,3 +159,57 @@ func TestComparisonExprNodocument(t *testing.T) {
 		})
 	}
 }
+
+func TestComparisonRegexExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"'foo' =~ 'fo+'", document.NewBoolValue(true), false},
+		{"'foo' =~ '^o'", document.NewBoolValue(false), false},
+		{"b.`foo bar` =~ 'foo'", document.NewBoolValue(false), false},
+		{"1 =~ '1'", document.NewBoolValue(false), false},
+		{"'foo' =~ NULL", nullLitteral, false},
+		{"notFound =~ 'foo'", nullLitteral, false},
+		{"'foo' =~ 1", nullLitteral, true},
+		{"'foo' =~ '('", nullLitteral, true},
+		{"'foo' !~ 'fo+'", document.NewBoolValue(false), false},
+		{"'foo' !~ '^o'", document.NewBoolValue(true), false},
+		{"1 !~ '1'", document.NewBoolValue(true), false},
+		{"'foo' !~ NULL", nullLitteral, false},
+		{"'foo' !~ '('", nullLitteral, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
+func TestRegexLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"^abc", "abc"},
+		{"^abc$", "abc"},
+		{"^ab+c", "a"},
+		{"^abc.*d", "abc"},
+		{`^a\.b`, "a.b"},
+		{"^(?:abc|abd)", "ab"},
+		{"^(abc)", ""},
+		{"abc", ""},
+		{"(?i)^abc", ""},
+		{"(?m)^abc", ""},
+		{"^", ""},
+		{"(", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			require.Equal(t, test.expected, expr.RegexLiteralPrefix(test.pattern))
+		})
+	}
+}
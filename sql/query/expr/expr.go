@@ -45,6 +45,24 @@ type EvalStack struct {
 	Document document.Document
 	Params   []Param
 	Info     *database.TableInfo
+	// Cache holds the state of the expressions that is specific to
+	// one execution of a statement, such as the results of subqueries.
+	// It can be nil.
+	Cache map[Expr]interface{}
+}
+
+// A StoredExpr is an expression stored in the catalog of the database,
+// such as the predicate of a partial index. It implements the database.Expr interface.
+type StoredExpr struct {
+	E Expr
+}
+
+// Eval evaluates the expression against a document of a table.
+func (s StoredExpr) Eval(tx *database.Transaction, d document.Document) (document.Value, error) {
+	return s.E.Eval(EvalStack{
+		Tx:       tx,
+		Document: d,
+	})
 }
 
 type simpleOperator struct {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/genjidb/genji/document"
 )
@@ -53,6 +54,48 @@ func BuiltinFunctions() map[string]func(args ...Expr) (Expr, error) {
 			}
 			return &AvgFunc{Expr: args[0]}, nil
 		},
+		"lower": func(args ...Expr) (Expr, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("LOWER() takes 1 argument")
+			}
+			return LowerFunc{Expr: args[0]}, nil
+		},
+		"upper": func(args ...Expr) (Expr, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("UPPER() takes 1 argument")
+			}
+			return UpperFunc{Expr: args[0]}, nil
+		},
+		"now": func(args ...Expr) (Expr, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("NOW() takes no arguments")
+			}
+			return NowFunc{}, nil
+		},
+		"date_trunc": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("DATE_TRUNC() takes 2 arguments")
+			}
+			return DateTruncFunc{Unit: args[0], Expr: args[1]}, nil
+		},
+		"extract": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("EXTRACT() takes 2 arguments")
+			}
+			return ExtractFunc{Field: args[0], Expr: args[1]}, nil
+		},
+		"contains": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("CONTAINS() takes 2 arguments")
+			}
+			return ContainsFunc{Array: args[0], Value: args[1]}, nil
+		},
+		"score": func(args ...Expr) (Expr, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("SCORE() takes no arguments")
+			}
+			return ScoreFunc{}, nil
+		},
 	}
 }
 
@@ -151,6 +194,368 @@ func (c CastFunc) String() string {
 	return fmt.Sprintf("CAST(%v AS %v)", c.Expr, c.CastAs)
 }
 
+// LowerFunc represents the LOWER function.
+// It returns the text converted to lower case,
+// or NULL if the value is not a text.
+type LowerFunc struct {
+	Expr Expr
+}
+
+// Eval returns the value converted to lower case.
+func (l LowerFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := l.Expr.Eval(ctx)
+	if err != nil || v.Type != document.TextValue {
+		return nullLitteral, err
+	}
+
+	return document.NewTextValue(strings.ToLower(v.V.(string))), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (l LowerFunc) IsEqual(other Expr) bool {
+	o, ok := other.(LowerFunc)
+	return ok && Equal(l.Expr, o.Expr)
+}
+
+func (l LowerFunc) String() string {
+	return fmt.Sprintf("LOWER(%v)", l.Expr)
+}
+
+// UpperFunc represents the UPPER function.
+// It returns the text converted to upper case,
+// or NULL if the value is not a text.
+type UpperFunc struct {
+	Expr Expr
+}
+
+// Eval returns the value converted to upper case.
+func (u UpperFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := u.Expr.Eval(ctx)
+	if err != nil || v.Type != document.TextValue {
+		return nullLitteral, err
+	}
+
+	return document.NewTextValue(strings.ToUpper(v.V.(string))), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (u UpperFunc) IsEqual(other Expr) bool {
+	o, ok := other.(UpperFunc)
+	return ok && Equal(u.Expr, o.Expr)
+}
+
+func (u UpperFunc) String() string {
+	return fmt.Sprintf("UPPER(%v)", u.Expr)
+}
+
+// NowFunc represents the NOW function.
+// It returns the current time.
+type NowFunc struct{}
+
+// Eval returns the current time as a timestamp.
+func (n NowFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return document.NewTimestampValue(time.Now()), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (n NowFunc) IsEqual(other Expr) bool {
+	_, ok := other.(NowFunc)
+	return ok
+}
+
+func (n NowFunc) String() string {
+	return "NOW()"
+}
+
+// evalTimestamp evaluates e and converts the result to a timestamp.
+// It returns false if the result is NULL or cannot be converted.
+func evalTimestamp(ctx EvalStack, e Expr) (time.Time, bool, error) {
+	v, err := e.Eval(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	switch v.Type {
+	case document.TimestampValue:
+		return v.V.(time.Time), true, nil
+	case document.TextValue:
+		v, err = v.CastAsTimestamp()
+		if err != nil {
+			return time.Time{}, false, nil
+		}
+		return v.V.(time.Time), true, nil
+	}
+
+	return time.Time{}, false, nil
+}
+
+// evalUnit evaluates e and returns the lower cased text it evaluates to.
+func evalUnit(ctx EvalStack, e Expr, fn string) (string, error) {
+	v, err := e.Eval(ctx)
+	if err != nil {
+		return "", err
+	}
+	if v.Type != document.TextValue {
+		return "", fmt.Errorf("%s() expects a text as first argument, got %s", fn, v.Type)
+	}
+
+	return strings.ToLower(v.V.(string)), nil
+}
+
+// DateTruncFunc represents the DATE_TRUNC function.
+// It returns the timestamp truncated to the given precision: one of
+// microsecond, millisecond, second, minute, hour, day, week, month, quarter or year,
+// or NULL if the value is not a timestamp or a text representing one.
+type DateTruncFunc struct {
+	Unit Expr
+	Expr Expr
+}
+
+// Eval returns the truncated timestamp.
+func (d DateTruncFunc) Eval(ctx EvalStack) (document.Value, error) {
+	unit, err := evalUnit(ctx, d.Unit, "DATE_TRUNC")
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	t, ok, err := evalTimestamp(ctx, d.Expr)
+	if err != nil || !ok {
+		return nullLitteral, err
+	}
+
+	switch unit {
+	case "microsecond", "microseconds":
+	case "millisecond", "milliseconds":
+		t = t.Truncate(time.Millisecond)
+	case "second", "seconds":
+		t = t.Truncate(time.Second)
+	case "minute", "minutes":
+		t = t.Truncate(time.Minute)
+	case "hour", "hours":
+		t = t.Truncate(time.Hour)
+	case "day", "days":
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "week", "weeks":
+		// weeks start on monday
+		offset := (int(t.Weekday()) + 6) % 7
+		t = time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	case "month", "months":
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "quarter", "quarters":
+		t = time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case "year", "years":
+		t = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return nullLitteral, fmt.Errorf("DATE_TRUNC(): unknown unit %q", unit)
+	}
+
+	return document.NewTimestampValue(t), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (d DateTruncFunc) IsEqual(other Expr) bool {
+	o, ok := other.(DateTruncFunc)
+	return ok && Equal(d.Unit, o.Unit) && Equal(d.Expr, o.Expr)
+}
+
+func (d DateTruncFunc) String() string {
+	return fmt.Sprintf("DATE_TRUNC(%v, %v)", d.Unit, d.Expr)
+}
+
+// ExtractFunc represents the EXTRACT function.
+// It returns a field of the timestamp, as an integer: one of
+// microsecond, millisecond, second, minute, hour, day, dow (day of the week, from 0 for sunday),
+// doy (day of the year), week (ISO 8601 week number), month, quarter, year or epoch (Unix time in seconds),
+// or NULL if the value is not a timestamp or a text representing one.
+type ExtractFunc struct {
+	Field Expr
+	Expr  Expr
+}
+
+// Eval returns the extracted field.
+func (e ExtractFunc) Eval(ctx EvalStack) (document.Value, error) {
+	field, err := evalUnit(ctx, e.Field, "EXTRACT")
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	t, ok, err := evalTimestamp(ctx, e.Expr)
+	if err != nil || !ok {
+		return nullLitteral, err
+	}
+
+	var x int
+	switch field {
+	case "microsecond", "microseconds":
+		x = t.Nanosecond() / 1e3
+	case "millisecond", "milliseconds":
+		x = t.Nanosecond() / 1e6
+	case "second", "seconds":
+		x = t.Second()
+	case "minute", "minutes":
+		x = t.Minute()
+	case "hour", "hours":
+		x = t.Hour()
+	case "day", "days":
+		x = t.Day()
+	case "dow":
+		x = int(t.Weekday())
+	case "doy":
+		x = t.YearDay()
+	case "week", "weeks":
+		_, x = t.ISOWeek()
+	case "month", "months":
+		x = int(t.Month())
+	case "quarter", "quarters":
+		x = (int(t.Month())-1)/3 + 1
+	case "year", "years":
+		x = t.Year()
+	case "epoch":
+		return document.NewIntegerValue(t.Unix()), nil
+	default:
+		return nullLitteral, fmt.Errorf("EXTRACT(): unknown field %q", field)
+	}
+
+	return document.NewIntegerValue(int64(x)), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (e ExtractFunc) IsEqual(other Expr) bool {
+	o, ok := other.(ExtractFunc)
+	return ok && Equal(e.Field, o.Field) && Equal(e.Expr, o.Expr)
+}
+
+func (e ExtractFunc) String() string {
+	return fmt.Sprintf("EXTRACT(%v, %v)", e.Field, e.Expr)
+}
+
+// ContainsFunc represents the CONTAINS function.
+// It returns true if the array contains the value, like the IN operator,
+// and NULL if one of them is NULL.
+type ContainsFunc struct {
+	Array Expr
+	Value Expr
+}
+
+// Eval returns whether the array contains the value.
+func (c ContainsFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return In(c.Value, c.Array).Eval(ctx)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (c ContainsFunc) IsEqual(other Expr) bool {
+	o, ok := other.(ContainsFunc)
+	return ok && Equal(c.Array, o.Array) && Equal(c.Value, o.Value)
+}
+
+func (c ContainsFunc) String() string {
+	return fmt.Sprintf("CONTAINS(%v, %v)", c.Array, c.Value)
+}
+
+// A FullTextQuery is a parsed full-text query.
+type FullTextQuery interface {
+	// MatchText reports whether text matches the query.
+	MatchText(text string) bool
+}
+
+// A FullTextParser parses the full-text queries of the MATCH function.
+type FullTextParser interface {
+	ParseFullTextQuery(q string) (FullTextQuery, error)
+}
+
+// MatchFunc represents the MATCH function.
+// It returns true if the text contains the words of a full-text query,
+// false if the value is not a text and NULL if one of them is NULL.
+// Queries are parsed by Parser. It is not a builtin function: it must be
+// added to the functions of the parser along with a FullTextParser.
+type MatchFunc struct {
+	Field  Expr
+	Query  Expr
+	Parser FullTextParser
+}
+
+// Eval returns whether the text matches the query.
+func (m MatchFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := m.Field.Eval(ctx)
+	if err != nil || v.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	qv, err := m.Query.Eval(ctx)
+	if err != nil || qv.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	if qv.Type != document.TextValue {
+		return nullLitteral, errors.New("MATCH(): the query must be a text")
+	}
+
+	q, err := m.Parser.ParseFullTextQuery(qv.V.(string))
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	if v.Type != document.TextValue {
+		return falseLitteral, nil
+	}
+
+	if q.MatchText(v.V.(string)) {
+		return trueLitteral, nil
+	}
+
+	return falseLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (m MatchFunc) IsEqual(other Expr) bool {
+	o, ok := other.(MatchFunc)
+	return ok && Equal(m.Field, o.Field) && Equal(m.Query, o.Query)
+}
+
+func (m MatchFunc) String() string {
+	return fmt.Sprintf("MATCH(%v, %v)", m.Field, m.Query)
+}
+
+// A Scorer is a document selected by a full-text index,
+// associated with its relevance score.
+type Scorer interface {
+	Score() (float64, bool)
+}
+
+// ScoreFunc represents the SCORE function. It returns the relevance score of
+// the current document, if it was selected by MATCH using a full-text index,
+// and NULL otherwise. The higher the score, the more relevant the document.
+type ScoreFunc struct{}
+
+// Eval returns the relevance score of the current document.
+func (s ScoreFunc) Eval(ctx EvalStack) (document.Value, error) {
+	if sc, ok := ctx.Document.(Scorer); ok {
+		if score, ok := sc.Score(); ok {
+			return document.NewDoubleValue(score), nil
+		}
+	}
+
+	return nullLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (s ScoreFunc) IsEqual(other Expr) bool {
+	_, ok := other.(ScoreFunc)
+	return ok
+}
+
+func (s ScoreFunc) String() string {
+	return "SCORE()"
+}
+
 // CountFunc is the COUNT aggregator function. It aggregates documents
 type CountFunc struct {
 	Expr     Expr
@@ -196,6 +601,10 @@ func (c *CountFunc) String() string {
 		return c.Alias
 	}
 
+	if c.Wildcard {
+		return "COUNT(*)"
+	}
+
 	return fmt.Sprintf("COUNT(%v)", c.Expr)
 }
 
@@ -303,7 +712,7 @@ func (m *MinAggregator) Add(d document.Document) error {
 		return nil
 	}
 
-	if m.Min.Type == v.Type || m.Min.Type.IsNumber() && m.Min.Type.IsNumber() {
+	if m.Min.Type == v.Type || m.Min.Type.IsNumber() && v.Type.IsNumber() {
 		ok, err := m.Min.IsGreaterThan(v)
 		if err != nil {
 			return err
@@ -400,7 +809,7 @@ func (m *MaxAggregator) Add(d document.Document) error {
 		return nil
 	}
 
-	if m.Max.Type == v.Type || m.Max.Type.IsNumber() && m.Max.Type.IsNumber() {
+	if m.Max.Type == v.Type || m.Max.Type.IsNumber() && v.Type.IsNumber() {
 		ok, err := m.Max.IsLesserThan(v)
 		if err != nil {
 			return err
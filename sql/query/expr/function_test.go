@@ -2,9 +2,11 @@ package expr_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPkExpr(t *testing.T) {
@@ -25,3 +27,135 @@ func TestPkExpr(t *testing.T) {
 		})
 	}
 }
+
+func TestLowerUpperExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"LOWER('HeLLo')", document.NewTextValue("hello"), false},
+		{"UPPER('HeLLo')", document.NewTextValue("HELLO"), false},
+		{"LOWER(c[1].foo)", document.NewTextValue("bar"), false},
+		{"UPPER(c[1].foo)", document.NewTextValue("BAR"), false},
+		{"LOWER(a)", nullLitteral, false},
+		{"UPPER(NULL)", nullLitteral, false},
+		{"LOWER(d)", nullLitteral, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
+func TestContainsExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"CONTAINS(c, 1)", document.NewBoolValue(true), false},
+		{"CONTAINS(c, 1.0)", document.NewBoolValue(true), false},
+		{"CONTAINS(c, [1, 2])", document.NewBoolValue(true), false},
+		{"CONTAINS(c, 2)", document.NewBoolValue(false), false},
+		{"CONTAINS(a, 1)", document.NewBoolValue(false), false},
+		{"CONTAINS(c, NULL)", nullLitteral, false},
+		{"CONTAINS(d, 1)", nullLitteral, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
+func TestMatchExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"MATCH('The quick brown fox', 'quick fox')", document.NewBoolValue(true), false},
+		{"MATCH('The quick brown fox', 'QUICK')", document.NewBoolValue(true), false},
+		{"MATCH('The quick brown fox', 'quick dog')", document.NewBoolValue(false), false},
+		{"MATCH('The quick brown fox', 'dog OR fox')", document.NewBoolValue(true), false},
+		{"MATCH('The quick brown fox', '\"quick brown\"')", document.NewBoolValue(true), false},
+		{"MATCH('The quick brown fox', '\"quick fox\"')", document.NewBoolValue(false), false},
+		{"MATCH('Crème brûlée', 'creme brulee')", document.NewBoolValue(true), false},
+		{"MATCH(a, 'quick')", document.NewBoolValue(false), false},
+		{"MATCH(NULL, 'quick')", nullLitteral, false},
+		{"MATCH(d, 'quick')", nullLitteral, false},
+		{"MATCH('The quick brown fox', NULL)", nullLitteral, false},
+		{"MATCH('The quick brown fox', 1)", nullLitteral, true},
+		{"MATCH('The quick brown fox', '\"quick')", nullLitteral, true},
+		{"MATCH('The quick brown fox', 'OR fox')", nullLitteral, true},
+		{"MATCH('The quick brown fox', '')", nullLitteral, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
+func TestScoreExpr(t *testing.T) {
+	// documents that weren't selected by a full-text index have no score.
+	testExpr(t, "SCORE()", stackWithDoc, nullLitteral, false)
+}
+
+func TestDateFuncs(t *testing.T) {
+	ts := func(s string) document.Value {
+		v, err := document.NewTextValue(s).CastAsTimestamp()
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"DATE_TRUNC('second', '2020-10-07T15:04:05.123456Z')", ts("2020-10-07T15:04:05Z"), false},
+		{"DATE_TRUNC('hour', '2020-10-07T15:04:05.123456Z')", ts("2020-10-07T15:00:00Z"), false},
+		{"DATE_TRUNC('DAY', '2020-10-07T15:04:05.123456Z')", ts("2020-10-07T00:00:00Z"), false},
+		{"DATE_TRUNC('week', '2020-10-07T15:04:05.123456Z')", ts("2020-10-05T00:00:00Z"), false},
+		{"DATE_TRUNC('month', '2020-10-07T15:04:05.123456Z')", ts("2020-10-01T00:00:00Z"), false},
+		{"DATE_TRUNC('quarter', '2020-11-07T15:04:05.123456Z')", ts("2020-10-01T00:00:00Z"), false},
+		{"DATE_TRUNC('year', CAST(1602083045 AS TIMESTAMP))", ts("2020-01-01T00:00:00Z"), false},
+		{"DATE_TRUNC('year', 'foo')", nullLitteral, false},
+		{"DATE_TRUNC('year', NULL)", nullLitteral, false},
+		{"DATE_TRUNC('foo', '2020-10-07T15:04:05Z')", nullLitteral, true},
+		{"DATE_TRUNC(1, '2020-10-07T15:04:05Z')", nullLitteral, true},
+		{"EXTRACT('year', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(2020), false},
+		{"EXTRACT('month', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(10), false},
+		{"EXTRACT('day', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(7), false},
+		{"EXTRACT('hour', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(15), false},
+		{"EXTRACT('microsecond', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(123456), false},
+		{"EXTRACT('dow', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(3), false},
+		{"EXTRACT('doy', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(281), false},
+		{"EXTRACT('quarter', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(4), false},
+		{"EXTRACT('epoch', '2020-10-07T15:04:05.123456Z')", document.NewIntegerValue(1602083045), false},
+		{"EXTRACT('year', a)", nullLitteral, false},
+		{"EXTRACT('foo', '2020-10-07T15:04:05Z')", nullLitteral, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+
+	t.Run("NOW()", func(t *testing.T) {
+		before := time.Now().UTC().Truncate(time.Microsecond)
+		v, err := expr.NowFunc{}.Eval(expr.EvalStack{})
+		require.NoError(t, err)
+		require.Equal(t, document.TimestampValue, v.Type)
+		require.False(t, v.V.(time.Time).Before(before))
+	})
+}
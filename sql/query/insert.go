@@ -15,6 +15,34 @@ type InsertStmt struct {
 	TableName  string
 	FieldNames []string
 	Values     expr.LiteralExprList
+
+	// OnConflict defines what to do with documents that conflict
+	// with existing ones. If nil, conflicts return an error.
+	OnConflict *OnConflictClause
+}
+
+// OnConflictClause describes the ON CONFLICT clause of an INSERT statement.
+type OnConflictClause struct {
+	// Paths of the primary key or unique index on which conflicts are handled.
+	// If empty, conflicts on the primary key and every unique index are handled.
+	Paths []document.ValuePath
+
+	// If true, conflicting documents are ignored.
+	DoNothing bool
+
+	// Paths to set in the existing document, in order, and their values.
+	// The expressions can refer to the document that was rejected using the
+	// "excluded" field.
+	SetPairs []SetPair
+
+	// If set, the existing document is only updated if this condition is true.
+	WhereExpr expr.Expr
+}
+
+// SetPair associates a path with the expression used to compute its new value.
+type SetPair struct {
+	Path document.ValuePath
+	Expr expr.Expr
 }
 
 // IsReadOnly always returns false. It implements the Statement interface.
@@ -25,6 +53,12 @@ func (stmt InsertStmt) IsReadOnly() bool {
 // Run the Insert statement in the given transaction.
 // It implements the Statement interface.
 func (stmt InsertStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	return stmt.Insert(tx, args, nil)
+}
+
+// Insert runs the Insert statement in the given transaction and calls fn with the key
+// of every document inserted or updated by the statement, in order. fn can be nil.
+func (stmt InsertStmt) Insert(tx *database.Transaction, args []expr.Param, fn func(key []byte) error) (Result, error) {
 	var res Result
 
 	if stmt.TableName == "" {
@@ -46,13 +80,13 @@ func (stmt InsertStmt) Run(ctx context.Context, tx *database.Transaction, args [
 	}
 
 	if len(stmt.FieldNames) > 0 {
-		return stmt.insertExprList(t, stack)
+		return stmt.insertExprList(t, stack, fn)
 	}
 
-	return stmt.insertDocuments(t, stack)
+	return stmt.insertDocuments(t, stack, fn)
 }
 
-func (stmt InsertStmt) insertDocuments(t *database.Table, stack expr.EvalStack) (Result, error) {
+func (stmt InsertStmt) insertDocuments(t *database.Table, stack expr.EvalStack, fn func(key []byte) error) (Result, error) {
 	var res Result
 
 	for _, e := range stmt.Values {
@@ -65,18 +99,16 @@ func (stmt InsertStmt) insertDocuments(t *database.Table, stack expr.EvalStack)
 			return res, fmt.Errorf("expected document, got %s", v.Type)
 		}
 
-		res.LastInsertKey, err = t.Insert(v.V.(document.Document))
+		err = stmt.insert(t, stack, v.V.(document.Document), &res, fn)
 		if err != nil {
 			return res, err
 		}
-
-		res.RowsAffected++
 	}
 
 	return res, nil
 }
 
-func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (Result, error) {
+func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack, fn func(key []byte) error) (Result, error) {
 	var res Result
 
 	// iterate over all of the documents (r1, r2, r3, ...)
@@ -105,13 +137,119 @@ func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (
 			return nil
 		})
 
-		res.LastInsertKey, err = t.Insert(&fb)
+		err = stmt.insert(t, stack, &fb, &res, fn)
 		if err != nil {
 			return res, err
 		}
+	}
+
+	return res, nil
+}
+
+// insert d into the table, or update the document it conflicts with if
+// an ON CONFLICT clause was specified, and calls fn with the key of the written document.
+func (stmt InsertStmt) insert(t *database.Table, stack expr.EvalStack, d document.Document, res *Result, fn func(key []byte) error) error {
+	var err error
+
+	if stmt.OnConflict == nil {
+		res.LastInsertKey, err = t.Insert(d)
+		if err != nil {
+			return err
+		}
 
 		res.RowsAffected++
+		return callKeyFn(fn, res.LastInsertKey)
 	}
 
-	return res, nil
+	d, err = t.ValidateConstraints(d)
+	if err != nil {
+		return err
+	}
+
+	key, err := t.FindConflict(d, stmt.OnConflict.Paths...)
+	if err == database.ErrDocumentNotFound {
+		res.LastInsertKey, err = t.InsertValidated(d)
+		if err != nil {
+			return err
+		}
+
+		res.RowsAffected++
+		return callKeyFn(fn, res.LastInsertKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	if stmt.OnConflict.DoNothing {
+		return nil
+	}
+
+	old, err := t.GetDocument(key)
+	if err != nil {
+		return err
+	}
+
+	var fb document.FieldBuffer
+	err = fb.ScanDocument(old)
+	if err != nil {
+		return err
+	}
+
+	stack.Document = excludedDocument{Document: &fb, excluded: d}
+
+	if stmt.OnConflict.WhereExpr != nil {
+		v, err := stmt.OnConflict.WhereExpr.Eval(stack)
+		if err != nil {
+			return err
+		}
+
+		ok, err := v.IsTruthy()
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	for _, pair := range stmt.OnConflict.SetPairs {
+		v, err := pair.Expr.Eval(stack)
+		if err != nil && err != document.ErrFieldNotFound {
+			return err
+		}
+
+		err = fb.Set(pair.Path, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = t.Replace(key, &fb)
+	if err != nil {
+		return err
+	}
+
+	res.RowsAffected++
+	return callKeyFn(fn, key)
+}
+
+func callKeyFn(fn func(key []byte) error, key []byte) error {
+	if fn == nil {
+		return nil
+	}
+
+	return fn(key)
+}
+
+// excludedDocument gives access to the document that was rejected
+// because of a conflict, using the "excluded" field.
+type excludedDocument struct {
+	document.Document
+
+	excluded document.Document
+}
+
+func (d excludedDocument) GetByField(field string) (document.Value, error) {
+	if field == "excluded" {
+		return document.NewDocumentValue(d.excluded), nil
+	}
+
+	return d.Document.GetByField(field)
 }
@@ -40,6 +40,7 @@ func TestInsertStmt(t *testing.T) {
 		{"Documents / strings", `INSERT INTO test VALUES {'a': 'a', b: 2.3}`, false, `{"pk()":1,"a":"a","b":2.3}`, nil},
 		{"Documents / double quotes", `INSERT INTO test VALUES {"a": "b"}`, false, `{"pk()":1,"a":"b"}`, nil},
 		{"Documents / with reference to other fields", `INSERT INTO test VALUES {a: 400, b: a * 4}`, false, `{"pk()":1,"a":400,"b":1600}`, nil},
+		{"Values / Keywords as fields", `INSERT INTO test (left, do, nothing, check, all, returning, outer) VALUES (1, 2, 3, 4, 5, 6, 7)`, false, `{"pk()":1,"left":1,"do":2,"nothing":3,"check":4,"all":5,"returning":6,"outer":7}`, nil},
 		{"Read-only tables", `INSERT INTO __genji_tables VALUES {a: 400, b: a * 4}`, true, ``, nil},
 	}
 
@@ -101,6 +102,155 @@ func TestInsertStmt(t *testing.T) {
 		require.Equal(t, err, database.ErrDuplicateDocument)
 	})
 
+	t.Run("with partial unique index", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			CREATE UNIQUE INDEX idx_foo ON test (foo) WHERE status = 'pending';
+			INSERT INTO test (foo, status) VALUES (1, 'pending');
+		`)
+		require.NoError(t, err)
+
+		// only the documents matching the predicate are checked.
+		err = db.Exec(ctx, `INSERT INTO test (foo, status) VALUES (1, 'done')`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, `INSERT INTO test (foo, status) VALUES (1, 'pending')`)
+		require.Equal(t, err, database.ErrDuplicateDocument)
+	})
+
+	t.Run("with check constraints", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test (
+				price DOUBLE CHECK (price >= 0),
+				qty INTEGER,
+				CONSTRAINT max_total CHECK (price * qty <= 100)
+			);
+			INSERT INTO test (price, qty) VALUES (10, 2);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (price, qty) VALUES (-1, 2)`)
+		require.EqualError(t, err, `document violates check constraint "test_price_check"`)
+		err = db.Exec(ctx, `INSERT INTO test (price, qty) VALUES (10, 20)`)
+		require.EqualError(t, err, `document violates check constraint "max_total"`)
+
+		// the predicate is NULL if a field is missing.
+		err = db.Exec(ctx, `INSERT INTO test (qty) VALUES (1000)`)
+		require.NoError(t, err)
+
+		// values are converted before being checked.
+		err = db.Exec(ctx, `INSERT INTO test (price, qty) VALUES ('-1', 1)`)
+		require.Error(t, err)
+
+		err = db.Exec(ctx, `UPDATE test SET price = -5 WHERE qty = 2`)
+		require.EqualError(t, err, `document violates check constraint "test_price_check"`)
+		err = db.Exec(ctx, `UPDATE test SET qty = 10 WHERE qty = 2`)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, `SELECT COUNT(*) FROM test`)
+		require.NoError(t, err)
+		v, err := d.GetByField("COUNT(*)")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(2), v)
+	})
+
+	t.Run("on conflict", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			fails    bool
+			expected string
+		}{
+			{"Do nothing / Primary key", `INSERT INTO test (a, b, c) VALUES (1, 'x', 10), (3, 'z', 30) ON CONFLICT DO NOTHING`, false,
+				`[{"a": 1, "b": "a", "c": 1}, {"a": 2, "b": "b", "c": 2}, {"a": 3, "b": "z", "c": 30}]`},
+			{"Do nothing / Unique index", `INSERT INTO test (a, b) VALUES (3, 'b') ON CONFLICT DO NOTHING`, false,
+				`[{"a": 1, "b": "a", "c": 1}, {"a": 2, "b": "b", "c": 2}]`},
+			{"Do nothing / Other target", `INSERT INTO test (a, b) VALUES (3, 'b') ON CONFLICT (a) DO NOTHING`, true, ``},
+			{"Do nothing / Unknown target", `INSERT INTO test (a, b) VALUES (3, 'c') ON CONFLICT (c) DO NOTHING`, true, ``},
+			{"Do update / Primary key", `INSERT INTO test (a, b, c) VALUES (1, 'x', 10) ON CONFLICT (a) DO UPDATE SET c = c + excluded.c`, false,
+				`[{"a": 1, "b": "a", "c": 11}, {"a": 2, "b": "b", "c": 2}]`},
+			{"Do update / Unique index", `INSERT INTO test VALUES {a: 3, b: 'b', d: 'foo'} ON CONFLICT (b) DO UPDATE SET d = excluded.d`, false,
+				`[{"a": 1, "b": "a", "c": 1}, {"a": 2, "b": "b", "c": 2, "d": "foo"}]`},
+			{"Do update / Where", `INSERT INTO test (a, c) VALUES (1, 10), (2, 20) ON CONFLICT DO UPDATE SET c = excluded.c WHERE a > 1`, false,
+				`[{"a": 1, "b": "a", "c": 1}, {"a": 2, "b": "b", "c": 20}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE test (a INTEGER PRIMARY KEY);
+					CREATE UNIQUE INDEX idx_b ON test (b);
+					INSERT INTO test (a, b, c) VALUES (1, 'a', 1), (2, 'b', 2);
+				`)
+				require.NoError(t, err)
+
+				err = db.Exec(ctx, test.query)
+				if test.fails {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, "SELECT * FROM test")
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("returning", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"Wildcard", `INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b') RETURNING *`,
+				`[{"a": 1, "b": "a", "c": 10}, {"a": 2, "b": "b", "c": 10}]`},
+			{"Expressions", `INSERT INTO test (a) VALUES (1) RETURNING pk(), a + c AS sum`,
+				`[{"pk()": 1, "sum": 11}]`},
+			{"On conflict", `INSERT INTO test (a, b) VALUES (1, 'a'), (1, 'b') ON CONFLICT DO UPDATE SET b = excluded.b RETURNING a, b`,
+				`[{"a": 1, "b": "a"}, {"a": 1, "b": "b"}]`},
+			{"On conflict / Do nothing", `INSERT INTO test (a, b) VALUES (1, 'a'), (1, 'b') ON CONFLICT DO NOTHING RETURNING b`,
+				`[{"b": "a"}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, "CREATE TABLE test (a INTEGER PRIMARY KEY, c INTEGER NOT NULL DEFAULT 10)")
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
 	t.Run("with shadowing", func(t *testing.T) {
 		db, err := genji.Open(":memory:")
 		require.NoError(t, err)
@@ -21,18 +21,20 @@ type Query struct {
 	autoCommit bool
 }
 
-// Run executes all the statements in their own transaction and returns the last result.
-func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param) (*Result, error) {
+// Run executes all the statements using the session. If a transaction is attached
+// to the session, statements are run within that transaction, otherwise they are run
+// in their own transaction. It returns the last result.
+func (q Query) Run(ctx context.Context, s *database.Session, args []expr.Param) (*Result, error) {
 	var res Result
 	var err error
 
-	q.tx = db.GetAttachedTx()
+	q.tx = s.GetAttachedTx()
 	if q.tx == nil {
 		q.autoCommit = true
 	}
 
 	type queryAlterer interface {
-		alterQuery(db *database.Database, q *Query) error
+		alterQuery(s *database.Session, q *Query) error
 	}
 
 	for i, stmt := range q.Statements {
@@ -43,9 +45,9 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 		}
 
 		if qa, ok := stmt.(queryAlterer); ok {
-			err = qa.alterQuery(db, &q)
+			err = qa.alterQuery(s, &q)
 			if err != nil {
-				if tx := db.GetAttachedTx(); tx != nil {
+				if tx := s.GetAttachedTx(); tx != nil {
 					tx.Rollback()
 				}
 				return nil, err
@@ -55,7 +57,7 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 		}
 
 		if q.tx == nil {
-			q.tx, err = db.Begin(!stmt.IsReadOnly())
+			q.tx, err = s.DB().Begin(!stmt.IsReadOnly())
 			if err != nil {
 				return nil, err
 			}
@@ -70,6 +72,12 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 			return nil, err
 		}
 
+		// only the last result is returned, the streams of the others
+		// won't be read.
+		if i+1 < len(q.Statements) {
+			res.release()
+		}
+
 		// it there is an opened transaction but there are still statements
 		// to be executed, close the current transaction.
 		if q.tx != nil && q.autoCommit && i+1 < len(q.Statements) {
@@ -102,7 +110,7 @@ func (q Query) Exec(ctx context.Context, tx *database.Transaction, args []expr.P
 	var res Result
 	var err error
 
-	for _, stmt := range q.Statements {
+	for i, stmt := range q.Statements {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -113,6 +121,10 @@ func (q Query) Exec(ctx context.Context, tx *database.Transaction, args []expr.P
 		if err != nil {
 			return nil, err
 		}
+
+		if i+1 < len(q.Statements) {
+			res.release()
+		}
 	}
 
 	return &res, nil
@@ -136,6 +148,21 @@ type Result struct {
 	LastInsertKey []byte
 	Tx            *database.Transaction
 	closed        bool
+	// functions releasing the resources used by the stream.
+	releasers []func()
+}
+
+// OnClose registers a function that releases the resources used by the stream.
+// It is called when the result is closed.
+func (r *Result) OnClose(fn func()) {
+	r.releasers = append(r.releasers, fn)
+}
+
+func (r *Result) release() {
+	for _, fn := range r.releasers {
+		fn()
+	}
+	r.releasers = nil
 }
 
 // Close the result stream.
@@ -152,6 +179,7 @@ func (r *Result) Close() (err error) {
 	}
 
 	r.closed = true
+	r.release()
 
 	if r.Tx != nil {
 		if r.Tx.Writable() {
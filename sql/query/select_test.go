@@ -7,7 +7,11 @@ import (
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,10 +52,22 @@ func TestSelectStmt(t *testing.T) {
 		{"With IN op", "SELECT color FROM test WHERE color IN ['red', 'purple'] ORDER BY k", false, `[{"color":"red"}]`, nil},
 		{"With IN op on PK", "SELECT color FROM test WHERE k IN [1.1, 1.0] ORDER BY k", false, `[{"color":"red"}]`, nil},
 		{"With NOT IN op", "SELECT color FROM test WHERE color NOT IN ['red', 'purple'] ORDER BY k", false, `[{"color":"blue"}]`, nil},
+		{"With regex op", "SELECT color FROM test WHERE color =~ 'e'", false, `[{"color":"red"},{"color":"blue"}]`, nil},
+		{"With regex op and prefix", "SELECT color FROM test WHERE color =~ '^r.d$'", false, `[{"color":"red"}]`, nil},
+		{"With regex op and prefix, no match", "SELECT color FROM test WHERE color =~ '^rouge'", false, `[]`, nil},
+		{"With regex op on non text", "SELECT color FROM test WHERE size =~ '10'", false, `[]`, nil},
+		{"With regex op and params", "SELECT color FROM test WHERE color =~ ?", false, `[{"color":"blue"}]`, []interface{}{"^b"}},
+		{"With not regex op", "SELECT color FROM test WHERE color !~ '^r'", false, `[{"color":"blue"}]`, nil},
 		{"With field comparison", "SELECT * FROM test WHERE color < shape", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With group by", "SELECT * FROM test GROUP BY color", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
 		{"With group by and count", "SELECT COUNT(k) FROM test GROUP BY size", false, `[{"COUNT(k)":2},{"COUNT(k)":1}]`, nil},
 		{"With group by and count wildcard", "SELECT COUNT(*  ) FROM test GROUP BY size", false, `[{"COUNT(*  )":2},{"COUNT(*  )":1}]`, nil},
+		{"With group by multiple fields", "SELECT size, color, COUNT(*) AS c FROM test GROUP BY size, color", false, `[{"size":10,"color":"red","c":1},{"size":10,"color":"blue","c":1},{"size":null,"color":null,"c":1}]`, nil},
+		{"With group by and having", "SELECT size, COUNT(*) AS c FROM test GROUP BY size HAVING c > 1", false, `[{"size":10,"c":2}]`, nil},
+		{"With having on unprojected aggregate", "SELECT size FROM test GROUP BY size HAVING MAX(k) = 3", false, `[{"size":null}]`, nil},
+		{"With having on group field", "SELECT size, SUM(weight) AS w FROM test GROUP BY size HAVING size IS NULL", false, `[{"size":null,"w":200}]`, nil},
+		{"With having without group by", "SELECT COUNT(*) FROM test HAVING COUNT(*) > 5", false, `[]`, nil},
+		{"With having on aliased aggregate", "SELECT size, COUNT(*) AS c, COUNT(*) AS d FROM test GROUP BY size HAVING COUNT(*) > 1", false, `[{"size":10,"c":2,"d":2}]`, nil},
 		{"With order by", "SELECT * FROM test ORDER BY color", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With order by asc", "SELECT * FROM test ORDER BY color ASC", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With order by asc numeric", "SELECT * FROM test ORDER BY weight ASC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
@@ -63,6 +79,12 @@ func TestSelectStmt(t *testing.T) {
 		{"With order by desc numeric", "SELECT * FROM test ORDER BY weight DESC", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With order by desc with limit", "SELECT * FROM test ORDER BY color DESC LIMIT 2", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by desc with offset", "SELECT * FROM test ORDER BY color DESC OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
+		{"With order by multiple keys", "SELECT k FROM test ORDER BY size DESC, k DESC", false, `[{"k":2},{"k":1},{"k":3}]`, nil},
+		{"With order by multiple keys on hidden fields", "SELECT k FROM test ORDER BY size, weight DESC", false, `[{"k":3},{"k":2},{"k":1}]`, nil},
+		{"With order by nulls last", "SELECT k FROM test ORDER BY color NULLS LAST", false, `[{"k":2},{"k":1},{"k":3}]`, nil},
+		{"With order by desc nulls first", "SELECT k FROM test ORDER BY weight DESC NULLS FIRST", false, `[{"k":1},{"k":3},{"k":2}]`, nil},
+		{"With order by expr", "SELECT k FROM test ORDER BY UPPER(color) DESC", false, `[{"k":1},{"k":2},{"k":3}]`, nil},
+		{"With order by alias", "SELECT k, 10 - k AS r FROM test ORDER BY r", false, `[{"k":3,"r":7},{"k":2,"r":8},{"k":1,"r":9}]`, nil},
 		{"With order by desc with limit offset", "SELECT * FROM test ORDER BY color DESC LIMIT 1 OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by pk asc", "SELECT * FROM test ORDER BY k ASC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
 		{"With order by pk desc", "SELECT * FROM test ORDER BY k DESC", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
@@ -221,4 +243,956 @@ func TestSelectStmt(t *testing.T) {
 		require.NoError(t, err)
 		require.JSONEq(t, `[{"foo": true},{"foo": 1}, {"foo": 2},{"foo": "hello"}]`, buf.String())
 	})
+
+	t.Run("with order by and a small sort buffer", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			err = db.Exec(ctx, "INSERT INTO test (a, b) VALUES (?, ?)", (i*37)%11, i)
+			require.NoError(t, err)
+		}
+
+		queries := []string{
+			"SELECT * FROM test ORDER BY a",
+			"SELECT * FROM test ORDER BY a DESC",
+			"SELECT b FROM test ORDER BY a LIMIT 10",
+			"SELECT b FROM test ORDER BY a DESC LIMIT 10 OFFSET 45",
+			"SELECT b FROM test ORDER BY a OFFSET 95",
+		}
+
+		query := func(q string) string {
+			st, err := db.Query(ctx, q)
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			return buf.String()
+		}
+
+		for _, q := range queries {
+			t.Run(q, func(t *testing.T) {
+				db.DB.SortBufferSize = database.DefaultSortBufferSize
+				expected := query(q)
+
+				// force the documents to be written to disk
+				for _, size := range []int{1, 100, 1000} {
+					db.DB.SortBufferSize = size
+					require.Equal(t, expected, query(q))
+				}
+			})
+		}
+	})
+
+	t.Run("with regex and typed index", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test (name TEXT); CREATE INDEX idx_name ON test(name);")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (name) VALUES ('abc'), ('abd'), ('b'), ('xabc')`)
+		require.NoError(t, err)
+
+		st, err := db.Query(ctx, "SELECT name FROM test WHERE name =~ '^ab[c-z]'")
+		require.NoError(t, err)
+		defer st.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"name": "abc"},{"name": "abd"}]`, buf.String())
+	})
+}
+
+func TestSelectStmtJoin(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+	}{
+		{"Inner join", "SELECT users.name, orders.item FROM users JOIN orders ON users.id = orders.user_id", false, `[{"users.name":"alice","orders.item":"book"},{"users.name":"alice","orders.item":"pen"},{"users.name":"bob","orders.item":"car"}]`},
+		{"Inner join, reversed", "SELECT users.name, orders.item FROM orders INNER JOIN users ON orders.user_id = users.id", false, `[{"users.name":"alice","orders.item":"book"},{"users.name":"alice","orders.item":"pen"},{"users.name":"bob","orders.item":"car"}]`},
+		{"Left join", "SELECT users.name, orders.item FROM users LEFT JOIN orders ON users.id = orders.user_id", false, `[{"users.name":"alice","orders.item":"book"},{"users.name":"alice","orders.item":"pen"},{"users.name":"bob","orders.item":"car"},{"users.name":"carol","orders.item":null}]`},
+		{"Left outer join", "SELECT users.name, orders FROM users LEFT OUTER JOIN orders ON users.id = orders.user_id WHERE orders IS NULL", false, `[{"users.name":"carol","orders":null}]`},
+		{"Wildcard", "SELECT * FROM users JOIN orders ON users.id = orders.user_id AND orders.item = 'car'", false, `[{"users":{"id":2,"name":"bob"},"orders":{"oid":3,"user_id":2,"item":"car"}}]`},
+		{"Unqualified fields", "SELECT name, item FROM users JOIN orders ON id = user_id WHERE item = 'pen'", false, `[{"name":"alice","item":"pen"}]`},
+		{"Where on the outer table", "SELECT users.name, orders.item FROM users JOIN orders ON users.id = orders.user_id WHERE users.name = 'alice' AND orders.item != 'book'", false, `[{"users.name":"alice","orders.item":"pen"}]`},
+		{"Multiple joins", "SELECT users.name, orders.item, items.price FROM users JOIN orders ON users.id = orders.user_id JOIN items ON orders.item = items.name ORDER BY items.price", false, `[{"users.name":"alice","orders.item":"pen","items.price":2},{"users.name":"alice","orders.item":"book","items.price":10}]`},
+		{"Unknown table", "SELECT * FROM users JOIN foo ON users.id = foo.id", true, ``},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE users (id INTEGER PRIMARY KEY);
+					CREATE TABLE orders (oid INTEGER PRIMARY KEY);
+					CREATE TABLE items;
+				`)
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, `
+						CREATE INDEX idx_users_name ON users (name);
+						CREATE INDEX idx_orders_user_id ON orders (user_id);
+						CREATE UNIQUE INDEX idx_items_name ON items (name);
+					`)
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO users (id, name) VALUES (1, 'alice'), (2, 'bob'), (3, 'carol');
+					INSERT INTO orders (oid, user_id, item) VALUES (1, 1, 'book'), (2, 1, 'pen'), (3, 2, 'car');
+					INSERT INTO items (name, price) VALUES ('book', 10), ('pen', 2);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				defer st.Close()
+				if test.fails {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtSubquery(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+	}{
+		{"IN", "SELECT name FROM users WHERE id IN (SELECT user_id FROM orders)", false, `[{"name":"alice"},{"name":"bob"}]`},
+		{"NOT IN", "SELECT name FROM users WHERE id NOT IN (SELECT user_id FROM orders)", false, `[{"name":"carol"}]`},
+		{"Nested IN", "SELECT name FROM users WHERE id IN (SELECT user_id FROM orders WHERE item IN (SELECT name FROM items WHERE price > 5))", false, `[{"name":"alice"}]`},
+		{"EXISTS", "SELECT name FROM users WHERE EXISTS (SELECT * FROM orders WHERE orders.user_id = users.id AND item = 'car')", false, `[{"name":"bob"}]`},
+		{"NOT EXISTS", "SELECT name FROM users WHERE NOT EXISTS (SELECT * FROM orders WHERE user_id = users.id)", false, `[{"name":"carol"}]`},
+		{"EXISTS referencing the outermost table", "SELECT name FROM users WHERE EXISTS (SELECT * FROM orders WHERE user_id = users.id AND EXISTS (SELECT * FROM items WHERE name = orders.item AND price < users.id * 5))", false, `[{"name":"alice"}]`},
+		{"Scalar", "SELECT name FROM users WHERE id = (SELECT MAX(user_id) FROM orders)", false, `[{"name":"bob"}]`},
+		{"Scalar in projection", "SELECT name, (SELECT item FROM orders WHERE user_id = users.id ORDER BY oid DESC LIMIT 1) AS last FROM users", false, `[{"name":"alice","last":"pen"},{"name":"bob","last":"car"},{"name":"carol","last":null}]`},
+		{"Scalar without table", "SELECT (SELECT COUNT(*) FROM users)", false, `[{"(SELECT COUNT(*) FROM users)":3}]`},
+		{"Scalar with more than one document", "SELECT name FROM users WHERE id = (SELECT user_id FROM orders)", true, ``},
+		{"Scalar with more than one field", "SELECT name FROM users WHERE id = (SELECT * FROM orders LIMIT 1)", true, ``},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE users (id INTEGER PRIMARY KEY);
+					CREATE TABLE orders (oid INTEGER PRIMARY KEY);
+					CREATE TABLE items;
+				`)
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, `
+						CREATE INDEX idx_users_name ON users (name);
+						CREATE INDEX idx_orders_user_id ON orders (user_id);
+						CREATE UNIQUE INDEX idx_items_name ON items (name);
+					`)
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO users (id, name) VALUES (1, 'alice'), (2, 'bob'), (3, 'carol');
+					INSERT INTO orders (oid, user_id, item) VALUES (1, 1, 'book'), (2, 1, 'pen'), (3, 2, 'car');
+					INSERT INTO items (name, price) VALUES ('book', 10), ('pen', 2);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				if test.fails {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+
+	t.Run("Cached results are cleared between executions", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE foo;
+			CREATE TABLE bar;
+			INSERT INTO foo (a) VALUES (1), (2);
+			INSERT INTO bar (b) VALUES (1);
+		`)
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		q, err := parser.ParseQuery(ctx, "UPDATE foo SET a = a + 10 WHERE a IN (SELECT b FROM bar)")
+		require.NoError(t, err)
+
+		_, err = q.Exec(ctx, tx.Transaction, nil)
+		require.NoError(t, err)
+
+		err = tx.Exec(ctx, "INSERT INTO bar (b) VALUES (2)")
+		require.NoError(t, err)
+
+		// the cached result of the subquery must not be reused by another execution
+		_, err = q.Exec(ctx, tx.Transaction, nil)
+		require.NoError(t, err)
+
+		st, err := tx.Query(ctx, "SELECT a FROM foo")
+		require.NoError(t, err)
+		defer st.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"a":11},{"a":12}]`, buf.String())
+	})
+
+	t.Run("Interleaved executions", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE users;
+			CREATE TABLE orders;
+			INSERT INTO users (id, name) VALUES (1, 'alice'), (2, 'bob'), (3, 'carol');
+			INSERT INTO orders (user_id, item) VALUES (1, 'book'), (1, 'pen'), (2, 'car');
+		`)
+		require.NoError(t, err)
+
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		q, err := parser.ParseQuery(ctx, `
+			SELECT name, (SELECT COUNT(*) FROM orders WHERE user_id = users.id) AS n FROM users
+			WHERE id IN (SELECT user_id FROM orders WHERE item = ?)
+		`)
+		require.NoError(t, err)
+
+		run := func(item string) *query.Result {
+			res, err := q.Exec(ctx, tx.Transaction, []expr.Param{{Value: item}})
+			require.NoError(t, err)
+			return res
+		}
+
+		toJSON := func(res *query.Result) string {
+			var buf bytes.Buffer
+			err := document.IteratorToJSONArray(&buf, res)
+			require.NoError(t, err)
+			return buf.String()
+		}
+
+		// the second execution must not change the state of the first one.
+		book, car := run("book"), run("car")
+		require.JSONEq(t, `[{"name":"alice","n":2}]`, toJSON(book))
+		require.JSONEq(t, `[{"name":"bob","n":1}]`, toJSON(car))
+
+		// the statement can be run again.
+		require.JSONEq(t, `[{"name":"alice","n":2}]`, toJSON(run("pen")))
+	})
+}
+
+func TestSelectStmtDistinctAndCompound(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+	}{
+		{"DISTINCT", "SELECT DISTINCT color FROM foo", false, `[{"color":"red"},{"color":"blue"},{"color":null}]`},
+		{"DISTINCT numbers", "SELECT DISTINCT size FROM foo", false, `[{"size":1},{"size":2}]`},
+		{"DISTINCT mixed numbers", "SELECT DISTINCT n FROM baz", false, `[{"n":1},{"n":2}]`},
+		{"DISTINCT multiple fields", "SELECT DISTINCT color, size FROM foo ORDER BY size, color", false, `[{"color":null,"size":1},{"color":"blue","size":1},{"color":"red","size":1},{"color":"red","size":2}]`},
+		{"DISTINCT wildcard", "SELECT DISTINCT * FROM bar", false, `[{"color":"red"},{"shade":"red"}]`},
+		{"DISTINCT with LIMIT", "SELECT DISTINCT color FROM foo LIMIT 2", false, `[{"color":"red"},{"color":"blue"}]`},
+		{"UNION", "SELECT color FROM foo UNION SELECT shade FROM bar", false, `[{"color":"red"},{"color":"blue"},{"color":null}]`},
+		{"UNION ALL", "SELECT color FROM foo WHERE size = 2 UNION ALL SELECT color FROM bar", false, `[{"color":"red"},{"color":"red"},{"color":null},{"color":null}]`},
+		{"UNION with ORDER BY", "SELECT color FROM foo UNION SELECT 'green' ORDER BY color DESC LIMIT 2", false, `[{"color":"red"},{"color":"green"}]`},
+		{"INTERSECT", "SELECT color FROM foo INTERSECT SELECT shade FROM bar", false, `[{"color":"red"},{"color":null}]`},
+		{"EXCEPT", "SELECT color FROM foo EXCEPT SELECT shade FROM bar", false, `[{"color":"blue"}]`},
+		{"UNION numbers", "SELECT size FROM foo UNION SELECT 2 AS size", false, `[{"size":1},{"size":2}]`},
+		{"INTERSECT numbers", "SELECT size FROM foo INTERSECT SELECT 2 AS size", false, `[{"size":2}]`},
+		{"EXCEPT numbers", "SELECT size FROM foo EXCEPT SELECT 2 AS size", false, `[{"size":1}]`},
+		{"Chained", "SELECT color FROM foo EXCEPT SELECT 'blue' UNION SELECT 'green'", false, `[{"color":"red"},{"color":null},{"color":"green"}]`},
+		{"Subquery", "SELECT DISTINCT size FROM foo WHERE color IN (SELECT shade FROM bar UNION SELECT 'blue')", false, `[{"size":1},{"size":2}]`},
+		{"Different number of fields", "SELECT color FROM foo UNION SELECT * FROM foo", true, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE foo;
+				CREATE TABLE bar;
+				INSERT INTO foo (color, size) VALUES ('red', 1), ('blue', 1), ('red', 2.0), ('red', 1), (null, 1), ('blue', 1);
+				INSERT INTO bar (color) VALUES ('red');
+				INSERT INTO bar (shade) VALUES ('red'), ('red');
+				CREATE TABLE baz;
+				INSERT INTO baz (n) VALUES (1), (2), (1.0);
+			`)
+			require.NoError(t, err)
+
+			query := func() (string, error) {
+				st, err := db.Query(ctx, test.query)
+				if err != nil {
+					return "", err
+				}
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				return buf.String(), err
+			}
+
+			// small buffer sizes force the documents seen to be written to disk
+			for _, size := range []int{database.DefaultSortBufferSize, 1, 100} {
+				db.DB.SortBufferSize = size
+
+				res, err := query()
+				if test.fails {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, res)
+			}
+		})
+	}
+}
+
+func TestSelectStmtCompositeIndex(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+		params   []interface{}
+	}{
+		{"Eq on first field", "SELECT id FROM test WHERE tenant = 1", `[{"id":1},{"id":2},{"id":3},{"id":4}]`, nil},
+		{"Eq on all fields", "SELECT id FROM test WHERE tenant = 1 AND created = 20 AND name = 'b'", `[{"id":3}]`, nil},
+		{"Eq on all fields, no match", "SELECT id FROM test WHERE tenant = 1 AND created = 20 AND name = 'z'", `[]`, nil},
+		{"Eq and gt", "SELECT id FROM test WHERE tenant = 1 AND created > 10", `[{"id":2},{"id":3},{"id":4}]`, nil},
+		{"Eq and gte", "SELECT id FROM test WHERE tenant = 1 AND created >= 20", `[{"id":2},{"id":3},{"id":4}]`, nil},
+		{"Eq and lt", "SELECT id FROM test WHERE tenant = 1 AND created < 20", `[{"id":1}]`, nil},
+		{"Eq and lte", "SELECT id FROM test WHERE tenant = 1 AND created <= 20", `[{"id":1},{"id":2},{"id":3}]`, nil},
+		{"Eq and reversed range", "SELECT id FROM test WHERE tenant = 1 AND 20 < created", `[{"id":4}]`, nil},
+		{"Eq and double range", "SELECT id FROM test WHERE tenant = 1 AND created > 20.5", `[{"id":4}]`, nil},
+		{"Range on text", "SELECT id FROM test WHERE tenant = 1 AND created = 20 AND name > 'a'", `[{"id":3}]`, nil},
+		{"Range on first field", "SELECT id FROM test WHERE tenant > 1", `[{"id":5},{"id":6},{"id":7}]`, nil},
+		{"Eq with params", "SELECT id FROM test WHERE tenant = ? AND created > ?", `[{"id":6}]`, []interface{}{2, 10}},
+		{"Eq with NULL", "SELECT id FROM test WHERE tenant = NULL AND created > 10", `[]`, nil},
+		{"Other types", "SELECT id FROM test WHERE tenant = 2 AND created < 30", `[{"id":6}]`, nil},
+		{"Other types, gt", "SELECT id FROM test WHERE tenant = 2 AND created > 10", `[{"id":6}]`, nil},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, "CREATE TABLE test (id INTEGER PRIMARY KEY)")
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, "CREATE INDEX idx_test ON test (tenant, created, name)")
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO test (id, tenant, created, name) VALUES
+						(1, 1, 10, 'a'),
+						(2, 1, 20, 'a'),
+						(3, 1, 20, 'b'),
+						(4, 1, 30, 'a'),
+						(6, 2, 20, 'a'),
+						(7, 2, 'late', 'a');
+					INSERT INTO test (id, tenant, name) VALUES (5, 2, 'a');
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query, test.params...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtPrimaryKey(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+		params   []interface{}
+	}{
+		{"Eq", "SELECT k FROM test WHERE k = 2", `[{"k":2}]`, nil},
+		{"Eq double", "SELECT k FROM test WHERE k = 2.0", `[{"k":2}]`, nil},
+		{"Eq double with fraction", "SELECT k FROM test WHERE k = 2.5", `[]`, nil},
+		{"Eq other type", "SELECT k FROM test WHERE k = 'a'", `[]`, nil},
+		{"Eq NULL", "SELECT k FROM test WHERE k = NULL", `[]`, nil},
+		{"Gt", "SELECT k FROM test WHERE k > 0", `[{"k":2},{"k":5}]`, nil},
+		{"Gte", "SELECT k FROM test WHERE k >= 0", `[{"k":0},{"k":2},{"k":5}]`, nil},
+		{"Lt", "SELECT k FROM test WHERE k < 0", `[{"k":-3},{"k":-1}]`, nil},
+		{"Lte", "SELECT k FROM test WHERE k <= 0", `[{"k":-3},{"k":-1},{"k":0}]`, nil},
+		{"Reversed", "SELECT k FROM test WHERE 2 < k", `[{"k":5}]`, nil},
+		{"Gt double", "SELECT k FROM test WHERE k > -1.5", `[{"k":-1},{"k":0},{"k":2},{"k":5}]`, nil},
+		{"Gte double", "SELECT k FROM test WHERE k >= -1.5", `[{"k":-1},{"k":0},{"k":2},{"k":5}]`, nil},
+		{"Lt double", "SELECT k FROM test WHERE k < -1.5", `[{"k":-3}]`, nil},
+		{"Lte double", "SELECT k FROM test WHERE k <= -1.5", `[{"k":-3}]`, nil},
+		{"Gt huge double", "SELECT k FROM test WHERE k > ?", `[]`, []interface{}{1e30}},
+		{"Lt huge double", "SELECT k FROM test WHERE k < ?", `[{"k":-3},{"k":-1},{"k":0},{"k":2},{"k":5}]`, []interface{}{1e30}},
+		{"Gt tiny double", "SELECT k FROM test WHERE k > ?", `[{"k":-3},{"k":-1},{"k":0},{"k":2},{"k":5}]`, []interface{}{-1e30}},
+		{"Range", "SELECT k FROM test WHERE k > 0 AND k < 5", `[{"k":2}]`, nil},
+		{"With params", "SELECT k FROM test WHERE k > ?", `[{"k":2},{"k":5}]`, []interface{}{1}},
+	}
+
+	for _, test := range tests {
+		testFn := func(analyze bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE test (k INTEGER PRIMARY KEY);
+					INSERT INTO test (k) VALUES (2), (-1), (5), (-3), (0);
+				`)
+				require.NoError(t, err)
+
+				if analyze {
+					err = db.Exec(ctx, "ANALYZE test")
+					require.NoError(t, err)
+				}
+
+				st, err := db.Query(ctx, test.query, test.params...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("Without statistics/"+test.name, testFn(false))
+		t.Run("With statistics/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtIndexUnion(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Two indexes", "SELECT k FROM test WHERE a = 1 OR b > 30", `[{"k":1},{"k":2},{"k":4}]`},
+		{"Overlapping branches", "SELECT k FROM test WHERE a = 1 OR b >= 20", `[{"k":1},{"k":2},{"k":3},{"k":4},{"k":5}]`},
+		{"Same index", "SELECT k FROM test WHERE a = 1 OR a = 3", `[{"k":1},{"k":2},{"k":5}]`},
+		{"Primary key", "SELECT k FROM test WHERE k = 3 OR a = 1 OR k > 4", `[{"k":1},{"k":2},{"k":3},{"k":5}]`},
+		{"No match", "SELECT k FROM test WHERE a = 10 OR b > 100", `[]`},
+		{"With other conditions", "SELECT k FROM test WHERE (a = 1 OR b > 30) AND k < 4", `[{"k":1},{"k":2}]`},
+		{"Unindexed branch", "SELECT k FROM test WHERE a = 1 OR c = 1", `[{"k":1},{"k":2},{"k":3}]`},
+	}
+
+	for _, test := range tests {
+		testFn := func(bufferSize int) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				db.DB.SortBufferSize = bufferSize
+
+				err = db.Exec(ctx, `
+					CREATE TABLE test (k INTEGER PRIMARY KEY);
+					CREATE INDEX idx_a ON test (a);
+					CREATE INDEX idx_b ON test (b);
+					INSERT INTO test (k, a, b, c) VALUES
+						(1, 1, 10, 1),
+						(2, 1, 40, 2),
+						(3, 2, 20, 1),
+						(4, 2, 50, 2),
+						(5, 3, 30, 2);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run(test.name, testFn(1024*1024))
+		t.Run("Spilled/"+test.name, testFn(1))
+	}
+}
+
+func TestSelectStmtPartialIndex(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Implied predicate", "SELECT k FROM test WHERE status = 'pending' AND a > 1", `[{"k":3},{"k":4}]`},
+		{"Predicate not implied", "SELECT k FROM test WHERE a > 3", `[{"k":4},{"k":5}]`},
+		{"Implied range", "SELECT k FROM test WHERE b > 20", `[{"k":3},{"k":4},{"k":5}]`},
+		{"Range not implied", "SELECT k FROM test WHERE b > 5", `[{"k":2},{"k":3},{"k":4},{"k":5}]`},
+		{"Implied IS NOT NULL", "SELECT k FROM test WHERE c = 2", `[{"k":2},{"k":4}]`},
+		{"Unique", "SELECT k FROM test WHERE u = 1 AND status = 'pending'", `[{"k":4}]`},
+		{"Unique, predicate not implied", "SELECT k FROM test WHERE u = 1", `[{"k":2},{"k":4}]`},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, "CREATE TABLE test (k INTEGER PRIMARY KEY)")
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, `
+						CREATE INDEX idx_pending_a ON test (a) WHERE status = 'pending';
+						CREATE INDEX idx_b ON test (b) WHERE b >= 10;
+						CREATE INDEX idx_c ON test (c) WHERE c IS NOT NULL;
+						CREATE UNIQUE INDEX idx_u ON test (u) WHERE status = 'pending';
+					`)
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO test (k, a, b, c, u, status) VALUES
+						(1, 1, 5, 1, 9, 'pending'),
+						(2, 2, 15, 2, 1, 'done'),
+						(4, 4, 35, 2, 1, 'pending'),
+						(5, 5, 45, 3, 3, 'done');
+					INSERT INTO test (k, a, b, u, status) VALUES (3, 3, 25, 2, 'done');
+					UPDATE test SET status = 'pending' WHERE k = 3;
+					DELETE FROM test WHERE k = 1;
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtExpressionIndex(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		params   []interface{}
+		expected string
+	}{
+		{"Equal", "SELECT k FROM test WHERE lower(email) = 'foo@example.com'", nil, `[{"k":1}]`},
+		{"Equal with param", "SELECT k FROM test WHERE lower(email) = ?", []interface{}{"bar@example.com"}, `[{"k":2},{"k":3}]`},
+		{"Regex", "SELECT k FROM test WHERE lower(email) =~ '^ba'", nil, `[{"k":2},{"k":3},{"k":4}]`},
+		{"Other expression", "SELECT k FROM test WHERE upper(email) = 'FOO@EXAMPLE.COM'", nil, `[{"k":1}]`},
+		{"Range", "SELECT k FROM test WHERE (a + b) > 40", nil, `[{"k":3},{"k":4}]`},
+		{"Missing field", "SELECT k FROM test WHERE a + b IS NULL", nil, `[{"k":5}]`},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, "CREATE TABLE test (k INTEGER PRIMARY KEY)")
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, `
+						CREATE INDEX ON test (lower(email));
+						CREATE INDEX ON test (a + b);
+					`)
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO test (k, email, a, b) VALUES
+						(1, 'Foo@example.com', 1, 2),
+						(2, 'bar@example.com', 10, 20),
+						(3, 'BAR@example.com', 20, 30),
+						(4, 'baz@example.com', 30, 40);
+					INSERT INTO test (k, email, a) VALUES (5, 'qux@example.com', 5);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query, test.params...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtMultiValuedIndex(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		params   []interface{}
+		expected string
+	}{
+		{"IN", "SELECT k FROM test WHERE 'a' IN tags", nil, `[{"k":1},{"k":3}]`},
+		{"IN with param", "SELECT k FROM test WHERE ? IN tags", []interface{}{2}, `[{"k":4}]`},
+		{"CONTAINS", "SELECT k FROM test WHERE contains(tags, 'b')", nil, `[{"k":1},{"k":2}]`},
+		{"CONTAINS number", "SELECT k FROM test WHERE contains(tags, 1)", nil, `[{"k":4},{"k":7}]`},
+		{"CONTAINS double", "SELECT k FROM test WHERE contains(tags, 1.0)", nil, `[{"k":4},{"k":7}]`},
+		{"IN double", "SELECT k FROM test WHERE 2.0 IN tags", nil, `[{"k":4}]`},
+		{"IN integer", "SELECT k FROM test WHERE 3 IN tags", nil, `[{"k":7}]`},
+		{"OR", "SELECT k FROM test WHERE 'c' IN tags OR contains(tags, 2)", nil, `[{"k":3},{"k":4}]`},
+		{"Other field", "SELECT k FROM test WHERE 'a' IN other", nil, `[{"k":2}]`},
+		{"Not found", "SELECT k FROM test WHERE 'd' IN tags", nil, `[]`},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, "CREATE TABLE test (k INTEGER PRIMARY KEY)")
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, `
+						CREATE INDEX ON test (tags[*]);
+						CREATE INDEX ON test (other);
+					`)
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO test (k, tags, other) VALUES
+						(1, ['a', 'b', 'a'], []),
+						(2, ['b'], ['a']),
+						(3, ['x'], []),
+						(4, [1, 1.0, 2], []),
+						(5, 'a', []),
+						(7, [1.0, 3.0, 1], []);
+					INSERT INTO test (k, other) VALUES (6, []);
+					UPDATE test SET tags = ['c', 'a'] WHERE k = 3;
+					DELETE FROM test WHERE k = 6;
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query, test.params...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtFullTextIndex(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		params   []interface{}
+		expected string
+	}{
+		{"Word", "SELECT k FROM test WHERE MATCH(description, 'fox') ORDER BY k", nil, `[{"k":1},{"k":3}]`},
+		{"AND", "SELECT k FROM test WHERE MATCH(description, 'dog fox')", nil, `[{"k":3}]`},
+		{"OR", "SELECT k FROM test WHERE MATCH(description, 'brown OR sleeps') ORDER BY k", nil, `[{"k":1},{"k":2}]`},
+		{"Phrase", `SELECT k FROM test WHERE MATCH(description, '"lazy dog"')`, nil, `[{"k":2}]`},
+		{"Accents", "SELECT k FROM test WHERE MATCH(description, 'CREME')", nil, `[{"k":4}]`},
+		{"Param", "SELECT k FROM test WHERE MATCH(description, ?) ORDER BY k", []interface{}{"dog"}, `[{"k":2},{"k":3}]`},
+		{"Other condition", "SELECT k FROM test WHERE MATCH(description, 'fox') AND k > 1", nil, `[{"k":3}]`},
+		{"Not a text", "SELECT k FROM test WHERE MATCH(description, '42')", nil, `[]`},
+		{"Updated", "SELECT k FROM test WHERE MATCH(description, 'jumps')", nil, `[{"k":1}]`},
+		{"Deleted", "SELECT k FROM test WHERE MATCH(description, 'wolf')", nil, `[]`},
+	}
+
+	setup := func(t *testing.T, withIndexes bool) *genji.DB {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "CREATE TABLE test (k INTEGER PRIMARY KEY)")
+		require.NoError(t, err)
+		if withIndexes {
+			err = db.Exec(ctx, "CREATE FULLTEXT INDEX ON test (description)")
+			require.NoError(t, err)
+		}
+
+		err = db.Exec(ctx, `
+			INSERT INTO test (k, description) VALUES
+				(1, 'The quick brown fox'),
+				(2, 'The lazy dog sleeps all day long and never wakes up'),
+				(3, 'A dog and a fox'),
+				(4, 'Crème brûlée'),
+				(5, 42),
+				(6, 'The big bad wolf');
+			INSERT INTO test (k) VALUES (7);
+			UPDATE test SET description = 'The quick brown fox jumps' WHERE k = 1;
+			DELETE FROM test WHERE k = 6;
+		`)
+		require.NoError(t, err)
+
+		return db
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db := setup(t, withIndexes)
+				defer db.Close()
+
+				st, err := db.Query(ctx, test.query, test.params...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+
+	t.Run("Score", func(t *testing.T) {
+		db := setup(t, true)
+		defer db.Close()
+
+		// both documents contain "dog" once, the third one is shorter.
+		st, err := db.Query(ctx, "SELECT k, score() > 0 AS scored FROM test WHERE MATCH(description, 'dog') ORDER BY score() DESC")
+		require.NoError(t, err)
+		defer st.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"k":3,"scored":true},{"k":2,"scored":true}]`, buf.String())
+	})
+
+	t.Run("No score without index", func(t *testing.T) {
+		db := setup(t, false)
+		defer db.Close()
+
+		d, err := db.QueryDocument(ctx, "SELECT score() AS s FROM test WHERE MATCH(description, 'dog') LIMIT 1")
+		require.NoError(t, err)
+
+		v, err := d.GetByField("s")
+		require.NoError(t, err)
+		require.Equal(t, document.NullValue, v.Type)
+	})
+}
+
+func TestSelectStmtIndexOrder(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Order by index", "SELECT k FROM test ORDER BY a", `[{"k":4},{"k":5},{"k":6},{"k":2},{"k":1},{"k":3}]`},
+		{"Order by index, desc", "SELECT k FROM test ORDER BY a DESC", `[{"k":3},{"k":1},{"k":2},{"k":6},{"k":5},{"k":4}]`},
+		{"Order by index, limit", "SELECT k FROM test ORDER BY a DESC LIMIT 2 OFFSET 1", `[{"k":1},{"k":2}]`},
+		{"Order by index, nulls last", "SELECT k FROM test ORDER BY a NULLS LAST", `[{"k":5},{"k":6},{"k":2},{"k":1},{"k":3},{"k":4}]`},
+		{"Order by index, filtered", "SELECT k FROM test WHERE a > 1 ORDER BY a", `[{"k":2},{"k":1}]`},
+		{"Order by unique index", "SELECT k FROM test ORDER BY b DESC, a", `[{"k":1},{"k":2},{"k":3},{"k":4},{"k":5},{"k":6}]`},
+		{"Order by primary key, desc", "SELECT k FROM test ORDER BY k DESC LIMIT 2", `[{"k":6},{"k":5}]`},
+		{"Order by primary key, filtered", "SELECT k FROM test WHERE k < 3 ORDER BY k", `[{"k":1},{"k":2}]`},
+		{"Order by renamed field", "SELECT k AS a FROM test ORDER BY a DESC LIMIT 1", `[{"a":6}]`},
+		{"Min", "SELECT MIN(a) FROM test", `[{"MIN(a)":true}]`},
+		{"Max", "SELECT MAX(a) FROM test", `[{"MAX(a)":"x"}]`},
+		{"Min, filtered", "SELECT MIN(a) FROM test WHERE k > 4", `[{"MIN(a)":true}]`},
+		{"Max, filtered", "SELECT MAX(a) AS m FROM test WHERE k > 4", `[{"m":-2}]`},
+		{"Min of primary key", "SELECT MIN(k) FROM test", `[{"MIN(k)":1}]`},
+		{"Max of primary key", "SELECT MAX(k) FROM test", `[{"MAX(k)":6}]`},
+	}
+
+	for _, test := range tests {
+		testFn := func(withIndexes bool) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, "CREATE TABLE test (k INTEGER PRIMARY KEY)")
+				require.NoError(t, err)
+				if withIndexes {
+					err = db.Exec(ctx, "CREATE INDEX idx_a ON test (a); CREATE UNIQUE INDEX idx_b ON test (b)")
+					require.NoError(t, err)
+				}
+
+				err = db.Exec(ctx, `
+					INSERT INTO test (k, a, b) VALUES
+						(1, 3, 6),
+						(2, 1.5, 5),
+						(3, 'x', 4),
+						(5, true, 2),
+						(6, -2, 1);
+					INSERT INTO test (k, b) VALUES (4, 3);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn(false))
+		t.Run("With Index/"+test.name, testFn(true))
+	}
+}
+
+func TestSelectStmtTimestamp(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+		params   []interface{}
+	}{
+		{"Eq", "SELECT k FROM test WHERE at = '2020-06-01T00:00:00Z'", `[{"k":2}]`, nil},
+		{"Eq other time zone", "SELECT k FROM test WHERE at = '2020-06-01T02:00:00+02:00'", `[{"k":2}]`, nil},
+		{"Eq not a timestamp", "SELECT k FROM test WHERE at = 'foo'", `[]`, nil},
+		{"Gt", "SELECT k FROM test WHERE at > '2020-01-01T00:00:00Z'", `[{"k":2},{"k":3}]`, nil},
+		{"Gte", "SELECT k FROM test WHERE at >= '2020-06-01T00:00:00Z'", `[{"k":2},{"k":3}]`, nil},
+		{"Lt", "SELECT k FROM test WHERE at < '2020-06-01T00:00:00Z'", `[{"k":1}]`, nil},
+		{"Lte", "SELECT k FROM test WHERE at <= '2020-06-01T00:00:00Z'", `[{"k":1},{"k":2}]`, nil},
+		{"In", "SELECT k FROM test WHERE at IN ('2019-06-01T00:00:00Z', '2021-06-01T00:00:00Z')", `[{"k":1},{"k":3}]`, nil},
+		{"With params", "SELECT k FROM test WHERE at > ?", `[{"k":3}]`, []interface{}{"2021-01-01T00:00:00Z"}},
+	}
+
+	for _, test := range tests {
+		testFn := func(schema string) func(t *testing.T) {
+			return func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, schema)
+				require.NoError(t, err)
+
+				err = db.Exec(ctx, `
+					INSERT INTO test (k, at) VALUES
+						(1, '2019-06-01T00:00:00Z'),
+						(2, '2020-06-01T00:00:00Z'),
+						(3, '2021-06-01T00:00:00Z');
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query, test.params...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			}
+		}
+		t.Run("No Index/"+test.name, testFn("CREATE TABLE test (k INTEGER, at TIMESTAMP)"))
+		t.Run("With Index/"+test.name, testFn("CREATE TABLE test (k INTEGER, at TIMESTAMP); CREATE INDEX idx_at ON test (at)"))
+		t.Run("Primary key/"+test.name, testFn("CREATE TABLE test (k INTEGER, at TIMESTAMP PRIMARY KEY)"))
+	}
 }
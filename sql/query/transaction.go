@@ -13,16 +13,13 @@ type BeginStmt struct {
 	Writable bool
 }
 
-func (stmt BeginStmt) alterQuery(db *database.Database, q *Query) error {
+func (stmt BeginStmt) alterQuery(s *database.Session, q *Query) error {
 	if q.tx != nil {
 		return errors.New("cannot begin a transaction within a transaction")
 	}
 
 	var err error
-	q.tx, err = db.BeginTx(&database.TxOptions{
-		ReadOnly: !stmt.Writable,
-		Attached: true,
-	})
+	q.tx, err = s.Begin(stmt.Writable)
 	q.autoCommit = false
 	return err
 }
@@ -38,7 +35,7 @@ func (stmt BeginStmt) Run(ctx context.Context, tx *database.Transaction, args []
 // RollbackStmt is a statement that rollbacks the current active transaction.
 type RollbackStmt struct{}
 
-func (stmt RollbackStmt) alterQuery(db *database.Database, q *Query) error {
+func (stmt RollbackStmt) alterQuery(s *database.Session, q *Query) error {
 	if q.tx == nil || q.autoCommit == true {
 		return errors.New("cannot rollback with no active transaction")
 	}
@@ -62,7 +59,7 @@ func (stmt RollbackStmt) Run(ctx context.Context, tx *database.Transaction, args
 // CommitStmt is a statement that commits the current active transaction.
 type CommitStmt struct{}
 
-func (stmt CommitStmt) alterQuery(db *database.Database, q *Query) error {
+func (stmt CommitStmt) alterQuery(s *database.Session, q *Query) error {
 	if q.tx == nil || q.autoCommit == true {
 		return errors.New("cannot commit with no active transaction")
 	}
@@ -49,3 +49,83 @@ func TestTransactionRun(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionSessions(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test")
+	require.NoError(t, err)
+
+	s1 := db.NewSession()
+	defer s1.Close()
+	s2 := db.NewSession()
+	defer s2.Close()
+
+	// count returns the number of documents of the test table seen by the session.
+	count := func(s *genji.Session) int {
+		res, err := s.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		n, err := res.Count()
+		require.NoError(t, err)
+		return n
+	}
+
+	err = s1.Exec(ctx, "BEGIN READ ONLY")
+	require.NoError(t, err)
+	err = s2.Exec(ctx, "BEGIN")
+	require.NoError(t, err)
+
+	// transactions of other sessions and of the database are independent.
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	err = tx.Rollback()
+	require.NoError(t, err)
+
+	err = s2.Exec(ctx, "INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+	require.Equal(t, 1, count(s2))
+	require.Equal(t, 0, count(s1))
+
+	err = s2.Exec(ctx, "COMMIT")
+	require.NoError(t, err)
+	require.Nil(t, s2.GetAttachedTx())
+
+	// s1 still uses its snapshot until its transaction ends.
+	require.Equal(t, 0, count(s1))
+	err = s1.Exec(ctx, "ROLLBACK")
+	require.NoError(t, err)
+	require.Equal(t, 1, count(s1))
+
+	// closing a session rolls back its transaction.
+	err = s1.Exec(ctx, "BEGIN")
+	require.NoError(t, err)
+	err = s1.Exec(ctx, "INSERT INTO test (a) VALUES (2)")
+	require.NoError(t, err)
+	err = s1.Close()
+	require.NoError(t, err)
+	require.Equal(t, 1, count(s2))
+
+	// writable transactions can't be started while a writable
+	// transaction is attached to the default session.
+	err = db.Exec(ctx, "BEGIN")
+	require.NoError(t, err)
+	_, err = db.Begin(true)
+	require.Error(t, err)
+	tx, err = db.Begin(false)
+	require.NoError(t, err)
+	err = tx.Rollback()
+	require.NoError(t, err)
+	err = db.Exec(ctx, "ROLLBACK")
+	require.NoError(t, err)
+
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	err = tx.Rollback()
+	require.NoError(t, err)
+}
@@ -79,6 +79,36 @@ func TestUpdateStmt(t *testing.T) {
 		})
 	}
 
+	t.Run("returning", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar'), (3, 'baz');
+		`)
+		require.NoError(t, err)
+
+		st, err := db.Query(ctx, "UPDATE test SET b = 'qux' WHERE a > 1 RETURNING pk(), *")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"pk()": 2, "a": 2, "b": "qux"}, {"pk()": 3, "a": 3, "b": "qux"}]`, buf.String())
+		require.NoError(t, st.Close())
+
+		st, err = db.Query(ctx, "UPDATE test SET b = 'qux' WHERE a > 10 RETURNING *")
+		require.NoError(t, err)
+		defer st.Close()
+
+		buf.Reset()
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.JSONEq(t, `[]`, buf.String())
+	})
+
 	t.Run("with arrays", func(t *testing.T) {
 		tests := []struct {
 			name     string
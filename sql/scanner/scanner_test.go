@@ -118,31 +118,49 @@ func TestScanner_Scan(t *testing.T) {
 		// Keywords
 		{s: `ADD`, tok: scanner.ADD_KEYWORD, raw: `ADD`},
 		{s: `ALTER`, tok: scanner.ALTER, raw: `ALTER`},
+		{s: `ANALYZE`, tok: scanner.IDENT, lit: `ANALYZE`, raw: `ANALYZE`},
 		{s: `AS`, tok: scanner.AS, raw: `AS`},
 		{s: `ASC`, tok: scanner.ASC, raw: `ASC`},
 		{s: `BY`, tok: scanner.BY, raw: `BY`},
 		{s: `BEGIN`, tok: scanner.BEGIN, raw: `BEGIN`},
 		{s: `CAST`, tok: scanner.CAST, raw: `CAST`},
+		{s: `CHECK`, tok: scanner.IDENT, lit: `CHECK`, raw: `CHECK`},
 		{s: `COMMIT`, tok: scanner.COMMIT, raw: `COMMIT`},
+		{s: `CONSTRAINT`, tok: scanner.IDENT, lit: `CONSTRAINT`, raw: `CONSTRAINT`},
+		{s: `CONFLICT`, tok: scanner.IDENT, lit: `CONFLICT`, raw: `CONFLICT`},
 		{s: `CREATE`, tok: scanner.CREATE, raw: `CREATE`},
 		{s: `EXPLAIN`, tok: scanner.EXPLAIN, raw: `EXPLAIN`},
 		{s: `DEFAULT`, tok: scanner.DEFAULT, raw: `DEFAULT`},
 		{s: `DELETE`, tok: scanner.DELETE, raw: `DELETE`},
 		{s: `DESC`, tok: scanner.DESC, raw: `DESC`},
+		{s: `DISTINCT`, tok: scanner.DISTINCT, raw: `DISTINCT`},
+		{s: `DO`, tok: scanner.IDENT, lit: `DO`, raw: `DO`},
+		{s: `NOTHING`, tok: scanner.IDENT, lit: `NOTHING`, raw: `NOTHING`},
 		{s: `DROP`, tok: scanner.DROP, raw: `DROP`},
 		{s: `FIELD`, tok: scanner.FIELD, raw: `FIELD`},
 		{s: `FROM`, tok: scanner.FROM, raw: `FROM`},
+		{s: `FULLTEXT`, tok: scanner.IDENT, lit: `FULLTEXT`, raw: `FULLTEXT`},
 		{s: `GROUP`, tok: scanner.GROUP, raw: `GROUP`},
+		{s: `HAVING`, tok: scanner.HAVING, raw: `HAVING`},
+		{s: `INNER`, tok: scanner.IDENT, lit: `INNER`, raw: `INNER`},
 		{s: `INSERT`, tok: scanner.INSERT, raw: `INSERT`},
+		{s: `INTERSECT`, tok: scanner.INTERSECT, raw: `INTERSECT`},
+		{s: `UNION`, tok: scanner.UNION, raw: `UNION`},
+		{s: `ALL`, tok: scanner.IDENT, lit: `ALL`, raw: `ALL`},
+		{s: `EXCEPT`, tok: scanner.EXCEPT, raw: `EXCEPT`},
 		{s: `INTO`, tok: scanner.INTO, raw: `INTO`},
+		{s: `JOIN`, tok: scanner.JOIN, raw: `JOIN`},
+		{s: `LEFT`, tok: scanner.IDENT, lit: `LEFT`, raw: `LEFT`},
 		{s: `LIMIT`, tok: scanner.LIMIT, raw: `LIMIT`},
 		{s: `ONLY`, tok: scanner.ONLY, raw: `ONLY`},
 		{s: `OFFSET`, tok: scanner.OFFSET, raw: `OFFSET`},
 		{s: `ORDER`, tok: scanner.ORDER, raw: `ORDER`},
+		{s: `OUTER`, tok: scanner.IDENT, lit: `OUTER`, raw: `OUTER`},
 		{s: `PRIMARY`, tok: scanner.PRIMARY, raw: `PRIMARY`},
 		{s: `READ`, tok: scanner.READ, raw: `READ`},
 		{s: `REINDEX`, tok: scanner.REINDEX, raw: `REINDEX`},
 		{s: `RENAME`, tok: scanner.RENAME, raw: `RENAME`},
+		{s: `RETURNING`, tok: scanner.IDENT, lit: `RETURNING`, raw: `RETURNING`},
 		{s: `ROLLBACK`, tok: scanner.ROLLBACK, raw: `ROLLBACK`},
 		{s: `SELECT`, tok: scanner.SELECT, raw: `SELECT`},
 		{s: `SET`, tok: scanner.SET, raw: `SET`},
@@ -162,6 +180,7 @@ func TestScanner_Scan(t *testing.T) {
 		{s: "DOUBLE", tok: scanner.TYPEDOUBLE, raw: `DOUBLE`},
 		{s: "INTEGER", tok: scanner.TYPEINTEGER, raw: `INTEGER`},
 		{s: "TEXT", tok: scanner.TYPETEXT, raw: `TEXT`},
+		{s: "TIMESTAMP", tok: scanner.IDENT, lit: `TIMESTAMP`, raw: `TIMESTAMP`},
 	}
 
 	for i, tt := range tests {
@@ -84,16 +84,21 @@ const (
 	DEFAULT
 	DELETE
 	DESC
+	DISTINCT
 	DROP
+	EXCEPT
 	EXISTS
 	EXPLAIN
 	FIELD
 	FROM
 	GROUP
+	HAVING
 	IF
 	INDEX
 	INSERT
+	INTERSECT
 	INTO
+	JOIN
 	KEY
 	LIMIT
 	NOT
@@ -112,6 +117,7 @@ const (
 	TABLE
 	TO
 	TRANSACTION
+	UNION
 	UNIQUE
 	UNSET
 	UPDATE
@@ -200,13 +206,16 @@ var tokens = [...]string{
 	BEGIN:       "BEGIN",
 	COMMIT:      "COMMIT",
 	GROUP:       "GROUP",
+	HAVING:      "HAVING",
 	BY:          "BY",
 	CREATE:      "CREATE",
 	CAST:        "CAST",
 	DEFAULT:     "DEFAULT",
 	DELETE:      "DELETE",
 	DESC:        "DESC",
+	DISTINCT:    "DISTINCT",
 	DROP:        "DROP",
+	EXCEPT:      "EXCEPT",
 	EXISTS:      "EXISTS",
 	EXPLAIN:     "EXPLAIN",
 	KEY:         "KEY",
@@ -215,7 +224,9 @@ var tokens = [...]string{
 	IF:          "IF",
 	INDEX:       "INDEX",
 	INSERT:      "INSERT",
+	INTERSECT:   "INTERSECT",
 	INTO:        "INTO",
+	JOIN:        "JOIN",
 	LIMIT:       "LIMIT",
 	NOT:         "NOT",
 	OFFSET:      "OFFSET",
@@ -233,6 +244,7 @@ var tokens = [...]string{
 	TABLE:       "TABLE",
 	TO:          "TO",
 	TRANSACTION: "TRANSACTION",
+	UNION:       "UNION",
 	UNIQUE:      "UNIQUE",
 	UNSET:       "UNSET",
 	UPDATE:      "UPDATE",